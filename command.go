@@ -0,0 +1,38 @@
+package main
+
+import (
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/ratio_setting"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+)
+
+// runCommand 执行不启动服务的子命令，不是子命令时返回 false
+func runCommand(args []string) bool {
+	switch args[0] {
+	case "config":
+		runConfigCommand(args[1:])
+	case "secrets":
+		runSecretsCommand(args[1:])
+	default:
+		return false
+	}
+	return true
+}
+
+// initCommandEnvironment 子命令只加载环境变量、数据库与配置项，日志输出到标准错误以免混入命令输出
+func initCommandEnvironment() {
+	gin.DefaultWriter = os.Stderr
+	_ = godotenv.Load(".env")
+	common.LoadEnv()
+	if err := model.InitDB(); err != nil {
+		common.FatalLog("failed to initialize database: " + err.Error())
+	}
+	ratio_setting.InitRatioSettings()
+	if err := model.InitOptionMap(); err != nil {
+		common.FatalLog("failed to load options: " + err.Error())
+	}
+}
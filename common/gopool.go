@@ -22,3 +22,11 @@ func init() {
 func RelayCtxGo(ctx context.Context, f func()) {
 	relayGoPool.CtxGo(ctx, f)
 }
+
+// RelayGoPoolWorkerCount 返回转发协程池当前运行的协程数
+func RelayGoPoolWorkerCount() int32 {
+	if p, ok := relayGoPool.(interface{ WorkerCount() int32 }); ok {
+		return p.WorkerCount()
+	}
+	return 0
+}
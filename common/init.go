@@ -52,6 +52,9 @@ func LoadEnv() {
 	} else {
 		CryptoSecret = SessionSecret
 	}
+	if err := InitSecretEncryption(); err != nil {
+		log.Fatal(err)
+	}
 	if os.Getenv("SQLITE_PATH") != "" {
 		SQLitePath = os.Getenv("SQLITE_PATH")
 	}
@@ -0,0 +1,110 @@
+package logsink
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileSink 按行写入 JSONL 文件，超过大小上限时轮转为 path.1、path.2 ...
+type FileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	mu         sync.Mutex
+	file       *os.File
+	size       int64
+}
+
+func NewFileSink(path string, maxSize int64, maxBackups int) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	s := &FileSink{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) Name() string {
+	return "file"
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+	if s.maxBackups <= 0 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return s.open()
+	}
+	_ = os.Remove(fmt.Sprintf("%s.%d", s.path, s.maxBackups))
+	for i := s.maxBackups - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", s.path, i)
+		if _, err := os.Stat(from); err == nil {
+			if err := os.Rename(from, fmt.Sprintf("%s.%d", s.path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := os.Rename(s.path, s.path+".1"); err != nil {
+		return err
+	}
+	return s.open()
+}
+
+func (s *FileSink) Write(records [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	var buf bytes.Buffer
+	for _, record := range records {
+		buf.Write(record)
+		buf.WriteByte('\n')
+	}
+	// 整批写入同一个文件，避免重试时在轮转前后重复写入
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(buf.Len()) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(buf.Bytes())
+	s.size += int64(n)
+	return err
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
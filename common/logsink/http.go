@@ -0,0 +1,55 @@
+package logsink
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HttpSink 将一批记录以 NDJSON 格式 POST 到采集端，非 2xx 响应视为失败
+type HttpSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func NewHttpSink(url string, headers map[string]string, timeout time.Duration) *HttpSink {
+	return &HttpSink{url: url, headers: headers, client: &http.Client{Timeout: timeout}}
+}
+
+func (s *HttpSink) Name() string {
+	return "http"
+}
+
+func (s *HttpSink) Write(records [][]byte) error {
+	var buf bytes.Buffer
+	for _, record := range records {
+		buf.Write(record)
+		buf.WriteByte('\n')
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("log collector returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *HttpSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,235 @@
+package logsink
+
+import (
+	"context"
+	"fmt"
+	"one-api/common"
+	"one-api/common/metrics"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sink 日志的外部输出目标，Write 返回错误时整批记录会按退避策略重试
+type Sink interface {
+	Name() string
+	Write(records [][]byte) error
+	Close() error
+}
+
+var (
+	writtenRecords = metrics.NewCounterVec("new_api_log_sink_records_total",
+		"Log records written to external log sinks.", "sink")
+	droppedRecords = metrics.NewCounterVec("new_api_log_sink_dropped_total",
+		"Log records dropped by external log sinks, by reason.", "sink", "reason")
+)
+
+const (
+	dropReasonBufferFull  = "buffer_full"
+	dropReasonWriteFailed = "write_failed"
+	dropReasonShutdown    = "shutdown"
+)
+
+type options struct {
+	bufferSize    int
+	batchSize     int
+	flushInterval time.Duration
+	maxRetries    int
+	retryBackoff  time.Duration
+	maxBackoff    time.Duration
+}
+
+// worker 每个输出目标独立的缓冲队列，慢的目标不会影响其他目标
+type worker struct {
+	sink    Sink
+	opts    options
+	queue   chan []byte
+	stopped chan struct{}
+	quit    chan struct{}
+}
+
+var (
+	workers []*worker
+	lock    sync.RWMutex
+	closed  bool
+)
+
+// Init 根据 LOG_SINK_* 环境变量创建日志输出目标，未配置任何目标时不做任何事
+func Init() error {
+	opts := options{
+		bufferSize:    common.GetEnvOrDefault("LOG_SINK_BUFFER_SIZE", 10000),
+		batchSize:     common.GetEnvOrDefault("LOG_SINK_BATCH_SIZE", 100),
+		flushInterval: time.Duration(common.GetEnvOrDefault("LOG_SINK_FLUSH_INTERVAL_MS", 1000)) * time.Millisecond,
+		maxRetries:    common.GetEnvOrDefault("LOG_SINK_MAX_RETRIES", 5),
+		retryBackoff:  time.Duration(common.GetEnvOrDefault("LOG_SINK_RETRY_BACKOFF_MS", 500)) * time.Millisecond,
+		maxBackoff:    30 * time.Second,
+	}
+	if opts.bufferSize <= 0 || opts.batchSize <= 0 || opts.flushInterval <= 0 {
+		return fmt.Errorf("LOG_SINK_BUFFER_SIZE, LOG_SINK_BATCH_SIZE and LOG_SINK_FLUSH_INTERVAL_MS must be positive")
+	}
+
+	var sinks []Sink
+	if path := common.GetEnvOrDefaultString("LOG_SINK_FILE_PATH", ""); path != "" {
+		sink, err := NewFileSink(path,
+			int64(common.GetEnvOrDefault("LOG_SINK_FILE_MAX_SIZE_MB", 100))*1024*1024,
+			common.GetEnvOrDefault("LOG_SINK_FILE_MAX_BACKUPS", 10))
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+	if address := common.GetEnvOrDefaultString("LOG_SINK_SYSLOG_ADDRESS", ""); address != "" {
+		sinks = append(sinks, NewSyslogSink(
+			common.GetEnvOrDefaultString("LOG_SINK_SYSLOG_NETWORK", "udp"),
+			address,
+			common.GetEnvOrDefaultString("LOG_SINK_SYSLOG_TAG", "new-api")))
+	}
+	if url := common.GetEnvOrDefaultString("LOG_SINK_HTTP_URL", ""); url != "" {
+		headers, err := parseHeaders(common.GetEnvOrDefaultString("LOG_SINK_HTTP_HEADERS", ""))
+		if err != nil {
+			return fmt.Errorf("failed to parse LOG_SINK_HTTP_HEADERS: %w", err)
+		}
+		sinks = append(sinks, NewHttpSink(url, headers,
+			time.Duration(common.GetEnvOrDefault("LOG_SINK_HTTP_TIMEOUT_SECONDS", 10))*time.Second))
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	for _, sink := range sinks {
+		w := &worker{
+			sink:    sink,
+			opts:    opts,
+			queue:   make(chan []byte, opts.bufferSize),
+			stopped: make(chan struct{}),
+			quit:    make(chan struct{}),
+		}
+		workers = append(workers, w)
+		go w.run()
+		common.SysLog("log sink enabled: " + sink.Name())
+	}
+	return nil
+}
+
+// Enabled 是否配置了日志输出目标
+func Enabled() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return len(workers) > 0 && !closed
+}
+
+// Publish 将一条 JSON 记录放入各输出目标的缓冲队列，队列已满时丢弃并计数，不会阻塞调用方
+func Publish(record []byte) {
+	lock.RLock()
+	defer lock.RUnlock()
+	if closed {
+		return
+	}
+	for _, w := range workers {
+		select {
+		case w.queue <- record:
+		default:
+			droppedRecords.WithLabelValues(w.sink.Name(), dropReasonBufferFull).Inc()
+		}
+	}
+}
+
+// Shutdown 停止接收新记录，并在 ctx 结束前尽量写完缓冲中的记录
+func Shutdown(ctx context.Context) {
+	lock.Lock()
+	if closed {
+		lock.Unlock()
+		return
+	}
+	closed = true
+	for _, w := range workers {
+		close(w.queue)
+	}
+	lock.Unlock()
+
+	for _, w := range workers {
+		select {
+		case <-w.stopped:
+		case <-ctx.Done():
+			// 放弃剩余的重试与缓冲
+			close(w.quit)
+			<-w.stopped
+		}
+		if err := w.sink.Close(); err != nil {
+			common.SysError(fmt.Sprintf("failed to close log sink %s: %s", w.sink.Name(), err.Error()))
+		}
+	}
+}
+
+func (w *worker) run() {
+	defer close(w.stopped)
+	ticker := time.NewTicker(w.opts.flushInterval)
+	defer ticker.Stop()
+	batch := make([][]byte, 0, w.opts.batchSize)
+	for {
+		select {
+		case record, ok := <-w.queue:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) < w.opts.batchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+		if len(batch) > 0 {
+			w.flush(batch)
+			batch = make([][]byte, 0, w.opts.batchSize)
+		}
+	}
+}
+
+// flush 写入一批记录，失败时指数退避重试，超过重试次数后丢弃
+func (w *worker) flush(batch [][]byte) {
+	if len(batch) == 0 {
+		return
+	}
+	select {
+	case <-w.quit:
+		droppedRecords.WithLabelValues(w.sink.Name(), dropReasonShutdown).Add(float64(len(batch)))
+		return
+	default:
+	}
+	backoff := w.opts.retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := w.sink.Write(batch)
+		if err == nil {
+			writtenRecords.WithLabelValues(w.sink.Name()).Add(float64(len(batch)))
+			return
+		}
+		if attempt >= w.opts.maxRetries {
+			common.SysError(fmt.Sprintf("log sink %s dropped %d records after %d retries: %s", w.sink.Name(), len(batch), attempt, err.Error()))
+			droppedRecords.WithLabelValues(w.sink.Name(), dropReasonWriteFailed).Add(float64(len(batch)))
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-w.quit:
+			droppedRecords.WithLabelValues(w.sink.Name(), dropReasonShutdown).Add(float64(len(batch)))
+			return
+		}
+		backoff = min(backoff*2, w.opts.maxBackoff)
+	}
+}
+
+// parseHeaders 解析 "Key: Value" 形式、以逗号分隔的请求头
+func parseHeaders(raw string) (map[string]string, error) {
+	headers := make(map[string]string)
+	if strings.TrimSpace(raw) == "" {
+		return headers, nil
+	}
+	for _, item := range strings.Split(raw, ",") {
+		key, value, ok := strings.Cut(item, ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid header %q", item)
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
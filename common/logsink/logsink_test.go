@@ -0,0 +1,146 @@
+package logsink
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSink 记录写入的内容，前 failures 次写入返回错误
+type fakeSink struct {
+	mu       sync.Mutex
+	failures int
+	writes   int
+	records  []string
+}
+
+func (s *fakeSink) Name() string {
+	return "fake"
+}
+
+func (s *fakeSink) Write(records [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes++
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("collector unavailable")
+	}
+	for _, record := range records {
+		s.records = append(s.records, string(record))
+	}
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	return nil
+}
+
+func newTestWorker(sink Sink, maxRetries int) *worker {
+	w := &worker{
+		sink: sink,
+		opts: options{
+			bufferSize:    10,
+			batchSize:     2,
+			flushInterval: time.Hour,
+			maxRetries:    maxRetries,
+			retryBackoff:  time.Millisecond,
+			maxBackoff:    time.Millisecond,
+		},
+		queue:   make(chan []byte, 10),
+		stopped: make(chan struct{}),
+		quit:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// TestWorkerRetry 测试写入失败时退避重试，队列关闭时写完剩余的不满一批的记录
+func TestWorkerRetry(t *testing.T) {
+	sink := &fakeSink{failures: 2}
+	w := newTestWorker(sink, 5)
+	for _, record := range []string{"a", "b", "c"} {
+		w.queue <- []byte(record)
+	}
+	close(w.queue)
+	<-w.stopped
+	assert.Equal(t, []string{"a", "b", "c"}, sink.records)
+	assert.Equal(t, 4, sink.writes)
+}
+
+// TestWorkerDropAfterRetries 测试超过重试次数后丢弃该批记录，不影响后续批次
+func TestWorkerDropAfterRetries(t *testing.T) {
+	sink := &fakeSink{failures: 2}
+	w := newTestWorker(sink, 1)
+	for _, record := range []string{"a", "b", "c"} {
+		w.queue <- []byte(record)
+	}
+	close(w.queue)
+	<-w.stopped
+	assert.Equal(t, []string{"c"}, sink.records)
+}
+
+// TestFileSinkRotate 测试超过大小上限时整批写入新文件，并保留指定数量的备份
+func TestFileSinkRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "new-api.jsonl")
+	sink, err := NewFileSink(path, 10, 1)
+	require.NoError(t, err)
+	require.NoError(t, sink.Write([][]byte{[]byte(`{"a":1}`)}))
+	require.NoError(t, sink.Write([][]byte{[]byte(`{"b":2}`)}))
+	require.NoError(t, sink.Write([][]byte{[]byte(`{"c":3}`)}))
+	require.NoError(t, sink.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"c\":3}\n", string(data))
+	data, err = os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Equal(t, "{\"b\":2}\n", string(data))
+	_, err = os.Stat(path + ".2")
+	assert.True(t, os.IsNotExist(err))
+}
+
+// TestHttpSink 测试以 NDJSON 发送一批记录并带上配置的请求头，非 2xx 响应视为失败
+func TestHttpSink(t *testing.T) {
+	var body string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	headers, err := parseHeaders("Authorization: Bearer secret, X-Source: new-api")
+	require.NoError(t, err)
+	assert.Equal(t, "new-api", headers["X-Source"])
+	sink := NewHttpSink(server.URL, headers, time.Second)
+	require.NoError(t, sink.Write([][]byte{[]byte(`{"a":1}`), []byte(`{"b":2}`)}))
+	assert.Equal(t, "{\"a\":1}\n{\"b\":2}\n", body)
+
+	status = http.StatusServiceUnavailable
+	assert.Error(t, sink.Write([][]byte{[]byte(`{"a":1}`)}))
+	assert.NoError(t, sink.Close())
+}
+
+// TestParseHeaders 测试请求头格式错误时返回错误
+func TestParseHeaders(t *testing.T) {
+	headers, err := parseHeaders("  ")
+	assert.NoError(t, err)
+	assert.Empty(t, headers)
+	_, err = parseHeaders("Authorization")
+	assert.Error(t, err)
+	_, err = parseHeaders(": value")
+	assert.Error(t, err)
+}
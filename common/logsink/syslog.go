@@ -0,0 +1,73 @@
+package logsink
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// syslogPriority facility local0、severity info
+const syslogPriority = 16*8 + 6
+
+// SyslogSink 以 RFC 5424 格式发送到 syslog 服务，network 可为 udp、tcp、unix 或 unixgram
+type SyslogSink struct {
+	network  string
+	address  string
+	tag      string
+	hostname string
+	mu       sync.Mutex
+	conn     net.Conn
+}
+
+func NewSyslogSink(network string, address string, tag string) *SyslogSink {
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	return &SyslogSink{network: network, address: address, tag: tag, hostname: hostname}
+}
+
+func (s *SyslogSink) Name() string {
+	return "syslog"
+}
+
+func (s *SyslogSink) Write(records [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.address, 5*time.Second)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	for _, record := range records {
+		msg := fmt.Sprintf("<%d>1 %s %s %s %d - - %s", syslogPriority,
+			time.Now().UTC().Format(time.RFC3339Nano), s.hostname, s.tag, os.Getpid(), record)
+		// 流式连接以换行分隔消息
+		if s.network == "tcp" || s.network == "unix" {
+			msg += "\n"
+		}
+		_ = s.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if _, err := s.conn.Write([]byte(msg)); err != nil {
+			// 连接异常时在下次重试时重新建立
+			_ = s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
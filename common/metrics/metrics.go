@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Registry 网关自身的指标注册表，/metrics 接口只输出该注册表中的指标
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+func NewCounterVec(name string, help string, labels ...string) *prometheus.CounterVec {
+	return factory.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)
+}
+
+func NewHistogramVec(name string, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	return factory.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labels)
+}
+
+func NewGauge(name string, help string) prometheus.Gauge {
+	return factory.NewGauge(prometheus.GaugeOpts{Name: name, Help: help})
+}
+
+func NewGaugeFunc(name string, help string, fn func() float64) prometheus.GaugeFunc {
+	return factory.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, fn)
+}
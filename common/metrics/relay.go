@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+// OtherModelLabel 不在已配置模型列表中的模型名统一记为该标签值，避免任意模型名导致标签基数无限增长
+const OtherModelLabel = "other"
+
+var knownModels atomic.Pointer[map[string]struct{}]
+
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 20, 30, 60, 120, 300}
+
+var (
+	RelayRequests = NewCounterVec("new_api_relay_requests_total",
+		"Total relay requests.", "channel", "model", "group", "relay_format", "status_code")
+	RelayRequestDuration = NewHistogramVec("new_api_relay_request_duration_seconds",
+		"Total duration of relay requests in seconds.", latencyBuckets, "channel", "model", "group", "relay_format", "status_code")
+	RelayFirstTokenDuration = NewHistogramVec("new_api_relay_first_token_seconds",
+		"Time to first token of streaming relay requests in seconds.", latencyBuckets, "channel", "model", "group", "relay_format", "status_code")
+	UpstreamErrors = NewCounterVec("new_api_upstream_errors_total",
+		"Errors returned by upstream channels, by OpenAI error type.", "channel", "model", "type")
+	QuotaConsumed = NewCounterVec("new_api_quota_consumed_total",
+		"Quota consumed by relay requests.", "channel", "model", "group")
+	ChannelStatusEvents = NewCounterVec("new_api_channel_status_events_total",
+		"Automatic channel enable and disable events.", "channel", "event")
+	StreamScannerGoroutines = NewGauge("new_api_stream_scanner_goroutines",
+		"Running stream scanner and ping goroutines.")
+)
+
+// ChannelLabel 将渠道 id 转为标签值，未选择渠道时为空
+func ChannelLabel(channelId int) string {
+	if channelId == 0 {
+		return ""
+	}
+	return strconv.Itoa(channelId)
+}
+
+// SetKnownModels 设置允许作为标签值的模型名列表
+func SetKnownModels(models []string) {
+	modelSet := make(map[string]struct{}, len(models))
+	for _, model := range models {
+		modelSet[model] = struct{}{}
+	}
+	knownModels.Store(&modelSet)
+}
+
+// ModelLabel 将模型名转为标签值，不在已配置模型列表中的模型名记为 other
+func ModelLabel(model string) string {
+	if model == "" {
+		return ""
+	}
+	if modelSet := knownModels.Load(); modelSet != nil {
+		if _, ok := (*modelSet)[model]; ok {
+			return model
+		}
+	}
+	return OtherModelLabel
+}
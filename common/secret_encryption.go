@@ -0,0 +1,150 @@
+package common
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// secretPrefix 加密存储值的前缀，完整格式为 enc:v1:<主密钥标识>:<加密后的数据密钥>:<密文>
+const secretPrefix = "enc:v1:"
+
+var ErrSecretMasterKeyMissing = errors.New("secret is encrypted but no matching master key is configured")
+
+type secretMasterKey struct {
+	id  string
+	key []byte
+}
+
+// currentSecretMasterKey 当前用于加密的主密钥，为空时不加密
+var currentSecretMasterKey *secretMasterKey
+
+// secretMasterKeys 可用于解密的全部主密钥，包括轮换前的旧主密钥
+var secretMasterKeys = map[string]*secretMasterKey{}
+
+// parseSecretMasterKey 32 字节的 base64 字符串直接作为主密钥，其他字符串取 SHA-256 作为主密钥
+func parseSecretMasterKey(value string) *secretMasterKey {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(key) != 32 {
+		sum := sha256.Sum256([]byte(value))
+		key = sum[:]
+	}
+	id := sha256.Sum256(key)
+	return &secretMasterKey{id: hex.EncodeToString(id[:4]), key: key}
+}
+
+func readSecretKeyEnv(name string) (string, error) {
+	if value := os.Getenv(name); value != "" {
+		return value, nil
+	}
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s_FILE: %w", name, err)
+		}
+		return string(data), nil
+	}
+	return "", nil
+}
+
+// InitSecretEncryption 从 SECRET_ENCRYPTION_KEY（或 SECRET_ENCRYPTION_KEY_FILE）读取主密钥，
+// 轮换时将旧主密钥放入 SECRET_ENCRYPTION_PREVIOUS_KEYS（或对应的 _FILE），多个以逗号或换行分隔
+func InitSecretEncryption() error {
+	currentSecretMasterKey = nil
+	secretMasterKeys = map[string]*secretMasterKey{}
+	current, err := readSecretKeyEnv("SECRET_ENCRYPTION_KEY")
+	if err != nil {
+		return err
+	}
+	previous, err := readSecretKeyEnv("SECRET_ENCRYPTION_PREVIOUS_KEYS")
+	if err != nil {
+		return err
+	}
+	for _, value := range strings.FieldsFunc(previous, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if key := parseSecretMasterKey(value); key != nil {
+			secretMasterKeys[key.id] = key
+		}
+	}
+	if key := parseSecretMasterKey(current); key != nil {
+		currentSecretMasterKey = key
+		secretMasterKeys[key.id] = key
+	} else if len(secretMasterKeys) > 0 {
+		return errors.New("SECRET_ENCRYPTION_PREVIOUS_KEYS is set without SECRET_ENCRYPTION_KEY")
+	}
+	return nil
+}
+
+// SecretEncryptionEnabled 是否配置了主密钥
+func SecretEncryptionEnabled() bool {
+	return currentSecretMasterKey != nil
+}
+
+// IsEncryptedSecret 判断值是否为加密存储的格式
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, secretPrefix)
+}
+
+// SecretNeedsReencrypt 判断存储值是否需要用当前主密钥重新加密，明文与旧主密钥加密的值都需要
+func SecretNeedsReencrypt(value string) bool {
+	if value == "" || currentSecretMasterKey == nil {
+		return false
+	}
+	if !IsEncryptedSecret(value) {
+		return true
+	}
+	return !strings.HasPrefix(value, secretPrefix+currentSecretMasterKey.id+":")
+}
+
+// EncryptSecret 使用信封加密：每个值生成随机数据密钥加密，数据密钥再由主密钥加密后一并保存，
+// 未配置主密钥或值为空时原样返回
+func EncryptSecret(plaintext string) (string, error) {
+	if plaintext == "" || currentSecretMasterKey == nil {
+		return plaintext, nil
+	}
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	wrappedKey, err := EncryptAESGCM(currentSecretMasterKey.key, string(dataKey))
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := EncryptAESGCM(dataKey, plaintext)
+	if err != nil {
+		return "", err
+	}
+	return secretPrefix + currentSecretMasterKey.id + ":" + wrappedKey + ":" + ciphertext, nil
+}
+
+// DecryptSecret 解密 EncryptSecret 的结果，未加密的值原样返回
+func DecryptSecret(value string) (string, error) {
+	if !IsEncryptedSecret(value) {
+		return value, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(value, secretPrefix), ":")
+	if len(parts) != 3 {
+		return "", errors.New("invalid encrypted secret format")
+	}
+	masterKey, ok := secretMasterKeys[parts[0]]
+	if !ok {
+		return "", ErrSecretMasterKeyMissing
+	}
+	dataKey, err := DecryptAESGCM(masterKey.key, parts[1])
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+	plaintext, err := DecryptAESGCM([]byte(dataKey), parts[2])
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return plaintext, nil
+}
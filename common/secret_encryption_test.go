@@ -0,0 +1,120 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setSecretKeys 设置主密钥环境变量并重新初始化，测试结束后恢复为未加密状态
+func setSecretKeys(t *testing.T, current string, previous string) {
+	t.Setenv("SECRET_ENCRYPTION_KEY", current)
+	t.Setenv("SECRET_ENCRYPTION_PREVIOUS_KEYS", previous)
+	t.Cleanup(func() {
+		currentSecretMasterKey = nil
+		secretMasterKeys = map[string]*secretMasterKey{}
+	})
+	assert.NoError(t, InitSecretEncryption())
+}
+
+// TestSecretEncryptionDisabled 测试未配置主密钥时原样存取
+func TestSecretEncryptionDisabled(t *testing.T) {
+	setSecretKeys(t, "", "")
+	assert.False(t, SecretEncryptionEnabled())
+
+	value, err := EncryptSecret("sk-plain")
+	assert.NoError(t, err)
+	assert.Equal(t, "sk-plain", value)
+	assert.False(t, SecretNeedsReencrypt(value))
+
+	plaintext, err := DecryptSecret(value)
+	assert.NoError(t, err)
+	assert.Equal(t, "sk-plain", plaintext)
+}
+
+// TestEncryptDecryptSecret 测试加密结果的格式、随机性以及解密还原
+func TestEncryptDecryptSecret(t *testing.T) {
+	setSecretKeys(t, "master-key-1", "")
+	assert.True(t, SecretEncryptionEnabled())
+
+	first, err := EncryptSecret("sk-secret")
+	assert.NoError(t, err)
+	second, err := EncryptSecret("sk-secret")
+	assert.NoError(t, err)
+	assert.True(t, IsEncryptedSecret(first))
+	assert.Len(t, strings.Split(strings.TrimPrefix(first, secretPrefix), ":"), 3)
+	assert.NotContains(t, first, "sk-secret")
+	// 每次加密使用新的数据密钥
+	assert.NotEqual(t, first, second)
+	assert.False(t, SecretNeedsReencrypt(first))
+
+	plaintext, err := DecryptSecret(first)
+	assert.NoError(t, err)
+	assert.Equal(t, "sk-secret", plaintext)
+
+	empty, err := EncryptSecret("")
+	assert.NoError(t, err)
+	assert.Equal(t, "", empty)
+	assert.True(t, SecretNeedsReencrypt("sk-plain"))
+}
+
+// TestDecryptSecretInvalid 测试密文被篡改或格式错误时返回错误
+func TestDecryptSecretInvalid(t *testing.T) {
+	setSecretKeys(t, "master-key-1", "")
+	value, err := EncryptSecret("sk-secret")
+	assert.NoError(t, err)
+	other, err := EncryptSecret("sk-other")
+	assert.NoError(t, err)
+
+	// 密文与数据密钥不匹配时认证失败
+	parts := strings.Split(value, ":")
+	otherParts := strings.Split(other, ":")
+	parts[len(parts)-1] = otherParts[len(otherParts)-1]
+	_, err = DecryptSecret(strings.Join(parts, ":"))
+	assert.Error(t, err)
+
+	_, err = DecryptSecret(secretPrefix + "only:two")
+	assert.Error(t, err)
+}
+
+// TestSecretKeyRotation 测试轮换主密钥后旧密文仍可解密并标记为需要重新加密，移除旧主密钥后无法解密
+func TestSecretKeyRotation(t *testing.T) {
+	setSecretKeys(t, "master-key-1", "")
+	oldValue, err := EncryptSecret("sk-secret")
+	assert.NoError(t, err)
+
+	setSecretKeys(t, "master-key-2", "master-key-0,\nmaster-key-1")
+	assert.True(t, SecretNeedsReencrypt(oldValue))
+	plaintext, err := DecryptSecret(oldValue)
+	assert.NoError(t, err)
+	assert.Equal(t, "sk-secret", plaintext)
+
+	newValue, err := EncryptSecret(plaintext)
+	assert.NoError(t, err)
+	assert.False(t, SecretNeedsReencrypt(newValue))
+
+	setSecretKeys(t, "master-key-2", "")
+	_, err = DecryptSecret(oldValue)
+	assert.ErrorIs(t, err, ErrSecretMasterKeyMissing)
+	plaintext, err = DecryptSecret(newValue)
+	assert.NoError(t, err)
+	assert.Equal(t, "sk-secret", plaintext)
+}
+
+// TestInitSecretEncryptionFromFile 测试从文件读取主密钥，以及只配置旧主密钥时报错
+func TestInitSecretEncryptionFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	assert.NoError(t, os.WriteFile(path, []byte("master-key-file\n"), 0600))
+	setSecretKeys(t, "", "")
+	t.Setenv("SECRET_ENCRYPTION_KEY_FILE", path)
+	assert.NoError(t, InitSecretEncryption())
+	assert.True(t, SecretEncryptionEnabled())
+	assert.Equal(t, parseSecretMasterKey("master-key-file").id, currentSecretMasterKey.id)
+
+	t.Setenv("SECRET_ENCRYPTION_KEY_FILE", "")
+	t.Setenv("SECRET_ENCRYPTION_PREVIOUS_KEYS", "master-key-0")
+	assert.Error(t, InitSecretEncryption())
+}
@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"one-api/common"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "one-api"
+
+var enabled bool
+
+// InitTracer 初始化 OTLP/HTTP 导出，导出地址与采样率使用 OTEL_EXPORTER_OTLP_* 与 OTEL_TRACES_SAMPLER* 环境变量配置
+func InitTracer() (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", common.GetEnvOrDefaultString("OTEL_SERVICE_NAME", "new-api")),
+		attribute.String("service.version", common.Version),
+	))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	enabled = true
+	return provider.Shutdown, nil
+}
+
+func Enabled() bool {
+	return enabled
+}
+
+// Start 创建子 span，未启用追踪时返回空实现
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServer 从请求头中恢复上游调用方的追踪上下文并创建入口 span
+func StartServer(ctx context.Context, name string, header http.Header) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer))
+}
+
+// Inject 将当前追踪上下文以 W3C traceparent 写入请求头
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// SetAttributes 为 ctx 中的当前 span 添加属性
+func SetAttributes(ctx context.Context, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+}
+
+// RecordError 记录错误并将 span 标记为失败
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
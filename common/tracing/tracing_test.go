@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// useTestTracer 使用内存记录器代替 OTLP 导出，测试结束后恢复全局配置
+func useTestTracer(t *testing.T) *tracetest.SpanRecorder {
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	enabled = true
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+		enabled = false
+	})
+	return recorder
+}
+
+// TestStartServerContinuesTrace 测试入口 span 延续调用方 traceparent 中的追踪，子 span 与上游请求头属于同一追踪
+func TestStartServerContinuesTrace(t *testing.T) {
+	recorder := useTestTracer(t)
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx, server := StartServer(context.Background(), "POST /v1/chat/completions", header)
+	childCtx, child := Start(ctx, "relay.upstream", attribute.Int("channel.id", 1))
+	upstream := http.Header{}
+	Inject(childCtx, upstream)
+	RecordError(child, errors.New("upstream timeout"))
+	RecordError(child, nil)
+	child.End()
+	server.End()
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Contains(t, upstream.Get("traceparent"), "4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.Contains(t, upstream.Get("traceparent"), child.SpanContext().SpanID().String())
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "relay.upstream", spans[0].Name())
+	assert.Equal(t, server.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "upstream timeout", spans[0].Status().Description)
+	assert.Contains(t, spans[0].Attributes(), attribute.Int("channel.id", 1))
+	assert.True(t, spans[1].Parent().IsRemote())
+}
+
+// TestSetAttributes 测试为上下文中的当前 span 添加属性
+func TestSetAttributes(t *testing.T) {
+	recorder := useTestTracer(t)
+	assert.True(t, Enabled())
+	ctx, span := Start(context.Background(), "relay.distribute")
+	SetAttributes(ctx, attribute.String("model", "gpt-4o"))
+	span.End()
+	require.Len(t, recorder.Ended(), 1)
+	assert.Contains(t, recorder.Ended()[0].Attributes(), attribute.String("model", "gpt-4o"))
+}
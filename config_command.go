@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"one-api/service"
+	"os"
+)
+
+const configCommandUsage = `Usage:
+  one-api config export [-format yaml|json] [-file path] [-include-secrets -passphrase xxx]
+  one-api config import [-file path] [-dry-run] [-passphrase xxx]
+
+The passphrase can also be set with the CONFIG_PASSPHRASE environment variable.
+`
+
+// runConfigCommand 处理 config 子命令，直接读写数据库，运行中的实例会在下次同步时加载导入的渠道与配置
+func runConfigCommand(args []string) {
+	if len(args) == 0 || (args[0] != "export" && args[0] != "import") {
+		fmt.Fprint(os.Stderr, configCommandUsage)
+		os.Exit(2)
+	}
+	flags := flag.NewFlagSet("config "+args[0], flag.ExitOnError)
+	format := flags.String("format", service.ConfigFormatYAML, "export format, yaml or json")
+	file := flags.String("file", "", "document path, defaults to stdout for export and stdin for import")
+	includeSecrets := flags.Bool("include-secrets", false, "export channel keys and sensitive options encrypted with the passphrase")
+	passphrase := flags.String("passphrase", os.Getenv("CONFIG_PASSPHRASE"), "passphrase used to encrypt or decrypt secrets")
+	dryRun := flags.Bool("dry-run", false, "print the import plan without applying it")
+	_ = flags.Parse(args[1:])
+
+	initCommandEnvironment()
+
+	var err error
+	if args[0] == "export" {
+		err = exportConfigDocument(*file, *format, *includeSecrets, *passphrase)
+	} else {
+		err = importConfigDocument(*file, *dryRun, *passphrase)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "config "+args[0]+" failed: "+err.Error())
+		os.Exit(1)
+	}
+}
+
+func exportConfigDocument(file string, format string, includeSecrets bool, passphrase string) error {
+	doc, err := service.ExportConfig(service.ConfigExportOptions{
+		IncludeSecrets: includeSecrets,
+		Passphrase:     passphrase,
+	})
+	if err != nil {
+		return err
+	}
+	data, err := service.MarshalConfigDocument(doc, format)
+	if err != nil {
+		return err
+	}
+	if file == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(file, data, 0600)
+}
+
+func importConfigDocument(file string, dryRun bool, passphrase string) error {
+	var data []byte
+	var err error
+	if file == "" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return err
+	}
+	doc, err := service.ParseConfigDocument(data)
+	if err != nil {
+		return err
+	}
+	plan, err := service.PlanConfigImport(doc, passphrase)
+	if err != nil {
+		return err
+	}
+	if !dryRun {
+		if err = service.ApplyConfigImport(plan, 0, "cli"); err != nil {
+			return err
+		}
+	}
+	output, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(output))
+	return nil
+}
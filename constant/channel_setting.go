@@ -1,7 +1,9 @@
 package constant
 
 var (
-	ForceFormat                     = "force_format"        // ForceFormat 强制格式化为OpenAI格式
-	ChanelSettingProxy              = "proxy"               // Proxy 代理
-	ChannelSettingThinkingToContent = "thinking_to_content" // ThinkingToContent
+	ForceFormat                      = "force_format"        // ForceFormat 强制格式化为OpenAI格式
+	ChanelSettingProxy               = "proxy"               // Proxy 代理
+	ChannelSettingThinkingToContent  = "thinking_to_content" // ThinkingToContent
+	ChannelSettingPassTraceContext   = "pass_trace_context"  // PassTraceContext 向上游传递 W3C traceparent
+	ChannelSettingResponsesEmulation = "responses_emulation" // ResponsesEmulation 将 /v1/responses 转换为 Chat Completions 请求
 )
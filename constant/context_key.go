@@ -2,9 +2,20 @@ package constant
 
 const (
 	ContextKeyRequestStartTime = "request_start_time"
+	ContextKeyChannelStartTime = "channel_start_time"
 	ContextKeyUserSetting      = "user_setting"
 	ContextKeyUserQuota        = "user_quota"
 	ContextKeyUserStatus       = "user_status"
 	ContextKeyUserEmail        = "user_email"
 	ContextKeyUserGroup        = "user_group"
+	// 流式请求首次向客户端发送响应的时间
+	ContextKeyFirstResponseTime = "first_response_time"
+	// 批处理请求的 batch id，保存在 http.Request 的 context 中，外部请求无法伪造
+	ContextKeyBatchId = "batch_id"
+	// 本次请求最近写入的日志 id，用于关联审计记录
+	ContextKeyLogId = "log_id"
+	// 管理员在本次请求中拥有的权限集合
+	ContextKeyPermissions = "permissions"
+	// 当前转发尝试在半开熔断渠道上占用的探测名额
+	ContextKeyChannelProbe = "channel_probe"
 )
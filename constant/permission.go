@@ -0,0 +1,45 @@
+package constant
+
+// 管理接口的细粒度权限
+const (
+	PermissionChannelRead     = "channel.read"
+	PermissionChannelWrite    = "channel.write"
+	PermissionChannelKeyRead  = "channel.key.read"
+	PermissionUserRead        = "user.read"
+	PermissionUserWrite       = "user.write"
+	PermissionUserQuotaAdjust = "user.quota.adjust"
+	PermissionRedemptionRead  = "redemption.read"
+	PermissionRedemptionWrite = "redemption.write"
+	PermissionAuthCodeRead    = "auth_code.read"
+	PermissionAuthCodeWrite   = "auth_code.write"
+	PermissionLogRead         = "log.read"
+	PermissionLogWrite        = "log.write"
+	PermissionAuditRead       = "audit.read"
+	PermissionAuditWrite      = "audit.write"
+	PermissionAdminAuditRead  = "admin_audit.read"
+	PermissionStatementRead   = "statement.read"
+	PermissionOrgRead         = "org.read"
+	PermissionOrgWrite        = "org.write"
+)
+
+// AllPermissions 全部可分配的权限及说明
+var AllPermissions = map[string]string{
+	PermissionChannelRead:     "查看渠道（不含密钥）",
+	PermissionChannelWrite:    "创建、修改、测试与删除渠道",
+	PermissionChannelKeyRead:  "查看渠道密钥",
+	PermissionUserRead:        "查看用户",
+	PermissionUserWrite:       "创建、修改、封禁与删除用户",
+	PermissionUserQuotaAdjust: "调整用户额度",
+	PermissionRedemptionRead:  "查看兑换码",
+	PermissionRedemptionWrite: "创建、修改与删除兑换码",
+	PermissionAuthCodeRead:    "查看授权码",
+	PermissionAuthCodeWrite:   "创建、修改与删除授权码",
+	PermissionLogRead:         "查看日志、统计与任务",
+	PermissionLogWrite:        "删除与清理日志",
+	PermissionAuditRead:       "查看请求审计记录",
+	PermissionAuditWrite:      "开启或关闭令牌的请求审计",
+	PermissionAdminAuditRead:  "查看管理操作审计日志",
+	PermissionStatementRead:   "查看与导出账单",
+	PermissionOrgRead:         "查看组织",
+	PermissionOrgWrite:        "修改组织与组织额度",
+}
@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// hasPermission 判断当前管理员是否拥有指定权限，优先使用鉴权中间件已加载的权限
+func hasPermission(c *gin.Context, permission string) bool {
+	if granted, ok := c.Get(constant.ContextKeyPermissions); ok {
+		return granted.(map[string]bool)[permission]
+	}
+	granted, err := model.GetUserPermissions(c.GetInt("id"), c.GetInt("role"))
+	if err != nil {
+		common.SysError("failed to get user permissions: " + err.Error())
+		return false
+	}
+	c.Set(constant.ContextKeyPermissions, granted)
+	return granted[permission]
+}
+
+// redactChannelKeys 没有查看密钥权限时清空渠道密钥
+func redactChannelKeys(c *gin.Context, channels ...*model.Channel) {
+	if hasPermission(c, constant.PermissionChannelKeyRead) {
+		return
+	}
+	for _, channel := range channels {
+		if channel != nil {
+			channel.Key = ""
+		}
+	}
+}
+
+func adminRoleError(c *gin.Context, message string) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": false,
+		"message": message,
+	})
+}
+
+// GetAllPermissions 获取全部可分配的权限
+func GetAllPermissions(c *gin.Context) {
+	type permissionInfo struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	permissions := make([]permissionInfo, 0, len(constant.AllPermissions))
+	for name, description := range constant.AllPermissions {
+		permissions = append(permissions, permissionInfo{Name: name, Description: description})
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		return permissions[i].Name < permissions[j].Name
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    permissions,
+	})
+}
+
+// GetSelfPermissions 获取当前用户拥有的管理权限
+func GetSelfPermissions(c *gin.Context) {
+	granted, err := model.GetUserPermissions(c.GetInt("id"), c.GetInt("role"))
+	if err != nil {
+		adminRoleError(c, err.Error())
+		return
+	}
+	permissions := make([]string, 0, len(granted))
+	for permission := range granted {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    permissions,
+	})
+}
+
+func GetAdminRoles(c *gin.Context) {
+	roles, err := model.GetAllAdminRoles()
+	if err != nil {
+		adminRoleError(c, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    roles,
+	})
+}
+
+func AddAdminRole(c *gin.Context) {
+	var role model.AdminRole
+	if err := c.ShouldBindJSON(&role); err != nil {
+		adminRoleError(c, "无效的参数")
+		return
+	}
+	role.Id = 0
+	role.Name = strings.TrimSpace(role.Name)
+	if role.Name == "" || len(role.Name) > 64 {
+		adminRoleError(c, "角色名称不能为空且不能超过 64 个字符")
+		return
+	}
+	if err := role.ValidatePermissions(); err != nil {
+		adminRoleError(c, err.Error())
+		return
+	}
+	if err := role.Insert(); err != nil {
+		adminRoleError(c, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    role,
+	})
+}
+
+func UpdateAdminRole(c *gin.Context) {
+	var role model.AdminRole
+	if err := c.ShouldBindJSON(&role); err != nil || role.Id == 0 {
+		adminRoleError(c, "无效的参数")
+		return
+	}
+	if _, err := model.GetAdminRoleById(role.Id); err != nil {
+		adminRoleError(c, "角色不存在")
+		return
+	}
+	role.Name = strings.TrimSpace(role.Name)
+	if role.Name == "" || len(role.Name) > 64 {
+		adminRoleError(c, "角色名称不能为空且不能超过 64 个字符")
+		return
+	}
+	if err := role.ValidatePermissions(); err != nil {
+		adminRoleError(c, err.Error())
+		return
+	}
+	if err := role.Update(); err != nil {
+		adminRoleError(c, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    role,
+	})
+}
+
+func DeleteAdminRole(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := model.DeleteAdminRoleById(id); err != nil {
+		adminRoleError(c, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// AssignAdminRole 为管理员分配权限角色，admin_role_id 为 0 时恢复全部管理权限
+func AssignAdminRole(c *gin.Context) {
+	var req struct {
+		UserId      int `json:"user_id"`
+		AdminRoleId int `json:"admin_role_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.UserId == 0 {
+		adminRoleError(c, "无效的参数")
+		return
+	}
+	if _, err := model.GetUserById(req.UserId, false); err != nil {
+		adminRoleError(c, "用户不存在")
+		return
+	}
+	if err := model.AssignAdminRole(req.UserId, req.AdminRoleId); err != nil {
+		adminRoleError(c, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
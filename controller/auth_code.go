@@ -169,6 +169,7 @@ func AddAuthCode(c *gin.Context) {
 		})
 		return
 	}
+	model.RecordAdminAudit(c, model.AdminAuditActionCreate, model.AdminAuditEntityAuthCode, strconv.Itoa(authCode.Id), nil, authCode)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -197,6 +198,7 @@ func UpdateAuthCode(c *gin.Context) {
 		})
 		return
 	}
+	oldAuthCode := *originAuthCode
 
 	if statusOnly != "" {
 		// 只更新状态
@@ -244,6 +246,7 @@ func UpdateAuthCode(c *gin.Context) {
 		})
 		return
 	}
+	model.RecordAdminAudit(c, model.AdminAuditActionUpdate, model.AdminAuditEntityAuthCode, strconv.Itoa(originAuthCode.Id), oldAuthCode, originAuthCode)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -262,6 +265,7 @@ func DeleteAuthCode(c *gin.Context) {
 		return
 	}
 
+	originAuthCode, _ := model.GetAuthCodeById(id)
 	err = model.DeleteAuthCodeById(id)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -270,6 +274,7 @@ func DeleteAuthCode(c *gin.Context) {
 		})
 		return
 	}
+	model.RecordAdminAudit(c, model.AdminAuditActionDelete, model.AdminAuditEntityAuthCode, strconv.Itoa(id), originAuthCode, nil)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
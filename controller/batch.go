@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/model"
+	"one-api/service"
+	"one-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+func CreateBatch(c *gin.Context) {
+	if !operation_setting.GetBatchSetting().Enabled {
+		RelayNotImplemented(c)
+		return
+	}
+	if err := service.CheckBatchNode(); err != nil {
+		openAIErrorResponse(c, http.StatusServiceUnavailable, "batch_unavailable", err.Error())
+		return
+	}
+	var request dto.BatchRequest
+	if err := common.UnmarshalBodyReusable(c, &request); err != nil {
+		openAIErrorResponse(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if !service.BatchEndpoints[request.Endpoint] {
+		openAIErrorResponse(c, http.StatusBadRequest, "invalid_endpoint", fmt.Sprintf("unsupported endpoint: %s", request.Endpoint))
+		return
+	}
+	if request.CompletionWindow != "24h" {
+		openAIErrorResponse(c, http.StatusBadRequest, "invalid_completion_window", "completion_window must be '24h'")
+		return
+	}
+	userId := c.GetInt("id")
+	file, err := model.GetUserFileById(userId, request.InputFileId)
+	if err != nil {
+		openAIErrorResponse(c, http.StatusBadRequest, "file_not_found", "No such File object: "+request.InputFileId)
+		return
+	}
+	if file.Purpose != model.FilePurposeBatch {
+		openAIErrorResponse(c, http.StatusBadRequest, "invalid_file", "input file must have purpose 'batch'")
+		return
+	}
+	var userQuota int
+	if orgId := c.GetInt("token_org_id"); orgId != 0 {
+		userQuota, err = model.GetOrganizationBillingQuota(orgId, userId)
+	} else {
+		userQuota, err = model.GetUserQuota(userId, false)
+	}
+	if err != nil {
+		openAIErrorResponse(c, http.StatusInternalServerError, "get_user_quota_failed", err.Error())
+		return
+	}
+	if userQuota <= 0 {
+		openAIErrorResponse(c, http.StatusForbidden, "insufficient_user_quota", "user quota is not enough")
+		return
+	}
+
+	now := common.GetTimestamp()
+	batch := &model.Batch{
+		Id:               model.NewBatchId(),
+		UserId:           userId,
+		TokenId:          c.GetInt("token_id"),
+		ClientIp:         c.ClientIP(),
+		Endpoint:         request.Endpoint,
+		InputFileId:      request.InputFileId,
+		CompletionWindow: request.CompletionWindow,
+		Status:           model.BatchStatusValidating,
+		CreatedAt:        now,
+		ExpiresAt:        now + 24*60*60,
+	}
+	if len(request.Metadata) > 0 {
+		metadata, _ := json.Marshal(request.Metadata)
+		batch.Metadata = string(metadata)
+	}
+	if err = batch.Insert(); err != nil {
+		openAIErrorResponse(c, http.StatusInternalServerError, "create_batch_failed", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, batch.ToOpenAIBatch())
+}
+
+func RetrieveBatch(c *gin.Context) {
+	batch, err := model.GetUserBatchById(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		openAIErrorResponse(c, http.StatusNotFound, "batch_not_found", "No such Batch object: "+c.Param("id"))
+		return
+	}
+	c.JSON(http.StatusOK, batch.ToOpenAIBatch())
+}
+
+func CancelBatch(c *gin.Context) {
+	batch, err := model.GetUserBatchById(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		openAIErrorResponse(c, http.StatusNotFound, "batch_not_found", "No such Batch object: "+c.Param("id"))
+		return
+	}
+	if batch.Status != model.BatchStatusValidating && batch.Status != model.BatchStatusInProgress {
+		openAIErrorResponse(c, http.StatusConflict, "invalid_batch_status", fmt.Sprintf("Cannot cancel a batch with status '%s'.", batch.Status))
+		return
+	}
+	now := common.GetTimestamp()
+	ok, err := batch.TransitionStatus(batch.Status, model.BatchStatusCancelling, "cancelling_at", now)
+	if err != nil {
+		openAIErrorResponse(c, http.StatusInternalServerError, "cancel_batch_failed", err.Error())
+		return
+	}
+	if !ok {
+		openAIErrorResponse(c, http.StatusConflict, "invalid_batch_status", "The batch status changed, please retry.")
+		return
+	}
+	batch.CancellingAt = now
+	c.JSON(http.StatusOK, batch.ToOpenAIBatch())
+}
+
+func ListBatches(c *gin.Context) {
+	limit := getListLimit(c, 20, 100)
+	batches, err := model.GetUserBatches(c.GetInt("id"), c.Query("after"), limit+1)
+	if err != nil {
+		openAIErrorResponse(c, http.StatusInternalServerError, "list_batches_failed", err.Error())
+		return
+	}
+	response := dto.OpenAIListResponse[*dto.OpenAIBatch]{
+		Object: "list",
+		Data:   make([]*dto.OpenAIBatch, 0, len(batches)),
+	}
+	if len(batches) > limit {
+		batches = batches[:limit]
+		response.HasMore = true
+	}
+	for _, batch := range batches {
+		response.Data = append(response.Data, batch.ToOpenAIBatch())
+	}
+	if len(batches) > 0 {
+		response.FirstId = batches[0].Id
+		response.LastId = batches[len(batches)-1].Id
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -152,7 +152,7 @@ func GetResponseBody(method, url string, channel *model.Channel, headers http.He
 
 func updateChannelCloseAIBalance(channel *model.Channel) (float64, error) {
 	url := fmt.Sprintf("%s/dashboard/billing/credit_grants", channel.GetBaseURL())
-	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
+	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.GetFirstKey()))
 
 	if err != nil {
 		return 0, err
@@ -167,8 +167,8 @@ func updateChannelCloseAIBalance(channel *model.Channel) (float64, error) {
 }
 
 func updateChannelOpenAISBBalance(channel *model.Channel) (float64, error) {
-	url := fmt.Sprintf("https://api.openai-sb.com/sb-api/user/status?api_key=%s", channel.Key)
-	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
+	url := fmt.Sprintf("https://api.openai-sb.com/sb-api/user/status?api_key=%s", channel.GetFirstKey())
+	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.GetFirstKey()))
 	if err != nil {
 		return 0, err
 	}
@@ -191,7 +191,7 @@ func updateChannelOpenAISBBalance(channel *model.Channel) (float64, error) {
 func updateChannelAIProxyBalance(channel *model.Channel) (float64, error) {
 	url := "https://aiproxy.io/api/report/getUserOverview"
 	headers := http.Header{}
-	headers.Add("Api-Key", channel.Key)
+	headers.Add("Api-Key", channel.GetFirstKey())
 	body, err := GetResponseBody("GET", url, channel, headers)
 	if err != nil {
 		return 0, err
@@ -210,7 +210,7 @@ func updateChannelAIProxyBalance(channel *model.Channel) (float64, error) {
 
 func updateChannelAPI2GPTBalance(channel *model.Channel) (float64, error) {
 	url := "https://api.api2gpt.com/dashboard/billing/credit_grants"
-	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
+	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.GetFirstKey()))
 
 	if err != nil {
 		return 0, err
@@ -226,7 +226,7 @@ func updateChannelAPI2GPTBalance(channel *model.Channel) (float64, error) {
 
 func updateChannelSiliconFlowBalance(channel *model.Channel) (float64, error) {
 	url := "https://api.siliconflow.cn/v1/user/info"
-	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
+	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.GetFirstKey()))
 	if err != nil {
 		return 0, err
 	}
@@ -248,7 +248,7 @@ func updateChannelSiliconFlowBalance(channel *model.Channel) (float64, error) {
 
 func updateChannelDeepSeekBalance(channel *model.Channel) (float64, error) {
 	url := "https://api.deepseek.com/user/balance"
-	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
+	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.GetFirstKey()))
 	if err != nil {
 		return 0, err
 	}
@@ -277,7 +277,7 @@ func updateChannelDeepSeekBalance(channel *model.Channel) (float64, error) {
 
 func updateChannelAIGC2DBalance(channel *model.Channel) (float64, error) {
 	url := "https://api.aigc2d.com/dashboard/billing/credit_grants"
-	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
+	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.GetFirstKey()))
 	if err != nil {
 		return 0, err
 	}
@@ -292,7 +292,7 @@ func updateChannelAIGC2DBalance(channel *model.Channel) (float64, error) {
 
 func updateChannelOpenRouterBalance(channel *model.Channel) (float64, error) {
 	url := "https://openrouter.ai/api/v1/credits"
-	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
+	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.GetFirstKey()))
 	if err != nil {
 		return 0, err
 	}
@@ -308,7 +308,7 @@ func updateChannelOpenRouterBalance(channel *model.Channel) (float64, error) {
 
 func updateChannelMoonshotBalance(channel *model.Channel) (float64, error) {
 	url := "https://api.moonshot.cn/v1/users/me/balance"
-	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
+	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.GetFirstKey()))
 	if err != nil {
 		return 0, err
 	}
@@ -375,7 +375,7 @@ func updateChannelBalance(channel *model.Channel) (float64, error) {
 	}
 	url := fmt.Sprintf("%s/v1/dashboard/billing/subscription", baseURL)
 
-	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
+	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.GetFirstKey()))
 	if err != nil {
 		return 0, err
 	}
@@ -391,7 +391,7 @@ func updateChannelBalance(channel *model.Channel) (float64, error) {
 		startDate = now.AddDate(0, 0, -100).Format("2006-01-02")
 	}
 	url = fmt.Sprintf("%s/v1/dashboard/billing/usage?start_date=%s&end_date=%s", baseURL, startDate, endDate)
-	body, err = GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
+	body, err = GetResponseBody("GET", url, channel, GetAuthHeader(channel.GetFirstKey()))
 	if err != nil {
 		return 0, err
 	}
@@ -98,6 +98,8 @@ func GetAllChannels(c *gin.Context) {
 		total, _ = model.CountAllChannels()
 	}
 
+	redactChannelKeys(c, channelData...)
+
 	// calculate type counts
 	typeCounts, _ := model.CountChannelsGroupByType()
 
@@ -152,7 +154,7 @@ func FetchUpstreamModels(c *gin.Context) {
 	case common.ChannelTypeAli:
 		url = fmt.Sprintf("%s/compatible-mode/v1/models", baseURL)
 	}
-	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
+	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.GetFirstKey()))
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -238,6 +240,8 @@ func SearchChannels(c *gin.Context) {
 		channelData = channels
 	}
 
+	redactChannelKeys(c, channelData...)
+
 	// calculate type counts for search results
 	typeCounts := make(map[int64]int64)
 	for _, channel := range channelData {
@@ -280,6 +284,33 @@ func GetChannel(c *gin.Context) {
 	return
 }
 
+// GetChannelKey 获取渠道密钥，需要 channel.key.read 权限
+func GetChannelKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	channel, err := model.GetChannelById(id, true)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"key": channel.Key,
+		},
+	})
+}
+
 func AddChannel(c *gin.Context) {
 	channel := model.Channel{}
 	err := c.ShouldBindJSON(&channel)
@@ -291,7 +322,18 @@ func AddChannel(c *gin.Context) {
 		return
 	}
 	channel.CreatedTime = common.GetTimestamp()
+	if !model.IsValidChannelMultiKeyMode(channel.MultiKeyMode) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的多密钥模式",
+		})
+		return
+	}
 	keys := strings.Split(channel.Key, "\n")
+	if channel.IsMultiKey() {
+		// 多密钥模式下所有密钥保存在同一个渠道中
+		keys = []string{channel.Key}
+	}
 	if channel.Type == common.ChannelTypeVertexAi {
 		if channel.Other == "" {
 			c.JSON(http.StatusOK, gin.H{
@@ -461,7 +503,16 @@ func EditTagChannels(c *gin.Context) {
 		})
 		return
 	}
-	err = model.EditChannelByTag(channelTag.Tag, channelTag.NewTag, channelTag.ModelMapping, channelTag.Models, channelTag.Groups, channelTag.Priority, channelTag.Weight)
+	originChannels, _ := model.GetChannelsByTag(channelTag.Tag, false)
+	batchId := fmt.Sprintf("tag-%d-%s", common.GetTimestamp(), common.GetRandomString(8))
+	err = model.EditChannelByTag(channelTag.Tag, channelTag.NewTag, channelTag.ModelMapping, channelTag.Models, channelTag.Groups, channelTag.Priority, channelTag.Weight,
+		model.ChannelVersion{
+			Source:       model.ChannelVersionSourceTagEdit,
+			BatchId:      batchId,
+			Comment:      "标签 " + channelTag.Tag,
+			OperatorId:   c.GetInt("id"),
+			OperatorName: c.GetString("username"),
+		})
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -469,9 +520,17 @@ func EditTagChannels(c *gin.Context) {
 		})
 		return
 	}
+	for _, originChannel := range originChannels {
+		if updatedChannel, err := model.GetChannelById(originChannel.Id, true); err == nil {
+			model.RecordAdminAudit(c, model.AdminAuditActionUpdate, model.AdminAuditEntityChannel, strconv.Itoa(originChannel.Id), originChannel, updatedChannel)
+		}
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
+		"data": gin.H{
+			"batch_id": batchId,
+		},
 	})
 	return
 }
@@ -517,6 +576,13 @@ func UpdateChannel(c *gin.Context) {
 		})
 		return
 	}
+	if !model.IsValidChannelMultiKeyMode(channel.MultiKeyMode) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的多密钥模式",
+		})
+		return
+	}
 	if channel.Type == common.ChannelTypeVertexAi {
 		if channel.Other == "" {
 			c.JSON(http.StatusOK, gin.H{
@@ -538,7 +604,12 @@ func UpdateChannel(c *gin.Context) {
 			}
 		}
 	}
-	err = channel.Update()
+	originChannel, _ := model.GetChannelById(channel.Id, true)
+	err = channel.UpdateWithVersion(model.ChannelVersion{
+		Source:       model.ChannelVersionSourceUpdate,
+		OperatorId:   c.GetInt("id"),
+		OperatorName: c.GetString("username"),
+	})
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -546,6 +617,9 @@ func UpdateChannel(c *gin.Context) {
 		})
 		return
 	}
+	if updatedChannel, err := model.GetChannelById(channel.Id, true); err == nil {
+		model.RecordAdminAudit(c, model.AdminAuditActionUpdate, model.AdminAuditEntityChannel, strconv.Itoa(channel.Id), originChannel, updatedChannel)
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -701,3 +775,119 @@ func GetTagModels(c *gin.Context) {
 	})
 	return
 }
+
+func GetChannelKeyStatus(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	channel, err := model.GetChannelById(id, true)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"multi_key_mode": channel.MultiKeyMode,
+			"keys":           model.GetChannelKeyInfos(channel),
+		},
+	})
+}
+
+type ChannelKeyStatusRequest struct {
+	Index  int  `json:"index"`
+	Enable bool `json:"enable"`
+}
+
+func UpdateChannelKeyStatus(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	req := ChannelKeyStatusRequest{}
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "参数错误",
+		})
+		return
+	}
+	status := common.ChannelStatusManuallyDisabled
+	if req.Enable {
+		status = common.ChannelStatusEnabled
+	}
+	_, _, err = model.UpdateChannelKeyStatus(id, req.Index, status, "手动操作")
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func GetChannelBreakers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    model.GetAllChannelBreakerInfos(),
+	})
+}
+
+func GetChannelBreaker(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    model.GetChannelBreakerInfo(id),
+	})
+}
+
+func ResetChannelBreaker(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	model.ResetChannelBreaker(id)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func GetChannelRoutingStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    model.GetChannelRoutingStats(),
+	})
+}
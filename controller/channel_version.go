@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"one-api/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChannelVersions 获取渠道的配置历史版本，按版本号倒序
+func GetChannelVersions(c *gin.Context) {
+	channelId, _ := strconv.Atoi(c.Param("id"))
+	versions, err := model.GetChannelVersions(channelId)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    versions,
+	})
+}
+
+// DiffChannelVersions 比较同一渠道的两个版本，from 与 to 为版本记录 id
+func DiffChannelVersions(c *gin.Context) {
+	fromId, _ := strconv.Atoi(c.Query("from"))
+	toId, _ := strconv.Atoi(c.Query("to"))
+	from, err := model.GetChannelVersionById(fromId)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "版本 from 不存在",
+		})
+		return
+	}
+	to, err := model.GetChannelVersionById(toId)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "版本 to 不存在",
+		})
+		return
+	}
+	if from.ChannelId != to.ChannelId {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "只能比较同一渠道的版本",
+		})
+		return
+	}
+	diff, err := model.DiffChannelVersions(from, to)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"channel_id":   from.ChannelId,
+			"from_version": from.Version,
+			"to_version":   to.Version,
+			"diff":         diff,
+		},
+	})
+}
+
+var errChannelRollbackTarget = errors.New("需要提供 channel_id 与 version_id，或 batch_id")
+
+type ChannelRollbackRequest struct {
+	ChannelId int    `json:"channel_id"`
+	VersionId int    `json:"version_id"`
+	BatchId   string `json:"batch_id"`
+}
+
+// RollbackChannel 将渠道恢复到指定版本，或通过 batch_id 撤销一次标签批量编辑
+func RollbackChannel(c *gin.Context) {
+	var req ChannelRollbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "参数错误",
+		})
+		return
+	}
+	operatorId := c.GetInt("id")
+	operatorName := c.GetString("username")
+	var err error
+	var entityId string
+	switch {
+	case req.BatchId != "":
+		entityId = req.BatchId
+		err = model.RollbackChannelBatch(req.BatchId, operatorId, operatorName)
+	case req.ChannelId != 0 && req.VersionId != 0:
+		entityId = strconv.Itoa(req.ChannelId)
+		err = model.RollbackChannelVersion(req.ChannelId, req.VersionId, operatorId, operatorName)
+	default:
+		err = errChannelRollbackTarget
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	model.RecordAdminAudit(c, model.AdminAuditActionUpdate, model.AdminAuditEntityChannel, entityId, nil, req)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
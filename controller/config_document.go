@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// configPassphraseHeader 加密或解密密钥所用的口令，通过请求头传递以免出现在访问日志中
+const configPassphraseHeader = "X-Config-Passphrase"
+
+// ExportConfig 导出渠道、分组、倍率与系统配置，format 为 yaml 或 json，include_secrets 为 true 时使用口令加密导出密钥
+func ExportConfig(c *gin.Context) {
+	format := c.DefaultQuery("format", service.ConfigFormatYAML)
+	doc, err := service.ExportConfig(service.ConfigExportOptions{
+		IncludeSecrets: c.Query("include_secrets") == "true",
+		Passphrase:     c.GetHeader(configPassphraseHeader),
+	})
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	data, err := service.MarshalConfigDocument(doc, format)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	contentType := "application/yaml; charset=utf-8"
+	if format == service.ConfigFormatJSON {
+		contentType = "application/json; charset=utf-8"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=new-api-config-%d.%s", doc.ExportedAt, format))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ImportConfig 导入 YAML 或 JSON 格式的配置文档，dry_run 为 true 时只返回变更计划不写入
+func ImportConfig(c *gin.Context) {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	doc, err := service.ParseConfigDocument(data)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	plan, err := service.PlanConfigImport(doc, c.GetHeader(configPassphraseHeader))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if c.Query("dry_run") != "true" {
+		if err = service.ApplyConfigImport(plan, c.GetInt("id"), c.GetString("username")); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		service.RecordConfigImportAudit(c, plan)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    plan,
+	})
+}
@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/model"
+	"one-api/service"
+	"one-api/setting/operation_setting"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func openAIErrorResponse(c *gin.Context, statusCode int, code string, message string) {
+	c.JSON(statusCode, gin.H{
+		"error": dto.OpenAIError{
+			Message: message,
+			Type:    "invalid_request_error",
+			Code:    code,
+		},
+	})
+}
+
+func fileToOpenAIFile(file *model.File) dto.OpenAIFile {
+	return dto.OpenAIFile{
+		Id:        file.Id,
+		Object:    "file",
+		Bytes:     file.Bytes,
+		CreatedAt: file.CreatedAt,
+		Filename:  file.Filename,
+		Purpose:   file.Purpose,
+		Status:    "processed",
+	}
+}
+
+func getListLimit(c *gin.Context, defaultLimit int, maxLimit int) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		return defaultLimit
+	}
+	if limit > maxLimit {
+		return maxLimit
+	}
+	return limit
+}
+
+func UploadFile(c *gin.Context) {
+	if !operation_setting.GetBatchSetting().Enabled {
+		RelayNotImplemented(c)
+		return
+	}
+	if err := service.CheckBatchNode(); err != nil {
+		openAIErrorResponse(c, http.StatusServiceUnavailable, "batch_unavailable", err.Error())
+		return
+	}
+	purpose := c.PostForm("purpose")
+	if purpose != model.FilePurposeBatch {
+		openAIErrorResponse(c, http.StatusBadRequest, "invalid_purpose", "only purpose 'batch' is supported")
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		openAIErrorResponse(c, http.StatusBadRequest, "invalid_file", "field file is required")
+		return
+	}
+	store, err := service.GetFileStore()
+	if err != nil {
+		openAIErrorResponse(c, http.StatusInternalServerError, "file_store_error", err.Error())
+		return
+	}
+	src, err := fileHeader.Open()
+	if err != nil {
+		openAIErrorResponse(c, http.StatusBadRequest, "invalid_file", err.Error())
+		return
+	}
+	defer src.Close()
+
+	file := &model.File{
+		Id:        model.NewFileId(),
+		UserId:    c.GetInt("id"),
+		Purpose:   purpose,
+		Filename:  fileHeader.Filename,
+		CreatedAt: common.GetTimestamp(),
+	}
+	file.StoragePath = file.Id
+	maxBytes := operation_setting.GetBatchSetting().MaxFileBytes
+	file.Bytes, err = store.Save(file.StoragePath, src, maxBytes)
+	if err != nil {
+		if errors.Is(err, service.ErrFileTooLarge) {
+			openAIErrorResponse(c, http.StatusBadRequest, "file_too_large", fmt.Sprintf("file exceeds the maximum size of %d bytes", maxBytes))
+			return
+		}
+		openAIErrorResponse(c, http.StatusInternalServerError, "file_store_error", err.Error())
+		return
+	}
+	if err = file.Insert(); err != nil {
+		_ = store.Delete(file.StoragePath)
+		openAIErrorResponse(c, http.StatusInternalServerError, "file_store_error", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, fileToOpenAIFile(file))
+}
+
+func ListFiles(c *gin.Context) {
+	limit := getListLimit(c, 10000, 10000)
+	files, err := model.GetUserFiles(c.GetInt("id"), c.Query("purpose"), c.Query("after"), limit+1)
+	if err != nil {
+		openAIErrorResponse(c, http.StatusInternalServerError, "list_files_failed", err.Error())
+		return
+	}
+	response := dto.OpenAIListResponse[dto.OpenAIFile]{
+		Object: "list",
+		Data:   make([]dto.OpenAIFile, 0, len(files)),
+	}
+	if len(files) > limit {
+		files = files[:limit]
+		response.HasMore = true
+	}
+	for _, file := range files {
+		response.Data = append(response.Data, fileToOpenAIFile(file))
+	}
+	if len(files) > 0 {
+		response.FirstId = files[0].Id
+		response.LastId = files[len(files)-1].Id
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func RetrieveFile(c *gin.Context) {
+	file, err := model.GetUserFileById(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		openAIErrorResponse(c, http.StatusNotFound, "file_not_found", "No such File object: "+c.Param("id"))
+		return
+	}
+	c.JSON(http.StatusOK, fileToOpenAIFile(file))
+}
+
+func DeleteFile(c *gin.Context) {
+	file, err := model.GetUserFileById(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		openAIErrorResponse(c, http.StatusNotFound, "file_not_found", "No such File object: "+c.Param("id"))
+		return
+	}
+	store, err := service.GetFileStore()
+	if err != nil {
+		openAIErrorResponse(c, http.StatusInternalServerError, "file_store_error", err.Error())
+		return
+	}
+	if err = file.Delete(); err != nil {
+		openAIErrorResponse(c, http.StatusInternalServerError, "delete_file_failed", err.Error())
+		return
+	}
+	if err = store.Delete(file.StoragePath); err != nil {
+		common.SysError("failed to delete stored file: " + err.Error())
+	}
+	c.JSON(http.StatusOK, dto.OpenAIFileDeleted{
+		Id:      file.Id,
+		Object:  "file",
+		Deleted: true,
+	})
+}
+
+func RetrieveFileContent(c *gin.Context) {
+	file, err := model.GetUserFileById(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		openAIErrorResponse(c, http.StatusNotFound, "file_not_found", "No such File object: "+c.Param("id"))
+		return
+	}
+	store, err := service.GetFileStore()
+	if err != nil {
+		openAIErrorResponse(c, http.StatusInternalServerError, "file_store_error", err.Error())
+		return
+	}
+	reader, err := store.Open(file.StoragePath)
+	if err != nil {
+		openAIErrorResponse(c, http.StatusInternalServerError, "file_store_error", err.Error())
+		return
+	}
+	defer reader.Close()
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Length", strconv.FormatInt(file.Bytes, 10))
+	c.Status(http.StatusOK)
+	_, _ = io.Copy(c.Writer, reader)
+}
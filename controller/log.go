@@ -1,11 +1,14 @@
 package controller
 
 import (
+	"context"
 	"net/http"
 	"one-api/common"
 	"one-api/model"
+	"one-api/service"
 	"strconv"
 
+	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-gonic/gin"
 )
 
@@ -211,3 +214,130 @@ func DeleteHistoryLogs(c *gin.Context) {
 	})
 	return
 }
+
+// GetAuditRecord 按日志 id 或请求 id 获取审计记录
+func GetAuditRecord(c *gin.Context) {
+	var record *model.AuditRecord
+	var err error
+	if requestId := c.Query("request_id"); requestId != "" {
+		record, err = model.GetAuditRecordByRequestId(requestId)
+	} else if logId, _ := strconv.Atoi(c.Query("log_id")); logId > 0 {
+		record, err = model.GetAuditRecordByLogId(logId)
+	} else {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "需要提供 log_id 或 request_id",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "审计记录不存在",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    record,
+	})
+}
+
+type TokenAuditRequest struct {
+	TokenId      int  `json:"token_id"`
+	AuditEnabled bool `json:"audit_enabled"`
+}
+
+// UpdateTokenAudit 开启或关闭令牌的请求审计，令牌所有者不能自行修改
+func UpdateTokenAudit(c *gin.Context) {
+	var req TokenAuditRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.TokenId == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "参数错误",
+		})
+		return
+	}
+	token, err := model.GetTokenById(req.TokenId)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "令牌不存在",
+		})
+		return
+	}
+	oldValue := map[string]interface{}{"audit_enabled": token.AuditEnabled}
+	if err = model.UpdateTokenAuditEnabled(token, req.AuditEnabled); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	model.RecordAdminAudit(c, model.AdminAuditActionUpdate, model.AdminAuditEntityToken, strconv.Itoa(token.Id),
+		oldValue, map[string]interface{}{"audit_enabled": req.AuditEnabled})
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// GetLogRetentionStatus 获取日志定期清理的执行进度
+func GetLogRetentionStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    service.GetLogRetentionStatus(),
+	})
+}
+
+// RunLogRetention 立即在后台执行一次日志清理
+func RunLogRetention(c *gin.Context) {
+	if service.GetLogRetentionStatus().Running {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "日志清理正在执行中",
+		})
+		return
+	}
+	gopool.Go(func() {
+		_ = service.RunLogRetention(context.Background())
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// GetLogDailyStats 查询日志清理前汇总的按天统计
+func GetLogDailyStats(c *gin.Context) {
+	p, _ := strconv.Atoi(c.Query("p"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if p < 1 {
+		p = 1
+	}
+	if pageSize <= 0 {
+		pageSize = common.ItemsPerPage
+	}
+	logType, _ := strconv.Atoi(c.Query("type"))
+	stats, total, err := model.GetLogDailyStats(logType, c.Query("start_day"), c.Query("end_day"),
+		c.Query("username"), c.Query("model_name"), (p-1)*pageSize, pageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": map[string]any{
+			"items":     stats,
+			"total":     total,
+			"page":      p,
+			"page_size": pageSize,
+		},
+	})
+}
@@ -0,0 +1,15 @@
+package controller
+
+import (
+	"one-api/common/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var metricsHandler = promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})
+
+// GetMetrics 以 Prometheus 文本格式输出指标
+func GetMetrics(c *gin.Context) {
+	metricsHandler.ServeHTTP(c.Writer, c.Request)
+}
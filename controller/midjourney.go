@@ -17,6 +17,12 @@ import (
 	"time"
 )
 
+// mjTaskGroup 轮询 Midjourney 任务时的分组键
+type mjTaskGroup struct {
+	channelId int
+	keyIndex  int
+}
+
 func UpdateMidjourneyTaskBulk() {
 	//imageModel := "midjourney"
 	ctx := context.TODO()
@@ -29,7 +35,8 @@ func UpdateMidjourneyTaskBulk() {
 		}
 
 		common.LogInfo(ctx, fmt.Sprintf("检测到未完成的任务数有: %v", len(tasks)))
-		taskChannelM := make(map[int][]string)
+		// 多密钥渠道的任务只能用提交时的密钥查询，按渠道和密钥下标分组
+		taskChannelM := make(map[mjTaskGroup][]string)
 		taskM := make(map[string]*model.Midjourney)
 		nullTaskIds := make([]int, 0)
 		for _, task := range tasks {
@@ -39,7 +46,8 @@ func UpdateMidjourneyTaskBulk() {
 				continue
 			}
 			taskM[task.MjId] = task
-			taskChannelM[task.ChannelId] = append(taskChannelM[task.ChannelId], task.MjId)
+			group := mjTaskGroup{channelId: task.ChannelId, keyIndex: task.KeyIndex}
+			taskChannelM[group] = append(taskChannelM[group], task.MjId)
 		}
 		if len(nullTaskIds) > 0 {
 			err := model.MjBulkUpdateByTaskIds(nullTaskIds, map[string]any{
@@ -56,7 +64,8 @@ func UpdateMidjourneyTaskBulk() {
 			continue
 		}
 
-		for channelId, taskIds := range taskChannelM {
+		for group, taskIds := range taskChannelM {
+			channelId := group.channelId
 			common.LogInfo(ctx, fmt.Sprintf("渠道 #%d 未完成的任务有: %d", channelId, len(taskIds)))
 			if len(taskIds) == 0 {
 				continue
@@ -90,7 +99,7 @@ func UpdateMidjourneyTaskBulk() {
 			// 使用带有超时的 context 创建新的请求
 			req = req.WithContext(ctx)
 			req.Header.Set("Content-Type", "application/json")
-			req.Header.Set("mj-api-secret", midjourneyChannel.Key)
+			req.Header.Set("mj-api-secret", midjourneyChannel.GetKeyByIndex(group.keyIndex))
 			resp, err := service.GetHttpClient().Do(req)
 			if err != nil {
 				common.LogError(ctx, fmt.Sprintf("Get Task Do req error: %v", err))
@@ -158,7 +167,7 @@ func UpdateMidjourneyTaskBulk() {
 					common.LogError(ctx, "UpdateMidjourneyTask task error: "+err.Error())
 				} else {
 					if shouldReturnQuota {
-						err = model.IncreaseUserQuota(task.UserId, task.Quota, false)
+						err = model.RefundBillingQuota(task.UserId, task.OrgId, task.Quota)
 						if err != nil {
 							common.LogError(ctx, "fail to increase user quota: "+err.Error())
 						}
@@ -142,76 +142,66 @@ func init() {
 	})
 }
 
+// getTokenModels 返回当前令牌可以使用的模型，令牌限制了模型时返回限制的模型，否则返回令牌分组下的模型
+func getTokenModels(c *gin.Context) ([]string, error) {
+	if c.GetBool("token_model_limit_enabled") {
+		var models []string
+		if s, ok := c.Get("token_model_limit"); ok {
+			for allowModel := range s.(map[string]bool) {
+				models = append(models, allowModel)
+			}
+		}
+		return models, nil
+	}
+	userGroup, err := model.GetUserGroup(c.GetInt("id"), true)
+	if err != nil {
+		return nil, err
+	}
+	group := userGroup
+	tokenGroup := c.GetString("token_group")
+	if tokenGroup != "" {
+		group = tokenGroup
+	}
+	if tokenGroup != "auto" {
+		return model.GetGroupModels(group), nil
+	}
+	var models []string
+	for _, autoGroup := range setting.AutoGroups {
+		groupModels := model.GetGroupModels(autoGroup)
+		for _, g := range groupModels {
+			if !common.StringsContains(models, g) {
+				models = append(models, g)
+			}
+		}
+	}
+	return models, nil
+}
+
 func ListModels(c *gin.Context) {
 	userOpenAiModels := make([]dto.OpenAIModels, 0)
 	permission := getPermission()
 
-	modelLimitEnable := c.GetBool("token_model_limit_enabled")
-	if modelLimitEnable {
-		s, ok := c.Get("token_model_limit")
-		var tokenModelLimit map[string]bool
-		if ok {
-			tokenModelLimit = s.(map[string]bool)
+	models, err := getTokenModels(c)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "get user group failed",
+		})
+		return
+	}
+	for _, s := range models {
+		if _, ok := openAIModelsMap[s]; ok {
+			userOpenAiModels = append(userOpenAiModels, openAIModelsMap[s])
 		} else {
-			tokenModelLimit = map[string]bool{}
-		}
-		for allowModel, _ := range tokenModelLimit {
-			if _, ok := openAIModelsMap[allowModel]; ok {
-				userOpenAiModels = append(userOpenAiModels, openAIModelsMap[allowModel])
-			} else {
-				userOpenAiModels = append(userOpenAiModels, dto.OpenAIModels{
-					Id:         allowModel,
-					Object:     "model",
-					Created:    1626777600,
-					OwnedBy:    "custom",
-					Permission: permission,
-					Root:       allowModel,
-					Parent:     nil,
-				})
-			}
-		}
-	} else {
-		userId := c.GetInt("id")
-		userGroup, err := model.GetUserGroup(userId, true)
-		if err != nil {
-			c.JSON(http.StatusOK, gin.H{
-				"success": false,
-				"message": "get user group failed",
+			userOpenAiModels = append(userOpenAiModels, dto.OpenAIModels{
+				Id:         s,
+				Object:     "model",
+				Created:    1626777600,
+				OwnedBy:    "custom",
+				Permission: permission,
+				Root:       s,
+				Parent:     nil,
 			})
-			return
-		}
-		group := userGroup
-		tokenGroup := c.GetString("token_group")
-		if tokenGroup != "" {
-			group = tokenGroup
-		}
-		var models []string
-		if tokenGroup == "auto" {
-			for _, autoGroup := range setting.AutoGroups {
-				groupModels := model.GetGroupModels(autoGroup)
-				for _, g := range groupModels {
-					if !common.StringsContains(models, g) {
-						models = append(models, g)
-					}
-				}
-			}
-		} else {
-			models = model.GetGroupModels(group)
-		}
-		for _, s := range models {
-			if _, ok := openAIModelsMap[s]; ok {
-				userOpenAiModels = append(userOpenAiModels, openAIModelsMap[s])
-			} else {
-				userOpenAiModels = append(userOpenAiModels, dto.OpenAIModels{
-					Id:         s,
-					Object:     "model",
-					Created:    1626777600,
-					OwnedBy:    "custom",
-					Permission: permission,
-					Root:       s,
-					Parent:     nil,
-				})
-			}
 		}
 	}
 	c.JSON(200, gin.H{
@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"one-api/relay/channel/ollama"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OllamaListModels Ollama 的 /api/tags，返回当前令牌可以使用的模型
+func OllamaListModels(c *gin.Context) {
+	models, err := getTokenModels(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "get user group failed"})
+		return
+	}
+	modifiedAt := time.Unix(1626777600, 0).UTC().Format(time.RFC3339)
+	response := ollama.OllamaTagsResponse{Models: make([]ollama.OllamaModel, 0, len(models))}
+	for _, name := range models {
+		digest := sha256.Sum256([]byte(name))
+		response.Models = append(response.Models, ollama.OllamaModel{
+			Name:       name,
+			Model:      name,
+			ModifiedAt: modifiedAt,
+			Digest:     hex.EncodeToString(digest[:]),
+			Details:    ollama.OllamaModelDetails{Families: []string{}},
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
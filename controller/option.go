@@ -18,7 +18,7 @@ func GetOptions(c *gin.Context) {
 	var options []*model.Option
 	common.OptionMapRWMutex.Lock()
 	for k, v := range common.OptionMap {
-		if strings.HasSuffix(k, "Token") || strings.HasSuffix(k, "Secret") || strings.HasSuffix(k, "Key") {
+		if strings.HasSuffix(k, "Token") || strings.HasSuffix(k, "Secret") || strings.HasSuffix(k, "Key") || strings.HasSuffix(k, ".token") {
 			continue
 		}
 		options = append(options, &model.Option{
@@ -158,6 +158,9 @@ func UpdateOption(c *gin.Context) {
 			return
 		}
 	}
+	common.OptionMapRWMutex.RLock()
+	oldValue, existed := common.OptionMap[option.Key]
+	common.OptionMapRWMutex.RUnlock()
 	err = model.UpdateOption(option.Key, option.Value)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -166,6 +169,11 @@ func UpdateOption(c *gin.Context) {
 		})
 		return
 	}
+	var oldOption map[string]interface{}
+	if existed {
+		oldOption = map[string]interface{}{option.Key: oldValue}
+	}
+	model.RecordAdminAudit(c, model.AdminAuditActionUpdate, model.AdminAuditEntityOption, option.Key, oldOption, map[string]interface{}{option.Key: option.Value})
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
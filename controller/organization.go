@@ -0,0 +1,390 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type organizationMemberRequest struct {
+	UserId     int    `json:"user_id"`
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+	QuotaLimit *int   `json:"quota_limit"`
+}
+
+func organizationError(c *gin.Context, err error) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": false,
+		"message": err.Error(),
+	})
+}
+
+// getSelfOrganizationMember 获取当前用户在路径中组织的成员身份，尚未接受邀请时视为不是成员
+func getSelfOrganizationMember(c *gin.Context) (*model.OrganizationMember, bool) {
+	orgId, _ := strconv.Atoi(c.Param("id"))
+	member, err := model.GetOrganizationMember(orgId, c.GetInt("id"))
+	if err != nil || !member.IsActive() {
+		organizationError(c, errors.New("不是该组织的成员"))
+		return nil, false
+	}
+	return member, true
+}
+
+// GetSelfOrganizations 获取当前用户所属以及收到邀请的组织
+func GetSelfOrganizations(c *gin.Context) {
+	orgs, err := model.GetUserOrganizations(c.GetInt("id"))
+	if err != nil {
+		organizationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    orgs,
+	})
+}
+
+// CreateOrganization 创建组织，创建者成为所有者
+func CreateOrganization(c *gin.Context) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		organizationError(c, err)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 64 {
+		organizationError(c, errors.New("组织名称不能为空且不能超过 64 个字符"))
+		return
+	}
+	org, err := model.CreateOrganization(req.Name, c.GetInt("id"))
+	if err != nil {
+		organizationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    org,
+	})
+}
+
+// GetOrganizationMembers 获取组织成员及各自的消费，普通成员只能看到自己
+func GetOrganizationMembers(c *gin.Context) {
+	self, ok := getSelfOrganizationMember(c)
+	if !ok {
+		return
+	}
+	members := []*model.OrganizationMember{self}
+	if self.CanManageBilling() {
+		var err error
+		members, err = model.GetOrganizationMembers(self.OrgId)
+		if err != nil {
+			organizationError(c, err)
+			return
+		}
+	} else {
+		self.Username = c.GetString("username")
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    members,
+	})
+}
+
+// AddOrganizationMember 所有者邀请用户加入组织，用户接受邀请后才成为成员
+func AddOrganizationMember(c *gin.Context) {
+	self, ok := getSelfOrganizationMember(c)
+	if !ok {
+		return
+	}
+	if !self.CanManageMembers() {
+		organizationError(c, errors.New("只有组织所有者可以添加成员"))
+		return
+	}
+	var req organizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		organizationError(c, err)
+		return
+	}
+	if req.UserId == 0 && req.Username != "" {
+		userId, err := model.GetUserIdByUsername(req.Username)
+		if err != nil {
+			organizationError(c, errors.New("用户不存在"))
+			return
+		}
+		req.UserId = userId
+	}
+	if req.UserId == 0 {
+		organizationError(c, errors.New("user_id 或 username 不能为空"))
+		return
+	}
+	if req.Role == "" {
+		req.Role = model.OrgRoleMember
+	}
+	if !model.IsValidOrgRole(req.Role) {
+		organizationError(c, errors.New("无效的组织角色"))
+		return
+	}
+	if _, err := model.GetOrganizationMember(self.OrgId, req.UserId); err == nil {
+		organizationError(c, errors.New("该用户已是组织成员或已被邀请"))
+		return
+	}
+	member := &model.OrganizationMember{OrgId: self.OrgId, UserId: req.UserId, Role: req.Role}
+	if req.QuotaLimit != nil {
+		member.QuotaLimit = max(*req.QuotaLimit, 0)
+	}
+	if err := model.InviteOrganizationMember(member); err != nil {
+		organizationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    member,
+	})
+}
+
+// AcceptOrganizationInvitation 当前用户接受组织邀请
+func AcceptOrganizationInvitation(c *gin.Context) {
+	orgId, _ := strconv.Atoi(c.Param("id"))
+	if err := model.AcceptOrganizationInvitation(orgId, c.GetInt("id")); err != nil {
+		organizationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// DeclineOrganizationInvitation 当前用户拒绝组织邀请
+func DeclineOrganizationInvitation(c *gin.Context) {
+	orgId, _ := strconv.Atoi(c.Param("id"))
+	member, err := model.GetOrganizationMember(orgId, c.GetInt("id"))
+	if err != nil || member.IsActive() {
+		organizationError(c, errors.New("没有待接受的邀请"))
+		return
+	}
+	if err = model.RemoveOrganizationMember(orgId, member.UserId); err != nil {
+		organizationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// UpdateOrganizationMember 所有者修改成员角色，所有者与账单管理员修改成员消费上限
+func UpdateOrganizationMember(c *gin.Context) {
+	self, ok := getSelfOrganizationMember(c)
+	if !ok {
+		return
+	}
+	var req organizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		organizationError(c, err)
+		return
+	}
+	member, err := model.GetOrganizationMember(self.OrgId, req.UserId)
+	if err != nil {
+		organizationError(c, errors.New("成员不存在"))
+		return
+	}
+	if req.Role != "" && req.Role != member.Role {
+		if !self.CanManageMembers() {
+			organizationError(c, errors.New("只有组织所有者可以修改成员角色"))
+			return
+		}
+		if !model.IsValidOrgRole(req.Role) {
+			organizationError(c, errors.New("无效的组织角色"))
+			return
+		}
+		if member.Role == model.OrgRoleOwner && member.IsActive() {
+			if count, err := model.CountOrganizationOwners(self.OrgId); err != nil || count <= 1 {
+				organizationError(c, errors.New("组织至少需要保留一个所有者"))
+				return
+			}
+		}
+		member.Role = req.Role
+	}
+	if req.QuotaLimit != nil {
+		if !self.CanManageBilling() {
+			organizationError(c, errors.New("只有组织所有者或账单管理员可以设置成员消费上限"))
+			return
+		}
+		member.QuotaLimit = max(*req.QuotaLimit, 0)
+	}
+	if err = member.Update(); err != nil {
+		organizationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    member,
+	})
+}
+
+// RemoveOrganizationMember 所有者移除成员，成员也可以退出组织
+func RemoveOrganizationMember(c *gin.Context) {
+	self, ok := getSelfOrganizationMember(c)
+	if !ok {
+		return
+	}
+	userId, _ := strconv.Atoi(c.Param("user_id"))
+	if userId != self.UserId && !self.CanManageMembers() {
+		organizationError(c, errors.New("只有组织所有者可以移除成员"))
+		return
+	}
+	member, err := model.GetOrganizationMember(self.OrgId, userId)
+	if err != nil {
+		organizationError(c, errors.New("成员不存在"))
+		return
+	}
+	if member.Role == model.OrgRoleOwner && member.IsActive() {
+		if count, err := model.CountOrganizationOwners(self.OrgId); err != nil || count <= 1 {
+			organizationError(c, errors.New("组织至少需要保留一个所有者"))
+			return
+		}
+	}
+	if err = model.RemoveOrganizationMember(self.OrgId, userId); err != nil {
+		organizationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// TransferQuotaToOrganization 所有者或账单管理员将自己的额度转入组织额度池
+func TransferQuotaToOrganization(c *gin.Context) {
+	self, ok := getSelfOrganizationMember(c)
+	if !ok {
+		return
+	}
+	if !self.CanManageBilling() {
+		organizationError(c, errors.New("只有组织所有者或账单管理员可以为组织充值"))
+		return
+	}
+	var req struct {
+		Quota int `json:"quota"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		organizationError(c, err)
+		return
+	}
+	if err := model.TransferUserQuotaToOrganization(self.UserId, self.OrgId, req.Quota); err != nil {
+		organizationError(c, err)
+		return
+	}
+	model.RecordLog(self.UserId, model.LogTypeManage, fmt.Sprintf("转入组织 %d 额度 %s", self.OrgId, common.LogQuota(req.Quota)))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// GetAllOrganizations 管理员获取组织列表
+func GetAllOrganizations(c *gin.Context) {
+	pageInfo, err := common.GetPageQuery(c)
+	if err != nil {
+		organizationError(c, err)
+		return
+	}
+	orgs, total, err := model.GetAllOrganizations(c.Query("keyword"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		organizationError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(orgs)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    pageInfo,
+	})
+}
+
+// AdminGetOrganizationMembers 管理员获取组织成员及各自的消费
+func AdminGetOrganizationMembers(c *gin.Context) {
+	orgId, _ := strconv.Atoi(c.Param("id"))
+	members, err := model.GetOrganizationMembers(orgId)
+	if err != nil {
+		organizationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    members,
+	})
+}
+
+// UpdateOrganization 管理员修改组织名称与状态
+func UpdateOrganization(c *gin.Context) {
+	var req model.Organization
+	if err := c.ShouldBindJSON(&req); err != nil {
+		organizationError(c, err)
+		return
+	}
+	org, err := model.GetOrganizationById(req.Id)
+	if err != nil {
+		organizationError(c, err)
+		return
+	}
+	if name := strings.TrimSpace(req.Name); name != "" {
+		org.Name = name
+	}
+	if req.Status == model.OrganizationStatusEnabled || req.Status == model.OrganizationStatusDisabled {
+		org.Status = req.Status
+	}
+	if err = org.Update(); err != nil {
+		organizationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    org,
+	})
+}
+
+// AdjustOrganizationQuota 管理员调整组织额度池，quota 为负数时扣减
+func AdjustOrganizationQuota(c *gin.Context) {
+	orgId, _ := strconv.Atoi(c.Param("id"))
+	var req struct {
+		Quota int `json:"quota"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		organizationError(c, err)
+		return
+	}
+	org, err := model.GetOrganizationById(orgId)
+	if err != nil {
+		organizationError(c, err)
+		return
+	}
+	if err = model.IncreaseOrganizationQuota(orgId, req.Quota); err != nil {
+		organizationError(c, err)
+		return
+	}
+	model.RecordAdminAudit(c, model.AdminAuditActionUpdate, model.AdminAuditEntityOrganization, strconv.Itoa(orgId),
+		map[string]interface{}{"quota": org.Quota}, map[string]interface{}{"quota": org.Quota + req.Quota})
+	model.RecordLog(c.GetInt("id"), model.LogTypeManage, fmt.Sprintf("管理员调整组织 %d 额度 %s", orgId, common.LogQuota(req.Quota)))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
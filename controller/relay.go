@@ -2,12 +2,15 @@ package controller
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"one-api/common"
+	"one-api/common/metrics"
+	"one-api/common/tracing"
 	constant2 "one-api/constant"
 	"one-api/dto"
 	"one-api/middleware"
@@ -21,6 +24,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func relayHandler(c *gin.Context, relayMode int) *dto.OpenAIErrorWithStatusCode {
@@ -74,6 +80,7 @@ func Relay(c *gin.Context) {
 	group := c.GetString("group")
 	originalModel := c.GetString("original_model")
 	var openaiErr *dto.OpenAIErrorWithStatusCode
+	defer model.ReleaseContextChannelProbe(c)
 
 	for i := 0; i <= common.RetryTimes; i++ {
 		channel, err := getChannel(c, group, originalModel, i)
@@ -83,13 +90,18 @@ func Relay(c *gin.Context) {
 			break
 		}
 
-		openaiErr = relayRequest(c, relayMode, channel)
+		openaiErr = tracedRelayRequest(c, relayMode, channel, i)
+		probe := model.TakeContextChannelProbe(c)
 
 		if openaiErr == nil {
+			if !c.GetBool("response_cache_hit") {
+				model.RecordChannelSuccess(channel.Id)
+			}
+			model.ReleaseChannelProbe(probe)
 			return // 成功处理请求，直接返回
 		}
 
-		go processChannelError(c, channel.Id, channel.Type, channel.Name, channel.GetAutoBan(), openaiErr)
+		go processChannelError(c, channel.Id, channel.Type, channel.Name, channel.GetAutoBan(), getChannelKeyIndex(c), probe, openaiErr)
 
 		if !shouldRetry(c, openaiErr, common.RetryTimes-i) {
 			break
@@ -113,6 +125,50 @@ func Relay(c *gin.Context) {
 	}
 }
 
+// tracedRelayRequest 在独立的 span 中执行一次转发尝试
+func tracedRelayRequest(c *gin.Context, relayMode int, channel *model.Channel, attempt int) *dto.OpenAIErrorWithStatusCode {
+	attemptSpan := startRelayAttemptSpan(c, attempt, channel)
+	defer attemptSpan.End()
+	openaiErr := relayRequest(c, relayMode, channel)
+	if openaiErr != nil {
+		attemptSpan.Fail(openaiErr.StatusCode, openaiErr.Error.Type, openaiErr.Error.Message)
+	}
+	return openaiErr
+}
+
+// relayAttemptSpan 一次转发尝试的 span，转发过程中创建的 span 均挂在该 span 下
+type relayAttemptSpan struct {
+	c         *gin.Context
+	parentCtx context.Context
+	span      trace.Span
+}
+
+func startRelayAttemptSpan(c *gin.Context, attempt int, channel *model.Channel) *relayAttemptSpan {
+	parentCtx := c.Request.Context()
+	ctx, span := tracing.Start(parentCtx, "relay.attempt",
+		attribute.Int("relay.attempt", attempt),
+		attribute.Int("channel.id", channel.Id),
+		attribute.Int("channel.type", channel.Type),
+	)
+	c.Request = c.Request.WithContext(ctx)
+	return &relayAttemptSpan{c: c, parentCtx: parentCtx, span: span}
+}
+
+// Fail 记录失败的状态码与错误信息
+func (s *relayAttemptSpan) Fail(statusCode int, errorType string, message string) {
+	s.span.SetAttributes(
+		attribute.Int("http.status_code", statusCode),
+		attribute.String("error.type", errorType),
+	)
+	s.span.SetStatus(codes.Error, message)
+}
+
+// End 结束 span 并恢复请求原有的上下文
+func (s *relayAttemptSpan) End() {
+	s.c.Request = s.c.Request.WithContext(s.parentCtx)
+	s.span.End()
+}
+
 var upgrader = websocket.Upgrader{
 	Subprotocols: []string{"realtime"}, // WS 握手支持的协议，如果有使用 Sec-WebSocket-Protocol，则必须在此声明对应的 Protocol TODO add other protocol
 	CheckOrigin: func(r *http.Request) bool {
@@ -147,13 +203,21 @@ func WssRelay(c *gin.Context) {
 			break
 		}
 
+		attemptSpan := startRelayAttemptSpan(c, i, channel)
 		openaiErr = wssRequest(c, ws, relayMode, channel)
+		if openaiErr != nil {
+			attemptSpan.Fail(openaiErr.StatusCode, openaiErr.Error.Type, openaiErr.Error.Message)
+		}
+		attemptSpan.End()
+		probe := model.TakeContextChannelProbe(c)
 
 		if openaiErr == nil {
+			model.RecordChannelSuccess(channel.Id)
+			model.ReleaseChannelProbe(probe)
 			return // 成功处理请求，直接返回
 		}
 
-		go processChannelError(c, channel.Id, channel.Type, channel.Name, channel.GetAutoBan(), openaiErr)
+		go processChannelError(c, channel.Id, channel.Type, channel.Name, channel.GetAutoBan(), getChannelKeyIndex(c), probe, openaiErr)
 
 		if !shouldRetry(c, openaiErr, common.RetryTimes-i) {
 			break
@@ -189,15 +253,23 @@ func RelayClaude(c *gin.Context) {
 			break
 		}
 
+		attemptSpan := startRelayAttemptSpan(c, i, channel)
 		claudeErr = claudeRequest(c, channel)
+		if claudeErr != nil {
+			attemptSpan.Fail(claudeErr.StatusCode, claudeErr.Error.Type, claudeErr.Error.Message)
+		}
+		attemptSpan.End()
+		probe := model.TakeContextChannelProbe(c)
 
 		if claudeErr == nil {
+			model.RecordChannelSuccess(channel.Id)
+			model.ReleaseChannelProbe(probe)
 			return // 成功处理请求，直接返回
 		}
 
 		openaiErr := service.ClaudeErrorToOpenAIError(claudeErr)
 
-		go processChannelError(c, channel.Id, channel.Type, channel.Name, channel.GetAutoBan(), openaiErr)
+		go processChannelError(c, channel.Id, channel.Type, channel.Name, channel.GetAutoBan(), getChannelKeyIndex(c), probe, openaiErr)
 
 		if !shouldRetry(c, openaiErr, common.RetryTimes-i) {
 			break
@@ -310,10 +382,43 @@ func shouldRetry(c *gin.Context, openaiErr *dto.OpenAIErrorWithStatusCode, retry
 	return true
 }
 
-func processChannelError(c *gin.Context, channelId int, channelType int, channelName string, autoBan bool, err *dto.OpenAIErrorWithStatusCode) {
+// getChannelKeyIndex 返回当前请求使用的多密钥下标，单密钥渠道返回 -1
+func getChannelKeyIndex(c *gin.Context) int {
+	if !c.GetBool("channel_multi_key") {
+		return -1
+	}
+	return c.GetInt("channel_key_index")
+}
+
+// processChannelError 记录渠道错误，probe 为本次尝试占用的熔断探测名额，记录失败后才归还，
+// 避免结果记录前其他请求占用名额继续探测
+func processChannelError(c *gin.Context, channelId int, channelType int, channelName string, autoBan bool, keyIndex int, probe *model.ChannelProbe, err *dto.OpenAIErrorWithStatusCode) {
 	// 不要使用context获取渠道信息，异步处理时可能会出现渠道信息不一致的情况
 	// do not use context to get channel info, there may be inconsistent channel info when processing asynchronously
+	defer model.ReleaseChannelProbe(probe)
 	common.LogError(c, fmt.Sprintf("relay error (channel #%d, status code: %d): %s", channelId, err.StatusCode, err.Error.Message))
+	if !err.LocalError {
+		errorType := err.Error.Type
+		if errorType == "" {
+			errorType = "unknown"
+		}
+		metrics.UpstreamErrors.WithLabelValues(metrics.ChannelLabel(channelId), metrics.ModelLabel(c.GetString("original_model")), errorType).Inc()
+	}
+	if service.ShouldRecordChannelFailure(err) {
+		model.RecordChannelFailure(channelId, fmt.Sprintf("status code %d: %s", err.StatusCode, err.Error.Message))
+		model.RecordChannelError(channelId, c.GetString("original_model"))
+	}
+	if keyIndex >= 0 {
+		// 多密钥渠道：限流的密钥进入冷却，失效的密钥单独禁用，渠道保持可用
+		if service.ShouldDisableChannel(channelType, err) && autoBan {
+			service.DisableChannelKey(channelId, channelName, keyIndex, err.Error.Message)
+		} else if service.ShouldCoolDownChannelKey(err) {
+			service.CoolDownChannelKey(channelId, keyIndex)
+		} else if !err.LocalError {
+			model.RecordChannelKeyFailure(channelId, keyIndex)
+		}
+		return
+	}
 	if service.ShouldDisableChannel(channelType, err) && autoBan {
 		service.DisableChannel(channelId, channelName, err.Error.Message)
 	}
@@ -322,6 +427,8 @@ func processChannelError(c *gin.Context, channelId int, channelType int, channel
 func RelayMidjourney(c *gin.Context) {
 	relayMode := c.GetInt("relay_mode")
 	var err *dto.MidjourneyResponse
+	attemptSpan := startRelayAttemptSpan(c, 0, &model.Channel{Id: c.GetInt("channel_id"), Type: c.GetInt("channel_type")})
+	defer attemptSpan.End()
 	switch relayMode {
 	case relayconstant.RelayModeMidjourneyNotify:
 		err = relay.RelayMidjourneyNotify(c)
@@ -342,6 +449,7 @@ func RelayMidjourney(c *gin.Context) {
 			err.Result = "当前分组负载已饱和，请稍后再试，或升级账户以提升服务质量。"
 			statusCode = http.StatusTooManyRequests
 		}
+		attemptSpan.Fail(statusCode, "upstream_error", fmt.Sprintf("%s %s", err.Description, err.Result))
 		c.JSON(statusCode, gin.H{
 			"description": fmt.Sprintf("%s %s", err.Description, err.Result),
 			"type":        "upstream_error",
@@ -383,7 +491,7 @@ func RelayTask(c *gin.Context) {
 	group := c.GetString("group")
 	originalModel := c.GetString("original_model")
 	c.Set("use_channel", []string{fmt.Sprintf("%d", channelId)})
-	taskErr := taskRelayHandler(c, relayMode)
+	taskErr := tracedTaskRelayRequest(c, relayMode, &model.Channel{Id: channelId, Type: c.GetInt("channel_type")}, 0)
 	if taskErr == nil {
 		retryTimes = 0
 	}
@@ -402,7 +510,7 @@ func RelayTask(c *gin.Context) {
 
 		requestBody, err := common.GetRequestBody(c)
 		c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
-		taskErr = taskRelayHandler(c, relayMode)
+		taskErr = tracedTaskRelayRequest(c, relayMode, channel, i+1)
 	}
 	useChannel := c.GetStringSlice("use_channel")
 	if len(useChannel) > 1 {
@@ -417,6 +525,18 @@ func RelayTask(c *gin.Context) {
 	}
 }
 
+// tracedTaskRelayRequest 在独立的 span 中执行一次任务转发尝试，结束后归还熔断探测名额
+func tracedTaskRelayRequest(c *gin.Context, relayMode int, channel *model.Channel, attempt int) *dto.TaskError {
+	defer model.ReleaseContextChannelProbe(c)
+	attemptSpan := startRelayAttemptSpan(c, attempt, channel)
+	defer attemptSpan.End()
+	taskErr := taskRelayHandler(c, relayMode)
+	if taskErr != nil {
+		attemptSpan.Fail(taskErr.StatusCode, taskErr.Code, taskErr.Message)
+	}
+	return taskErr
+}
+
 func taskRelayHandler(c *gin.Context, relayMode int) *dto.TaskError {
 	var err *dto.TaskError
 	switch relayMode {
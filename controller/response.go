@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/dto"
+	"one-api/model"
+	"one-api/service"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func getStoredResponse(c *gin.Context) (*model.StoredResponse, bool) {
+	response, err := model.GetStoredResponse(c.GetInt("id"), service.ResponseStoreTokenId(c.GetInt("token_id")), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			openAIErrorResponse(c, http.StatusNotFound, "response_not_found", fmt.Sprintf("Response with id '%s' not found.", c.Param("id")))
+		} else {
+			openAIErrorResponse(c, http.StatusInternalServerError, "get_response_failed", err.Error())
+		}
+		return nil, false
+	}
+	return response, true
+}
+
+func RetrieveResponse(c *gin.Context) {
+	response, ok := getStoredResponse(c)
+	if !ok {
+		return
+	}
+	c.Data(http.StatusOK, "application/json", []byte(response.Response))
+}
+
+func DeleteResponse(c *gin.Context) {
+	err := model.DeleteStoredResponse(c.GetInt("id"), service.ResponseStoreTokenId(c.GetInt("token_id")), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			openAIErrorResponse(c, http.StatusNotFound, "response_not_found", fmt.Sprintf("Response with id '%s' not found.", c.Param("id")))
+		} else {
+			openAIErrorResponse(c, http.StatusInternalServerError, "delete_response_failed", err.Error())
+		}
+		return
+	}
+	c.JSON(http.StatusOK, dto.OpenAIFileDeleted{
+		Id:      c.Param("id"),
+		Object:  "response",
+		Deleted: true,
+	})
+}
+
+// withInputItemId 为没有 id 的输入项生成固定的 id，用于分页
+func withInputItemId(responseId string, index int, item json.RawMessage) (string, json.RawMessage) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(item, &fields); err != nil {
+		return "", item
+	}
+	var id string
+	if err := json.Unmarshal(fields["id"], &id); err == nil && id != "" {
+		return id, item
+	}
+	id = fmt.Sprintf("msg_%s_%d", strings.TrimPrefix(responseId, "resp_"), index)
+	fields["id"], _ = json.Marshal(id)
+	if data, err := json.Marshal(fields); err == nil {
+		item = data
+	}
+	return id, item
+}
+
+func ListResponseInputItems(c *gin.Context) {
+	response, ok := getStoredResponse(c)
+	if !ok {
+		return
+	}
+	items, err := response.GetInputItems()
+	if err != nil {
+		openAIErrorResponse(c, http.StatusInternalServerError, "get_response_failed", err.Error())
+		return
+	}
+	ids := make([]string, len(items))
+	for i := range items {
+		ids[i], items[i] = withInputItemId(response.Id, i, items[i])
+	}
+	// 默认按时间倒序返回
+	if c.DefaultQuery("order", "desc") == "desc" {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+			ids[i], ids[j] = ids[j], ids[i]
+		}
+	}
+	start := 0
+	if after := c.Query("after"); after != "" {
+		for i, id := range ids {
+			if id == after {
+				start = i + 1
+				break
+			}
+		}
+	}
+	items, ids = items[start:], ids[start:]
+
+	limit := getListLimit(c, 20, 100)
+	list := dto.OpenAIListResponse[json.RawMessage]{
+		Object: "list",
+		Data:   make([]json.RawMessage, 0, limit),
+	}
+	if len(items) > limit {
+		items, ids = items[:limit], ids[:limit]
+		list.HasMore = true
+	}
+	list.Data = append(list.Data, items...)
+	if len(ids) > 0 {
+		list.FirstId = ids[0]
+		list.LastId = ids[len(ids)-1]
+	}
+	c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseStatementPeriod 解析账单周期，支持 month=YYYY-MM 或 start_timestamp/end_timestamp，默认为当月。
+// 已清理日志只保留按天汇总，自定义时间戳不在整天边界时，首尾不足一天部分中已清理的消费不计入账单
+func parseStatementPeriod(c *gin.Context) (int64, int64, error) {
+	if month := c.Query("month"); month != "" {
+		start, err := time.ParseInLocation("2006-01", month, time.Local)
+		if err != nil {
+			return 0, 0, errors.New("month 格式应为 YYYY-MM")
+		}
+		return start.Unix(), start.AddDate(0, 1, 0).Unix(), nil
+	}
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	if startTimestamp == 0 && endTimestamp == 0 {
+		now := time.Now()
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return start.Unix(), start.AddDate(0, 1, 0).Unix(), nil
+	}
+	if endTimestamp == 0 {
+		endTimestamp = common.GetTimestamp()
+	}
+	if startTimestamp >= endTimestamp {
+		return 0, 0, errors.New("账单开始时间必须早于结束时间")
+	}
+	if endTimestamp-startTimestamp > 366*24*3600 {
+		return 0, 0, errors.New("账单周期不能超过一年")
+	}
+	return startTimestamp, endTimestamp, nil
+}
+
+func statementError(c *gin.Context, err error) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": false,
+		"message": err.Error(),
+	})
+}
+
+func respondStatement(c *gin.Context, statement *service.Statement) {
+	switch c.DefaultQuery("format", "json") {
+	case "csv":
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, service.StatementFileName(statement, "csv")))
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		if err := service.WriteStatementsCSV(c.Writer, []*service.Statement{statement}); err != nil {
+			common.SysError("failed to write statement csv: " + err.Error())
+		}
+	case "html":
+		c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, service.StatementFileName(statement, "html")))
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		if err := service.WriteStatementHTML(c.Writer, statement); err != nil {
+			common.SysError("failed to write statement html: " + err.Error())
+		}
+	default:
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "",
+			"data":    statement,
+		})
+	}
+}
+
+// GetSelfStatement 获取当前用户的账单，format 可为 json、csv 或 html
+func GetSelfStatement(c *gin.Context) {
+	startTimestamp, endTimestamp, err := parseStatementPeriod(c)
+	if err != nil {
+		statementError(c, err)
+		return
+	}
+	statement, err := service.GenerateStatement(c.GetInt("id"), startTimestamp, endTimestamp)
+	if err != nil {
+		statementError(c, err)
+		return
+	}
+	respondStatement(c, statement)
+}
+
+// GetUserStatement 管理员获取指定用户的账单
+func GetUserStatement(c *gin.Context) {
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	if userId == 0 {
+		statementError(c, errors.New("user_id 不能为空"))
+		return
+	}
+	startTimestamp, endTimestamp, err := parseStatementPeriod(c)
+	if err != nil {
+		statementError(c, err)
+		return
+	}
+	statement, err := service.GenerateStatement(userId, startTimestamp, endTimestamp)
+	if err != nil {
+		statementError(c, err)
+		return
+	}
+	respondStatement(c, statement)
+}
+
+// ExportStatements 管理员批量导出账单，format=csv 导出为单个 CSV，format=zip 为每个用户导出 CSV 与 HTML
+func ExportStatements(c *gin.Context) {
+	startTimestamp, endTimestamp, err := parseStatementPeriod(c)
+	if err != nil {
+		statementError(c, err)
+		return
+	}
+	statements, err := service.GenerateStatements(startTimestamp, endTimestamp)
+	if err != nil {
+		statementError(c, err)
+		return
+	}
+	period := fmt.Sprintf("%s-%s", time.Unix(startTimestamp, 0).Format("20060102"), time.Unix(endTimestamp, 0).Format("20060102"))
+	if c.DefaultQuery("format", "csv") != "zip" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="statements-%s.csv"`, period))
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		if err := service.WriteStatementsCSV(c.Writer, statements); err != nil {
+			common.SysError("failed to write statements csv: " + err.Error())
+		}
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="statements-%s.zip"`, period))
+	c.Header("Content-Type", "application/zip")
+	archive := zip.NewWriter(c.Writer)
+	for _, statement := range statements {
+		w, err := archive.Create(service.StatementFileName(statement, "csv"))
+		if err == nil {
+			err = service.WriteStatementsCSV(w, []*service.Statement{statement})
+		}
+		if err == nil {
+			w, err = archive.Create(service.StatementFileName(statement, "html"))
+		}
+		if err == nil {
+			err = service.WriteStatementHTML(w, statement)
+		}
+		if err != nil {
+			common.SysError("failed to write statements zip: " + err.Error())
+			break
+		}
+	}
+	if err := archive.Close(); err != nil {
+		common.SysError("failed to close statements zip: " + err.Error())
+	}
+}
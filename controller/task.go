@@ -14,6 +14,7 @@ import (
 	"one-api/dto"
 	"one-api/model"
 	"one-api/relay"
+	"one-api/relay/channel"
 	"sort"
 	"strconv"
 	"time"
@@ -113,7 +114,32 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 	if adaptor == nil {
 		return errors.New("adaptor not found")
 	}
-	resp, err := adaptor.FetchTask(*channel.BaseURL, channel.Key, map[string]any{
+	// 多密钥渠道的任务只能用提交时的密钥查询，按密钥下标分组
+	keyTaskIds := groupTaskIdsByKeyIndex(taskIds, func(taskId string) int {
+		return taskM[taskId].KeyIndex
+	})
+	for keyIndex, ids := range keyTaskIds {
+		err = updateSunoTaskByKey(ctx, adaptor, channel, channel.GetKeyByIndex(keyIndex), ids, taskM)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupTaskIdsByKeyIndex 按提交任务时使用的密钥下标对任务 ID 分组
+func groupTaskIdsByKeyIndex(taskIds []string, keyIndex func(taskId string) int) map[int][]string {
+	groups := make(map[int][]string)
+	for _, taskId := range taskIds {
+		index := keyIndex(taskId)
+		groups[index] = append(groups[index], taskId)
+	}
+	return groups
+}
+
+func updateSunoTaskByKey(ctx context.Context, adaptor channel.TaskAdaptor, channel *model.Channel, channelKey string, taskIds []string, taskM map[string]*model.Task) error {
+	channelId := channel.Id
+	resp, err := adaptor.FetchTask(*channel.BaseURL, channelKey, map[string]any{
 		"ids": taskIds,
 	})
 	if err != nil {
@@ -161,7 +187,7 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 			} else {
 				quota := task.Quota
 				if quota != 0 {
-					err = model.IncreaseUserQuota(task.UserId, quota, false)
+					err = model.RefundBillingQuota(task.UserId, task.OrgId, quota)
 					if err != nil {
 						common.LogError(ctx, "fail to increase user quota: "+err.Error())
 					}
@@ -56,7 +56,8 @@ func updateVideoSingleTask(ctx context.Context, adaptor channel.TaskAdaptor, cha
 	if channel.GetBaseURL() != "" {
 		baseURL = channel.GetBaseURL()
 	}
-	resp, err := adaptor.FetchTask(baseURL, channel.Key, map[string]any{
+	channelKey := channel.GetKeyByIndex(taskM[taskId].KeyIndex)
+	resp, err := adaptor.FetchTask(baseURL, channelKey, map[string]any{
 		"task_id": taskId,
 	})
 	if err != nil {
@@ -123,7 +124,7 @@ func updateVideoSingleTask(ctx context.Context, adaptor channel.TaskAdaptor, cha
 		common.LogInfo(ctx, fmt.Sprintf("Task %s failed: %s", task.TaskID, task.FailReason))
 		quota := task.Quota
 		if quota != 0 {
-			if err := model.IncreaseUserQuota(task.UserId, quota, false); err != nil {
+			if err := model.RefundBillingQuota(task.UserId, task.OrgId, quota); err != nil {
 				common.LogError(ctx, "Failed to increase user quota: "+err.Error())
 			}
 			logContent := fmt.Sprintf("Video async task failed %s, refund %s", task.TaskID, common.LogQuota(quota))
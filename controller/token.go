@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"one-api/common"
@@ -8,6 +9,34 @@ import (
 	"strconv"
 )
 
+// tokenWithBudget 在令牌信息中附带各预算窗口的剩余额度，详情接口还附带各模型的消耗
+type tokenWithBudget struct {
+	*model.Token
+	Budgets    []model.TokenBudgetStatus `json:"budgets"`
+	ModelUsage []*model.TokenModelUsage  `json:"model_usage,omitempty"`
+}
+
+func withTokenBudget(token *model.Token) tokenWithBudget {
+	result := tokenWithBudget{Token: token, Budgets: []model.TokenBudgetStatus{}}
+	if token.HasBudget() {
+		budgets, err := model.GetTokenBudgetStatus(token)
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to get budget status of token %d: %s", token.Id, err.Error()))
+		} else {
+			result.Budgets = budgets
+		}
+	}
+	return result
+}
+
+func withTokenBudgets(tokens []*model.Token) []tokenWithBudget {
+	result := make([]tokenWithBudget, 0, len(tokens))
+	for _, token := range tokens {
+		result = append(result, withTokenBudget(token))
+	}
+	return result
+}
+
 func GetAllTokens(c *gin.Context) {
 	userId := c.GetInt("id")
 	p, _ := strconv.Atoi(c.Query("p"))
@@ -35,7 +64,7 @@ func GetAllTokens(c *gin.Context) {
 		"success": true,
 		"message": "",
 		"data": gin.H{
-			"items":     tokens,
+			"items":     withTokenBudgets(tokens),
 			"total":     total,
 			"page":      p,
 			"page_size": size,
@@ -59,7 +88,7 @@ func SearchTokens(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
-		"data":    tokens,
+		"data":    withTokenBudgets(tokens),
 	})
 	return
 }
@@ -82,10 +111,15 @@ func GetToken(c *gin.Context) {
 		})
 		return
 	}
+	result := withTokenBudget(token)
+	result.ModelUsage, err = model.GetTokenModelUsage(token)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to get model usage of token %d: %s", token.Id, err.Error()))
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
-		"data":    token,
+		"data":    result,
 	})
 	return
 }
@@ -131,6 +165,29 @@ func AddToken(c *gin.Context) {
 		})
 		return
 	}
+	if token.DailyBudget < 0 || token.WeeklyBudget < 0 || token.MonthlyBudget < 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "令牌预算不能为负数",
+		})
+		return
+	}
+	if err := model.ValidateTokenModelQuotas(token.ModelQuotas); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "模型上限配置无效：" + err.Error(),
+		})
+		return
+	}
+	if token.OrgId != 0 {
+		if member, err := model.GetOrganizationMember(token.OrgId, c.GetInt("id")); err != nil || !member.IsActive() {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "不是该组织的成员，无法使用组织额度",
+			})
+			return
+		}
+	}
 	key, err := common.GenerateKey()
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -153,6 +210,13 @@ func AddToken(c *gin.Context) {
 		ModelLimits:        token.ModelLimits,
 		AllowIps:           token.AllowIps,
 		Group:              token.Group,
+		ResponseCache:      token.ResponseCache,
+		DailyBudget:        token.DailyBudget,
+		WeeklyBudget:       token.WeeklyBudget,
+		MonthlyBudget:      token.MonthlyBudget,
+		BudgetRolling:      token.BudgetRolling,
+		ModelQuotas:        token.ModelQuotas,
+		OrgId:              token.OrgId,
 	}
 	err = cleanToken.Insert()
 	if err != nil {
@@ -206,6 +270,29 @@ func UpdateToken(c *gin.Context) {
 		})
 		return
 	}
+	if token.DailyBudget < 0 || token.WeeklyBudget < 0 || token.MonthlyBudget < 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "令牌预算不能为负数",
+		})
+		return
+	}
+	if err := model.ValidateTokenModelQuotas(token.ModelQuotas); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "模型上限配置无效：" + err.Error(),
+		})
+		return
+	}
+	if token.OrgId != 0 {
+		if member, err := model.GetOrganizationMember(token.OrgId, c.GetInt("id")); err != nil || !member.IsActive() {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "不是该组织的成员，无法使用组织额度",
+			})
+			return
+		}
+	}
 	cleanToken, err := model.GetTokenByIds(token.Id, userId)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -242,6 +329,13 @@ func UpdateToken(c *gin.Context) {
 		cleanToken.ModelLimits = token.ModelLimits
 		cleanToken.AllowIps = token.AllowIps
 		cleanToken.Group = token.Group
+		cleanToken.ResponseCache = token.ResponseCache
+		cleanToken.DailyBudget = token.DailyBudget
+		cleanToken.WeeklyBudget = token.WeeklyBudget
+		cleanToken.MonthlyBudget = token.MonthlyBudget
+		cleanToken.BudgetRolling = token.BudgetRolling
+		cleanToken.ModelQuotas = token.ModelQuotas
+		cleanToken.OrgId = token.OrgId
 	}
 	err = cleanToken.Update()
 	if err != nil {
@@ -547,6 +547,19 @@ func UpdateUser(c *gin.Context) {
 	if updatedUser.Password == "$I_LOVE_U" {
 		updatedUser.Password = "" // rollback to what it should be
 	}
+	if !hasPermission(c, constant.PermissionUserWrite) {
+		// 仅有调整额度权限时只修改额度
+		quota := updatedUser.Quota
+		updatedUser = *originUser
+		updatedUser.Quota = quota
+		updatedUser.Password = ""
+	} else if originUser.Quota != updatedUser.Quota && !hasPermission(c, constant.PermissionUserQuotaAdjust) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无权调整用户额度",
+		})
+		return
+	}
 	updatePassword := updatedUser.Password != ""
 	if err := updatedUser.Edit(updatePassword); err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -558,6 +571,9 @@ func UpdateUser(c *gin.Context) {
 	if originUser.Quota != updatedUser.Quota {
 		model.RecordLog(originUser.Id, model.LogTypeManage, fmt.Sprintf("管理员将用户额度从 %s修改为 %s", common.LogQuota(originUser.Quota), common.LogQuota(updatedUser.Quota)))
 	}
+	if editedUser, err := model.GetUserById(originUser.Id, false); err == nil {
+		model.RecordAdminAudit(c, model.AdminAuditActionUpdate, model.AdminAuditEntityUser, strconv.Itoa(originUser.Id), originUser, editedUser)
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
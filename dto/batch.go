@@ -0,0 +1,104 @@
+package dto
+
+import "encoding/json"
+
+// OpenAIFile https://platform.openai.com/docs/api-reference/files/object
+type OpenAIFile struct {
+	Id        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Status    string `json:"status"`
+}
+
+type OpenAIFileDeleted struct {
+	Id      string `json:"id"`
+	Object  string `json:"object"`
+	Deleted bool   `json:"deleted"`
+}
+
+type BatchRequest struct {
+	InputFileId      string            `json:"input_file_id"`
+	Endpoint         string            `json:"endpoint"`
+	CompletionWindow string            `json:"completion_window"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+type BatchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+type BatchError struct {
+	Code    string  `json:"code"`
+	Message string  `json:"message"`
+	Param   *string `json:"param"`
+	Line    *int    `json:"line"`
+}
+
+type BatchErrors struct {
+	Object string       `json:"object"`
+	Data   []BatchError `json:"data"`
+}
+
+// OpenAIBatch https://platform.openai.com/docs/api-reference/batch/object
+type OpenAIBatch struct {
+	Id               string             `json:"id"`
+	Object           string             `json:"object"`
+	Endpoint         string             `json:"endpoint"`
+	Errors           *BatchErrors       `json:"errors"`
+	InputFileId      string             `json:"input_file_id"`
+	CompletionWindow string             `json:"completion_window"`
+	Status           string             `json:"status"`
+	OutputFileId     *string            `json:"output_file_id"`
+	ErrorFileId      *string            `json:"error_file_id"`
+	CreatedAt        int64              `json:"created_at"`
+	InProgressAt     *int64             `json:"in_progress_at"`
+	ExpiresAt        *int64             `json:"expires_at"`
+	FinalizingAt     *int64             `json:"finalizing_at"`
+	CompletedAt      *int64             `json:"completed_at"`
+	FailedAt         *int64             `json:"failed_at"`
+	ExpiredAt        *int64             `json:"expired_at"`
+	CancellingAt     *int64             `json:"cancelling_at"`
+	CancelledAt      *int64             `json:"cancelled_at"`
+	RequestCounts    BatchRequestCounts `json:"request_counts"`
+	Metadata         map[string]string  `json:"metadata"`
+}
+
+// BatchInputLine 批处理输入文件中的一行
+type BatchInputLine struct {
+	CustomId string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	Url      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+type BatchOutputResponse struct {
+	StatusCode int             `json:"status_code"`
+	RequestId  string          `json:"request_id"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type BatchOutputError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// BatchOutputLine 批处理输出/错误文件中的一行
+type BatchOutputLine struct {
+	Id       string               `json:"id"`
+	CustomId string               `json:"custom_id"`
+	Response *BatchOutputResponse `json:"response"`
+	Error    *BatchOutputError    `json:"error"`
+}
+
+type OpenAIListResponse[T any] struct {
+	Object  string `json:"object"`
+	Data    []T    `json:"data"`
+	FirstId string `json:"first_id,omitempty"`
+	LastId  string `json:"last_id,omitempty"`
+	HasMore bool   `json:"has_more"`
+}
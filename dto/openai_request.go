@@ -612,7 +612,7 @@ type OpenAIResponsesRequest struct {
 	PreviousResponseID string               `json:"previous_response_id,omitempty"`
 	Reasoning          *Reasoning           `json:"reasoning,omitempty"`
 	ServiceTier        string               `json:"service_tier,omitempty"`
-	Store              bool                 `json:"store,omitempty"`
+	Store              *bool                `json:"store,omitempty"`
 	Stream             bool                 `json:"stream,omitempty"`
 	Temperature        float64              `json:"temperature,omitempty"`
 	Text               json.RawMessage      `json:"text,omitempty"`
@@ -646,3 +646,69 @@ type ResponsesToolsCall struct {
 	Description string          `json:"description,omitempty"`
 	Parameters  json.RawMessage `json:"parameters,omitempty"`
 }
+
+// ResponsesInputItem /v1/responses 的输入项，同时用于保存会话中的输出项
+type ResponsesInputItem struct {
+	Type      string          `json:"type,omitempty"`
+	Id        string          `json:"id,omitempty"`
+	Status    string          `json:"status,omitempty"`
+	Role      string          `json:"role,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	CallId    string          `json:"call_id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Arguments string          `json:"arguments,omitempty"`
+	Output    json.RawMessage `json:"output,omitempty"`
+}
+
+type ResponsesInputContent struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageUrl string `json:"image_url,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	FileId   string `json:"file_id,omitempty"`
+	FileData string `json:"file_data,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// GetInputItems 返回原始的输入项，字符串输入视为一条用户消息
+func (r *OpenAIResponsesRequest) GetInputItems() ([]json.RawMessage, error) {
+	var text string
+	if err := json.Unmarshal(r.Input, &text); err == nil {
+		content, _ := json.Marshal(text)
+		item, err := json.Marshal(ResponsesInputItem{Type: "message", Role: "user", Content: content})
+		if err != nil {
+			return nil, err
+		}
+		return []json.RawMessage{item}, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(r.Input, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ParseResponsesInputItems 解析原始的输入项，未指定 type 的视为消息
+func ParseResponsesInputItems(rawItems []json.RawMessage) ([]ResponsesInputItem, error) {
+	items := make([]ResponsesInputItem, 0, len(rawItems))
+	for _, rawItem := range rawItems {
+		var item ResponsesInputItem
+		if err := json.Unmarshal(rawItem, &item); err != nil {
+			return nil, err
+		}
+		if item.Type == "" {
+			item.Type = "message"
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// GetInstructions 返回字符串形式的 instructions
+func (r *OpenAIResponsesRequest) GetInstructions() string {
+	var instructions string
+	if len(r.Instructions) > 0 {
+		_ = json.Unmarshal(r.Instructions, &instructions)
+	}
+	return instructions
+}
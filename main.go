@@ -1,20 +1,28 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"one-api/common"
+	"one-api/common/logsink"
+	"one-api/common/tracing"
 	"one-api/constant"
 	"one-api/controller"
 	"one-api/middleware"
 	"one-api/model"
 	"one-api/router"
 	"one-api/service"
+	"one-api/setting/operation_setting"
 	"one-api/setting/ratio_setting"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-contrib/sessions"
@@ -32,6 +40,10 @@ var buildFS embed.FS
 var indexPage []byte
 
 func main() {
+	if len(os.Args) > 1 && runCommand(os.Args[1:]) {
+		return
+	}
+
 	err := godotenv.Load(".env")
 	if err != nil {
 		common.SysLog("Support for .env file is disabled: " + err.Error())
@@ -78,7 +90,23 @@ func main() {
 	// Initialize constants
 	constant.InitEnv()
 	// Initialize options
-	model.InitOptionMap()
+	if err := model.InitOptionMap(); err != nil {
+		common.FatalLog("failed to load options: " + err.Error())
+	}
+	if common.IsMasterNode && operation_setting.GetAdminAuditSetting().AppendOnly {
+		if err := model.InstallAdminAuditAppendOnlyTriggers(); err != nil {
+			common.SysError("failed to install admin audit append-only triggers: " + err.Error())
+		}
+	}
+	if common.IsMasterNode && common.SecretEncryptionEnabled() {
+		updated, err := model.EncryptSecretsAtRest(false)
+		if err != nil {
+			common.FatalLog("failed to encrypt secrets at rest: " + err.Error())
+		}
+		if updated > 0 {
+			common.SysLog(fmt.Sprintf("encrypted secrets in %d rows", updated))
+		}
+	}
 
 	service.InitTokenEncoders()
 
@@ -110,10 +138,25 @@ func main() {
 
 	// 热更新配置
 	go model.SyncOptions(common.SyncFrequency)
+	go model.SyncMetricsModels(common.SyncFrequency)
+
+	// 多节点共享渠道熔断状态
+	if common.RedisEnabled {
+		go model.SyncChannelBreakers(5)
+	}
 
 	// 数据看板
 	go model.UpdateQuotaData()
 
+	// 清理过期的审计记录、日志与保存的响应
+	if common.IsMasterNode {
+		go model.CleanAuditRecords(func() int {
+			return operation_setting.GetAuditSetting().RetentionDays
+		})
+		go service.StartLogRetentionTask()
+		go model.CleanExpiredStoredResponses()
+	}
+
 	if os.Getenv("CHANNEL_UPDATE_FREQUENCY") != "" {
 		frequency, err := strconv.Atoi(os.Getenv("CHANNEL_UPDATE_FREQUENCY"))
 		if err != nil {
@@ -150,6 +193,32 @@ func main() {
 		common.SysLog("pprof enabled")
 	}
 
+	// 日志外部输出
+	if err := logsink.Init(); err != nil {
+		common.FatalLog("failed to initialize log sinks: " + err.Error())
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		logsink.Shutdown(ctx)
+	}()
+
+	if os.Getenv("ENABLE_TRACING") == "true" {
+		shutdownTracer, err := tracing.InitTracer()
+		if err != nil {
+			common.SysError("failed to init tracing: " + err.Error())
+		} else {
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				if err := shutdownTracer(ctx); err != nil {
+					common.SysError("failed to shutdown tracer: " + err.Error())
+				}
+			}()
+			common.SysLog("tracing enabled")
+		}
+	}
+
 	// Initialize HTTP server
 	server := gin.New()
 	server.Use(gin.CustomRecovery(func(c *gin.Context, err any) {
@@ -177,12 +246,34 @@ func main() {
 	server.Use(sessions.Sessions("session", store))
 
 	router.SetRouter(server, buildFS, indexPage)
+
+	// 批处理任务经由网关自身的路由执行
+	if common.IsMasterNode {
+		go service.StartBatchWorker(server)
+	}
 	var port = os.Getenv("PORT")
 	if port == "" {
 		port = strconv.Itoa(*common.Port)
 	}
-	err = server.Run(":" + port)
-	if err != nil {
-		common.FatalLog("failed to start HTTP server: " + err.Error())
+	httpServer := &http.Server{
+		Addr:    ":" + port,
+		Handler: server.Handler(),
+	}
+	go func() {
+		err := httpServer.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			common.FatalLog("failed to start HTTP server: " + err.Error())
+		}
+	}()
+
+	// 收到退出信号后优雅关闭，使 main 正常返回以执行日志外部输出等的延迟清理
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	common.SysLog("shutting down server...")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		common.SysError("failed to shutdown HTTP server: " + err.Error())
 	}
 }
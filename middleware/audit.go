@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"one-api/service"
+	"one-api/setting/operation_setting"
+	"strings"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+// 单行 SSE 数据的上限，超出的行不参与拼接
+const auditMaxLineBytes = 1 << 20
+
+// auditResponseWriter 在写给客户端的同时保存响应内容，流式响应逐行拼接
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	// 是否审计在首次写入或请求结束时才确定，以便放在鉴权与格式转换之前
+	shouldAudit func() bool
+	enabled     *bool
+	maxBytes    int
+	body        bytes.Buffer
+	truncated   bool
+	stream      *bool
+	line        []byte
+	assembler   *service.AuditStreamAssembler
+}
+
+func (w *auditResponseWriter) isStream() bool {
+	if w.stream == nil {
+		stream := strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream")
+		w.stream = &stream
+	}
+	return *w.stream
+}
+
+func (w *auditResponseWriter) isEnabled() bool {
+	if w.enabled == nil {
+		enabled := w.shouldAudit()
+		w.enabled = &enabled
+	}
+	return *w.enabled
+}
+
+func (w *auditResponseWriter) capture(data []byte) {
+	if !w.isEnabled() {
+		return
+	}
+	if !w.isStream() {
+		// 预留空间给脱敏前的 base64 内容，脱敏后再截断
+		remaining := w.maxBytes*4 - w.body.Len()
+		if len(data) > remaining {
+			data = data[:max(remaining, 0)]
+			w.truncated = true
+		}
+		w.body.Write(data)
+		return
+	}
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			if len(w.line)+len(data) <= auditMaxLineBytes {
+				w.line = append(w.line, data...)
+			} else {
+				w.line = w.line[:0]
+			}
+			return
+		}
+		if len(w.line)+i <= auditMaxLineBytes {
+			w.line = append(w.line, data[:i]...)
+			w.assembler.AddLine(string(w.line))
+		}
+		w.line = w.line[:0]
+		data = data[i+1:]
+	}
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *auditResponseWriter) content() (string, bool) {
+	if w.isStream() {
+		if len(w.line) > 0 {
+			w.assembler.AddLine(string(w.line))
+		}
+		return service.RedactAuditContent(w.assembler.String()), w.assembler.Truncated()
+	}
+	content, truncated := service.TruncateAuditContent(service.RedactAuditContent(w.body.String()), w.maxBytes)
+	return content, truncated || w.truncated
+}
+
+// auditBodyReadable 只有 JSON 与文本请求体会被审计，multipart 等请求体不读取，以免影响后续的表单解析
+func auditBodyReadable(contentType string) bool {
+	return contentType == "" || strings.Contains(contentType, "json") || strings.HasPrefix(contentType, "text/")
+}
+
+// auditReadRequestBody 读取请求体后放回，后续中间件仍可以按原样读取
+func auditReadRequestBody(c *gin.Context) []byte {
+	if requestBody, ok := c.Get(common.KeyRequestBody); ok {
+		return requestBody.([]byte)
+	}
+	requestBody, err := io.ReadAll(c.Request.Body)
+	_ = c.Request.Body.Close()
+	c.Request.Body = io.NopCloser(bytes.NewReader(requestBody))
+	if err != nil {
+		return nil
+	}
+	return requestBody
+}
+
+// auditGroup 分发渠道之前使用令牌分组或用户分组判断是否审计
+func auditGroup(c *gin.Context) string {
+	if group := c.GetString("group"); group != "" {
+		return group
+	}
+	if group := c.GetString("token_group"); group != "" {
+		return group
+	}
+	return c.GetString(constant.ContextKeyUserGroup)
+}
+
+func auditRequestBody(requestBody []byte, contentType string, contentLength int64, maxBytes int) (string, bool) {
+	if !auditBodyReadable(contentType) {
+		return fmt.Sprintf("[%s body omitted, %d bytes]", contentType, contentLength), false
+	}
+	return service.TruncateAuditContent(service.RedactAuditContent(string(requestBody)), maxBytes)
+}
+
+// AuditCapture 对开启审计的分组或令牌保存请求体与返回给客户端的响应内容
+// 放在格式转换中间件之前时，记录的是客户端原始的路径、请求体与收到的响应
+func AuditCapture() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !operation_setting.GetAuditSetting().Enabled {
+			c.Next()
+			return
+		}
+		maxBytes := operation_setting.GetAuditSetting().MaxBodyBytes
+		if maxBytes <= 0 {
+			maxBytes = 64 * 1024
+		}
+		method, path := c.Request.Method, c.Request.URL.Path
+		contentType, contentLength := c.Request.Header.Get("Content-Type"), c.Request.ContentLength
+		var requestBody []byte
+		if auditBodyReadable(contentType) {
+			// 放在鉴权之前时还无法确定是否审计，先读取请求体
+			if _, authenticated := c.Get("token_id"); !authenticated ||
+				operation_setting.ShouldAudit(auditGroup(c), c.GetBool("token_audit_enabled")) {
+				requestBody = auditReadRequestBody(c)
+			}
+		}
+		writer := &auditResponseWriter{
+			ResponseWriter: c.Writer,
+			shouldAudit: func() bool {
+				return operation_setting.ShouldAudit(c.GetString("group"), c.GetBool("token_audit_enabled"))
+			},
+			maxBytes:  maxBytes,
+			assembler: service.NewAuditStreamAssembler(maxBytes),
+		}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+		if !writer.isEnabled() {
+			return
+		}
+		if requestBody == nil && auditBodyReadable(contentType) {
+			// 分发后才确定审计的请求（例如 auto 分组），使用后续中间件读取的请求体
+			if cached, ok := c.Get(common.KeyRequestBody); ok {
+				requestBody = cached.([]byte)
+			}
+		}
+
+		record := &model.AuditRecord{
+			RequestId:  c.GetString(common.RequestIdKey),
+			LogId:      c.GetInt(constant.ContextKeyLogId),
+			UserId:     c.GetInt("id"),
+			TokenId:    c.GetInt("token_id"),
+			Group:      c.GetString("group"),
+			ModelName:  c.GetString("original_model"),
+			ChannelId:  c.GetInt("channel_id"),
+			Method:     method,
+			Path:       path,
+			StatusCode: writer.Status(),
+			IsStream:   writer.isStream(),
+			CreatedAt:  common.GetTimestamp(),
+		}
+		record.RequestBody, record.RequestTruncated = auditRequestBody(requestBody, contentType, contentLength, maxBytes)
+		record.ResponseBody, record.ResponseTruncated = writer.content()
+		gopool.Go(func() {
+			if err := record.Insert(); err != nil {
+				common.SysError("failed to save audit record: " + err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useAuditSetting 修改审计配置，测试结束后恢复
+func useAuditSetting(t *testing.T, setting operation_setting.AuditSetting) {
+	current := operation_setting.GetAuditSetting()
+	saved := *current
+	*current = setting
+	t.Cleanup(func() {
+		*current = saved
+	})
+}
+
+// newAuditTestRouter 模拟鉴权中间件设置分组与令牌审计开关，handler 返回给客户端的内容由调用方决定
+func newAuditTestRouter(group string, tokenAudit bool, handler gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(common.RequestIdKey, c.GetHeader("X-Test-Request-Id"))
+		c.Set("group", group)
+		c.Set("token_audit_enabled", tokenAudit)
+		c.Set("id", 1)
+	})
+	router.Use(AuditCapture())
+	router.POST("/v1/chat/completions", handler)
+	return router
+}
+
+func waitAuditRecord(t *testing.T, requestId string) *model.AuditRecord {
+	var record *model.AuditRecord
+	require.Eventually(t, func() bool {
+		var err error
+		record, err = model.GetAuditRecordByRequestId(requestId)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return record
+}
+
+func postAuditRequest(router *gin.Engine, requestId string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-Request-Id", requestId)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// TestAuditCapture 测试审计分组的请求保存脱敏后的请求体与响应内容，未开启审计的分组不保存
+func TestAuditCapture(t *testing.T) {
+	setupTestDB(t)
+	useAuditSetting(t, operation_setting.AuditSetting{Enabled: true, Groups: []string{"audit"}, MaxBodyBytes: 1024,
+		RedactBase64: true, RedactFields: []string{"api_key"}})
+	handler := func(c *gin.Context) {
+		_, err := common.GetRequestBody(c)
+		assert.NoError(t, err)
+		c.Set("original_model", "gpt-4o")
+		c.JSON(http.StatusOK, gin.H{"image": strings.Repeat("A", 2000)})
+	}
+
+	router := newAuditTestRouter("audit", false, handler)
+	recorder := postAuditRequest(router, "audit-req-1", `{"model":"gpt-4o","api_key":"secret"}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	record := waitAuditRecord(t, "audit-req-1")
+	assert.Equal(t, "/v1/chat/completions", record.Path)
+	assert.Equal(t, "gpt-4o", record.ModelName)
+	assert.Equal(t, `{"model":"gpt-4o","api_key":"[redacted]"}`, record.RequestBody)
+	assert.Equal(t, `{"image":"[base64 redacted, 2000 bytes]"}`, record.ResponseBody)
+	assert.False(t, record.ResponseTruncated)
+
+	// 令牌单独开启审计时不受分组限制
+	router = newAuditTestRouter("default", true, handler)
+	postAuditRequest(router, "audit-req-2", `{"model":"gpt-4o"}`)
+	waitAuditRecord(t, "audit-req-2")
+
+	router = newAuditTestRouter("default", false, handler)
+	postAuditRequest(router, "audit-req-3", `{"model":"gpt-4o"}`)
+	time.Sleep(100 * time.Millisecond)
+	_, err := model.GetAuditRecordByRequestId("audit-req-3")
+	assert.Error(t, err)
+}
+
+// TestAuditCaptureStream 测试流式响应拼接为完整文本保存
+func TestAuditCaptureStream(t *testing.T) {
+	setupTestDB(t)
+	useAuditSetting(t, operation_setting.AuditSetting{Enabled: true, Groups: []string{"audit"}, MaxBodyBytes: 1024})
+	router := newAuditTestRouter("audit", false, func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Writer.WriteString("data: {\"choices\":[{\"delta\":{\"content\":\"hel\"}}]}\n\n")
+		c.Writer.WriteString("data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\ndata: [DONE]\n\n")
+	})
+	postAuditRequest(router, "audit-stream-1", `{"model":"gpt-4o","stream":true}`)
+	record := waitAuditRecord(t, "audit-stream-1")
+	assert.True(t, record.IsStream)
+	assert.Equal(t, "hello", record.ResponseBody)
+}
+
+// TestAuditCaptureRequestBody 测试读取后的请求体放回给后续处理，multipart 请求不读取并保留表单解析，已鉴权且不审计的请求不读取
+func TestAuditCaptureRequestBody(t *testing.T) {
+	setupTestDB(t)
+	useAuditSetting(t, operation_setting.AuditSetting{Enabled: true, Groups: []string{"audit"}, MaxBodyBytes: 1024})
+	router := newAuditTestRouter("audit", false, func(c *gin.Context) {
+		if c.ContentType() == "multipart/form-data" {
+			file, _, err := c.Request.FormFile("file")
+			require.NoError(t, err)
+			data, _ := io.ReadAll(file)
+			c.String(http.StatusOK, string(data))
+			return
+		}
+		data, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(data))
+	})
+	recorder := postAuditRequest(router, "audit-body-1", `{"model":"gpt-4o"}`)
+	assert.Equal(t, `{"model":"gpt-4o"}`, recorder.Body.String())
+	assert.Equal(t, `{"model":"gpt-4o"}`, waitAuditRecord(t, "audit-body-1").RequestBody)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "audio.mp3")
+	require.NoError(t, err)
+	part.Write([]byte("audio"))
+	require.NoError(t, writer.Close())
+	size := body.Len()
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("X-Test-Request-Id", "audit-body-2")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, "audio", recorder.Body.String())
+	assert.Equal(t, fmt.Sprintf("[%s body omitted, %d bytes]", writer.FormDataContentType(), size), waitAuditRecord(t, "audit-body-2").RequestBody)
+
+	router = gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("token_id", 1)
+		c.Set("token_group", "default")
+	}, AuditCapture())
+	router.POST("/v1/chat/completions", func(c *gin.Context) {
+		_, read := c.Get(common.KeyRequestBody)
+		assert.False(t, read)
+		c.Status(http.StatusOK)
+	})
+	postAuditRequest(router, "audit-body-3", `{"model":"gpt-4o"}`)
+}
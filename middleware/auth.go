@@ -3,6 +3,7 @@ package middleware
 import (
 	"net/http"
 	"one-api/common"
+	"one-api/constant"
 	"one-api/model"
 	"strconv"
 	"strings"
@@ -22,7 +23,8 @@ func validUserInfo(username string, role int) bool {
 	return true
 }
 
-func authHelper(c *gin.Context, minRole int) {
+// authHelper 校验登录状态与角色等级，指定 permissions 时还要求管理员拥有其中任一权限
+func authHelper(c *gin.Context, minRole int, permissions ...string) {
 	session := sessions.Default(c)
 	username := session.Get("username")
 	role := session.Get("role")
@@ -117,6 +119,26 @@ func authHelper(c *gin.Context, minRole int) {
 		c.Abort()
 		return
 	}
+	if len(permissions) > 0 {
+		granted, err := model.GetUserPermissions(id.(int), role.(int))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "无权进行此操作，获取权限失败",
+			})
+			c.Abort()
+			return
+		}
+		if !hasAnyPermission(granted, permissions) {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "无权进行此操作，缺少权限 " + strings.Join(permissions, " 或 "),
+			})
+			c.Abort()
+			return
+		}
+		c.Set(constant.ContextKeyPermissions, granted)
+	}
 	c.Set("username", username)
 	c.Set("role", role)
 	c.Set("id", id)
@@ -148,6 +170,22 @@ func AdminAuth() func(c *gin.Context) {
 	}
 }
 
+// PermissionAuth 要求管理员拥有任一指定权限
+func PermissionAuth(permissions ...string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		authHelper(c, common.RoleAdminUser, permissions...)
+	}
+}
+
+func hasAnyPermission(granted map[string]bool, permissions []string) bool {
+	for _, permission := range permissions {
+		if granted[permission] {
+			return true
+		}
+	}
+	return false
+}
+
 func RootAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		authHelper(c, common.RoleRootUser)
@@ -183,6 +221,13 @@ func TokenAuth() func(c *gin.Context) {
 				c.Request.Header.Set("Authorization", "Bearer "+key)
 			}
 		}
+		// Azure OpenAI 兼容接口从api-key中获取key
+		if strings.HasPrefix(c.Request.URL.Path, "/openai/deployments/") {
+			key := c.Request.Header.Get("api-key")
+			if key != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+key)
+			}
+		}
 		// gemini api 从query中获取key
 		if strings.HasPrefix(c.Request.URL.Path, "/v1beta/models/") {
 			skKey := c.Query("key")
@@ -249,6 +294,13 @@ func TokenAuth() func(c *gin.Context) {
 		}
 		c.Set("allow_ips", token.GetIpLimitsMap())
 		c.Set("token_group", token.Group)
+		c.Set("token_response_cache", token.ResponseCache)
+		c.Set("token_budget_enabled", token.HasBudget())
+		c.Set("token_audit_enabled", token.AuditEnabled)
+		c.Set("token_org_id", token.OrgId)
+		if token.ModelQuotas != "" {
+			c.Set("token_model_quotas", token.GetModelQuotas())
+		}
 		if len(parts) > 1 {
 			if model.IsAdmin(token.UserId) {
 				c.Set("specific_channel_id", parts[1])
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"strconv"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPermissionAuth 测试管理接口按权限放行，缺少权限的管理员和普通用户被拒绝
+func TestPermissionAuth(t *testing.T) {
+	setupTestDB(t)
+	role := &model.AdminRole{Name: "log reader", Permissions: `["log.read"]`}
+	require.NoError(t, role.Insert())
+	newUser := func(username string, userRole int, adminRoleId int) *model.User {
+		accessToken := username + "-access-token"
+		user := &model.User{Username: username, Password: "password", Role: userRole, Status: common.UserStatusEnabled,
+			AffCode: username, AccessToken: &accessToken, AdminRoleId: adminRoleId}
+		require.NoError(t, model.DB.Create(user).Error)
+		return user
+	}
+	admin := newUser("admin", common.RoleAdminUser, 0)
+	logReader := newUser("logreader", common.RoleAdminUser, role.Id)
+	user := newUser("user", common.RoleCommonUser, 0)
+
+	router := gin.New()
+	router.Use(sessions.Sessions("session", cookie.NewStore([]byte("secret"))))
+	router.GET("/channel", PermissionAuth(constant.PermissionChannelRead), func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	router.GET("/log", PermissionAuth(constant.PermissionChannelRead, constant.PermissionLogRead), func(c *gin.Context) {
+		_, ok := c.Get(constant.ContextKeyPermissions)
+		assert.True(t, ok)
+		c.String(http.StatusOK, "ok")
+	})
+	request := func(path string, user *model.User) string {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+*user.AccessToken)
+		req.Header.Set("New-Api-User", strconv.Itoa(user.Id))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Body.String()
+	}
+
+	assert.Equal(t, "ok", request("/channel", admin))
+	assert.Contains(t, request("/channel", logReader), "缺少权限 channel.read")
+	assert.Equal(t, "ok", request("/log", logReader))
+	assert.Contains(t, request("/log", user), "权限不足")
+}
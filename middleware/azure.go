@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/setting/operation_setting"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setAzureRequestModel 将部署名对应的模型写入请求，multipart 请求写入表单字段，JSON 请求写入 model 字段
+func setAzureRequestModel(c *gin.Context, modelName string) error {
+	if strings.HasPrefix(c.Request.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+			return err
+		}
+		c.Request.MultipartForm.Value["model"] = []string{modelName}
+		c.Request.PostForm.Set("model", modelName)
+		c.Request.Form.Set("model", modelName)
+		return nil
+	}
+	body, err := common.GetRequestBody(c)
+	if err != nil {
+		return err
+	}
+	request := make(map[string]json.RawMessage)
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err = json.Unmarshal(body, &request); err != nil {
+			return err
+		}
+	}
+	request["model"], _ = json.Marshal(modelName)
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	c.Set(common.KeyRequestBody, data)
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(data))
+	c.Request.ContentLength = int64(len(data))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return nil
+}
+
+// AzureCompatible 将 /openai/deployments/{deployment}/... 的 Azure OpenAI 请求转换为对应的 /v1 请求，
+// 部署名按分组映射为模型名
+func AzureCompatible() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		deployment := c.Param("deployment")
+		group := c.GetString("token_group")
+		if group == "" {
+			group = c.GetString(constant.ContextKeyUserGroup)
+		}
+		modelName := operation_setting.GetDeploymentModel(group, deployment)
+		if err := setAzureRequestModel(c, modelName); err != nil {
+			abortWithOpenAiMessage(c, http.StatusBadRequest, "Invalid request, "+err.Error())
+			return
+		}
+		c.Request.URL.Path = "/v1" + strings.TrimPrefix(c.Request.URL.Path, "/openai/deployments/"+deployment)
+		// 上游的 api-version 由渠道决定，不转发客户端的参数
+		query := c.Request.URL.Query()
+		query.Del("api-version")
+		c.Request.URL.RawQuery = query.Encode()
+		c.Next()
+	}
+}
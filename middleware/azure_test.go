@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useAzureDeploymentModels 设置部署名映射，测试结束后恢复
+func useAzureDeploymentModels(t *testing.T, deploymentModels map[string]string, groupDeploymentModels map[string]map[string]string) {
+	setting := operation_setting.GetAzureCompatSetting()
+	saved := *setting
+	setting.DeploymentModels = deploymentModels
+	setting.GroupDeploymentModels = groupDeploymentModels
+	t.Cleanup(func() {
+		*setting = saved
+	})
+}
+
+// newAzureTestRouter 在 AzureCompatible 之后记录改写后的路径、查询参数与请求体
+func newAzureTestRouter(pre gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	group := router.Group("/openai/deployments/:deployment", pre, AzureCompatible())
+	group.POST("/*path", func(c *gin.Context) {
+		if c.ContentType() == "multipart/form-data" {
+			file, _, err := c.Request.FormFile("file")
+			if err != nil {
+				c.String(http.StatusBadRequest, err.Error())
+				return
+			}
+			data, _ := io.ReadAll(file)
+			c.String(http.StatusOK, c.Request.URL.String()+" "+c.Request.FormValue("model")+" "+string(data))
+			return
+		}
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, c.Request.URL.String()+" "+string(body))
+	})
+	return router
+}
+
+// TestAzureCompatible 测试部署名按分组映射为模型，路径改写为 /v1 接口并去掉 api-version
+func TestAzureCompatible(t *testing.T) {
+	useAzureDeploymentModels(t, map[string]string{"prod": "gpt-4o"}, map[string]map[string]string{"vip": {"prod": "gpt-4.1"}})
+	router := newAzureTestRouter(func(c *gin.Context) {
+		c.Set("token_group", c.GetHeader("X-Test-Group"))
+	})
+	request := func(group string, deployment string, body string) string {
+		req := httptest.NewRequest(http.MethodPost, "/openai/deployments/"+deployment+"/chat/completions?api-version=2024-10-21&foo=bar", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-Group", group)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Body.String()
+	}
+
+	assert.Equal(t, `/v1/chat/completions?foo=bar {"messages":[],"model":"gpt-4o"}`, request("default", "prod", `{"model":"ignored","messages":[]}`))
+	assert.Equal(t, `/v1/chat/completions?foo=bar {"model":"gpt-4.1"}`, request("vip", "prod", ``))
+	assert.Equal(t, `/v1/chat/completions?foo=bar {"model":"gpt-4o-mini"}`, request("vip", "gpt-4o-mini", `{}`))
+	assert.Contains(t, request("default", "prod", `not json`), "Invalid request")
+}
+
+// TestAzureCompatibleMultipart 测试 multipart 请求写入 model 表单字段，文件内容保持可读
+func TestAzureCompatibleMultipart(t *testing.T) {
+	useAzureDeploymentModels(t, map[string]string{"whisper": "whisper-1"}, nil)
+	router := newAzureTestRouter(func(c *gin.Context) {})
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "audio.mp3")
+	require.NoError(t, err)
+	part.Write([]byte("audio"))
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/openai/deployments/whisper/audio/transcriptions?api-version=2024-10-21", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, "/v1/audio/transcriptions whisper-1 audio", recorder.Body.String())
+}
+
+// TestAzureApiKeyAuth 测试 Azure 兼容接口使用 api-key 请求头认证
+func TestAzureApiKeyAuth(t *testing.T) {
+	setupTestDB(t)
+	user := &model.User{Username: "azure", Password: "password", Status: common.UserStatusEnabled, Group: "default"}
+	require.NoError(t, model.DB.Create(user).Error)
+	token := &model.Token{UserId: user.Id, Key: "azuretestkey", Status: common.TokenStatusEnabled, ExpiredTime: -1, RemainQuota: 100}
+	require.NoError(t, model.DB.Create(token).Error)
+
+	router := gin.New()
+	router.POST("/openai/deployments/:deployment/chat/completions", TokenAuth(), func(c *gin.Context) {
+		c.String(http.StatusOK, "%d", c.GetInt("token_id"))
+	})
+	request := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/openai/deployments/prod/chat/completions", bytes.NewBufferString(`{}`))
+		req.Header.Set("api-key", apiKey)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := request(token.Key)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, strconv.Itoa(token.Id), recorder.Body.String())
+	assert.Equal(t, http.StatusUnauthorized, request("wrongkey").Code)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"one-api/common"
+	"one-api/common/tracing"
 	"one-api/constant"
 	"one-api/dto"
 	"one-api/model"
@@ -17,6 +18,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ModelRequest struct {
@@ -25,106 +28,169 @@ type ModelRequest struct {
 
 func Distribute() func(c *gin.Context) {
 	return func(c *gin.Context) {
-		allowIpsMap := c.GetStringMap("allow_ips")
-		if len(allowIpsMap) != 0 {
-			clientIp := c.ClientIP()
-			if _, ok := allowIpsMap[clientIp]; !ok {
-				abortWithOpenAiMessage(c, http.StatusForbidden, "您的 IP 不在令牌允许访问的列表中")
-				return
-			}
+		_, span := tracing.Start(c.Request.Context(), "distribute")
+		distribute(c, span)
+		span.End()
+		if c.IsAborted() {
+			model.ReleaseContextChannelProbe(c)
+			refundTokenModelRequest(c)
+			return
 		}
-		var channel *model.Channel
-		channelId, ok := c.Get("specific_channel_id")
-		modelRequest, shouldSelectChannel, err := getModelRequest(c)
-		if err != nil {
-			abortWithOpenAiMessage(c, http.StatusBadRequest, "Invalid request, "+err.Error())
+		c.Next()
+		// 未经过重试循环的转发（如 Midjourney）在这里归还熔断探测名额
+		model.ReleaseContextChannelProbe(c)
+		if c.Writer.Status() >= http.StatusBadRequest {
+			// 未成功转发的请求不计入令牌的模型请求数
+			refundTokenModelRequest(c)
+		}
+	}
+}
+
+// tokenModelReservation 预先记录的令牌模型请求
+type tokenModelReservation struct {
+	modelName string
+	quota     model.TokenModelQuota
+}
+
+// refundTokenModelRequest 退还 distribute 中预先记录的模型请求次数
+func refundTokenModelRequest(c *gin.Context) {
+	reserved, ok := c.Get("token_model_request_reserved")
+	if !ok {
+		return
+	}
+	c.Set("token_model_request_reserved", nil)
+	reservation, ok := reserved.(*tokenModelReservation)
+	if !ok {
+		return
+	}
+	err := model.RefundTokenModelRequest(c.GetInt("token_id"), reservation.modelName, reservation.quota)
+	if err != nil {
+		common.SysError("failed to refund token model request: " + err.Error())
+	}
+}
+
+// distribute 校验令牌的访问限制并选择渠道，失败时中止请求
+func distribute(c *gin.Context, span trace.Span) {
+	allowIpsMap := c.GetStringMap("allow_ips")
+	if len(allowIpsMap) != 0 {
+		clientIp := c.ClientIP()
+		if _, ok := allowIpsMap[clientIp]; !ok {
+			abortWithOpenAiMessage(c, http.StatusForbidden, "您的 IP 不在令牌允许访问的列表中")
+			return
+		}
+	}
+	var channel *model.Channel
+	channelId, ok := c.Get("specific_channel_id")
+	modelRequest, shouldSelectChannel, err := getModelRequest(c)
+	if err != nil {
+		abortWithOpenAiMessage(c, http.StatusBadRequest, "Invalid request, "+err.Error())
+		return
+	}
+	userGroup := c.GetString(constant.ContextKeyUserGroup)
+	tokenGroup := c.GetString("token_group")
+	if tokenGroup != "" {
+		// check common.UserUsableGroups[userGroup]
+		if _, ok := setting.GetUserUsableGroups(userGroup)[tokenGroup]; !ok {
+			abortWithOpenAiMessage(c, http.StatusForbidden, fmt.Sprintf("令牌分组 %s 已被禁用", tokenGroup))
 			return
 		}
-		userGroup := c.GetString(constant.ContextKeyUserGroup)
-		tokenGroup := c.GetString("token_group")
-		if tokenGroup != "" {
-			// check common.UserUsableGroups[userGroup]
-			if _, ok := setting.GetUserUsableGroups(userGroup)[tokenGroup]; !ok {
-				abortWithOpenAiMessage(c, http.StatusForbidden, fmt.Sprintf("令牌分组 %s 已被禁用", tokenGroup))
+		// check group in common.GroupRatio
+		if !ratio_setting.ContainsGroupRatio(tokenGroup) {
+			if tokenGroup != "auto" {
+				abortWithOpenAiMessage(c, http.StatusForbidden, fmt.Sprintf("分组 %s 已被弃用", tokenGroup))
 				return
 			}
-			// check group in common.GroupRatio
-			if !ratio_setting.ContainsGroupRatio(tokenGroup) {
-				if tokenGroup != "auto" {
-					abortWithOpenAiMessage(c, http.StatusForbidden, fmt.Sprintf("分组 %s 已被弃用", tokenGroup))
-					return
-				}
-			}
-			userGroup = tokenGroup
 		}
-		c.Set("group", userGroup)
-		if ok {
-			id, err := strconv.Atoi(channelId.(string))
-			if err != nil {
-				abortWithOpenAiMessage(c, http.StatusBadRequest, "无效的渠道 Id")
+		userGroup = tokenGroup
+	}
+	c.Set("group", userGroup)
+	// 检查令牌对该模型的请求数上限
+	if quotas, exists := c.Get("token_model_quotas"); exists {
+		if quota, found := quotas.(map[string]model.TokenModelQuota)[modelRequest.Model]; found {
+			err = model.ConsumeTokenModelRequest(c.GetInt("token_id"), modelRequest.Model, quota)
+			var quotaErr *model.TokenModelQuotaExceededError
+			if errors.As(err, &quotaErr) {
+				abortWithOpenAiMessage(c, http.StatusTooManyRequests, err.Error())
 				return
+			} else if err != nil {
+				common.SysError("failed to consume token model request: " + err.Error())
 			}
-			channel, err = model.GetChannelById(id, true)
-			if err != nil {
-				abortWithOpenAiMessage(c, http.StatusBadRequest, "无效的渠道 Id")
-				return
+			if err == nil {
+				c.Set("token_model_request_reserved", &tokenModelReservation{modelName: modelRequest.Model, quota: quota})
 			}
-			if channel.Status != common.ChannelStatusEnabled {
-				abortWithOpenAiMessage(c, http.StatusForbidden, "该渠道已被禁用")
-				return
+			c.Set("token_model_capped", quota.HasTokenLimit())
+		}
+	}
+	if ok {
+		id, err := strconv.Atoi(channelId.(string))
+		if err != nil {
+			abortWithOpenAiMessage(c, http.StatusBadRequest, "无效的渠道 Id")
+			return
+		}
+		channel, err = model.GetChannelById(id, true)
+		if err != nil {
+			abortWithOpenAiMessage(c, http.StatusBadRequest, "无效的渠道 Id")
+			return
+		}
+		if channel.Status != common.ChannelStatusEnabled {
+			abortWithOpenAiMessage(c, http.StatusForbidden, "该渠道已被禁用")
+			return
+		}
+	} else {
+		// Select a channel for the user
+		// check token model mapping
+		modelLimitEnable := c.GetBool("token_model_limit_enabled")
+		if modelLimitEnable {
+			s, ok := c.Get("token_model_limit")
+			var tokenModelLimit map[string]bool
+			if ok {
+				tokenModelLimit = s.(map[string]bool)
+			} else {
+				tokenModelLimit = map[string]bool{}
 			}
-		} else {
-			// Select a channel for the user
-			// check token model mapping
-			modelLimitEnable := c.GetBool("token_model_limit_enabled")
-			if modelLimitEnable {
-				s, ok := c.Get("token_model_limit")
-				var tokenModelLimit map[string]bool
-				if ok {
-					tokenModelLimit = s.(map[string]bool)
-				} else {
-					tokenModelLimit = map[string]bool{}
-				}
-				if tokenModelLimit != nil {
-					if _, ok := tokenModelLimit[modelRequest.Model]; !ok {
-						abortWithOpenAiMessage(c, http.StatusForbidden, "该令牌无权访问模型 "+modelRequest.Model)
-						return
-					}
-				} else {
-					// token model limit is empty, all models are not allowed
-					abortWithOpenAiMessage(c, http.StatusForbidden, "该令牌无权访问任何模型")
+			if tokenModelLimit != nil {
+				if _, ok := tokenModelLimit[modelRequest.Model]; !ok {
+					abortWithOpenAiMessage(c, http.StatusForbidden, "该令牌无权访问模型 "+modelRequest.Model)
 					return
 				}
+			} else {
+				// token model limit is empty, all models are not allowed
+				abortWithOpenAiMessage(c, http.StatusForbidden, "该令牌无权访问任何模型")
+				return
 			}
+		}
 
-			if shouldSelectChannel {
-				var selectGroup string
-				channel, selectGroup, err = model.CacheGetRandomSatisfiedChannel(c, userGroup, modelRequest.Model, 0)
-				if err != nil {
-					showGroup := userGroup
-					if userGroup == "auto" {
-						showGroup = fmt.Sprintf("auto(%s)", selectGroup)
-					}
-					message := fmt.Sprintf("当前分组 %s 下对于模型 %s 无可用渠道", showGroup, modelRequest.Model)
-					// 如果错误，但是渠道不为空，说明是数据库一致性问题
-					if channel != nil {
-						common.SysError(fmt.Sprintf("渠道不存在：%d", channel.Id))
-						message = "数据库一致性已被破坏，请联系管理员"
-					}
-					// 如果错误，而且渠道为空，说明是没有可用渠道
-					abortWithOpenAiMessage(c, http.StatusServiceUnavailable, message)
-					return
+		if shouldSelectChannel {
+			var selectGroup string
+			channel, selectGroup, err = model.CacheGetRandomSatisfiedChannel(c, userGroup, modelRequest.Model, 0)
+			if err != nil {
+				showGroup := userGroup
+				if userGroup == "auto" {
+					showGroup = fmt.Sprintf("auto(%s)", selectGroup)
 				}
-				if channel == nil {
-					abortWithOpenAiMessage(c, http.StatusServiceUnavailable, fmt.Sprintf("当前分组 %s 下对于模型 %s 无可用渠道（数据库一致性已被破坏）", userGroup, modelRequest.Model))
-					return
+				message := fmt.Sprintf("当前分组 %s 下对于模型 %s 无可用渠道", showGroup, modelRequest.Model)
+				// 如果错误，但是渠道不为空，说明是数据库一致性问题
+				if channel != nil {
+					common.SysError(fmt.Sprintf("渠道不存在：%d", channel.Id))
+					message = "数据库一致性已被破坏，请联系管理员"
 				}
+				// 如果错误，而且渠道为空，说明是没有可用渠道
+				abortWithOpenAiMessage(c, http.StatusServiceUnavailable, message)
+				return
+			}
+			if channel == nil {
+				abortWithOpenAiMessage(c, http.StatusServiceUnavailable, fmt.Sprintf("当前分组 %s 下对于模型 %s 无可用渠道（数据库一致性已被破坏）", userGroup, modelRequest.Model))
+				return
 			}
 		}
-		c.Set(constant.ContextKeyRequestStartTime, time.Now())
-		SetupContextForSelectedChannel(c, channel, modelRequest.Model)
-		c.Next()
 	}
+	c.Set(constant.ContextKeyRequestStartTime, time.Now())
+	SetupContextForSelectedChannel(c, channel, modelRequest.Model)
+	span.SetAttributes(
+		attribute.Int("channel.id", c.GetInt("channel_id")),
+		attribute.String("model", modelRequest.Model),
+		attribute.String("group", c.GetString("group")),
+	)
 }
 
 func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
@@ -235,6 +301,7 @@ func SetupContextForSelectedChannel(c *gin.Context, channel *model.Channel, mode
 	if channel == nil {
 		return
 	}
+	c.Set(constant.ContextKeyChannelStartTime, time.Now())
 	c.Set("channel_id", channel.Id)
 	c.Set("channel_name", channel.Name)
 	c.Set("channel_type", channel.Type)
@@ -247,7 +314,10 @@ func SetupContextForSelectedChannel(c *gin.Context, channel *model.Channel, mode
 	c.Set("auto_ban", channel.GetAutoBan())
 	c.Set("model_mapping", channel.GetModelMapping())
 	c.Set("status_code_mapping", channel.GetStatusCodeMapping())
-	c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", channel.Key))
+	key, keyIndex := channel.SelectKey()
+	c.Set("channel_multi_key", channel.IsMultiKey())
+	c.Set("channel_key_index", keyIndex)
+	c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key))
 	c.Set("base_url", channel.GetBaseURL())
 	// TODO: api_version统一
 	switch channel.Type {
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"one-api/common"
+	"one-api/common/metrics"
+	"one-api/constant"
+	relaycommon "one-api/relay/common"
+	"one-api/setting/operation_setting"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	metrics.NewGaugeFunc("new_api_active_connections", "Number of in-flight HTTP requests.", func() float64 {
+		return float64(atomic.LoadInt64(&globalStats.activeConnections))
+	})
+	metrics.NewGaugeFunc("new_api_relay_gopool_workers", "Running goroutines of the relay goroutine pool.", func() float64 {
+		return float64(common.RelayGoPoolWorkerCount())
+	})
+}
+
+// 兼容接口的入站格式，仅用于指标标签
+const (
+	relayFormatOllama = "ollama"
+	relayFormatAzure  = "azure"
+)
+
+// getRelayFormat 根据客户端请求的原始路径判断入站格式
+func getRelayFormat(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/api/"):
+		return relayFormatOllama
+	case strings.HasPrefix(path, "/openai/deployments/"):
+		return relayFormatAzure
+	case strings.HasPrefix(path, "/v1beta/"):
+		return relaycommon.RelayFormatGemini
+	case strings.HasPrefix(path, "/v1/messages"):
+		return relaycommon.RelayFormatClaude
+	case strings.HasPrefix(path, "/v1/responses"):
+		return relaycommon.RelayFormatOpenAIResponses
+	case strings.HasSuffix(path, "/embeddings"):
+		return relaycommon.RelayFormatEmbedding
+	case strings.HasPrefix(path, "/v1/rerank"):
+		return relaycommon.RelayFormatRerank
+	case strings.HasPrefix(path, "/v1/audio/"):
+		return relaycommon.RelayFormatOpenAIAudio
+	case strings.HasPrefix(path, "/v1/images/"):
+		return relaycommon.RelayFormatOpenAIImage
+	default:
+		return relaycommon.RelayFormatOpenAI
+	}
+}
+
+// RelayMetrics 记录转发请求的数量、耗时与首字时间
+func RelayMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		// 兼容接口会在后续中间件中改写路径，需在转发前记录
+		path := c.Request.URL.Path
+		c.Next()
+		labels := []string{
+			metrics.ChannelLabel(c.GetInt("channel_id")),
+			metrics.ModelLabel(c.GetString("original_model")),
+			c.GetString("group"),
+			getRelayFormat(path),
+			strconv.Itoa(c.Writer.Status()),
+		}
+		metrics.RelayRequests.WithLabelValues(labels...).Inc()
+		metrics.RelayRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		if firstResponse := c.GetTime(constant.ContextKeyFirstResponseTime); !firstResponse.IsZero() {
+			metrics.RelayFirstTokenDuration.WithLabelValues(labels...).Observe(firstResponse.Sub(start).Seconds())
+		}
+	}
+}
+
+func isMetricsIpAllowed(clientIp string, allowIps []string) bool {
+	ip := net.ParseIP(clientIp)
+	for _, allow := range allowIps {
+		allow = strings.TrimSpace(allow)
+		if allow == "" {
+			continue
+		}
+		if strings.Contains(allow, "/") {
+			if _, ipNet, err := net.ParseCIDR(allow); err == nil && ip != nil && ipNet.Contains(ip) {
+				return true
+			}
+		} else if allow == clientIp {
+			return true
+		}
+	}
+	return false
+}
+
+// MetricsAuth 校验指标接口的访问权限，允许配置的 IP 或携带正确的 Bearer Token
+func MetricsAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setting := operation_setting.GetMetricsSetting()
+		if !setting.Enabled {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		if isMetricsIpAllowed(c.ClientIP(), setting.AllowIps) {
+			c.Next()
+			return
+		}
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if setting.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(setting.Token)) == 1 {
+			c.Next()
+			return
+		}
+		c.AbortWithStatus(http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"one-api/common/metrics"
+	relaycommon "one-api/relay/common"
+	"one-api/setting/operation_setting"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+// useMetricsSetting 修改指标接口配置，测试结束后恢复
+func useMetricsSetting(t *testing.T, setting operation_setting.MetricsSetting) {
+	current := operation_setting.GetMetricsSetting()
+	saved := *current
+	*current = setting
+	t.Cleanup(func() {
+		*current = saved
+	})
+}
+
+func scrapeMetrics(t *testing.T) string {
+	recorder := httptest.NewRecorder()
+	promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	return recorder.Body.String()
+}
+
+// TestGetRelayFormat 测试按入站路径区分请求格式
+func TestGetRelayFormat(t *testing.T) {
+	assert.Equal(t, relaycommon.RelayFormatOpenAI, getRelayFormat("/v1/chat/completions"))
+	assert.Equal(t, relaycommon.RelayFormatClaude, getRelayFormat("/v1/messages"))
+	assert.Equal(t, relaycommon.RelayFormatGemini, getRelayFormat("/v1beta/models/gemini-pro:generateContent"))
+	assert.Equal(t, relaycommon.RelayFormatOpenAIResponses, getRelayFormat("/v1/responses"))
+	assert.Equal(t, relaycommon.RelayFormatEmbedding, getRelayFormat("/v1/engines/text-embedding-ada-002/embeddings"))
+	assert.Equal(t, relaycommon.RelayFormatOpenAIAudio, getRelayFormat("/v1/audio/speech"))
+	assert.Equal(t, relaycommon.RelayFormatOpenAIImage, getRelayFormat("/v1/images/generations"))
+}
+
+// TestRelayMetrics 测试转发请求按渠道、模型、分组、格式与状态码计数，未配置的模型名记为 other
+func TestRelayMetrics(t *testing.T) {
+	metrics.SetKnownModels([]string{"metrics-test-model"})
+	t.Cleanup(func() {
+		metrics.SetKnownModels(nil)
+	})
+	router := gin.New()
+	router.Use(RelayMetrics())
+	router.POST("/v1/chat/completions", func(c *gin.Context) {
+		c.Set("channel_id", 7)
+		c.Set("original_model", c.Query("model"))
+		c.Set("group", "default")
+		c.Status(http.StatusTooManyRequests)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/chat/completions?model=metrics-test-model", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/chat/completions?model=unknown-1", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/chat/completions?model=unknown-2", nil))
+
+	body := scrapeMetrics(t)
+	assert.Contains(t, body, `new_api_relay_requests_total{channel="7",group="default",model="metrics-test-model",relay_format="openai",status_code="429"} 1`)
+	assert.Contains(t, body, `new_api_relay_request_duration_seconds_count{channel="7",group="default",model="metrics-test-model",relay_format="openai",status_code="429"} 1`)
+	assert.Contains(t, body, `new_api_relay_requests_total{channel="7",group="default",model="other",relay_format="openai",status_code="429"} 2`)
+	assert.NotContains(t, body, `model="unknown-1"`)
+}
+
+// TestMetricsAuth 测试指标接口未启用时返回 404，允许配置的 IP 段或正确的 Bearer Token 访问
+func TestMetricsAuth(t *testing.T) {
+	router := gin.New()
+	router.GET("/metrics", MetricsAuth(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	request := func(remoteAddr string, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	useMetricsSetting(t, operation_setting.MetricsSetting{Enabled: false, Token: "secret"})
+	assert.Equal(t, http.StatusNotFound, request("10.0.0.1:1234", "secret"))
+
+	useMetricsSetting(t, operation_setting.MetricsSetting{Enabled: true, Token: "secret", AllowIps: []string{"10.0.0.0/8", "192.168.1.1"}})
+	assert.Equal(t, http.StatusOK, request("10.1.2.3:1234", ""))
+	assert.Equal(t, http.StatusOK, request("192.168.1.1:1234", ""))
+	assert.Equal(t, http.StatusUnauthorized, request("192.168.1.2:1234", ""))
+	assert.Equal(t, http.StatusUnauthorized, request("192.168.1.2:1234", "wrong"))
+	assert.Equal(t, http.StatusOK, request("192.168.1.2:1234", "secret"))
+
+	// 未设置 Token 时只允许配置的 IP
+	useMetricsSetting(t, operation_setting.MetricsSetting{Enabled: true})
+	assert.Equal(t, http.StatusUnauthorized, request("192.168.1.2:1234", ""))
+}
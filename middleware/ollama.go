@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/relay/channel/ollama"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// convertOllamaRequest 将 Ollama 请求体转换为 OpenAI 请求，返回转换后的请求、转发路径与是否流式
+func convertOllamaRequest(api string, body []byte) (any, string, bool, error) {
+	switch api {
+	case ollama.OllamaApiChat:
+		var request ollama.OllamaChatRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			return nil, "", false, err
+		}
+		openAIRequest, err := ollama.OllamaChatToOpenAIRequest(&request)
+		if err != nil {
+			return nil, "", false, err
+		}
+		return openAIRequest, "/v1/chat/completions", openAIRequest.Stream, nil
+	case ollama.OllamaApiGenerate:
+		var request ollama.OllamaGenerateRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			return nil, "", false, err
+		}
+		openAIRequest, err := ollama.OllamaGenerateToOpenAIRequest(&request)
+		if err != nil {
+			return nil, "", false, err
+		}
+		return openAIRequest, "/v1/chat/completions", openAIRequest.Stream, nil
+	case ollama.OllamaApiEmbed:
+		var request ollama.OllamaEmbedRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			return nil, "", false, err
+		}
+		embeddingRequest, err := ollama.OllamaEmbedToOpenAIRequest(&request)
+		if err != nil {
+			return nil, "", false, err
+		}
+		return embeddingRequest, "/v1/embeddings", false, nil
+	}
+	return nil, "", false, fmt.Errorf("unsupported ollama api: %s", api)
+}
+
+// OllamaCompatible 将 /api/chat、/api/generate 与 /api/embed 的 Ollama 请求转换为 OpenAI 请求，
+// 之后按 OpenAI 请求鉴权、分发与转发，响应（包括错误）转换回 Ollama 格式
+func OllamaCompatible() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		api := strings.TrimPrefix(c.Request.URL.Path, "/api/")
+		body, err := common.GetRequestBody(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		var modelRequest ModelRequest
+		_ = json.Unmarshal(body, &modelRequest)
+		request, path, stream, err := convertOllamaRequest(api, body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		data, err := json.Marshal(request)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		c.Set(common.KeyRequestBody, data)
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(data))
+		c.Request.ContentLength = int64(len(data))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.URL.Path = path
+
+		writer := ollama.NewOllamaConvertWriter(c.Writer, api, modelRequest.Model, stream)
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+		writer.Finish()
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"one-api/common/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Tracing 为转发请求创建入口 span，并记录令牌、渠道与模型信息
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !tracing.Enabled() {
+			c.Next()
+			return
+		}
+		ctx, span := tracing.StartServer(c.Request.Context(), c.Request.Method+" "+c.FullPath(), c.Request.Header)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		status := c.Writer.Status()
+		span.SetAttributes(
+			attribute.Int("http.status_code", status),
+			attribute.Int("token.id", c.GetInt("token_id")),
+			attribute.Int("channel.id", c.GetInt("channel_id")),
+			attribute.String("model", c.GetString("original_model")),
+			attribute.String("group", c.GetString("group")),
+		)
+		if status >= 500 {
+			span.SetStatus(codes.Error, "")
+		}
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"one-api/common"
+	"one-api/common/metrics"
+	"one-api/setting/operation_setting"
 	"strings"
+	"time"
 
 	"github.com/samber/lo"
 	"gorm.io/gorm"
@@ -35,12 +38,25 @@ func GetEnabledModels() []string {
 	return models
 }
 
+// SyncMetricsModels 定期将已启用的模型列表同步给指标标签，列表之外的模型名记为 other
+func SyncMetricsModels(frequency int) {
+	for {
+		metrics.SetKnownModels(GetEnabledModels())
+		time.Sleep(time.Duration(frequency) * time.Second)
+	}
+}
+
 func GetAllEnableAbilities() []Ability {
 	var abilities []Ability
 	DB.Find(&abilities, "enabled = ?", true)
 	return abilities
 }
 
+func GetAllAbilities() (abilities []Ability, err error) {
+	err = DB.Order("channel_id asc").Find(&abilities).Error
+	return abilities, err
+}
+
 func getPriority(group string, model string, retry int) (int, error) {
 
 	var priorities []int
@@ -99,8 +115,17 @@ func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 	if err != nil {
 		return nil, err
 	}
+	abilities = filterAbilitiesByBreaker(abilities)
 	channel := Channel{}
-	if len(abilities) > 0 {
+	if len(abilities) > 0 && operation_setting.IsAdaptiveRoutingGroup(group) {
+		channelIds := make([]int, len(abilities))
+		weights := make([]float64, len(abilities))
+		for i, ability_ := range abilities {
+			channelIds[i] = ability_.ChannelId
+			weights[i] = float64(ability_.Weight + 10)
+		}
+		channel.Id = abilities[pickWeightedIndex(adaptiveWeights(model, channelIds, weights))].ChannelId
+	} else if len(abilities) > 0 {
 		// Randomly choose one
 		weightSum := uint(0)
 		for _, ability_ := range abilities {
@@ -235,6 +260,27 @@ func (channel *Channel) UpdateAbilities(tx *gorm.DB) error {
 	return nil
 }
 
+// applyAbilityOverrides 覆盖渠道已有能力的启用状态、优先级与权重，不存在的能力会被忽略
+func applyAbilityOverrides(tx *gorm.DB, channelId int, abilities []Ability) error {
+	for _, ability := range abilities {
+		priority := int64(0)
+		if ability.Priority != nil {
+			priority = *ability.Priority
+		}
+		err := tx.Model(&Ability{}).
+			Where("channel_id = ? AND "+commonGroupCol+" = ? AND model = ?", channelId, ability.Group, ability.Model).
+			Updates(map[string]interface{}{
+				"enabled":  ability.Enabled,
+				"priority": priority,
+				"weight":   ability.Weight,
+			}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func UpdateAbilityStatus(channelId int, status bool) error {
 	return DB.Model(&Ability{}).Where("channel_id = ?", channelId).Select("enabled").Update("enabled", status).Error
 }
@@ -244,6 +290,10 @@ func UpdateAbilityStatusByTag(tag string, status bool) error {
 }
 
 func UpdateAbilityByTag(tag string, newTag *string, priority *int64, weight *uint) error {
+	return updateAbilityByTag(DB, tag, newTag, priority, weight)
+}
+
+func updateAbilityByTag(tx *gorm.DB, tag string, newTag *string, priority *int64, weight *uint) error {
 	ability := Ability{}
 	if newTag != nil {
 		ability.Tag = newTag
@@ -254,7 +304,7 @@ func UpdateAbilityByTag(tag string, newTag *string, priority *int64, weight *uin
 	if weight != nil {
 		ability.Weight = *weight
 	}
-	return DB.Model(&Ability{}).Where("tag = ?", tag).Updates(ability).Error
+	return tx.Model(&Ability{}).Where("tag = ?", tag).Updates(ability).Error
 }
 
 func FixAbility() (int, error) {
@@ -0,0 +1,245 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"one-api/common"
+	"one-api/setting/operation_setting"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	AdminAuditActionCreate = "create"
+	AdminAuditActionUpdate = "update"
+	AdminAuditActionDelete = "delete"
+)
+
+const (
+	AdminAuditEntityOption       = "option"
+	AdminAuditEntityChannel      = "channel"
+	AdminAuditEntityUser         = "user"
+	AdminAuditEntityAuthCode     = "auth_code"
+	AdminAuditEntityOrganization = "organization"
+	AdminAuditEntityToken        = "token"
+)
+
+const adminAuditMaskedValue = "******"
+
+var ErrAdminAuditAppendOnly = errors.New("admin audit log is append-only")
+
+// AdminAuditLog 管理操作审计日志，Diff 为变更字段的新旧值
+type AdminAuditLog struct {
+	Id         int    `json:"id"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint;index"`
+	ActorId    int    `json:"actor_id" gorm:"index"`
+	ActorName  string `json:"actor_name" gorm:"type:varchar(64)"`
+	Ip         string `json:"ip" gorm:"type:varchar(64)"`
+	Action     string `json:"action" gorm:"type:varchar(16)"`
+	EntityType string `json:"entity_type" gorm:"type:varchar(32);index:idx_admin_audit_entity,priority:1"`
+	EntityId   string `json:"entity_id" gorm:"type:varchar(128);index:idx_admin_audit_entity,priority:2"`
+	Diff       string `json:"diff" gorm:"type:text"`
+}
+
+// AdminAuditFieldChange 单个字段的新旧值
+type AdminAuditFieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+func (log *AdminAuditLog) BeforeUpdate(tx *gorm.DB) error {
+	if operation_setting.GetAdminAuditSetting().AppendOnly {
+		return ErrAdminAuditAppendOnly
+	}
+	return nil
+}
+
+func (log *AdminAuditLog) BeforeDelete(tx *gorm.DB) error {
+	if operation_setting.GetAdminAuditSetting().AppendOnly {
+		return ErrAdminAuditAppendOnly
+	}
+	return nil
+}
+
+// IsSensitiveFieldName 判断字段值是否需要脱敏
+func IsSensitiveFieldName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range []string{"key", "secret", "token", "password"} {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return slices.ContainsFunc(operation_setting.GetAdminAuditSetting().MaskFields, func(field string) bool {
+		return strings.EqualFold(field, name)
+	})
+}
+
+// secretAuditFields 返回结构体中带有 serializer:secret 标签的字段的 JSON 名称，这些字段加密存储，值需要脱敏
+func secretAuditFields(v interface{}) map[string]bool {
+	fields := make(map[string]bool)
+	if v == nil {
+		return fields
+	}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fields
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !strings.Contains(field.Tag.Get("gorm"), "serializer:secret") {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = true
+	}
+	return fields
+}
+
+// toAuditMap 将实体转换为字段映射，结构体按 JSON 字段名展开
+func toAuditMap(v interface{}) map[string]interface{} {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	if err = json.Unmarshal(data, &m); err != nil {
+		return map[string]interface{}{"value": v}
+	}
+	return m
+}
+
+// BuildAdminAuditDiff 计算新旧值中发生变化的字段，敏感字段与加密存储字段的值会被脱敏
+func BuildAdminAuditDiff(oldValue interface{}, newValue interface{}) map[string]AdminAuditFieldChange {
+	oldMap := toAuditMap(oldValue)
+	newMap := toAuditMap(newValue)
+	secretFields := secretAuditFields(oldValue)
+	for k := range secretAuditFields(newValue) {
+		secretFields[k] = true
+	}
+	diff := make(map[string]AdminAuditFieldChange)
+	keys := make(map[string]bool)
+	for k := range oldMap {
+		keys[k] = true
+	}
+	for k := range newMap {
+		keys[k] = true
+	}
+	for k := range keys {
+		oldField, oldOk := oldMap[k]
+		newField, newOk := newMap[k]
+		if oldOk && newOk && reflect.DeepEqual(oldField, newField) {
+			continue
+		}
+		if secretFields[k] || IsSensitiveFieldName(k) {
+			if oldOk && oldField != nil && oldField != "" {
+				oldField = adminAuditMaskedValue
+			}
+			if newOk && newField != nil && newField != "" {
+				newField = adminAuditMaskedValue
+			}
+		}
+		diff[k] = AdminAuditFieldChange{Old: oldField, New: newField}
+	}
+	return diff
+}
+
+// RecordAdminAudit 记录管理操作，oldValue 与 newValue 可以是结构体或字段映射，创建时 oldValue 为 nil，删除时 newValue 为 nil
+func RecordAdminAudit(c *gin.Context, action string, entityType string, entityId string, oldValue interface{}, newValue interface{}) {
+	if !operation_setting.GetAdminAuditSetting().Enabled {
+		return
+	}
+	diff := BuildAdminAuditDiff(oldValue, newValue)
+	if action == AdminAuditActionUpdate && len(diff) == 0 {
+		return
+	}
+	diffStr, err := json.Marshal(diff)
+	if err != nil {
+		common.SysError("failed to marshal admin audit diff: " + err.Error())
+		return
+	}
+	log := &AdminAuditLog{
+		CreatedAt:  common.GetTimestamp(),
+		ActorId:    c.GetInt("id"),
+		ActorName:  c.GetString("username"),
+		Ip:         c.ClientIP(),
+		Action:     action,
+		EntityType: entityType,
+		EntityId:   entityId,
+		Diff:       string(diffStr),
+	}
+	if err = DB.Create(log).Error; err != nil {
+		common.SysError("failed to record admin audit log: " + err.Error())
+	}
+}
+
+// GetAdminAuditLogs 按操作者、实体与时间范围查询管理操作审计日志
+func GetAdminAuditLogs(actorId int, entityType string, entityId string, startTimestamp int64, endTimestamp int64, startIdx int, num int) (logs []*AdminAuditLog, total int64, err error) {
+	tx := DB.Model(&AdminAuditLog{})
+	if actorId != 0 {
+		tx = tx.Where("actor_id = ?", actorId)
+	}
+	if entityType != "" {
+		tx = tx.Where("entity_type = ?", entityType)
+	}
+	if entityId != "" {
+		tx = tx.Where("entity_id = ?", entityId)
+	}
+	if startTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", startTimestamp)
+	}
+	if endTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", endTimestamp)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&logs).Error
+	return logs, total, err
+}
+
+// InstallAdminAuditAppendOnlyTriggers 在数据库层禁止修改与删除审计日志，触发器安装后关闭配置不会移除
+func InstallAdminAuditAppendOnlyTriggers() error {
+	var statements []string
+	switch {
+	case common.UsingPostgreSQL:
+		statements = []string{
+			`CREATE OR REPLACE FUNCTION admin_audit_logs_append_only() RETURNS trigger AS $$ BEGIN RAISE EXCEPTION 'admin audit log is append-only'; END; $$ LANGUAGE plpgsql`,
+			`DROP TRIGGER IF EXISTS admin_audit_logs_append_only ON admin_audit_logs`,
+			`CREATE TRIGGER admin_audit_logs_append_only BEFORE UPDATE OR DELETE ON admin_audit_logs FOR EACH ROW EXECUTE PROCEDURE admin_audit_logs_append_only()`,
+		}
+	case common.UsingMySQL:
+		statements = []string{
+			`DROP TRIGGER IF EXISTS admin_audit_logs_no_update`,
+			`CREATE TRIGGER admin_audit_logs_no_update BEFORE UPDATE ON admin_audit_logs FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'admin audit log is append-only'`,
+			`DROP TRIGGER IF EXISTS admin_audit_logs_no_delete`,
+			`CREATE TRIGGER admin_audit_logs_no_delete BEFORE DELETE ON admin_audit_logs FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'admin audit log is append-only'`,
+		}
+	default:
+		statements = []string{
+			`CREATE TRIGGER IF NOT EXISTS admin_audit_logs_no_update BEFORE UPDATE ON admin_audit_logs BEGIN SELECT RAISE(ABORT, 'admin audit log is append-only'); END`,
+			`CREATE TRIGGER IF NOT EXISTS admin_audit_logs_no_delete BEFORE DELETE ON admin_audit_logs BEGIN SELECT RAISE(ABORT, 'admin audit log is append-only'); END`,
+		}
+	}
+	for _, statement := range statements {
+		if err := DB.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
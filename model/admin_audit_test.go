@@ -0,0 +1,109 @@
+package model
+
+import (
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/setting/operation_setting"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useAdminAuditSetting 修改管理审计配置，测试结束后恢复
+func useAdminAuditSetting(t *testing.T, update func(setting *operation_setting.AdminAuditSetting)) {
+	setting := operation_setting.GetAdminAuditSetting()
+	saved := *setting
+	update(setting)
+	t.Cleanup(func() {
+		*setting = saved
+	})
+}
+
+// TestBuildAdminAuditDiff 测试只记录变化的字段，密钥类字段和配置的字段值被脱敏
+func TestBuildAdminAuditDiff(t *testing.T) {
+	useAdminAuditSetting(t, func(setting *operation_setting.AdminAuditSetting) {
+		setting.MaskFields = []string{"code"}
+	})
+	diff := BuildAdminAuditDiff(
+		map[string]interface{}{"name": "a", "key": "sk-old", "code": "", "weight": 1.0},
+		map[string]interface{}{"name": "b", "key": "sk-new", "code": "abc", "weight": 1.0},
+	)
+	assert.Equal(t, map[string]AdminAuditFieldChange{
+		"name": {Old: "a", New: "b"},
+		"key":  {Old: adminAuditMaskedValue, New: adminAuditMaskedValue},
+		"code": {Old: "", New: adminAuditMaskedValue},
+	}, diff)
+
+	diff = BuildAdminAuditDiff(nil, &Redemption{Name: "gift", Quota: 10})
+	assert.Equal(t, "gift", diff["name"].New)
+	assert.Nil(t, diff["name"].Old)
+
+	// 加密存储的渠道字段即使名称不敏感也需要脱敏
+	diff = BuildAdminAuditDiff(
+		&Channel{Name: "a", OtherInfo: `{"a":1}`, Setting: common.GetPointer(`{"proxy":"old"}`)},
+		&Channel{Name: "a", OtherInfo: `{"a":2}`, Setting: common.GetPointer(`{"proxy":"new"}`)},
+	)
+	assert.Equal(t, map[string]AdminAuditFieldChange{
+		"other_info": {Old: adminAuditMaskedValue, New: adminAuditMaskedValue},
+		"setting":    {Old: adminAuditMaskedValue, New: adminAuditMaskedValue},
+	}, diff)
+}
+
+// TestRecordAdminAudit 测试记录管理操作并按实体查询，未变化的修改不记录，只追加模式下拒绝修改与删除
+func TestRecordAdminAudit(t *testing.T) {
+	setupTestDB(t)
+	useAdminAuditSetting(t, func(setting *operation_setting.AdminAuditSetting) {
+		setting.Enabled = true
+		setting.AppendOnly = false
+	})
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("PUT", "/api/channel/", nil)
+	c.Set("id", 1)
+	c.Set("username", "root")
+
+	RecordAdminAudit(c, AdminAuditActionUpdate, AdminAuditEntityChannel, "1", map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "a"})
+	RecordAdminAudit(c, AdminAuditActionUpdate, AdminAuditEntityChannel, "1", map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "b"})
+	RecordAdminAudit(c, AdminAuditActionDelete, AdminAuditEntityUser, "2", map[string]interface{}{"username": "u"}, nil)
+	logs, total, err := GetAdminAuditLogs(1, AdminAuditEntityChannel, "1", 0, 0, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "root", logs[0].ActorName)
+	assert.JSONEq(t, `{"name":{"old":"a","new":"b"}}`, logs[0].Diff)
+
+	operation_setting.GetAdminAuditSetting().AppendOnly = true
+	assert.ErrorIs(t, DB.Model(logs[0]).Update("action", "create").Error, ErrAdminAuditAppendOnly)
+	assert.ErrorIs(t, DB.Delete(logs[0]).Error, ErrAdminAuditAppendOnly)
+
+	// 触发器在数据库层拒绝绕过模型钩子的修改
+	require.NoError(t, InstallAdminAuditAppendOnlyTriggers())
+	assert.Error(t, DB.Exec("DELETE FROM admin_audit_logs").Error)
+	_, total, err = GetAdminAuditLogs(0, "", "", 0, 0, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+}
+
+// TestAdminAuditAppendOnlyOption 测试运行时开启只追加配置后立即安装触发器
+func TestAdminAuditAppendOnlyOption(t *testing.T) {
+	setupTestDB(t)
+	useAdminAuditSetting(t, func(setting *operation_setting.AdminAuditSetting) {
+		setting.Enabled = true
+		setting.AppendOnly = false
+	})
+	require.NoError(t, DB.Create(&AdminAuditLog{Action: AdminAuditActionCreate}).Error)
+	common.OptionMapRWMutex.Lock()
+	saved := common.OptionMap
+	common.OptionMap = make(map[string]string)
+	common.OptionMapRWMutex.Unlock()
+	t.Cleanup(func() {
+		common.OptionMapRWMutex.Lock()
+		common.OptionMap = saved
+		common.OptionMapRWMutex.Unlock()
+	})
+
+	require.NoError(t, UpdateOption("admin_audit_setting.append_only", "true"))
+	assert.True(t, operation_setting.GetAdminAuditSetting().AppendOnly)
+	assert.Error(t, DB.Exec("DELETE FROM admin_audit_logs").Error)
+}
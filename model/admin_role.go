@@ -0,0 +1,118 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/constant"
+)
+
+// AdminRole 管理员权限角色，由一组命名权限组成
+type AdminRole struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name" gorm:"type:varchar(64);uniqueIndex"`
+	Description string `json:"description" gorm:"type:varchar(255)"`
+	// JSON 数组，例如 ["log.read","channel.read"]
+	Permissions string `json:"permissions" gorm:"type:text"`
+	CreatedTime int64  `json:"created_time" gorm:"bigint"`
+}
+
+func (role *AdminRole) GetPermissions() []string {
+	var permissions []string
+	if role.Permissions == "" {
+		return permissions
+	}
+	if err := common.DecodeJsonStr(role.Permissions, &permissions); err != nil {
+		common.SysError(fmt.Sprintf("failed to unmarshal permissions of admin role %d: %s", role.Id, err.Error()))
+	}
+	return permissions
+}
+
+// ValidatePermissions 检查角色中的权限名称是否均为已知权限
+func (role *AdminRole) ValidatePermissions() error {
+	if role.Permissions == "" {
+		return nil
+	}
+	var permissions []string
+	if err := common.DecodeJsonStr(role.Permissions, &permissions); err != nil {
+		return errors.New("权限格式应为字符串数组")
+	}
+	for _, permission := range permissions {
+		if _, ok := constant.AllPermissions[permission]; !ok {
+			return fmt.Errorf("未知的权限：%s", permission)
+		}
+	}
+	return nil
+}
+
+func GetAllAdminRoles() (roles []*AdminRole, err error) {
+	err = DB.Order("id").Find(&roles).Error
+	return roles, err
+}
+
+func GetAdminRoleById(id int) (*AdminRole, error) {
+	var role AdminRole
+	if err := DB.First(&role, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (role *AdminRole) Insert() error {
+	role.CreatedTime = common.GetTimestamp()
+	return DB.Create(role).Error
+}
+
+func (role *AdminRole) Update() error {
+	return DB.Model(role).Select("name", "description", "permissions").Updates(role).Error
+}
+
+// DeleteAdminRoleById 删除角色，仍分配给管理员的角色不能删除
+func DeleteAdminRoleById(id int) error {
+	var count int64
+	if err := DB.Model(&User{}).Where("admin_role_id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("仍有管理员使用该角色，请先解除分配")
+	}
+	return DB.Delete(&AdminRole{}, "id = ?", id).Error
+}
+
+// AssignAdminRole 为用户分配权限角色，roleId 为 0 时解除分配
+func AssignAdminRole(userId int, roleId int) error {
+	if roleId != 0 {
+		if _, err := GetAdminRoleById(roleId); err != nil {
+			return errors.New("角色不存在")
+		}
+	}
+	return DB.Model(&User{}).Where("id = ?", userId).Update("admin_role_id", roleId).Error
+}
+
+// GetUserPermissions 获取用户的管理权限。超级管理员和未分配角色的管理员拥有全部权限，普通用户没有管理权限
+func GetUserPermissions(userId int, role int) (map[string]bool, error) {
+	permissions := make(map[string]bool)
+	if role < common.RoleAdminUser {
+		return permissions, nil
+	}
+	roleId := 0
+	if role < common.RoleRootUser {
+		if err := DB.Model(&User{}).Where("id = ?", userId).Select("admin_role_id").Find(&roleId).Error; err != nil {
+			return nil, err
+		}
+	}
+	if roleId == 0 {
+		for permission := range constant.AllPermissions {
+			permissions[permission] = true
+		}
+		return permissions, nil
+	}
+	adminRole, err := GetAdminRoleById(roleId)
+	if err != nil {
+		return nil, err
+	}
+	for _, permission := range adminRole.GetPermissions() {
+		permissions[permission] = true
+	}
+	return permissions, nil
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"one-api/common"
+	"one-api/constant"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGetUserPermissions 测试超级管理员和未分配角色的管理员拥有全部权限，分配角色后只拥有角色中的权限，普通用户没有管理权限
+func TestGetUserPermissions(t *testing.T) {
+	setupTestDB(t)
+	admin := &User{Username: "admin", Password: "password", Role: common.RoleAdminUser, AffCode: "admin"}
+	require.NoError(t, DB.Create(admin).Error)
+
+	permissions, err := GetUserPermissions(admin.Id, common.RoleAdminUser)
+	require.NoError(t, err)
+	assert.Len(t, permissions, len(constant.AllPermissions))
+
+	role := &AdminRole{Name: "auditor", Permissions: `["log.read","audit.read"]`}
+	require.NoError(t, role.ValidatePermissions())
+	require.NoError(t, role.Insert())
+	require.NoError(t, AssignAdminRole(admin.Id, role.Id))
+	permissions, err = GetUserPermissions(admin.Id, common.RoleAdminUser)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{constant.PermissionLogRead: true, constant.PermissionAuditRead: true}, permissions)
+
+	permissions, err = GetUserPermissions(admin.Id, common.RoleRootUser)
+	require.NoError(t, err)
+	assert.Len(t, permissions, len(constant.AllPermissions))
+	permissions, err = GetUserPermissions(admin.Id, common.RoleCommonUser)
+	require.NoError(t, err)
+	assert.Empty(t, permissions)
+
+	assert.Error(t, AssignAdminRole(admin.Id, role.Id+1))
+	assert.Error(t, DeleteAdminRoleById(role.Id))
+	require.NoError(t, AssignAdminRole(admin.Id, 0))
+	assert.NoError(t, DeleteAdminRoleById(role.Id))
+}
+
+// TestValidatePermissions 测试角色权限必须是已知权限组成的字符串数组
+func TestValidatePermissions(t *testing.T) {
+	assert.NoError(t, (&AdminRole{}).ValidatePermissions())
+	assert.NoError(t, (&AdminRole{Permissions: `["channel.read"]`}).ValidatePermissions())
+	assert.Error(t, (&AdminRole{Permissions: `"channel.read"`}).ValidatePermissions())
+	assert.Error(t, (&AdminRole{Permissions: `["channel.delete"]`}).ValidatePermissions())
+}
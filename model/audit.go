@@ -0,0 +1,86 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"one-api/common"
+	"time"
+)
+
+// AuditRecord 开启审计的请求保存的请求体与响应内容，流式响应保存拼接后的文本
+type AuditRecord struct {
+	Id                int    `json:"id"`
+	RequestId         string `json:"request_id" gorm:"type:varchar(64);index"`
+	LogId             int    `json:"log_id" gorm:"index"`
+	UserId            int    `json:"user_id" gorm:"index"`
+	TokenId           int    `json:"token_id" gorm:"index"`
+	Group             string `json:"group" gorm:"type:varchar(64)"`
+	ModelName         string `json:"model_name" gorm:"type:varchar(255)"`
+	ChannelId         int    `json:"channel_id"`
+	Method            string `json:"method" gorm:"type:varchar(16)"`
+	Path              string `json:"path" gorm:"type:varchar(255)"`
+	StatusCode        int    `json:"status_code"`
+	IsStream          bool   `json:"is_stream"`
+	RequestBody       string `json:"request_body"`
+	RequestTruncated  bool   `json:"request_truncated"`
+	ResponseBody      string `json:"response_body"`
+	ResponseTruncated bool   `json:"response_truncated"`
+	CreatedAt         int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+func (record *AuditRecord) Insert() error {
+	return LOG_DB.Create(record).Error
+}
+
+func GetAuditRecordByRequestId(requestId string) (*AuditRecord, error) {
+	var record AuditRecord
+	err := LOG_DB.Where("request_id = ?", requestId).First(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func GetAuditRecordByLogId(logId int) (*AuditRecord, error) {
+	var record AuditRecord
+	err := LOG_DB.Where("log_id = ?", logId).First(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// DeleteOldAuditRecords 分批删除早于 targetTimestamp 的审计记录
+func DeleteOldAuditRecords(ctx context.Context, targetTimestamp int64, limit int) (int64, error) {
+	var total int64 = 0
+	for {
+		if nil != ctx.Err() {
+			return total, ctx.Err()
+		}
+		result := LOG_DB.Where("created_at < ?", targetTimestamp).Limit(limit).Delete(&AuditRecord{})
+		if nil != result.Error {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < int64(limit) {
+			break
+		}
+	}
+	return total, nil
+}
+
+// CleanAuditRecords 每小时删除超过保留天数的审计记录，retentionDays 返回 0 时不清理
+func CleanAuditRecords(retentionDays func() int) {
+	for {
+		if days := retentionDays(); days > 0 {
+			target := time.Now().AddDate(0, 0, -days).Unix()
+			count, err := DeleteOldAuditRecords(context.Background(), target, 1000)
+			if err != nil {
+				common.SysError("failed to clean audit records: " + err.Error())
+			} else if count > 0 {
+				common.SysLog(fmt.Sprintf("cleaned %d audit records", count))
+			}
+		}
+		time.Sleep(time.Hour)
+	}
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"one-api/common"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAuditRecordLogDB 测试单独配置日志库时审计记录与日志统计只建在日志库中
+func TestAuditRecordLogDB(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SQL_DSN", "")
+	t.Setenv("LOG_SQL_DSN", "local")
+	isMasterNode, redisEnabled := common.IsMasterNode, common.RedisEnabled
+	sqlitePath, usingSQLite, logSqlType := common.SQLitePath, common.UsingSQLite, common.LogSqlType
+	common.IsMasterNode = true
+	common.RedisEnabled = false
+	t.Cleanup(func() {
+		_ = CloseDB()
+		common.IsMasterNode, common.RedisEnabled = isMasterNode, redisEnabled
+		common.SQLitePath, common.UsingSQLite, common.LogSqlType = sqlitePath, usingSQLite, logSqlType
+	})
+	common.SQLitePath = filepath.Join(dir, "one-api.db") + "?_busy_timeout=5000"
+	require.NoError(t, InitDB())
+	common.SQLitePath = filepath.Join(dir, "one-api-log.db") + "?_busy_timeout=5000"
+	require.NoError(t, InitLogDB())
+
+	assert.False(t, DB.Migrator().HasTable(&AuditRecord{}))
+	assert.False(t, DB.Migrator().HasTable(&LogDailyStat{}))
+	assert.True(t, LOG_DB.Migrator().HasTable(&AuditRecord{}))
+	assert.True(t, LOG_DB.Migrator().HasTable(&LogDailyStat{}))
+}
+
+// TestUpdateTokenAuditEnabled 测试令牌的审计开关只能通过管理员接口修改，用户修改令牌时保持不变
+func TestUpdateTokenAuditEnabled(t *testing.T) {
+	setupTestDB(t)
+	token := &Token{UserId: 1, Name: "audit", Key: "audittokenkey", Status: common.TokenStatusEnabled}
+	require.NoError(t, token.Insert())
+
+	require.NoError(t, UpdateTokenAuditEnabled(token, true))
+	token, err := GetTokenById(token.Id)
+	require.NoError(t, err)
+	assert.True(t, token.AuditEnabled)
+
+	token.Name = "renamed"
+	token.AuditEnabled = false
+	require.NoError(t, token.Update())
+	token, err = GetTokenById(token.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", token.Name)
+	assert.True(t, token.AuditEnabled)
+}
@@ -0,0 +1,168 @@
+package model
+
+import (
+	"encoding/json"
+	"one-api/common"
+	"one-api/dto"
+)
+
+const (
+	BatchStatusValidating = "validating"
+	BatchStatusFailed     = "failed"
+	BatchStatusInProgress = "in_progress"
+	BatchStatusFinalizing = "finalizing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusExpired    = "expired"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+)
+
+// Batch 由网关执行的 OpenAI 批处理任务
+type Batch struct {
+	Id               string `json:"id" gorm:"type:varchar(64);primaryKey"`
+	UserId           int    `json:"user_id" gorm:"index"`
+	TokenId          int    `json:"token_id" gorm:"index"`
+	ClientIp         string `json:"client_ip" gorm:"type:varchar(64)"`
+	Endpoint         string `json:"endpoint" gorm:"type:varchar(64)"`
+	InputFileId      string `json:"input_file_id" gorm:"type:varchar(64)"`
+	CompletionWindow string `json:"completion_window" gorm:"type:varchar(16)"`
+	Status           string `json:"status" gorm:"type:varchar(16);index"`
+	OutputFileId     string `json:"output_file_id" gorm:"type:varchar(64)"`
+	ErrorFileId      string `json:"error_file_id" gorm:"type:varchar(64)"`
+	Errors           string `json:"errors" gorm:"type:text"`
+	Metadata         string `json:"metadata" gorm:"type:text"`
+	RequestTotal     int    `json:"request_total"`
+	RequestCompleted int    `json:"request_completed"`
+	RequestFailed    int    `json:"request_failed"`
+	CreatedAt        int64  `json:"created_at" gorm:"bigint;index"`
+	InProgressAt     int64  `json:"in_progress_at" gorm:"bigint"`
+	ExpiresAt        int64  `json:"expires_at" gorm:"bigint"`
+	FinalizingAt     int64  `json:"finalizing_at" gorm:"bigint"`
+	CompletedAt      int64  `json:"completed_at" gorm:"bigint"`
+	FailedAt         int64  `json:"failed_at" gorm:"bigint"`
+	ExpiredAt        int64  `json:"expired_at" gorm:"bigint"`
+	CancellingAt     int64  `json:"cancelling_at" gorm:"bigint"`
+	CancelledAt      int64  `json:"cancelled_at" gorm:"bigint"`
+}
+
+func NewBatchId() string {
+	return "batch_" + common.GetRandomString(32)
+}
+
+func (batch *Batch) Insert() error {
+	return DB.Create(batch).Error
+}
+
+// UpdateFields 更新指定字段
+func (batch *Batch) UpdateFields(fields ...string) error {
+	return DB.Model(batch).Select(fields).Updates(batch).Error
+}
+
+// TransitionStatus 仅当当前状态为 from 时更新为 to，用于多节点下抢占任务与取消
+func (batch *Batch) TransitionStatus(from string, to string, timeField string, now int64) (bool, error) {
+	updates := map[string]any{"status": to}
+	if timeField != "" {
+		updates[timeField] = now
+	}
+	result := DB.Model(&Batch{}).Where("id = ? and status = ?", batch.Id, from).Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	batch.Status = to
+	return true, nil
+}
+
+func (batch *Batch) GetStatus() (string, error) {
+	var status string
+	err := DB.Model(&Batch{}).Where("id = ?", batch.Id).Select("status").Scan(&status).Error
+	return status, err
+}
+
+func (batch *Batch) SetErrors(errors []dto.BatchError) {
+	data, err := json.Marshal(errors)
+	if err != nil {
+		return
+	}
+	batch.Errors = string(data)
+}
+
+func (batch *Batch) ToOpenAIBatch() *dto.OpenAIBatch {
+	result := &dto.OpenAIBatch{
+		Id:               batch.Id,
+		Object:           "batch",
+		Endpoint:         batch.Endpoint,
+		InputFileId:      batch.InputFileId,
+		CompletionWindow: batch.CompletionWindow,
+		Status:           batch.Status,
+		CreatedAt:        batch.CreatedAt,
+		InProgressAt:     nonZeroTime(batch.InProgressAt),
+		ExpiresAt:        nonZeroTime(batch.ExpiresAt),
+		FinalizingAt:     nonZeroTime(batch.FinalizingAt),
+		CompletedAt:      nonZeroTime(batch.CompletedAt),
+		FailedAt:         nonZeroTime(batch.FailedAt),
+		ExpiredAt:        nonZeroTime(batch.ExpiredAt),
+		CancellingAt:     nonZeroTime(batch.CancellingAt),
+		CancelledAt:      nonZeroTime(batch.CancelledAt),
+		RequestCounts: dto.BatchRequestCounts{
+			Total:     batch.RequestTotal,
+			Completed: batch.RequestCompleted,
+			Failed:    batch.RequestFailed,
+		},
+	}
+	if batch.OutputFileId != "" {
+		result.OutputFileId = common.GetPointer[string](batch.OutputFileId)
+	}
+	if batch.ErrorFileId != "" {
+		result.ErrorFileId = common.GetPointer[string](batch.ErrorFileId)
+	}
+	if batch.Errors != "" {
+		var errors []dto.BatchError
+		if err := json.Unmarshal([]byte(batch.Errors), &errors); err == nil && len(errors) > 0 {
+			result.Errors = &dto.BatchErrors{Object: "list", Data: errors}
+		}
+	}
+	if batch.Metadata != "" {
+		_ = json.Unmarshal([]byte(batch.Metadata), &result.Metadata)
+	}
+	return result
+}
+
+func nonZeroTime(t int64) *int64 {
+	if t == 0 {
+		return nil
+	}
+	return &t
+}
+
+func GetUserBatchById(userId int, id string) (*Batch, error) {
+	var batch Batch
+	err := DB.Where("id = ? and user_id = ?", id, userId).First(&batch).Error
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func GetUserBatches(userId int, after string, limit int) ([]*Batch, error) {
+	var batches []*Batch
+	query := DB.Where("user_id = ?", userId)
+	if after != "" {
+		var afterBatch Batch
+		if err := DB.Where("id = ? and user_id = ?", after, userId).First(&afterBatch).Error; err == nil {
+			query = query.Where("created_at < ? or (created_at = ? and id < ?)", afterBatch.CreatedAt, afterBatch.CreatedAt, afterBatch.Id)
+		}
+	}
+	err := query.Order("created_at desc, id desc").Limit(limit).Find(&batches).Error
+	return batches, err
+}
+
+// GetPendingBatches 获取需要后台处理的批处理任务
+func GetPendingBatches(limit int) ([]*Batch, error) {
+	var batches []*Batch
+	err := DB.Where("status in ?", []string{BatchStatusValidating, BatchStatusInProgress, BatchStatusCancelling}).
+		Order("created_at").Limit(limit).Find(&batches).Error
+	return batches, err
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"math/rand"
 	"one-api/common"
+	"one-api/constant"
 	"one-api/setting"
+	"one-api/setting/operation_setting"
 	"sort"
 	"strings"
 	"sync"
@@ -78,7 +80,31 @@ func SyncChannelCache(frequency int) {
 	}
 }
 
+// CacheGetRandomSatisfiedChannel 选择渠道并在半开熔断的渠道上占用探测名额，名额已被占满时重新选择；
+// 同时归还上一次尝试占用的名额
 func CacheGetRandomSatisfiedChannel(c *gin.Context, group string, model string, retry int) (*Channel, string, error) {
+	ReleaseContextChannelProbe(c)
+	var channel *Channel
+	var selectGroup string
+	var err error
+	for i := 0; i < 3; i++ {
+		channel, selectGroup, err = cacheSelectChannel(c, group, model, retry)
+		if err != nil {
+			return channel, selectGroup, err
+		}
+		probe, ok := TryAcquireChannel(channel.Id)
+		if ok {
+			if probe != nil {
+				c.Set(constant.ContextKeyChannelProbe, probe)
+			}
+			return channel, selectGroup, nil
+		}
+	}
+	// 候选渠道的探测名额均已占满时沿用最后选中的渠道，与熔断过滤全部渠道时的兜底行为一致
+	return channel, selectGroup, nil
+}
+
+func cacheSelectChannel(c *gin.Context, group string, model string, retry int) (*Channel, string, error) {
 	var channel *Channel
 	var err error
 	selectGroup := group
@@ -134,6 +160,8 @@ func getRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 	if len(channels) == 0 {
 		return nil, errors.New("channel not found")
 	}
+	// 跳过熔断中的渠道
+	channels = filterChannelsByBreaker(channels)
 
 	uniquePriorities := make(map[int]bool)
 	for _, channel := range channels {
@@ -160,6 +188,15 @@ func getRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 
 	// 平滑系数
 	smoothingFactor := 10
+	if operation_setting.IsAdaptiveRoutingGroup(group) {
+		channelIds := make([]int, len(targetChannels))
+		weights := make([]float64, len(targetChannels))
+		for i, channel := range targetChannels {
+			channelIds[i] = channel.Id
+			weights[i] = float64(channel.GetWeight() + smoothingFactor)
+		}
+		return targetChannels[pickWeightedIndex(adaptiveWeights(model, channelIds, weights))], nil
+	}
 	// Calculate the total weight of all channels up to endIdx
 	totalWeight := 0
 	for _, channel := range targetChannels {
@@ -203,3 +240,14 @@ func CacheUpdateChannelStatus(id int, status int) {
 		channel.Status = status
 	}
 }
+
+func CacheUpdateChannelKeyStatus(id int, keyStatusList *string) {
+	if !common.MemoryCacheEnabled {
+		return
+	}
+	channelSyncLock.Lock()
+	defer channelSyncLock.Unlock()
+	if channel, ok := channelsIDM[id]; ok {
+		channel.KeyStatusList = keyStatusList
+	}
+}
@@ -13,7 +13,7 @@ type Channel struct {
 	Id                 int     `json:"id"`
 	Type               int     `json:"type" gorm:"default:0"`
 	BusinessType       int     `json:"business_type" gorm:"default:1"` // 业务类型：1-对话，2-应用，3-工作流
-	Key                string  `json:"key" gorm:"not null"`
+	Key                string  `json:"key" gorm:"not null;serializer:secret"`
 	OpenAIOrganization *string `json:"openai_organization"`
 	TestModel          *string `json:"test_model"`
 	Status             int     `json:"status" gorm:"default:1"`
@@ -34,10 +34,12 @@ type Channel struct {
 	StatusCodeMapping *string `json:"status_code_mapping" gorm:"type:varchar(1024);default:''"`
 	Priority          *int64  `json:"priority" gorm:"bigint;default:0"`
 	AutoBan           *int    `json:"auto_ban" gorm:"default:1"`
-	OtherInfo         string  `json:"other_info"`
+	OtherInfo         string  `json:"other_info" gorm:"serializer:secret"`
 	Tag               *string `json:"tag" gorm:"index"`
-	Setting           *string `json:"setting" gorm:"type:text"`
+	Setting           *string `json:"setting" gorm:"type:text;serializer:secret"`
 	ParamOverride     *string `json:"param_override" gorm:"type:text"`
+	MultiKeyMode      string  `json:"multi_key_mode" gorm:"type:varchar(32);default:''"` // 多密钥选择策略，为空表示单密钥
+	KeyStatusList     *string `json:"key_status_list" gorm:"type:text"`                  // 多密钥渠道中被禁用的密钥状态
 }
 
 func (channel *Channel) GetModels() []string {
@@ -156,6 +158,7 @@ func SearchChannels(keyword string, group string, model string, idSort bool) ([]
 	baseQuery := DB.Model(&Channel{}).Omit("key")
 
 	// 构造WHERE子句
+	// 密钥经 serializer:secret 加密且每次使用随机 nonce，无法在数据库中按密钥匹配，因此不支持按密钥搜索
 	var whereClause string
 	var args []interface{}
 	if group != "" && group != "null" {
@@ -166,11 +169,11 @@ func SearchChannels(keyword string, group string, model string, idSort bool) ([]
 			// sqlite, PostgreSQL
 			groupCondition = `(',' || ` + commonGroupCol + ` || ',') LIKE ?`
 		}
-		whereClause = "(id = ? OR name LIKE ? OR " + commonKeyCol + " = ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + ` LIKE ? AND ` + groupCondition
-		args = append(args, common.String2Int(keyword), "%"+keyword+"%", keyword, "%"+keyword+"%", "%"+model+"%", "%,"+group+",%")
+		whereClause = "(id = ? OR name LIKE ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + ` LIKE ? AND ` + groupCondition
+		args = append(args, common.String2Int(keyword), "%"+keyword+"%", "%"+keyword+"%", "%"+model+"%", "%,"+group+",%")
 	} else {
-		whereClause = "(id = ? OR name LIKE ? OR " + commonKeyCol + " = ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + " LIKE ?"
-		args = append(args, common.String2Int(keyword), "%"+keyword+"%", keyword, "%"+keyword+"%", "%"+model+"%")
+		whereClause = "(id = ? OR name LIKE ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + " LIKE ?"
+		args = append(args, common.String2Int(keyword), "%"+keyword+"%", "%"+keyword+"%", "%"+model+"%")
 	}
 
 	// 执行查询
@@ -273,14 +276,54 @@ func (channel *Channel) Insert() error {
 }
 
 func (channel *Channel) Update() error {
-	var err error
-	err = DB.Model(channel).Updates(channel).Error
+	return channel.update(nil)
+}
+
+// UpdateWithVersion 在同一事务中更新渠道、重建 abilities 并保存版本，渠道还没有历史版本时先将修改前的配置保存为初始版本
+func (channel *Channel) UpdateWithVersion(version ChannelVersion) error {
+	return channel.update(&version)
+}
+
+func (channel *Channel) update(version *ChannelVersion) error {
+	// 多密钥状态只能由系统维护，不接受管理接口提交的值
+	channel.KeyStatusList = nil
+	rekeyed := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var existing Channel
+		if err := tx.First(&existing, "id = ?", channel.Id).Error; err != nil {
+			return err
+		}
+		if channel.Key != "" && channel.Key != existing.Key {
+			// 密钥变更后下标失效，重置多密钥状态
+			channel.KeyStatusList = common.GetPointer[string]("")
+			rekeyed = true
+		}
+		if version != nil {
+			if err := ensureChannelVersionBaseline(tx, &existing); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(channel).Updates(channel).Error; err != nil {
+			return err
+		}
+		if err := tx.First(channel, "id = ?", channel.Id).Error; err != nil {
+			return err
+		}
+		if err := channel.UpdateAbilities(tx); err != nil {
+			return err
+		}
+		if version != nil {
+			return saveChannelVersion(tx, channel, *version)
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	DB.Model(channel).First(channel, "id = ?", channel.Id)
-	err = channel.UpdateAbilities(nil)
-	return err
+	if rekeyed {
+		resetChannelKeyRuntime(channel.Id)
+	}
+	return nil
 }
 
 func (channel *Channel) UpdateResponseTime(responseTime int64) {
@@ -357,6 +400,11 @@ func UpdateChannelStatusById(id int, status int, reason string) bool {
 		info["status_time"] = common.GetTimestamp()
 		channel.SetOtherInfo(info)
 		channel.Status = status
+		if status == common.ChannelStatusEnabled && channel.IsMultiKey() {
+			// 渠道重新启用时同时恢复所有密钥
+			channel.KeyStatusList = common.GetPointer[string]("")
+			resetChannelKeyRuntime(id)
+		}
 		err = channel.Save()
 		if err != nil {
 			common.SysError("failed to update channel status: " + err.Error())
@@ -384,14 +432,13 @@ func DisableChannelByTag(tag string) error {
 	return err
 }
 
-func EditChannelByTag(tag string, newTag *string, modelMapping *string, models *string, group *string, priority *int64, weight *uint) error {
+// EditChannelByTag 在同一事务中批量修改标签下的渠道并为每个渠道保存版本
+func EditChannelByTag(tag string, newTag *string, modelMapping *string, models *string, group *string, priority *int64, weight *uint, version ChannelVersion) error {
 	updateData := Channel{}
 	shouldReCreateAbilities := false
-	updatedTag := tag
 	// 如果 newTag 不为空且不等于 tag，则更新 tag
 	if newTag != nil && *newTag != tag {
 		updateData.Tag = newTag
-		updatedTag = *newTag
 	}
 	if modelMapping != nil && *modelMapping != "" {
 		updateData.ModelMapping = modelMapping
@@ -411,27 +458,39 @@ func EditChannelByTag(tag string, newTag *string, modelMapping *string, models *
 		updateData.Weight = weight
 	}
 
-	err := DB.Model(&Channel{}).Where("tag = ?", tag).Updates(updateData).Error
-	if err != nil {
-		return err
-	}
-	if shouldReCreateAbilities {
-		channels, err := GetChannelsByTag(updatedTag, false)
-		if err == nil {
-			for _, channel := range channels {
-				err = channel.UpdateAbilities(nil)
-				if err != nil {
-					common.SysError("failed to update abilities: " + err.Error())
-				}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var channels []*Channel
+		if err := tx.Where("tag = ?", tag).Find(&channels).Error; err != nil {
+			return err
+		}
+		for _, channel := range channels {
+			if err := ensureChannelVersionBaseline(tx, channel); err != nil {
+				return err
 			}
 		}
-	} else {
-		err := UpdateAbilityByTag(tag, newTag, priority, weight)
-		if err != nil {
+		if err := tx.Model(&Channel{}).Where("tag = ?", tag).Updates(updateData).Error; err != nil {
 			return err
 		}
-	}
-	return nil
+		if !shouldReCreateAbilities {
+			if err := updateAbilityByTag(tx, tag, newTag, priority, weight); err != nil {
+				return err
+			}
+		}
+		for _, channel := range channels {
+			if err := tx.First(channel, "id = ?", channel.Id).Error; err != nil {
+				return err
+			}
+			if shouldReCreateAbilities {
+				if err := channel.UpdateAbilities(tx); err != nil {
+					return err
+				}
+			}
+			if err := saveChannelVersion(tx, channel, version); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func UpdateChannelUsedQuota(id int, quota int) {
@@ -488,7 +547,7 @@ func SearchTags(keyword string, group string, model string, idSort bool) ([]*str
 	// 构造基础查询
 	baseQuery := DB.Model(&Channel{}).Omit("key")
 
-	// 构造WHERE子句
+	// 构造WHERE子句，密钥已加密存储，不参与匹配
 	var whereClause string
 	var args []interface{}
 	if group != "" && group != "null" {
@@ -499,11 +558,11 @@ func SearchTags(keyword string, group string, model string, idSort bool) ([]*str
 			// sqlite, PostgreSQL
 			groupCondition = `(',' || ` + commonGroupCol + ` || ',') LIKE ?`
 		}
-		whereClause = "(id = ? OR name LIKE ? OR " + commonKeyCol + " = ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + ` LIKE ? AND ` + groupCondition
-		args = append(args, common.String2Int(keyword), "%"+keyword+"%", keyword, "%"+keyword+"%", "%"+model+"%", "%,"+group+",%")
+		whereClause = "(id = ? OR name LIKE ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + ` LIKE ? AND ` + groupCondition
+		args = append(args, common.String2Int(keyword), "%"+keyword+"%", "%"+keyword+"%", "%"+model+"%", "%,"+group+",%")
 	} else {
-		whereClause = "(id = ? OR name LIKE ? OR " + commonKeyCol + " = ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + " LIKE ?"
-		args = append(args, common.String2Int(keyword), "%"+keyword+"%", keyword, "%"+keyword+"%", "%"+model+"%")
+		whereClause = "(id = ? OR name LIKE ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + " LIKE ?"
+		args = append(args, common.String2Int(keyword), "%"+keyword+"%", "%"+keyword+"%", "%"+model+"%")
 	}
 
 	subQuery := baseQuery.Where(whereClause, args...).
@@ -0,0 +1,444 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/setting/operation_setting"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	CircuitStateClosed   = "closed"
+	CircuitStateOpen     = "open"
+	CircuitStateHalfOpen = "half_open"
+)
+
+// 熔断状态在 Redis 中共享的哈希键，field 为渠道 id
+const channelBreakerRedisKey = "channel_breakers"
+
+// 滚动窗口的分桶数量
+const channelBreakerBuckets = 10
+
+type breakerBucket struct {
+	start    int64
+	requests int
+	failures int
+}
+
+type channelBreaker struct {
+	state               string
+	openedAt            int64
+	consecutiveFailures int
+	halfOpenProbes      int
+	halfOpenSuccesses   int
+	halfOpenSince       int64
+	halfOpenGeneration  int64 // 每进入一次半开周期（或探测名额被重置）加一
+	lastFailure         string
+	buckets             [channelBreakerBuckets]breakerBucket
+}
+
+// ChannelBreakerInfo 供管理接口展示的熔断状态
+type ChannelBreakerInfo struct {
+	ChannelId           int     `json:"channel_id"`
+	State               string  `json:"state"`
+	OpenedAt            int64   `json:"opened_at"`
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	WindowRequests      int     `json:"window_requests"`
+	WindowFailures      int     `json:"window_failures"`
+	ErrorRate           float64 `json:"error_rate"`
+	LastFailure         string  `json:"last_failure"`
+}
+
+type sharedBreakerState struct {
+	State    string `json:"state"`
+	OpenedAt int64  `json:"opened_at"`
+}
+
+var channelBreakers = make(map[int]*channelBreaker)
+var channelBreakerLock sync.Mutex
+
+func getChannelBreaker(channelId int) *channelBreaker {
+	breaker, ok := channelBreakers[channelId]
+	if !ok {
+		breaker = &channelBreaker{state: CircuitStateClosed}
+		channelBreakers[channelId] = breaker
+	}
+	return breaker
+}
+
+func (b *channelBreaker) bucketSeconds() int64 {
+	windowSeconds := operation_setting.GetCircuitBreakerSetting().WindowSeconds
+	if windowSeconds < channelBreakerBuckets {
+		windowSeconds = channelBreakerBuckets
+	}
+	return int64(windowSeconds / channelBreakerBuckets)
+}
+
+func (b *channelBreaker) currentBucket(now int64) *breakerBucket {
+	size := b.bucketSeconds()
+	start := now - now%size
+	bucket := &b.buckets[(start/size)%channelBreakerBuckets]
+	if bucket.start != start {
+		*bucket = breakerBucket{start: start}
+	}
+	return bucket
+}
+
+func (b *channelBreaker) windowStats(now int64) (requests int, failures int) {
+	size := b.bucketSeconds()
+	oldest := now - size*channelBreakerBuckets
+	for _, bucket := range b.buckets {
+		if bucket.start > oldest {
+			requests += bucket.requests
+			failures += bucket.failures
+		}
+	}
+	return
+}
+
+func (b *channelBreaker) resetWindow() {
+	b.buckets = [channelBreakerBuckets]breakerBucket{}
+	b.consecutiveFailures = 0
+}
+
+// refresh 处理到期的状态迁移：open 超时后进入 half_open，half_open 探测长时间无结果时重新放行
+func (b *channelBreaker) refresh(now int64) {
+	setting := operation_setting.GetCircuitBreakerSetting()
+	switch b.state {
+	case CircuitStateOpen:
+		if now-b.openedAt >= int64(setting.OpenSeconds) {
+			b.toHalfOpen(now)
+		}
+	case CircuitStateHalfOpen:
+		if b.halfOpenProbes > 0 && now-b.halfOpenSince >= int64(setting.OpenSeconds) {
+			b.halfOpenProbes = 0
+			b.halfOpenSince = now
+			b.halfOpenGeneration++
+		}
+	}
+}
+
+func (b *channelBreaker) toOpen(now int64) {
+	b.state = CircuitStateOpen
+	b.openedAt = now
+	b.halfOpenProbes = 0
+	b.halfOpenSuccesses = 0
+}
+
+func (b *channelBreaker) toHalfOpen(now int64) {
+	b.state = CircuitStateHalfOpen
+	b.halfOpenProbes = 0
+	b.halfOpenSuccesses = 0
+	b.halfOpenSince = now
+	b.halfOpenGeneration++
+}
+
+func (b *channelBreaker) toClosed() {
+	b.state = CircuitStateClosed
+	b.openedAt = 0
+	b.halfOpenProbes = 0
+	b.halfOpenSuccesses = 0
+	b.resetWindow()
+}
+
+// IsChannelAvailable 判断渠道是否可以参与选择，不改变探测计数
+func IsChannelAvailable(channelId int) bool {
+	setting := operation_setting.GetCircuitBreakerSetting()
+	if !setting.Enabled {
+		return true
+	}
+	channelBreakerLock.Lock()
+	defer channelBreakerLock.Unlock()
+	breaker, ok := channelBreakers[channelId]
+	if !ok {
+		return true
+	}
+	breaker.refresh(time.Now().Unix())
+	switch breaker.state {
+	case CircuitStateOpen:
+		return false
+	case CircuitStateHalfOpen:
+		return breaker.halfOpenProbes < setting.HalfOpenMaxProbes
+	}
+	return true
+}
+
+// ChannelProbe 半开状态下占用的探测名额，generation 用于识别所属的半开周期
+type ChannelProbe struct {
+	ChannelId  int
+	generation int64
+}
+
+// TryAcquireChannel 在同一临界区内判断渠道是否可用，半开状态下同时占用一个探测名额。
+// 返回的 probe 仅在占用了名额时不为 nil，需要在本次尝试结束后调用 ReleaseChannelProbe 归还
+func TryAcquireChannel(channelId int) (*ChannelProbe, bool) {
+	setting := operation_setting.GetCircuitBreakerSetting()
+	if !setting.Enabled {
+		return nil, true
+	}
+	channelBreakerLock.Lock()
+	defer channelBreakerLock.Unlock()
+	breaker, ok := channelBreakers[channelId]
+	if !ok {
+		return nil, true
+	}
+	breaker.refresh(time.Now().Unix())
+	switch breaker.state {
+	case CircuitStateOpen:
+		return nil, false
+	case CircuitStateHalfOpen:
+		if breaker.halfOpenProbes >= setting.HalfOpenMaxProbes {
+			return nil, false
+		}
+		breaker.halfOpenProbes++
+		return &ChannelProbe{ChannelId: channelId, generation: breaker.halfOpenGeneration}, true
+	}
+	return nil, true
+}
+
+// ReleaseChannelProbe 归还探测名额，渠道已离开占用时的半开周期时忽略
+func ReleaseChannelProbe(probe *ChannelProbe) {
+	if probe == nil {
+		return
+	}
+	channelBreakerLock.Lock()
+	defer channelBreakerLock.Unlock()
+	breaker, ok := channelBreakers[probe.ChannelId]
+	if !ok || breaker.state != CircuitStateHalfOpen || breaker.halfOpenGeneration != probe.generation {
+		return
+	}
+	if breaker.halfOpenProbes > 0 {
+		breaker.halfOpenProbes--
+	}
+}
+
+// TakeContextChannelProbe 从上下文取出当前请求占用的探测名额，调用方记录本次结果后再归还
+func TakeContextChannelProbe(c *gin.Context) *ChannelProbe {
+	value, ok := c.Get(constant.ContextKeyChannelProbe)
+	if !ok {
+		return nil
+	}
+	c.Set(constant.ContextKeyChannelProbe, nil)
+	probe, _ := value.(*ChannelProbe)
+	return probe
+}
+
+// ReleaseContextChannelProbe 归还当前请求占用且尚未取出的探测名额
+func ReleaseContextChannelProbe(c *gin.Context) {
+	ReleaseChannelProbe(TakeContextChannelProbe(c))
+}
+
+// RecordChannelSuccess 记录一次成功的上游请求
+func RecordChannelSuccess(channelId int) {
+	setting := operation_setting.GetCircuitBreakerSetting()
+	if !setting.Enabled {
+		return
+	}
+	channelBreakerLock.Lock()
+	breaker := getChannelBreaker(channelId)
+	now := time.Now().Unix()
+	breaker.refresh(now)
+	breaker.currentBucket(now).requests++
+	breaker.consecutiveFailures = 0
+	changed := false
+	if breaker.state == CircuitStateHalfOpen {
+		breaker.halfOpenSuccesses++
+		if breaker.halfOpenSuccesses >= setting.HalfOpenSuccessThreshold {
+			breaker.toClosed()
+			changed = true
+		}
+	}
+	state := sharedBreakerState{State: breaker.state, OpenedAt: breaker.openedAt}
+	channelBreakerLock.Unlock()
+
+	if changed {
+		common.SysLog(fmt.Sprintf("channel #%d circuit breaker closed", channelId))
+		publishChannelBreakerState(channelId, state)
+	}
+}
+
+// RecordChannelFailure 记录一次失败的上游请求，达到阈值时熔断
+func RecordChannelFailure(channelId int, reason string) {
+	setting := operation_setting.GetCircuitBreakerSetting()
+	if !setting.Enabled {
+		return
+	}
+	channelBreakerLock.Lock()
+	breaker := getChannelBreaker(channelId)
+	now := time.Now().Unix()
+	breaker.refresh(now)
+	bucket := breaker.currentBucket(now)
+	bucket.requests++
+	bucket.failures++
+	breaker.consecutiveFailures++
+	breaker.lastFailure = reason
+	changed := false
+	switch breaker.state {
+	case CircuitStateHalfOpen:
+		// 探测失败，重新熔断
+		breaker.toOpen(now)
+		changed = true
+	case CircuitStateClosed:
+		requests, failures := breaker.windowStats(now)
+		tripByRate := requests >= setting.MinRequests && requests > 0 &&
+			float64(failures)/float64(requests) >= setting.ErrorRateThreshold
+		tripByCount := setting.ConsecutiveFailures > 0 && breaker.consecutiveFailures >= setting.ConsecutiveFailures
+		if tripByRate || tripByCount {
+			breaker.toOpen(now)
+			changed = true
+		}
+	}
+	state := sharedBreakerState{State: breaker.state, OpenedAt: breaker.openedAt}
+	channelBreakerLock.Unlock()
+
+	if changed {
+		common.SysLog(fmt.Sprintf("channel #%d circuit breaker opened, last failure: %s", channelId, reason))
+		publishChannelBreakerState(channelId, state)
+	}
+}
+
+// ResetChannelBreaker 手动关闭熔断器
+func ResetChannelBreaker(channelId int) {
+	channelBreakerLock.Lock()
+	breaker := getChannelBreaker(channelId)
+	breaker.toClosed()
+	channelBreakerLock.Unlock()
+	publishChannelBreakerState(channelId, sharedBreakerState{State: CircuitStateClosed})
+}
+
+func buildChannelBreakerInfo(channelId int, breaker *channelBreaker, now int64) ChannelBreakerInfo {
+	requests, failures := breaker.windowStats(now)
+	info := ChannelBreakerInfo{
+		ChannelId:           channelId,
+		State:               breaker.state,
+		OpenedAt:            breaker.openedAt,
+		ConsecutiveFailures: breaker.consecutiveFailures,
+		WindowRequests:      requests,
+		WindowFailures:      failures,
+		LastFailure:         breaker.lastFailure,
+	}
+	if requests > 0 {
+		info.ErrorRate = float64(failures) / float64(requests)
+	}
+	return info
+}
+
+func GetChannelBreakerInfo(channelId int) ChannelBreakerInfo {
+	channelBreakerLock.Lock()
+	defer channelBreakerLock.Unlock()
+	now := time.Now().Unix()
+	breaker, ok := channelBreakers[channelId]
+	if !ok {
+		return ChannelBreakerInfo{ChannelId: channelId, State: CircuitStateClosed}
+	}
+	breaker.refresh(now)
+	return buildChannelBreakerInfo(channelId, breaker, now)
+}
+
+func GetAllChannelBreakerInfos() []ChannelBreakerInfo {
+	channelBreakerLock.Lock()
+	defer channelBreakerLock.Unlock()
+	now := time.Now().Unix()
+	infos := make([]ChannelBreakerInfo, 0, len(channelBreakers))
+	for channelId, breaker := range channelBreakers {
+		breaker.refresh(now)
+		infos = append(infos, buildChannelBreakerInfo(channelId, breaker, now))
+	}
+	return infos
+}
+
+// filterChannelsByBreaker 过滤掉熔断中的渠道，全部被熔断时返回原列表以免完全不可用
+func filterChannelsByBreaker(channels []*Channel) []*Channel {
+	if !operation_setting.GetCircuitBreakerSetting().Enabled {
+		return channels
+	}
+	available := make([]*Channel, 0, len(channels))
+	for _, channel := range channels {
+		if IsChannelAvailable(channel.Id) {
+			available = append(available, channel)
+		}
+	}
+	if len(available) == 0 {
+		return channels
+	}
+	return available
+}
+
+func publishChannelBreakerState(channelId int, state sharedBreakerState) {
+	if !common.RedisEnabled {
+		return
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	err = common.RDB.HSet(context.Background(), channelBreakerRedisKey, strconv.Itoa(channelId), string(data)).Err()
+	if err != nil {
+		common.SysError("failed to publish channel breaker state: " + err.Error())
+	}
+}
+
+// SyncChannelBreakers 定期从 Redis 拉取其他节点发布的熔断状态
+func SyncChannelBreakers(frequency int) {
+	if frequency <= 0 {
+		frequency = 5
+	}
+	for {
+		time.Sleep(time.Duration(frequency) * time.Second)
+		if !common.RedisEnabled || !operation_setting.GetCircuitBreakerSetting().Enabled {
+			continue
+		}
+		result, err := common.RDB.HGetAll(context.Background(), channelBreakerRedisKey).Result()
+		if err != nil {
+			common.SysError("failed to sync channel breakers: " + err.Error())
+			continue
+		}
+		channelBreakerLock.Lock()
+		for field, value := range result {
+			channelId, err := strconv.Atoi(field)
+			if err != nil {
+				continue
+			}
+			var state sharedBreakerState
+			if err := json.Unmarshal([]byte(value), &state); err != nil {
+				continue
+			}
+			breaker := getChannelBreaker(channelId)
+			switch state.State {
+			case CircuitStateOpen:
+				if breaker.state == CircuitStateClosed || breaker.openedAt < state.OpenedAt {
+					breaker.toOpen(state.OpenedAt)
+				}
+			case CircuitStateClosed:
+				if breaker.state != CircuitStateClosed {
+					breaker.toClosed()
+				}
+			}
+		}
+		channelBreakerLock.Unlock()
+	}
+}
+
+// filterAbilitiesByBreaker 数据库选择路径下的熔断过滤，规则同 filterChannelsByBreaker
+func filterAbilitiesByBreaker(abilities []Ability) []Ability {
+	if !operation_setting.GetCircuitBreakerSetting().Enabled {
+		return abilities
+	}
+	available := make([]Ability, 0, len(abilities))
+	for _, ability := range abilities {
+		if IsChannelAvailable(ability.ChannelId) {
+			available = append(available, ability)
+		}
+	}
+	if len(available) == 0 {
+		return abilities
+	}
+	return available
+}
@@ -0,0 +1,198 @@
+package model
+
+import (
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/setting/operation_setting"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// enableTestCircuitBreaker 启用熔断并使用便于测试的阈值，测试结束后恢复原配置
+func enableTestCircuitBreaker(t *testing.T) *operation_setting.CircuitBreakerSetting {
+	setting := operation_setting.GetCircuitBreakerSetting()
+	original := *setting
+	redisEnabled := common.RedisEnabled
+	t.Cleanup(func() {
+		*setting = original
+		common.RedisEnabled = redisEnabled
+	})
+	common.RedisEnabled = false
+	setting.Enabled = true
+	setting.WindowSeconds = 60
+	setting.MinRequests = 100
+	setting.ErrorRateThreshold = 0.5
+	setting.ConsecutiveFailures = 3
+	setting.OpenSeconds = 30
+	setting.HalfOpenMaxProbes = 1
+	setting.HalfOpenSuccessThreshold = 2
+	return setting
+}
+
+// forceHalfOpen 将熔断器的打开时间前移，使下一次检查进入半开状态
+func forceHalfOpen(channelId int) {
+	channelBreakerLock.Lock()
+	defer channelBreakerLock.Unlock()
+	breaker := getChannelBreaker(channelId)
+	breaker.toOpen(time.Now().Unix() - 3600)
+}
+
+func getBreakerState(channelId int) string {
+	return GetChannelBreakerInfo(channelId).State
+}
+
+// TestChannelBreakerOpensOnConsecutiveFailures 测试连续失败达到阈值后熔断
+func TestChannelBreakerOpensOnConsecutiveFailures(t *testing.T) {
+	enableTestCircuitBreaker(t)
+	channelId := 900001
+
+	RecordChannelFailure(channelId, "upstream error")
+	RecordChannelFailure(channelId, "upstream error")
+	assert.Equal(t, CircuitStateClosed, getBreakerState(channelId))
+	_, ok := TryAcquireChannel(channelId)
+	assert.True(t, ok)
+
+	RecordChannelFailure(channelId, "upstream error")
+	assert.Equal(t, CircuitStateOpen, getBreakerState(channelId))
+	_, ok = TryAcquireChannel(channelId)
+	assert.False(t, ok)
+	assert.False(t, IsChannelAvailable(channelId))
+}
+
+// TestChannelBreakerSuccessResetsConsecutiveFailures 测试成功请求会重置连续失败计数
+func TestChannelBreakerSuccessResetsConsecutiveFailures(t *testing.T) {
+	enableTestCircuitBreaker(t)
+	channelId := 900002
+
+	RecordChannelFailure(channelId, "upstream error")
+	RecordChannelFailure(channelId, "upstream error")
+	RecordChannelSuccess(channelId)
+	RecordChannelFailure(channelId, "upstream error")
+	RecordChannelFailure(channelId, "upstream error")
+	assert.Equal(t, CircuitStateClosed, getBreakerState(channelId))
+}
+
+// TestChannelBreakerHalfOpenProbeLimit 测试半开状态下并发选择不会超过探测名额
+func TestChannelBreakerHalfOpenProbeLimit(t *testing.T) {
+	setting := enableTestCircuitBreaker(t)
+	setting.HalfOpenMaxProbes = 2
+	channelId := 900003
+	forceHalfOpen(channelId)
+
+	var acquired int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if probe, ok := TryAcquireChannel(channelId); ok {
+				assert.NotNil(t, probe)
+				atomic.AddInt32(&acquired, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), acquired)
+	assert.Equal(t, CircuitStateHalfOpen, getBreakerState(channelId))
+	assert.False(t, IsChannelAvailable(channelId))
+}
+
+// TestChannelBreakerReleaseProbe 测试未计入熔断统计的探测结束后也会归还名额
+func TestChannelBreakerReleaseProbe(t *testing.T) {
+	enableTestCircuitBreaker(t)
+	channelId := 900004
+	forceHalfOpen(channelId)
+
+	probe, ok := TryAcquireChannel(channelId)
+	assert.True(t, ok)
+	_, ok = TryAcquireChannel(channelId)
+	assert.False(t, ok)
+
+	ReleaseChannelProbe(probe)
+	probe, ok = TryAcquireChannel(channelId)
+	assert.True(t, ok)
+	assert.NotNil(t, probe)
+}
+
+// TestChannelBreakerHalfOpenRecovery 测试半开状态下连续成功后恢复
+func TestChannelBreakerHalfOpenRecovery(t *testing.T) {
+	enableTestCircuitBreaker(t)
+	channelId := 900005
+	forceHalfOpen(channelId)
+
+	for i := 0; i < 2; i++ {
+		probe, ok := TryAcquireChannel(channelId)
+		assert.True(t, ok)
+		RecordChannelSuccess(channelId)
+		ReleaseChannelProbe(probe)
+	}
+	assert.Equal(t, CircuitStateClosed, getBreakerState(channelId))
+	probe, ok := TryAcquireChannel(channelId)
+	assert.True(t, ok)
+	assert.Nil(t, probe)
+}
+
+// TestChannelBreakerHalfOpenFailureReopens 测试探测失败后重新熔断，过期的探测名额不影响新的半开周期
+func TestChannelBreakerHalfOpenFailureReopens(t *testing.T) {
+	enableTestCircuitBreaker(t)
+	channelId := 900006
+	forceHalfOpen(channelId)
+
+	staleProbe, ok := TryAcquireChannel(channelId)
+	assert.True(t, ok)
+	RecordChannelFailure(channelId, "probe failed")
+	assert.Equal(t, CircuitStateOpen, getBreakerState(channelId))
+
+	forceHalfOpen(channelId)
+	probe, ok := TryAcquireChannel(channelId)
+	assert.True(t, ok)
+	ReleaseChannelProbe(staleProbe)
+	_, ok = TryAcquireChannel(channelId)
+	assert.False(t, ok, "stale probe must not free a slot of the new half-open period")
+	ReleaseChannelProbe(probe)
+	_, ok = TryAcquireChannel(channelId)
+	assert.True(t, ok)
+}
+
+// TestChannelBreakerDisabled 测试关闭熔断时所有渠道均可用
+func TestChannelBreakerDisabled(t *testing.T) {
+	setting := enableTestCircuitBreaker(t)
+	channelId := 900007
+	for i := 0; i < 3; i++ {
+		RecordChannelFailure(channelId, "upstream error")
+	}
+	setting.Enabled = false
+	probe, ok := TryAcquireChannel(channelId)
+	assert.True(t, ok)
+	assert.Nil(t, probe)
+}
+
+// TestTakeContextChannelProbe 测试从上下文取出的探测名额在记录失败前不会被归还，失败后渠道重新熔断
+func TestTakeContextChannelProbe(t *testing.T) {
+	enableTestCircuitBreaker(t)
+	channelId := 900008
+	forceHalfOpen(channelId)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	probe, ok := TryAcquireChannel(channelId)
+	assert.True(t, ok)
+	c.Set(constant.ContextKeyChannelProbe, probe)
+
+	taken := TakeContextChannelProbe(c)
+	assert.Equal(t, probe, taken)
+	ReleaseContextChannelProbe(c)
+	_, ok = TryAcquireChannel(channelId)
+	assert.False(t, ok)
+
+	RecordChannelFailure(channelId, "upstream error")
+	ReleaseChannelProbe(taken)
+	assert.Equal(t, CircuitStateOpen, getBreakerState(channelId))
+	_, ok = TryAcquireChannel(channelId)
+	assert.False(t, ok)
+}
@@ -0,0 +1,311 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"one-api/common"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ChannelMultiKeyModeRoundRobin          = "round_robin"
+	ChannelMultiKeyModeRandom              = "random"
+	ChannelMultiKeyModeLeastRecentlyFailed = "least_recently_failed"
+)
+
+func IsValidChannelMultiKeyMode(mode string) bool {
+	switch mode {
+	case "", ChannelMultiKeyModeRoundRobin, ChannelMultiKeyModeRandom, ChannelMultiKeyModeLeastRecentlyFailed:
+		return true
+	}
+	return false
+}
+
+// ChannelKeyStatus 持久化的单个密钥状态，保存在 channels.key_status_list 中
+type ChannelKeyStatus struct {
+	Status     int    `json:"status"`
+	Reason     string `json:"reason,omitempty"`
+	StatusTime int64  `json:"status_time,omitempty"`
+}
+
+// ChannelKeyInfo 供管理接口展示的密钥状态
+type ChannelKeyInfo struct {
+	Index         int    `json:"index"`
+	Key           string `json:"key"`
+	Status        int    `json:"status"`
+	Reason        string `json:"reason"`
+	StatusTime    int64  `json:"status_time"`
+	CooldownUntil int64  `json:"cooldown_until"`
+	LastFailTime  int64  `json:"last_fail_time"`
+	FailCount     int64  `json:"fail_count"`
+}
+
+// channelKeyRuntime 仅保存在内存中的密钥运行时状态（轮询游标、冷却、失败时间）。
+// 状态按节点独立维护：多节点部署时各节点分别轮询和冷却，持久化的禁用状态才会在节点间同步。
+type channelKeyRuntime struct {
+	cursor        int
+	cooldownUntil map[int]int64
+	lastFailTime  map[int]int64
+	failCount     map[int]int64
+}
+
+var channelKeyRuntimes = make(map[int]*channelKeyRuntime)
+var channelKeyRuntimeLock sync.Mutex
+
+func getChannelKeyRuntime(channelId int) *channelKeyRuntime {
+	runtime, ok := channelKeyRuntimes[channelId]
+	if !ok {
+		runtime = &channelKeyRuntime{
+			cooldownUntil: make(map[int]int64),
+			lastFailTime:  make(map[int]int64),
+			failCount:     make(map[int]int64),
+		}
+		channelKeyRuntimes[channelId] = runtime
+	}
+	return runtime
+}
+
+func (channel *Channel) IsMultiKey() bool {
+	return channel.MultiKeyMode != ""
+}
+
+// GetKeys 返回渠道的密钥列表，多密钥渠道按行分隔
+func (channel *Channel) GetKeys() []string {
+	if !channel.IsMultiKey() {
+		return []string{channel.Key}
+	}
+	keys := make([]string, 0)
+	for _, key := range strings.Split(channel.Key, "\n") {
+		key = strings.TrimSpace(key)
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// GetFirstKey 返回渠道的第一个密钥，用于余额查询等只需要单个凭据的场景
+func (channel *Channel) GetFirstKey() string {
+	keys := channel.GetKeys()
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[0]
+}
+
+func (channel *Channel) GetKeyStatusMap() map[int]*ChannelKeyStatus {
+	statusMap := make(map[int]*ChannelKeyStatus)
+	if channel.KeyStatusList != nil && *channel.KeyStatusList != "" {
+		err := json.Unmarshal([]byte(*channel.KeyStatusList), &statusMap)
+		if err != nil {
+			common.SysError("failed to unmarshal key status list: " + err.Error())
+		}
+	}
+	return statusMap
+}
+
+func (channel *Channel) SetKeyStatusMap(statusMap map[int]*ChannelKeyStatus) {
+	statusBytes, err := json.Marshal(statusMap)
+	if err != nil {
+		common.SysError("failed to marshal key status list: " + err.Error())
+		return
+	}
+	channel.KeyStatusList = common.GetPointer[string](string(statusBytes))
+}
+
+func (channel *Channel) isKeyEnabled(statusMap map[int]*ChannelKeyStatus, index int) bool {
+	status, ok := statusMap[index]
+	return !ok || status.Status == common.ChannelStatusEnabled
+}
+
+// SelectKey 按渠道的多密钥策略选择一个可用密钥，返回密钥和下标。
+// 单密钥渠道直接返回 Key；所有密钥都在冷却时退化为选择最早失败的启用密钥。
+func (channel *Channel) SelectKey() (string, int) {
+	if !channel.IsMultiKey() {
+		return channel.Key, 0
+	}
+	keys := channel.GetKeys()
+	if len(keys) == 0 {
+		return "", 0
+	}
+	statusMap := channel.GetKeyStatusMap()
+	now := time.Now().Unix()
+
+	channelKeyRuntimeLock.Lock()
+	defer channelKeyRuntimeLock.Unlock()
+	runtime := getChannelKeyRuntime(channel.Id)
+
+	enabled := make([]int, 0, len(keys))
+	available := make([]int, 0, len(keys))
+	for i := range keys {
+		if !channel.isKeyEnabled(statusMap, i) {
+			continue
+		}
+		enabled = append(enabled, i)
+		if runtime.cooldownUntil[i] <= now {
+			available = append(available, i)
+		}
+	}
+	if len(enabled) == 0 {
+		// 所有密钥均已禁用，渠道本应被禁用，这里保守地使用第一个密钥
+		return keys[0], 0
+	}
+	if len(available) == 0 {
+		available = enabled
+		best := available[0]
+		for _, i := range available {
+			if runtime.cooldownUntil[i] < runtime.cooldownUntil[best] {
+				best = i
+			}
+		}
+		return keys[best], best
+	}
+
+	var index int
+	switch channel.MultiKeyMode {
+	case ChannelMultiKeyModeRandom:
+		index = available[rand.Intn(len(available))]
+	case ChannelMultiKeyModeLeastRecentlyFailed:
+		index = available[0]
+		for _, i := range available {
+			if runtime.lastFailTime[i] < runtime.lastFailTime[index] {
+				index = i
+			}
+		}
+	default:
+		// round_robin: 从游标开始找到下一个可用密钥
+		index = available[0]
+		for _, i := range available {
+			if i >= runtime.cursor {
+				index = i
+				break
+			}
+		}
+		runtime.cursor = index + 1
+	}
+	return keys[index], index
+}
+
+// GetKeyByIndex 返回指定下标的密钥，用于异步任务沿用提交时的密钥；下标无效时退化为 SelectKey
+func (channel *Channel) GetKeyByIndex(index int) string {
+	if !channel.IsMultiKey() {
+		return channel.Key
+	}
+	keys := channel.GetKeys()
+	if index >= 0 && index < len(keys) {
+		return keys[index]
+	}
+	key, _ := channel.SelectKey()
+	return key
+}
+
+// CoolDownChannelKey 使密钥在一段时间内不参与选择，渠道本身保持可用
+func CoolDownChannelKey(channelId int, index int, duration time.Duration) {
+	channelKeyRuntimeLock.Lock()
+	defer channelKeyRuntimeLock.Unlock()
+	runtime := getChannelKeyRuntime(channelId)
+	now := time.Now().Unix()
+	runtime.cooldownUntil[index] = now + int64(duration.Seconds())
+	runtime.lastFailTime[index] = now
+	runtime.failCount[index]++
+}
+
+// RecordChannelKeyFailure 记录密钥失败时间，用于 least_recently_failed 策略
+func RecordChannelKeyFailure(channelId int, index int) {
+	channelKeyRuntimeLock.Lock()
+	defer channelKeyRuntimeLock.Unlock()
+	runtime := getChannelKeyRuntime(channelId)
+	runtime.lastFailTime[index] = time.Now().Unix()
+	runtime.failCount[index]++
+}
+
+func resetChannelKeyRuntime(channelId int) {
+	channelKeyRuntimeLock.Lock()
+	defer channelKeyRuntimeLock.Unlock()
+	delete(channelKeyRuntimes, channelId)
+}
+
+// UpdateChannelKeyStatus 更新单个密钥的状态，返回状态是否发生变化以及是否所有密钥都已禁用
+func UpdateChannelKeyStatus(channelId int, index int, status int, reason string) (changed bool, allDisabled bool, err error) {
+	channelStatusLock.Lock()
+	defer channelStatusLock.Unlock()
+
+	channel, err := GetChannelById(channelId, true)
+	if err != nil {
+		return false, false, err
+	}
+	if !channel.IsMultiKey() {
+		return false, false, errors.New("渠道未启用多密钥模式")
+	}
+	keys := channel.GetKeys()
+	if index < 0 || index >= len(keys) {
+		return false, false, errors.New("密钥下标超出范围")
+	}
+	statusMap := channel.GetKeyStatusMap()
+	if channel.isKeyEnabled(statusMap, index) == (status == common.ChannelStatusEnabled) {
+		return false, false, nil
+	}
+	if status == common.ChannelStatusEnabled {
+		delete(statusMap, index)
+	} else {
+		statusMap[index] = &ChannelKeyStatus{
+			Status:     status,
+			Reason:     reason,
+			StatusTime: common.GetTimestamp(),
+		}
+	}
+	channel.SetKeyStatusMap(statusMap)
+	err = DB.Model(channel).Select("key_status_list").Updates(channel).Error
+	if err != nil {
+		return false, false, err
+	}
+	CacheUpdateChannelKeyStatus(channelId, channel.KeyStatusList)
+
+	allDisabled = true
+	for i := range keys {
+		if channel.isKeyEnabled(statusMap, i) {
+			allDisabled = false
+			break
+		}
+	}
+	return true, allDisabled, nil
+}
+
+// GetChannelKeyInfos 汇总多密钥渠道每个密钥的持久化状态与运行时状态
+func GetChannelKeyInfos(channel *Channel) []ChannelKeyInfo {
+	keys := channel.GetKeys()
+	statusMap := channel.GetKeyStatusMap()
+
+	channelKeyRuntimeLock.Lock()
+	defer channelKeyRuntimeLock.Unlock()
+	runtime := getChannelKeyRuntime(channel.Id)
+
+	infos := make([]ChannelKeyInfo, 0, len(keys))
+	for i, key := range keys {
+		info := ChannelKeyInfo{
+			Index:         i,
+			Key:           maskChannelKey(key),
+			Status:        common.ChannelStatusEnabled,
+			CooldownUntil: runtime.cooldownUntil[i],
+			LastFailTime:  runtime.lastFailTime[i],
+			FailCount:     runtime.failCount[i],
+		}
+		if status, ok := statusMap[i]; ok {
+			info.Status = status.Status
+			info.Reason = status.Reason
+			info.StatusTime = status.StatusTime
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+func maskChannelKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return key[:4] + "****" + key[len(key)-4:]
+}
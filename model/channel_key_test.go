@@ -0,0 +1,122 @@
+package model
+
+import (
+	"one-api/common"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestMultiKeyChannel 创建多密钥渠道，disabled 中的下标标记为禁用，测试结束后清理运行时状态
+func newTestMultiKeyChannel(t *testing.T, id int, mode string, disabled ...int) *Channel {
+	channel := &Channel{Id: id, Key: "sk-key-0\n\n  sk-key-1  \nsk-key-2\n", MultiKeyMode: mode}
+	statusMap := make(map[int]*ChannelKeyStatus)
+	for _, index := range disabled {
+		statusMap[index] = &ChannelKeyStatus{Status: common.ChannelStatusManuallyDisabled}
+	}
+	channel.SetKeyStatusMap(statusMap)
+	resetChannelKeyRuntime(id)
+	t.Cleanup(func() {
+		resetChannelKeyRuntime(id)
+	})
+	return channel
+}
+
+func selectKeyIndexes(channel *Channel, n int) []int {
+	indexes := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		_, index := channel.SelectKey()
+		indexes = append(indexes, index)
+	}
+	return indexes
+}
+
+// TestChannelGetKeys 测试多密钥按行拆分并忽略空行，单密钥渠道整体作为一个密钥
+func TestChannelGetKeys(t *testing.T) {
+	channel := newTestMultiKeyChannel(t, 910001, ChannelMultiKeyModeRoundRobin)
+	assert.Equal(t, []string{"sk-key-0", "sk-key-1", "sk-key-2"}, channel.GetKeys())
+	assert.Equal(t, "sk-key-0", channel.GetFirstKey())
+
+	single := &Channel{Id: 910002, Key: "ak|sk|region"}
+	assert.Equal(t, []string{"ak|sk|region"}, single.GetKeys())
+	key, index := single.SelectKey()
+	assert.Equal(t, "ak|sk|region", key)
+	assert.Equal(t, 0, index)
+}
+
+// TestSelectKeyRoundRobin 测试轮询依次选择密钥并跳过已禁用的密钥
+func TestSelectKeyRoundRobin(t *testing.T) {
+	channel := newTestMultiKeyChannel(t, 910003, ChannelMultiKeyModeRoundRobin)
+	assert.Equal(t, []int{0, 1, 2, 0, 1}, selectKeyIndexes(channel, 5))
+
+	channel = newTestMultiKeyChannel(t, 910004, ChannelMultiKeyModeRoundRobin, 1)
+	assert.Equal(t, []int{0, 2, 0, 2}, selectKeyIndexes(channel, 4))
+	key, _ := channel.SelectKey()
+	assert.Equal(t, "sk-key-0", key)
+}
+
+// TestSelectKeyCooldown 测试冷却中的密钥不参与选择，全部冷却时选择最早结束冷却的密钥
+func TestSelectKeyCooldown(t *testing.T) {
+	channel := newTestMultiKeyChannel(t, 910005, ChannelMultiKeyModeRoundRobin)
+	CoolDownChannelKey(channel.Id, 0, time.Minute)
+	assert.Equal(t, []int{1, 2, 1, 2}, selectKeyIndexes(channel, 4))
+
+	CoolDownChannelKey(channel.Id, 1, 3*time.Minute)
+	CoolDownChannelKey(channel.Id, 2, 2*time.Minute)
+	_, index := channel.SelectKey()
+	assert.Equal(t, 0, index)
+
+	infos := GetChannelKeyInfos(channel)
+	assert.Len(t, infos, 3)
+	assert.Equal(t, "****", infos[0].Key)
+	assert.Equal(t, "sk-a****wxyz", maskChannelKey("sk-abcdefwxyz"))
+	assert.Equal(t, int64(1), infos[0].FailCount)
+	assert.Greater(t, infos[1].CooldownUntil, time.Now().Unix())
+}
+
+// TestSelectKeyLeastRecentlyFailed 测试优先选择最久未失败的密钥
+func TestSelectKeyLeastRecentlyFailed(t *testing.T) {
+	channel := newTestMultiKeyChannel(t, 910006, ChannelMultiKeyModeLeastRecentlyFailed)
+	_, index := channel.SelectKey()
+	assert.Equal(t, 0, index)
+
+	RecordChannelKeyFailure(channel.Id, 0)
+	_, index = channel.SelectKey()
+	assert.Equal(t, 1, index)
+
+	RecordChannelKeyFailure(channel.Id, 1)
+	_, index = channel.SelectKey()
+	assert.Equal(t, 2, index)
+}
+
+// TestSelectKeyRandom 测试随机模式只在启用且未冷却的密钥中选择
+func TestSelectKeyRandom(t *testing.T) {
+	channel := newTestMultiKeyChannel(t, 910007, ChannelMultiKeyModeRandom, 0)
+	CoolDownChannelKey(channel.Id, 1, time.Minute)
+	for _, index := range selectKeyIndexes(channel, 50) {
+		assert.Equal(t, 2, index)
+	}
+}
+
+// TestSelectKeyAllDisabled 测试所有密钥均被禁用时退化为第一个密钥
+func TestSelectKeyAllDisabled(t *testing.T) {
+	channel := newTestMultiKeyChannel(t, 910008, ChannelMultiKeyModeRandom, 0, 1, 2)
+	key, index := channel.SelectKey()
+	assert.Equal(t, "sk-key-0", key)
+	assert.Equal(t, 0, index)
+}
+
+// TestGetKeyByIndex 测试异步任务按提交时的下标取回同一密钥，不受轮询游标和冷却影响，下标无效时退化为 SelectKey
+func TestGetKeyByIndex(t *testing.T) {
+	channel := newTestMultiKeyChannel(t, 910009, ChannelMultiKeyModeRoundRobin)
+	CoolDownChannelKey(910009, 2, time.Minute)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "sk-key-2", channel.GetKeyByIndex(2))
+	}
+	assert.Equal(t, []int{0}, selectKeyIndexes(channel, 1))
+	assert.Equal(t, "sk-key-1", channel.GetKeyByIndex(5))
+
+	single := &Channel{Id: 910010, Key: "sk-single"}
+	assert.Equal(t, "sk-single", single.GetKeyByIndex(3))
+}
@@ -0,0 +1,161 @@
+package model
+
+import (
+	"math"
+	"math/rand"
+	"one-api/setting/operation_setting"
+	"sync"
+	"time"
+)
+
+type channelModelKey struct {
+	channelId int
+	model     string
+}
+
+// channelModelStats 单个 (渠道, 模型) 的 EWMA 统计，仅保存在内存中
+type channelModelStats struct {
+	latency    float64 // 首字时间，毫秒
+	errorRate  float64
+	samples    int64
+	updateTime int64
+}
+
+// ChannelRoutingStats 供管理接口展示的路由统计
+type ChannelRoutingStats struct {
+	ChannelId  int     `json:"channel_id"`
+	Model      string  `json:"model"`
+	Latency    float64 `json:"latency"`
+	ErrorRate  float64 `json:"error_rate"`
+	Samples    int64   `json:"samples"`
+	UpdateTime int64   `json:"update_time"`
+}
+
+var channelModelStatsMap = make(map[channelModelKey]*channelModelStats)
+var channelModelStatsLock sync.RWMutex
+
+func updateChannelModelStats(channelId int, model string, update func(stats *channelModelStats, alpha float64)) {
+	alpha := operation_setting.GetChannelRoutingSetting().EWMAAlpha
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.2
+	}
+	key := channelModelKey{channelId: channelId, model: model}
+	channelModelStatsLock.Lock()
+	defer channelModelStatsLock.Unlock()
+	stats, ok := channelModelStatsMap[key]
+	if !ok {
+		stats = &channelModelStats{}
+		channelModelStatsMap[key] = stats
+	}
+	if stats.samples == 0 {
+		// 第一个样本直接作为初始值
+		alpha = 1
+	}
+	update(stats, alpha)
+	stats.samples++
+	stats.updateTime = time.Now().Unix()
+}
+
+// RecordChannelLatency 记录一次成功请求的首字时间
+func RecordChannelLatency(channelId int, model string, latency time.Duration) {
+	ms := float64(latency.Milliseconds())
+	if ms <= 0 {
+		ms = 1
+	}
+	updateChannelModelStats(channelId, model, func(stats *channelModelStats, alpha float64) {
+		stats.latency = alpha*ms + (1-alpha)*stats.latency
+		stats.errorRate = (1 - alpha) * stats.errorRate
+	})
+}
+
+// RecordChannelError 记录一次失败请求，只影响错误率
+func RecordChannelError(channelId int, model string) {
+	updateChannelModelStats(channelId, model, func(stats *channelModelStats, alpha float64) {
+		stats.errorRate = alpha + (1-alpha)*stats.errorRate
+	})
+}
+
+func GetChannelRoutingStats() []ChannelRoutingStats {
+	channelModelStatsLock.RLock()
+	defer channelModelStatsLock.RUnlock()
+	result := make([]ChannelRoutingStats, 0, len(channelModelStatsMap))
+	for key, stats := range channelModelStatsMap {
+		result = append(result, ChannelRoutingStats{
+			ChannelId:  key.channelId,
+			Model:      key.model,
+			Latency:    stats.latency,
+			ErrorRate:  stats.errorRate,
+			Samples:    stats.samples,
+			UpdateTime: stats.updateTime,
+		})
+	}
+	return result
+}
+
+// adaptiveWeights 根据首字时间和错误率调整同一优先级内的静态权重。
+// 样本不足或统计过期的渠道保持原权重；延迟因子以已采样渠道的平均延迟为基准。
+func adaptiveWeights(model string, channelIds []int, weights []float64) []float64 {
+	setting := operation_setting.GetChannelRoutingSetting()
+	maxFactor := setting.MaxWeightFactor
+	if maxFactor < 1 {
+		maxFactor = 1
+	}
+	now := time.Now().Unix()
+
+	channelModelStatsLock.RLock()
+	sampled := make([]*channelModelStats, len(channelIds))
+	totalLatency := 0.0
+	count := 0
+	for i, id := range channelIds {
+		stats, ok := channelModelStatsMap[channelModelKey{channelId: id, model: model}]
+		if !ok || stats.samples < int64(setting.MinSamples) {
+			continue
+		}
+		if setting.StaleSeconds > 0 && now-stats.updateTime > int64(setting.StaleSeconds) {
+			continue
+		}
+		copied := *stats
+		sampled[i] = &copied
+		if copied.latency > 0 {
+			totalLatency += copied.latency
+			count++
+		}
+	}
+	channelModelStatsLock.RUnlock()
+
+	result := make([]float64, len(weights))
+	for i, weight := range weights {
+		stats := sampled[i]
+		if stats == nil {
+			result[i] = weight
+			continue
+		}
+		factor := 1.0
+		if count > 0 && stats.latency > 0 {
+			factor = (totalLatency / float64(count)) / stats.latency
+		}
+		factor *= math.Pow(1-stats.errorRate, setting.ErrorPenalty)
+		factor = math.Max(1/maxFactor, math.Min(maxFactor, factor))
+		result[i] = weight * factor
+	}
+	return result
+}
+
+// pickWeightedIndex 按浮点权重随机选择一个下标
+func pickWeightedIndex(weights []float64) int {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	if total <= 0 {
+		return rand.Intn(len(weights))
+	}
+	r := rand.Float64() * total
+	for i, weight := range weights {
+		r -= weight
+		if r < 0 {
+			return i
+		}
+	}
+	return len(weights) - 1
+}
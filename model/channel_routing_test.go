@@ -0,0 +1,96 @@
+package model
+
+import (
+	"one-api/setting/operation_setting"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// resetChannelRoutingStats 清空路由统计并恢复默认配置，避免测试之间互相影响
+func resetChannelRoutingStats(t *testing.T) {
+	setting := operation_setting.GetChannelRoutingSetting()
+	saved := *setting
+	channelModelStatsLock.Lock()
+	channelModelStatsMap = make(map[channelModelKey]*channelModelStats)
+	channelModelStatsLock.Unlock()
+	t.Cleanup(func() {
+		*setting = saved
+		channelModelStatsLock.Lock()
+		channelModelStatsMap = make(map[channelModelKey]*channelModelStats)
+		channelModelStatsLock.Unlock()
+	})
+}
+
+// TestRecordChannelStatsEWMA 测试首个样本直接作为初始值，之后按 EWMA 平滑延迟和错误率
+func TestRecordChannelStatsEWMA(t *testing.T) {
+	resetChannelRoutingStats(t)
+	operation_setting.GetChannelRoutingSetting().EWMAAlpha = 0.5
+
+	RecordChannelLatency(1, "gpt-4o", 100*time.Millisecond)
+	RecordChannelLatency(1, "gpt-4o", 200*time.Millisecond)
+	RecordChannelError(1, "gpt-4o")
+
+	stats := GetChannelRoutingStats()
+	assert.Len(t, stats, 1)
+	assert.Equal(t, 1, stats[0].ChannelId)
+	assert.Equal(t, "gpt-4o", stats[0].Model)
+	assert.InDelta(t, 150, stats[0].Latency, 0.001)
+	assert.InDelta(t, 0.5, stats[0].ErrorRate, 0.001)
+	assert.Equal(t, int64(3), stats[0].Samples)
+}
+
+// TestAdaptiveWeights 测试快渠道权重上调、慢渠道和高错误率渠道权重下调，样本不足的渠道保持原权重
+func TestAdaptiveWeights(t *testing.T) {
+	resetChannelRoutingStats(t)
+	setting := operation_setting.GetChannelRoutingSetting()
+	setting.MinSamples = 2
+	setting.MaxWeightFactor = 10
+	setting.ErrorPenalty = 2
+
+	for i := 0; i < 2; i++ {
+		RecordChannelLatency(1, "gpt-4o", 100*time.Millisecond)
+		RecordChannelLatency(2, "gpt-4o", 300*time.Millisecond)
+	}
+	RecordChannelLatency(3, "gpt-4o", 50*time.Millisecond)
+
+	weights := adaptiveWeights("gpt-4o", []int{1, 2, 3}, []float64{10, 10, 10})
+	assert.InDelta(t, 20, weights[0], 0.001)
+	assert.InDelta(t, 10.0*200/300, weights[1], 0.001)
+	assert.Equal(t, 10.0, weights[2])
+
+	// 默认 alpha 为 0.2，一次失败后错误率为 0.2，按 (1-错误率)^ErrorPenalty 惩罚
+	RecordChannelError(1, "gpt-4o")
+	weights = adaptiveWeights("gpt-4o", []int{1}, []float64{10})
+	assert.InDelta(t, 10*0.8*0.8, weights[0], 0.001)
+}
+
+// TestAdaptiveWeightsBounds 测试权重调整倍数受 MaxWeightFactor 限制，过期统计不参与调整
+func TestAdaptiveWeightsBounds(t *testing.T) {
+	resetChannelRoutingStats(t)
+	setting := operation_setting.GetChannelRoutingSetting()
+	setting.MinSamples = 1
+	setting.MaxWeightFactor = 1.5
+
+	RecordChannelLatency(1, "gpt-4o", 10*time.Millisecond)
+	RecordChannelLatency(2, "gpt-4o", 1000*time.Millisecond)
+	weights := adaptiveWeights("gpt-4o", []int{1, 2}, []float64{10, 10})
+	assert.InDelta(t, 15, weights[0], 0.001)
+	assert.InDelta(t, 10/1.5, weights[1], 0.001)
+
+	channelModelStatsLock.Lock()
+	for _, stats := range channelModelStatsMap {
+		stats.updateTime = time.Now().Unix() - int64(setting.StaleSeconds) - 1
+	}
+	channelModelStatsLock.Unlock()
+	weights = adaptiveWeights("gpt-4o", []int{1, 2}, []float64{10, 10})
+	assert.Equal(t, []float64{10, 10}, weights)
+}
+
+// TestPickWeightedIndex 测试权重为 0 的下标不会被选中
+func TestPickWeightedIndex(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, 1, pickWeightedIndex([]float64{0, 5, 0}))
+	}
+}
@@ -0,0 +1,242 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/setting/operation_setting"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ChannelVersionSourceInitial  = "initial"
+	ChannelVersionSourceUpdate   = "update"
+	ChannelVersionSourceTagEdit  = "tag_edit"
+	ChannelVersionSourceRollback = "rollback"
+	ChannelVersionSourceImport   = "import"
+)
+
+// channelVersionColumns 版本中保存并在回滚时恢复的渠道配置列，不包含密钥、状态与运行统计
+var channelVersionColumns = []string{
+	"type", "business_type", "openai_organization", "test_model", "name", "weight", "base_url", "other",
+	"models", "group", "model_mapping", "status_code_mapping", "priority", "auto_ban", "other_info", "tag",
+	"setting", "param_override", "multi_key_mode",
+}
+
+// ChannelVersion 渠道配置的历史版本，Snapshot 为当时的渠道配置
+type ChannelVersion struct {
+	Id           int    `json:"id"`
+	ChannelId    int    `json:"channel_id" gorm:"index:idx_channel_version,priority:1"`
+	Version      int    `json:"version" gorm:"index:idx_channel_version,priority:2"`
+	Source       string `json:"source" gorm:"type:varchar(16)"`
+	BatchId      string `json:"batch_id" gorm:"type:varchar(64);index"` // 同一次标签批量编辑产生的版本共享批次
+	Comment      string `json:"comment" gorm:"type:varchar(255)"`
+	OperatorId   int    `json:"operator_id"`
+	OperatorName string `json:"operator_name" gorm:"type:varchar(64)"`
+	CreatedAt    int64  `json:"created_at" gorm:"bigint"`
+	Snapshot     string `json:"snapshot" gorm:"type:text;serializer:secret"`
+}
+
+// channelVersionSnapshot 提取渠道中需要版本化的配置
+func channelVersionSnapshot(channel *Channel) (string, error) {
+	snapshot := Channel{
+		Type:               channel.Type,
+		BusinessType:       channel.BusinessType,
+		OpenAIOrganization: channel.OpenAIOrganization,
+		TestModel:          channel.TestModel,
+		Name:               channel.Name,
+		Weight:             channel.Weight,
+		BaseURL:            channel.BaseURL,
+		Other:              channel.Other,
+		Models:             channel.Models,
+		Group:              channel.Group,
+		ModelMapping:       channel.ModelMapping,
+		StatusCodeMapping:  channel.StatusCodeMapping,
+		Priority:           channel.Priority,
+		AutoBan:            channel.AutoBan,
+		OtherInfo:          channel.OtherInfo,
+		Tag:                channel.Tag,
+		Setting:            channel.Setting,
+		ParamOverride:      channel.ParamOverride,
+		MultiKeyMode:       channel.MultiKeyMode,
+	}
+	data, err := json.Marshal(snapshot)
+	return string(data), err
+}
+
+// GetSnapshotChannel 解析版本中保存的渠道配置
+func (version *ChannelVersion) GetSnapshotChannel() (*Channel, error) {
+	var channel Channel
+	if err := common.DecodeJsonStr(version.Snapshot, &channel); err != nil {
+		return nil, err
+	}
+	channel.Id = version.ChannelId
+	return &channel, nil
+}
+
+func getLatestChannelVersion(tx *gorm.DB, channelId int) (*ChannelVersion, error) {
+	var version ChannelVersion
+	err := tx.Where("channel_id = ?", channelId).Order("version desc").Limit(1).Find(&version).Error
+	if err != nil || version.Id == 0 {
+		return nil, err
+	}
+	return &version, nil
+}
+
+func saveChannelVersion(tx *gorm.DB, channel *Channel, version ChannelVersion) error {
+	snapshot, err := channelVersionSnapshot(channel)
+	if err != nil {
+		return err
+	}
+	latest, err := getLatestChannelVersion(tx, channel.Id)
+	if err != nil {
+		return err
+	}
+	if latest != nil && latest.Snapshot == snapshot {
+		return nil
+	}
+	version.Version = 1
+	if latest != nil {
+		version.Version = latest.Version + 1
+	}
+	version.ChannelId = channel.Id
+	version.Snapshot = snapshot
+	version.CreatedAt = common.GetTimestamp()
+	if err = tx.Create(&version).Error; err != nil {
+		return err
+	}
+	return pruneChannelVersions(tx, channel.Id, version.Version)
+}
+
+// pruneChannelVersions 删除超出保留数量的旧版本
+func pruneChannelVersions(tx *gorm.DB, channelId int, latestVersion int) error {
+	maxVersions := operation_setting.GetChannelVersionSetting().MaxVersions
+	if maxVersions <= 0 || latestVersion <= maxVersions {
+		return nil
+	}
+	return tx.Where("channel_id = ? and version <= ?", channelId, latestVersion-maxVersions).Delete(&ChannelVersion{}).Error
+}
+
+func ensureChannelVersionBaseline(tx *gorm.DB, channel *Channel) error {
+	latest, err := getLatestChannelVersion(tx, channel.Id)
+	if err != nil || latest != nil {
+		return err
+	}
+	return saveChannelVersion(tx, channel, ChannelVersion{Source: ChannelVersionSourceInitial})
+}
+
+func GetChannelVersions(channelId int) (versions []*ChannelVersion, err error) {
+	err = DB.Where("channel_id = ?", channelId).Order("version desc").Find(&versions).Error
+	return versions, err
+}
+
+func GetChannelVersionById(id int) (*ChannelVersion, error) {
+	var version ChannelVersion
+	if err := DB.First(&version, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// DiffChannelVersions 比较两个版本的渠道配置
+func DiffChannelVersions(from *ChannelVersion, to *ChannelVersion) (map[string]AdminAuditFieldChange, error) {
+	fromChannel, err := from.GetSnapshotChannel()
+	if err != nil {
+		return nil, err
+	}
+	toChannel, err := to.GetSnapshotChannel()
+	if err != nil {
+		return nil, err
+	}
+	return BuildAdminAuditDiff(fromChannel, toChannel), nil
+}
+
+// getChannelVersionsBeforeBatch 获取标签批量编辑中每个渠道在该批次之前的版本
+func getChannelVersionsBeforeBatch(batchId string) ([]*ChannelVersion, error) {
+	var batchVersions []*ChannelVersion
+	if err := DB.Where("batch_id = ?", batchId).Find(&batchVersions).Error; err != nil {
+		return nil, err
+	}
+	if len(batchVersions) == 0 {
+		return nil, errors.New("批次不存在")
+	}
+	targets := make([]*ChannelVersion, 0, len(batchVersions))
+	for _, batchVersion := range batchVersions {
+		var previous ChannelVersion
+		err := DB.Where("channel_id = ? and version < ?", batchVersion.ChannelId, batchVersion.Version).
+			Order("version desc").Limit(1).Find(&previous).Error
+		if err != nil {
+			return nil, err
+		}
+		if previous.Id == 0 {
+			return nil, fmt.Errorf("渠道 %d 没有批次之前的版本", batchVersion.ChannelId)
+		}
+		targets = append(targets, &previous)
+	}
+	return targets, nil
+}
+
+// RollbackChannelVersions 在同一事务中将渠道恢复到指定版本并重建 abilities，完成后刷新渠道缓存
+func RollbackChannelVersions(versions []*ChannelVersion, batchId string, operatorId int, operatorName string) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, version := range versions {
+			snapshot, err := version.GetSnapshotChannel()
+			if err != nil {
+				return err
+			}
+			result := tx.Model(&Channel{Id: version.ChannelId}).Select(channelVersionColumns).Updates(snapshot)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("渠道 %d 不存在", version.ChannelId)
+			}
+			var channel Channel
+			if err = tx.First(&channel, "id = ?", version.ChannelId).Error; err != nil {
+				return err
+			}
+			if err = channel.UpdateAbilities(tx); err != nil {
+				return err
+			}
+			err = saveChannelVersion(tx, &channel, ChannelVersion{
+				Source:       ChannelVersionSourceRollback,
+				BatchId:      batchId,
+				Comment:      fmt.Sprintf("回滚到版本 %d", version.Version),
+				OperatorId:   operatorId,
+				OperatorName: operatorName,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	InitChannelCache()
+	return nil
+}
+
+// RollbackChannelVersion 将单个渠道恢复到指定版本
+func RollbackChannelVersion(channelId int, versionId int, operatorId int, operatorName string) error {
+	version, err := GetChannelVersionById(versionId)
+	if err != nil {
+		return err
+	}
+	if version.ChannelId != channelId {
+		return errors.New("版本不属于该渠道")
+	}
+	return RollbackChannelVersions([]*ChannelVersion{version}, "", operatorId, operatorName)
+}
+
+// RollbackChannelBatch 撤销一次标签批量编辑，将涉及的渠道全部恢复到批次之前的版本
+func RollbackChannelBatch(batchId string, operatorId int, operatorName string) error {
+	versions, err := getChannelVersionsBeforeBatch(batchId)
+	if err != nil {
+		return err
+	}
+	return RollbackChannelVersions(versions, "rollback-"+batchId, operatorId, operatorName)
+}
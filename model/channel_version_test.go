@@ -0,0 +1,123 @@
+package model
+
+import (
+	"one-api/common"
+	"one-api/setting/operation_setting"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createVersionTestChannel 创建带标签的测试渠道
+func createVersionTestChannel(t *testing.T, name string) *Channel {
+	channel := &Channel{Type: 1, Key: "sk-" + name, Name: name, Models: "gpt-4o", Group: "default",
+		Status: common.ChannelStatusEnabled, Tag: common.GetPointer("team")}
+	require.NoError(t, channel.Insert())
+	return channel
+}
+
+// TestChannelVersionRollback 测试修改时保存初始版本，配置未变化时不产生新版本，回滚后恢复配置并重建 abilities
+func TestChannelVersionRollback(t *testing.T) {
+	setupTestDB(t)
+	channel := createVersionTestChannel(t, "a")
+
+	channel.Models = "gpt-4o,claude"
+	channel.Key = ""
+	require.NoError(t, channel.UpdateWithVersion(ChannelVersion{Source: ChannelVersionSourceUpdate, Comment: "add claude", OperatorId: 1, OperatorName: "root"}))
+	channel.Key = ""
+	require.NoError(t, channel.UpdateWithVersion(ChannelVersion{Source: ChannelVersionSourceUpdate, OperatorId: 1, OperatorName: "root"}))
+	versions, err := GetChannelVersions(channel.Id)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 2, versions[0].Version)
+	assert.Equal(t, ChannelVersionSourceInitial, versions[1].Source)
+	assert.NotContains(t, versions[0].Snapshot, "sk-a")
+
+	diff, err := DiffChannelVersions(versions[1], versions[0])
+	require.NoError(t, err)
+	assert.Equal(t, AdminAuditFieldChange{Old: "gpt-4o", New: "gpt-4o,claude"}, diff["models"])
+
+	require.NoError(t, RollbackChannelVersion(channel.Id, versions[1].Id, 1, "root"))
+	channel, err = GetChannelById(channel.Id, true)
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o", channel.Models)
+	assert.Equal(t, "sk-a", channel.Key)
+	var abilities int64
+	require.NoError(t, DB.Model(&Ability{}).Where("channel_id = ?", channel.Id).Count(&abilities).Error)
+	assert.Equal(t, int64(1), abilities)
+	versions, err = GetChannelVersions(channel.Id)
+	require.NoError(t, err)
+	assert.Equal(t, ChannelVersionSourceRollback, versions[0].Source)
+
+	assert.Error(t, RollbackChannelVersion(channel.Id+1, versions[1].Id, 1, "root"))
+}
+
+// TestRollbackChannelBatch 测试撤销标签批量编辑时所有渠道恢复到批次之前的版本
+func TestRollbackChannelBatch(t *testing.T) {
+	setupTestDB(t)
+	channels := []*Channel{createVersionTestChannel(t, "a"), createVersionTestChannel(t, "b")}
+	priority := int64(5)
+	require.NoError(t, EditChannelByTag("team", nil, nil, nil, nil, &priority, nil,
+		ChannelVersion{Source: ChannelVersionSourceTagEdit, BatchId: "batch-1", OperatorId: 1, OperatorName: "root"}))
+	for _, channel := range channels {
+		versions, err := GetChannelVersions(channel.Id)
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		assert.Equal(t, "batch-1", versions[0].BatchId)
+	}
+
+	require.NoError(t, RollbackChannelBatch("batch-1", 1, "root"))
+	for _, channel := range channels {
+		channel, err := GetChannelById(channel.Id, true)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), channel.GetPriority())
+	}
+	assert.Error(t, RollbackChannelBatch("missing", 1, "root"))
+}
+
+// TestChannelVersionUpdateRollback 测试渠道更新失败时不保存版本
+func TestChannelVersionUpdateRollback(t *testing.T) {
+	setupTestDB(t)
+	channel := createVersionTestChannel(t, "a")
+	missing := &Channel{Id: channel.Id + 100, Name: "missing"}
+	assert.Error(t, missing.UpdateWithVersion(ChannelVersion{Source: ChannelVersionSourceUpdate}))
+
+	// 版本写入失败时渠道修改一并回滚
+	require.NoError(t, DB.Migrator().DropTable(&ChannelVersion{}))
+	channel.Models = "gpt-4o,claude"
+	channel.Key = ""
+	assert.Error(t, channel.UpdateWithVersion(ChannelVersion{Source: ChannelVersionSourceUpdate}))
+	channel, err := GetChannelById(channel.Id, true)
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o", channel.Models)
+}
+
+// TestPruneChannelVersions 测试每个渠道只保留配置数量的最新版本
+func TestPruneChannelVersions(t *testing.T) {
+	setupTestDB(t)
+	setting := operation_setting.GetChannelVersionSetting()
+	saved := *setting
+	setting.MaxVersions = 2
+	t.Cleanup(func() {
+		*setting = saved
+	})
+	channel := createVersionTestChannel(t, "a")
+	other := createVersionTestChannel(t, "b")
+	for _, models := range []string{"gpt-4o,a", "gpt-4o,b", "gpt-4o,c"} {
+		channel.Models = models
+		channel.Key = ""
+		require.NoError(t, channel.UpdateWithVersion(ChannelVersion{Source: ChannelVersionSourceUpdate}))
+	}
+	other.Key = ""
+	other.Models = "gpt-4o,a"
+	require.NoError(t, other.UpdateWithVersion(ChannelVersion{Source: ChannelVersionSourceUpdate}))
+
+	versions, err := GetChannelVersions(channel.Id)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, []int{4, 3}, []int{versions[0].Version, versions[1].Version})
+	versions, err = GetChannelVersions(other.Id)
+	require.NoError(t, err)
+	assert.Len(t, versions, 2)
+}
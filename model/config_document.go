@@ -0,0 +1,91 @@
+package model
+
+import (
+	"fmt"
+	"one-api/common"
+
+	"gorm.io/gorm"
+)
+
+// ChannelImport 导入的渠道，Abilities 用于在重建 abilities 后覆盖单个能力的启用状态、优先级与权重
+type ChannelImport struct {
+	Channel   *Channel
+	Abilities []Ability
+}
+
+// ImportConfig 在同一事务中创建或更新导入的渠道、重建 abilities 并写入配置项，Id 为 0 的渠道会被创建，
+// Key 为空时保留已有渠道的密钥；事务提交后才更新内存中的配置项并刷新渠道缓存
+func ImportConfig(imports []ChannelImport, options []Option, batchId string, operatorId int, operatorName string) error {
+	var rekeyed []int
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, channelImport := range imports {
+			channel := channelImport.Channel
+			if channel.Id == 0 {
+				if err := tx.Create(channel).Error; err != nil {
+					return err
+				}
+			} else {
+				var existing Channel
+				if err := tx.First(&existing, "id = ?", channel.Id).Error; err != nil {
+					return err
+				}
+				if err := ensureChannelVersionBaseline(tx, &existing); err != nil {
+					return err
+				}
+				columns := append([]string{"status"}, channelVersionColumns...)
+				if channel.Key != "" {
+					columns = append(columns, "key", "key_status_list")
+					if channel.Key == existing.Key {
+						channel.KeyStatusList = existing.KeyStatusList
+					} else {
+						// 密钥变更后下标失效，重置多密钥状态
+						channel.KeyStatusList = common.GetPointer[string]("")
+						rekeyed = append(rekeyed, channel.Id)
+					}
+				}
+				if err := tx.Model(&Channel{Id: channel.Id}).Select(columns).Updates(channel).Error; err != nil {
+					return err
+				}
+				if err := tx.First(channel, "id = ?", channel.Id).Error; err != nil {
+					return err
+				}
+			}
+			if err := channel.UpdateAbilities(tx); err != nil {
+				return err
+			}
+			if err := applyAbilityOverrides(tx, channel.Id, channelImport.Abilities); err != nil {
+				return err
+			}
+			err := saveChannelVersion(tx, channel, ChannelVersion{
+				Source:       ChannelVersionSourceImport,
+				BatchId:      batchId,
+				OperatorId:   operatorId,
+				OperatorName: operatorName,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		for _, option := range options {
+			if err := saveOption(tx, option.Key, option.Value); err != nil {
+				return fmt.Errorf("更新配置项 %s 失败：%w", option.Key, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, option := range options {
+		if err := applyOption(option.Key, option.Value); err != nil {
+			return fmt.Errorf("更新配置项 %s 失败：%w", option.Key, err)
+		}
+	}
+	for _, id := range rekeyed {
+		resetChannelKeyRuntime(id)
+	}
+	if len(imports) > 0 {
+		InitChannelCache()
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"one-api/common"
+)
+
+const (
+	FilePurposeBatch       = "batch"
+	FilePurposeBatchOutput = "batch_output"
+)
+
+// File 通过 /v1/files 上传或由批处理任务生成的文件，内容保存在文件存储中
+type File struct {
+	Id          string `json:"id" gorm:"type:varchar(64);primaryKey"`
+	UserId      int    `json:"user_id" gorm:"index"`
+	Purpose     string `json:"purpose" gorm:"type:varchar(32);index"`
+	Filename    string `json:"filename" gorm:"type:varchar(255)"`
+	Bytes       int64  `json:"bytes"`
+	StoragePath string `json:"-" gorm:"type:varchar(512)"`
+	CreatedAt   int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+func NewFileId() string {
+	return "file-" + common.GetRandomString(24)
+}
+
+func (file *File) Insert() error {
+	return DB.Create(file).Error
+}
+
+func (file *File) Delete() error {
+	return DB.Delete(file).Error
+}
+
+func GetUserFileById(userId int, id string) (*File, error) {
+	var file File
+	err := DB.Where("id = ? and user_id = ?", id, userId).First(&file).Error
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+func GetUserFiles(userId int, purpose string, after string, limit int) ([]*File, error) {
+	var files []*File
+	query := DB.Where("user_id = ?", userId)
+	if purpose != "" {
+		query = query.Where("purpose = ?", purpose)
+	}
+	if after != "" {
+		var afterFile File
+		if err := DB.Where("id = ? and user_id = ?", after, userId).First(&afterFile).Error; err == nil {
+			query = query.Where("created_at < ? or (created_at = ? and id < ?)", afterFile.CreatedAt, afterFile.CreatedAt, afterFile.Id)
+		}
+	}
+	err := query.Order("created_at desc, id desc").Limit(limit).Find(&files).Error
+	return files, err
+}
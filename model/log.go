@@ -4,12 +4,16 @@ import (
 	"context"
 	"fmt"
 	"one-api/common"
+	"one-api/common/logsink"
+	"one-api/common/metrics"
+	"one-api/common/tracing"
 	"one-api/constant"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/bytedance/gopkg/util/gopool"
 	"gorm.io/gorm"
@@ -46,6 +50,25 @@ const (
 	LogTypeError
 )
 
+// LogExportRecord 导出到外部日志目标或归档文件的记录，other 以 JSON 对象展开
+type LogExportRecord struct {
+	*Log
+	Other map[string]interface{} `json:"other,omitempty"`
+}
+
+// publishLog 将日志异步输出到 LOG_SINK_* 配置的外部目标
+func publishLog(log *Log, other map[string]interface{}) {
+	if !logsink.Enabled() {
+		return
+	}
+	data, err := common.EncodeJson(LogExportRecord{Log: log, Other: other})
+	if err != nil {
+		common.SysError("failed to marshal log for log sink: " + err.Error())
+		return
+	}
+	logsink.Publish(data)
+}
+
 func formatUserLogs(logs []*Log) {
 	for i := range logs {
 		logs[i].ChannelName = ""
@@ -90,6 +113,7 @@ func RecordLog(userId int, logType int, content string) {
 	if err != nil {
 		common.SysError("failed to record log: " + err.Error())
 	}
+	publishLog(log, nil)
 }
 
 func RecordErrorLog(c *gin.Context, userId int, channelId int, modelName string, tokenName string, content string, tokenId int, useTimeSeconds int,
@@ -133,17 +157,35 @@ func RecordErrorLog(c *gin.Context, userId int, channelId int, modelName string,
 	err := LOG_DB.Create(log).Error
 	if err != nil {
 		common.LogError(c, "failed to record log: "+err.Error())
+	} else {
+		c.Set(constant.ContextKeyLogId, log.Id)
 	}
+	publishLog(log, other)
 }
 
 func RecordConsumeLog(c *gin.Context, userId int, channelId int, promptTokens int, completionTokens int,
 	modelName string, tokenName string, quota int, content string, tokenId int, userQuota int, useTimeSeconds int,
 	isStream bool, group string, other map[string]interface{}) {
+	metrics.QuotaConsumed.WithLabelValues(metrics.ChannelLabel(channelId), metrics.ModelLabel(modelName), group).Add(float64(quota))
+	if c.Request != nil {
+		tracing.SetAttributes(c.Request.Context(),
+			attribute.Int("token.id", tokenId),
+			attribute.Int("usage.prompt_tokens", promptTokens),
+			attribute.Int("usage.completion_tokens", completionTokens),
+			attribute.Int("usage.quota", quota),
+		)
+	}
 	common.LogInfo(c, fmt.Sprintf("record consume log: userId=%d, 用户调用前余额=%d, channelId=%d, promptTokens=%d, completionTokens=%d, modelName=%s, tokenName=%s, quota=%d, content=%s", userId, userQuota, channelId, promptTokens, completionTokens, modelName, tokenName, quota, content))
 	if !common.LogConsumeEnabled {
 		return
 	}
 	username := c.GetString("username")
+	if orgId := c.GetInt("token_org_id"); orgId != 0 {
+		if other == nil {
+			other = make(map[string]interface{})
+		}
+		other["org_id"] = orgId
+	}
 	otherStr := common.MapToJsonStr(other)
 	// 判断是否需要记录 IP
 	needRecordIp := false
@@ -181,7 +223,10 @@ func RecordConsumeLog(c *gin.Context, userId int, channelId int, promptTokens in
 	err := LOG_DB.Create(log).Error
 	if err != nil {
 		common.LogError(c, "failed to record log: "+err.Error())
+	} else {
+		c.Set(constant.ContextKeyLogId, log.Id)
 	}
+	publishLog(log, other)
 	if common.DataExportEnabled {
 		gopool.Go(func() {
 			LogQuotaData(userId, username, modelName, quota, common.GetTimestamp(), promptTokens+completionTokens)
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LogDailyStat 清理日志前按天汇总的统计，保留被删除日志的用量
+type LogDailyStat struct {
+	Id               int    `json:"id"`
+	Day              string `json:"day" gorm:"type:varchar(10);index:idx_log_daily_stat_day_type,priority:1"` // YYYY-MM-DD
+	Type             int    `json:"type" gorm:"index:idx_log_daily_stat_day_type,priority:2"`
+	UserId           int    `json:"user_id" gorm:"index"`
+	Username         string `json:"username" gorm:"default:''"`
+	TokenId          int    `json:"token_id" gorm:"default:0"`
+	TokenName        string `json:"token_name" gorm:"default:''"`
+	ModelName        string `json:"model_name" gorm:"default:''"`
+	ChannelId        int    `json:"channel" gorm:"default:0"`
+	Group            string `json:"group" gorm:"default:''"`
+	RequestCount     int    `json:"request_count" gorm:"default:0"`
+	Quota            int    `json:"quota" gorm:"default:0"`
+	PromptTokens     int    `json:"prompt_tokens" gorm:"default:0"`
+	CompletionTokens int    `json:"completion_tokens" gorm:"default:0"`
+	UseTime          int    `json:"use_time" gorm:"default:0"`
+}
+
+type logDailyStatKey struct {
+	day       string
+	logType   int
+	userId    int
+	username  string
+	tokenId   int
+	tokenName string
+	modelName string
+	channelId int
+	group     string
+}
+
+// GetExpiredLogs 按 id 顺序获取一批早于 targetTimestamp 的指定类型日志
+func GetExpiredLogs(logType int, targetTimestamp int64, limit int) (logs []*Log, err error) {
+	err = LOG_DB.Where("type = ? and created_at < ?", logType, targetTimestamp).Order("id").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+// RollupAndDeleteLogs 在同一事务中将日志累加到按天统计并删除，中断后重新执行不会重复统计
+func RollupAndDeleteLogs(logs []*Log) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	stats := make(map[logDailyStatKey]*LogDailyStat)
+	var keys []logDailyStatKey
+	ids := make([]int, 0, len(logs))
+	for _, log := range logs {
+		ids = append(ids, log.Id)
+		key := logDailyStatKey{
+			day:       time.Unix(log.CreatedAt, 0).Format("2006-01-02"),
+			logType:   log.Type,
+			userId:    log.UserId,
+			username:  log.Username,
+			tokenId:   log.TokenId,
+			tokenName: log.TokenName,
+			modelName: log.ModelName,
+			channelId: log.ChannelId,
+			group:     log.Group,
+		}
+		stat, ok := stats[key]
+		if !ok {
+			stat = &LogDailyStat{
+				Day:       key.day,
+				Type:      key.logType,
+				UserId:    key.userId,
+				Username:  key.username,
+				TokenId:   key.tokenId,
+				TokenName: key.tokenName,
+				ModelName: key.modelName,
+				ChannelId: key.channelId,
+				Group:     key.group,
+			}
+			stats[key] = stat
+			keys = append(keys, key)
+		}
+		stat.RequestCount++
+		stat.Quota += log.Quota
+		stat.PromptTokens += log.PromptTokens
+		stat.CompletionTokens += log.CompletionTokens
+		stat.UseTime += log.UseTime
+	}
+	return LOG_DB.Transaction(func(tx *gorm.DB) error {
+		for _, key := range keys {
+			stat := stats[key]
+			result := tx.Model(&LogDailyStat{}).
+				Where("day = ? and type = ? and user_id = ? and username = ? and token_id = ? and token_name = ? and model_name = ? and channel_id = ? and "+logGroupCol+" = ?",
+					stat.Day, stat.Type, stat.UserId, stat.Username, stat.TokenId, stat.TokenName, stat.ModelName, stat.ChannelId, stat.Group).
+				Updates(map[string]interface{}{
+					"request_count":     gorm.Expr("request_count + ?", stat.RequestCount),
+					"quota":             gorm.Expr("quota + ?", stat.Quota),
+					"prompt_tokens":     gorm.Expr("prompt_tokens + ?", stat.PromptTokens),
+					"completion_tokens": gorm.Expr("completion_tokens + ?", stat.CompletionTokens),
+					"use_time":          gorm.Expr("use_time + ?", stat.UseTime),
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				if err := tx.Create(stat).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Where("id in ?", ids).Delete(&Log{}).Error
+	})
+}
+
+// GetLogDailyStats 查询按天汇总的日志统计，day 格式为 YYYY-MM-DD
+func GetLogDailyStats(logType int, startDay string, endDay string, username string, modelName string, startIdx int, num int) (stats []*LogDailyStat, total int64, err error) {
+	tx := LOG_DB.Model(&LogDailyStat{})
+	if logType != LogTypeUnknown {
+		tx = tx.Where("type = ?", logType)
+	}
+	if startDay != "" {
+		tx = tx.Where("day >= ?", startDay)
+	}
+	if endDay != "" {
+		tx = tx.Where("day <= ?", endDay)
+	}
+	if username != "" {
+		tx = tx.Where("username = ?", username)
+	}
+	if modelName != "" {
+		tx = tx.Where("model_name = ?", modelName)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("day desc, id desc").Limit(num).Offset(startIdx).Find(&stats).Error
+	return stats, total, err
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRollupAndDeleteLogs 测试日志按天、用户、模型等维度汇总后删除，多批次汇总累加到同一条统计
+func TestRollupAndDeleteLogs(t *testing.T) {
+	setupTestDB(t)
+	day := time.Date(2026, 1, 2, 12, 0, 0, 0, time.Local).Unix()
+	logs := []*Log{
+		{UserId: 1, Username: "alice", CreatedAt: day, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 10, PromptTokens: 3, CompletionTokens: 2, UseTime: 1},
+		{UserId: 1, Username: "alice", CreatedAt: day + 60, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 20, PromptTokens: 4, CompletionTokens: 1, UseTime: 2},
+		{UserId: 1, Username: "alice", CreatedAt: day, Type: LogTypeConsume, ModelName: "claude", Quota: 5},
+		{UserId: 2, Username: "bob", CreatedAt: day + 86400, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 7},
+	}
+	require.NoError(t, LOG_DB.Create(&logs).Error)
+
+	require.NoError(t, RollupAndDeleteLogs(logs[:1]))
+	require.NoError(t, RollupAndDeleteLogs(logs[1:]))
+	var count int64
+	require.NoError(t, LOG_DB.Model(&Log{}).Count(&count).Error)
+	assert.Zero(t, count)
+
+	stats, total, err := GetLogDailyStats(LogTypeConsume, "2026-01-02", "2026-01-02", "alice", "gpt-4o", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 2, stats[0].RequestCount)
+	assert.Equal(t, 30, stats[0].Quota)
+	assert.Equal(t, 7, stats[0].PromptTokens)
+	assert.Equal(t, 3, stats[0].CompletionTokens)
+	assert.Equal(t, 3, stats[0].UseTime)
+
+	_, total, err = GetLogDailyStats(LogTypeConsume, "2026-01-02", "", "", "", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	stats, total, err = GetLogDailyStats(LogTypeUnknown, "2026-01-03", "", "", "", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "bob", stats[0].Username)
+}
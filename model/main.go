@@ -206,7 +206,11 @@ func InitDB() (err error) {
 func InitLogDB() (err error) {
 	if os.Getenv("LOG_SQL_DSN") == "" {
 		LOG_DB = DB
-		return
+		if !common.IsMasterNode {
+			return nil
+		}
+		// 审计记录与日志统计只在日志库中读写，未单独配置日志库时建在主库中
+		return LOG_DB.AutoMigrate(&AuditRecord{}, &LogDailyStat{})
 	}
 	db, err := chooseDB("LOG_SQL_DSN", true)
 	if err == nil {
@@ -257,6 +261,14 @@ func migrateDB() error {
 		&QuotaData{},
 		&Task{},
 		&Setup{},
+		&File{},
+		&Batch{},
+		&Organization{},
+		&OrganizationMember{},
+		&AdminRole{},
+		&AdminAuditLog{},
+		&ChannelVersion{},
+		&StoredResponse{},
 	)
 	if err != nil {
 		return err
@@ -266,7 +278,7 @@ func migrateDB() error {
 
 func migrateDBFast() error {
 	var wg sync.WaitGroup
-	errChan := make(chan error, 12) // Buffer size matches number of migrations
+	errChan := make(chan error, 20) // Buffer size matches number of migrations
 
 	migrations := []struct {
 		model interface{}
@@ -284,6 +296,14 @@ func migrateDBFast() error {
 		{&QuotaData{}, "QuotaData"},
 		{&Task{}, "Task"},
 		{&Setup{}, "Setup"},
+		{&File{}, "File"},
+		{&Batch{}, "Batch"},
+		{&Organization{}, "Organization"},
+		{&OrganizationMember{}, "OrganizationMember"},
+		{&AdminRole{}, "AdminRole"},
+		{&AdminAuditLog{}, "AdminAuditLog"},
+		{&ChannelVersion{}, "ChannelVersion"},
+		{&StoredResponse{}, "StoredResponse"},
 	}
 
 	for _, m := range migrations {
@@ -319,7 +339,7 @@ func migrateDBFast() error {
 
 func migrateLOGDB() error {
 	var err error
-	if err = LOG_DB.AutoMigrate(&Log{}); err != nil {
+	if err = LOG_DB.AutoMigrate(&Log{}, &AuditRecord{}, &LogDailyStat{}); err != nil {
 		return err
 	}
 	return nil
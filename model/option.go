@@ -9,6 +9,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Option struct {
@@ -23,7 +25,8 @@ func AllOption() ([]*Option, error) {
 	return options, err
 }
 
-func InitOptionMap() {
+// InitOptionMap 初始化配置项并加载数据库中保存的值，加载失败时返回错误
+func InitOptionMap() error {
 	common.OptionMapRWMutex.Lock()
 	common.OptionMap = make(map[string]string)
 
@@ -136,41 +139,64 @@ func InitOptionMap() {
 	}
 
 	common.OptionMapRWMutex.Unlock()
-	loadOptionsFromDatabase()
+	return loadOptionsFromDatabase()
 }
 
-func loadOptionsFromDatabase() {
-	options, _ := AllOption()
+func loadOptionsFromDatabase() error {
+	options, err := AllOption()
+	if err != nil {
+		return err
+	}
 	for _, option := range options {
 		err := updateOptionMap(option.Key, option.Value)
 		if err != nil {
 			common.SysError("failed to update option map: " + err.Error())
 		}
 	}
+	return nil
 }
 
 func SyncOptions(frequency int) {
 	for {
 		time.Sleep(time.Duration(frequency) * time.Second)
 		common.SysLog("syncing options from database")
-		loadOptionsFromDatabase()
+		if err := loadOptionsFromDatabase(); err != nil {
+			// 保留内存中的配置，避免无法解密的配置项被默认值覆盖
+			common.SysError("failed to sync options: " + err.Error())
+		}
 	}
 }
 
 func UpdateOption(key string, value string) error {
 	// Save to database first
+	saveOption(DB, key, value)
+	return applyOption(key, value)
+}
+
+func saveOption(tx *gorm.DB, key string, value string) error {
 	option := Option{
 		Key: key,
 	}
 	// https://gorm.io/docs/update.html#Save-All-Fields
-	DB.FirstOrCreate(&option, Option{Key: key})
+	tx.FirstOrCreate(&option, Option{Key: key})
 	option.Value = value
 	// Save is a combination function.
 	// If save value does not contain primary key, it will execute Create,
 	// otherwise it will execute Update (with all fields).
-	DB.Save(&option)
+	return tx.Save(&option).Error
+}
+
+// applyOption 配置项写入数据库后更新 OptionMap 及相关运行时状态
+func applyOption(key string, value string) error {
 	// Update OptionMap
-	return updateOptionMap(key, value)
+	if err := updateOptionMap(key, value); err != nil {
+		return err
+	}
+	if key == "admin_audit_setting.append_only" && value == "true" {
+		// 运行时开启只追加时立即安装触发器，不必等到重启
+		return InstallAdminAuditAppendOnlyTriggers()
+	}
+	return nil
 }
 
 func updateOptionMap(key string, value string) (err error) {
@@ -0,0 +1,344 @@
+package model
+
+import (
+	"errors"
+	"one-api/common"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"gorm.io/gorm"
+)
+
+const (
+	OrgRoleOwner   = "owner"
+	OrgRoleBilling = "billing"
+	OrgRoleMember  = "member"
+)
+
+const (
+	OrganizationStatusEnabled  = 1
+	OrganizationStatusDisabled = 2
+)
+
+const (
+	OrgMemberStatusActive  = 1
+	OrgMemberStatusInvited = 2 // 已邀请，用户接受后才成为成员
+)
+
+var ErrOrganizationMemberQuotaExceeded = errors.New("organization member quota limit exceeded")
+
+// Organization 组织，拥有成员共享的额度池
+type Organization struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name" gorm:"type:varchar(64);index"`
+	Status      int    `json:"status" gorm:"default:1"`
+	Quota       int    `json:"quota" gorm:"default:0"`
+	UsedQuota   int    `json:"used_quota" gorm:"default:0"`
+	CreatedTime int64  `json:"created_time" gorm:"bigint"`
+}
+
+// OrganizationMember 组织成员，成员的组织令牌从组织额度池扣费。被邀请的用户接受邀请前不能使用组织额度
+type OrganizationMember struct {
+	Id     int    `json:"id"`
+	OrgId  int    `json:"org_id" gorm:"uniqueIndex:idx_org_member,priority:1"`
+	UserId int    `json:"user_id" gorm:"uniqueIndex:idx_org_member,priority:2;index"`
+	Role   string `json:"role" gorm:"type:varchar(16)"`
+	Status int    `json:"status" gorm:"default:1"`
+	// 成员在组织额度池中的消费上限，0 表示不限制
+	QuotaLimit  int    `json:"quota_limit" gorm:"default:0"`
+	UsedQuota   int    `json:"used_quota" gorm:"default:0"`
+	CreatedTime int64  `json:"created_time" gorm:"bigint"`
+	Username    string `json:"username" gorm:"-:all"`
+}
+
+// UserOrganization 用户所属的组织及其角色
+type UserOrganization struct {
+	Organization
+	Role            string `json:"role"`
+	MemberStatus    int    `json:"member_status"`
+	QuotaLimit      int    `json:"quota_limit"`
+	MemberUsedQuota int    `json:"member_used_quota"`
+}
+
+func IsValidOrgRole(role string) bool {
+	return role == OrgRoleOwner || role == OrgRoleBilling || role == OrgRoleMember
+}
+
+// IsActive 是否已接受邀请
+func (member *OrganizationMember) IsActive() bool {
+	return member.Status == OrgMemberStatusActive
+}
+
+// CanManageMembers 是否可以管理成员与角色
+func (member *OrganizationMember) CanManageMembers() bool {
+	return member.Role == OrgRoleOwner
+}
+
+// CanManageBilling 是否可以为组织充值、设置成员上限并查看所有成员的消费
+func (member *OrganizationMember) CanManageBilling() bool {
+	return member.Role == OrgRoleOwner || member.Role == OrgRoleBilling
+}
+
+// RemainQuota 成员还可以使用的组织额度，未设置上限时返回 -1
+func (member *OrganizationMember) RemainQuota() int {
+	if member.QuotaLimit <= 0 {
+		return -1
+	}
+	return max(member.QuotaLimit-member.UsedQuota, 0)
+}
+
+// CreateOrganization 创建组织，创建者成为所有者
+func CreateOrganization(name string, ownerId int) (*Organization, error) {
+	now := common.GetTimestamp()
+	org := &Organization{Name: name, Status: OrganizationStatusEnabled, CreatedTime: now}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&OrganizationMember{OrgId: org.Id, UserId: ownerId, Role: OrgRoleOwner, Status: OrgMemberStatusActive, CreatedTime: now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func GetOrganizationById(id int) (*Organization, error) {
+	var org Organization
+	err := DB.First(&org, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func GetAllOrganizations(keyword string, startIdx int, num int) (orgs []*Organization, total int64, err error) {
+	tx := DB.Model(&Organization{})
+	if keyword != "" {
+		tx = tx.Where("name LIKE ?", "%"+keyword+"%")
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&orgs).Error
+	return orgs, total, err
+}
+
+func (org *Organization) Update() error {
+	if err := DB.Model(org).Select("name", "status").Updates(org).Error; err != nil {
+		return err
+	}
+	return invalidateOrganizationCache(org.Id)
+}
+
+// GetUserOrganizations 获取用户所属以及收到邀请的组织
+func GetUserOrganizations(userId int) ([]*UserOrganization, error) {
+	var members []*OrganizationMember
+	if err := DB.Where("user_id = ?", userId).Order("org_id").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	result := make([]*UserOrganization, 0, len(members))
+	for _, member := range members {
+		org, err := GetOrganizationById(member.OrgId)
+		if err != nil {
+			continue
+		}
+		result = append(result, &UserOrganization{
+			Organization:    *org,
+			Role:            member.Role,
+			MemberStatus:    member.Status,
+			QuotaLimit:      member.QuotaLimit,
+			MemberUsedQuota: member.UsedQuota,
+		})
+	}
+	return result, nil
+}
+
+func GetOrganizationMember(orgId int, userId int) (*OrganizationMember, error) {
+	var member OrganizationMember
+	err := DB.Where("org_id = ? and user_id = ?", orgId, userId).First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// GetOrganizationMembers 获取组织的成员及各自的消费
+func GetOrganizationMembers(orgId int) ([]*OrganizationMember, error) {
+	var members []*OrganizationMember
+	if err := DB.Where("org_id = ?", orgId).Order("id").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		member.Username, _ = GetUsernameById(member.UserId, false)
+	}
+	return members, nil
+}
+
+func AddOrganizationMember(member *OrganizationMember) error {
+	member.CreatedTime = common.GetTimestamp()
+	return DB.Create(member).Error
+}
+
+// InviteOrganizationMember 邀请用户加入组织，用户接受前不能使用组织额度
+func InviteOrganizationMember(member *OrganizationMember) error {
+	member.Status = OrgMemberStatusInvited
+	return AddOrganizationMember(member)
+}
+
+// AcceptOrganizationInvitation 用户接受组织邀请
+func AcceptOrganizationInvitation(orgId int, userId int) error {
+	result := DB.Model(&OrganizationMember{}).Where("org_id = ? and user_id = ? and status = ?", orgId, userId, OrgMemberStatusInvited).
+		Update("status", OrgMemberStatusActive)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("没有待接受的邀请")
+	}
+	return invalidateOrganizationMemberCache(orgId, userId)
+}
+
+func (member *OrganizationMember) Update() error {
+	if err := DB.Model(member).Select("role", "quota_limit").Updates(member).Error; err != nil {
+		return err
+	}
+	return invalidateOrganizationMemberCache(member.OrgId, member.UserId)
+}
+
+// CountOrganizationOwners 统计组织已接受邀请的所有者数量，用于防止移除最后一个所有者
+func CountOrganizationOwners(orgId int) (int64, error) {
+	var count int64
+	err := DB.Model(&OrganizationMember{}).Where("org_id = ? and role = ? and status = ?", orgId, OrgRoleOwner, OrgMemberStatusActive).Count(&count).Error
+	return count, err
+}
+
+// RemoveOrganizationMember 移除成员，成员使用该组织额度的令牌在计费时会被拒绝
+func RemoveOrganizationMember(orgId int, userId int) error {
+	if err := DB.Where("org_id = ? and user_id = ?", orgId, userId).Delete(&OrganizationMember{}).Error; err != nil {
+		return err
+	}
+	return invalidateOrganizationMemberCache(orgId, userId)
+}
+
+// IncreaseOrganizationQuota 增加组织额度池，quota 为负数时扣减
+func IncreaseOrganizationQuota(orgId int, quota int) error {
+	err := DB.Model(&Organization{}).Where("id = ?", orgId).
+		Update("quota", gorm.Expr("quota + ?", quota)).Error
+	if err != nil {
+		return err
+	}
+	gopool.Go(func() {
+		if err := cacheIncrOrganizationQuota(orgId, int64(quota)); err != nil {
+			common.SysError("failed to update organization quota cache: " + err.Error())
+		}
+	})
+	return nil
+}
+
+func consumeOrganizationQuota(orgId int, quota int) error {
+	return DB.Model(&Organization{}).Where("id = ?", orgId).Updates(map[string]interface{}{
+		"quota":      gorm.Expr("quota - ?", quota),
+		"used_quota": gorm.Expr("used_quota + ?", quota),
+	}).Error
+}
+
+func increaseOrganizationMemberUsedQuota(memberId int, quota int) error {
+	return DB.Model(&OrganizationMember{}).Where("id = ?", memberId).
+		Update("used_quota", gorm.Expr("used_quota + ?", quota)).Error
+}
+
+// ConsumeOrganizationQuota 从组织额度池扣费并累计成员的消费，quota 为负数时退还
+func ConsumeOrganizationQuota(orgId int, userId int, quota int) error {
+	if quota == 0 {
+		return nil
+	}
+	gopool.Go(func() {
+		if err := cacheIncrOrganizationQuota(orgId, -int64(quota)); err != nil {
+			common.SysError("failed to update organization quota cache: " + err.Error())
+		}
+		if err := cacheIncrOrganizationMemberUsedQuota(orgId, userId, int64(quota)); err != nil {
+			common.SysError("failed to update organization member quota cache: " + err.Error())
+		}
+	})
+	// 成员已被移除时仍需退还组织额度池
+	member, err := GetOrganizationMemberCache(orgId, userId)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if common.BatchUpdateEnabled {
+		addNewRecord(BatchUpdateTypeOrganizationQuota, orgId, quota)
+		if member != nil {
+			addNewRecord(BatchUpdateTypeOrganizationMemberUsedQuota, member.Id, quota)
+		}
+		return nil
+	}
+	if err = consumeOrganizationQuota(orgId, quota); err != nil {
+		return err
+	}
+	if member != nil {
+		return increaseOrganizationMemberUsedQuota(member.Id, quota)
+	}
+	return nil
+}
+
+// RefundBillingQuota 退还异步任务等预扣的额度，orgId 非 0 时退回组织额度池
+func RefundBillingQuota(userId int, orgId int, quota int) error {
+	if orgId != 0 {
+		return ConsumeOrganizationQuota(orgId, userId, -quota)
+	}
+	return IncreaseUserQuota(userId, quota, false)
+}
+
+// TransferUserQuotaToOrganization 将用户自己的额度转入组织额度池
+func TransferUserQuotaToOrganization(userId int, orgId int, quota int) error {
+	if quota <= 0 {
+		return errors.New("转入额度必须大于 0")
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("id = ? and quota >= ?", userId, quota).
+			Update("quota", gorm.Expr("quota - ?", quota))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("用户额度不足")
+		}
+		return tx.Model(&Organization{}).Where("id = ?", orgId).
+			Update("quota", gorm.Expr("quota + ?", quota)).Error
+	})
+	if err != nil {
+		return err
+	}
+	gopool.Go(func() {
+		if err := cacheDecrUserQuota(userId, int64(quota)); err != nil {
+			common.SysError("failed to decrease user quota cache: " + err.Error())
+		}
+		if err := cacheIncrOrganizationQuota(orgId, int64(quota)); err != nil {
+			common.SysError("failed to update organization quota cache: " + err.Error())
+		}
+	})
+	return nil
+}
+
+// GetOrganizationBillingQuota 返回成员可使用的组织额度，为组织额度池与成员剩余上限中的较小值
+func GetOrganizationBillingQuota(orgId int, userId int) (int, error) {
+	org, err := GetOrganizationCache(orgId)
+	if err != nil {
+		return 0, err
+	}
+	if org.Status != OrganizationStatusEnabled {
+		return 0, errors.New("organization is disabled")
+	}
+	member, err := GetOrganizationMemberCache(orgId, userId)
+	if err != nil {
+		return 0, errors.New("user is not a member of the organization")
+	}
+	quota := org.Quota
+	if remain := member.RemainQuota(); remain >= 0 {
+		if remain == 0 {
+			return 0, ErrOrganizationMemberQuotaExceeded
+		}
+		quota = min(quota, remain)
+	}
+	return quota, nil
+}
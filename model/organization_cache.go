@@ -0,0 +1,126 @@
+package model
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/constant"
+	"time"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+// OrganizationBase 组织计费时使用的缓存数据
+type OrganizationBase struct {
+	Id     int `json:"id"`
+	Status int `json:"status"`
+	Quota  int `json:"quota"`
+}
+
+// OrganizationMemberBase 成员计费时使用的缓存数据
+type OrganizationMemberBase struct {
+	Id         int `json:"id"`
+	QuotaLimit int `json:"quota_limit"`
+	UsedQuota  int `json:"used_quota"`
+}
+
+// RemainQuota 成员还可以使用的组织额度，未设置上限时返回 -1
+func (member *OrganizationMemberBase) RemainQuota() int {
+	if member.QuotaLimit <= 0 {
+		return -1
+	}
+	return max(member.QuotaLimit-member.UsedQuota, 0)
+}
+
+func getOrganizationCacheKey(orgId int) string {
+	return fmt.Sprintf("organization:%d", orgId)
+}
+
+func getOrganizationMemberCacheKey(orgId int, userId int) string {
+	return fmt.Sprintf("organization_member:%d:%d", orgId, userId)
+}
+
+func invalidateOrganizationCache(orgId int) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+	return common.RedisDelKey(getOrganizationCacheKey(orgId))
+}
+
+func invalidateOrganizationMemberCache(orgId int, userId int) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+	return common.RedisDelKey(getOrganizationMemberCacheKey(orgId, userId))
+}
+
+// GetOrganizationCache 获取组织的计费数据，优先从 Redis 读取
+func GetOrganizationCache(orgId int) (orgCache *OrganizationBase, err error) {
+	var fromDB bool
+	defer func() {
+		if shouldUpdateRedis(fromDB, err) && orgCache != nil {
+			cache := *orgCache
+			gopool.Go(func() {
+				err := common.RedisHSetObj(getOrganizationCacheKey(orgId), &cache, time.Duration(constant.RedisKeyCacheSeconds())*time.Second)
+				if err != nil {
+					common.SysError("failed to update organization cache: " + err.Error())
+				}
+			})
+		}
+	}()
+	if common.RedisEnabled {
+		var cache OrganizationBase
+		if err := common.RedisHGetObj(getOrganizationCacheKey(orgId), &cache); err == nil {
+			return &cache, nil
+		}
+	}
+	fromDB = true
+	var org Organization
+	if err = DB.Select("id", "status", "quota").First(&org, "id = ?", orgId).Error; err != nil {
+		return nil, err
+	}
+	return &OrganizationBase{Id: org.Id, Status: org.Status, Quota: org.Quota}, nil
+}
+
+// GetOrganizationMemberCache 获取已接受邀请的成员的计费数据，优先从 Redis 读取
+func GetOrganizationMemberCache(orgId int, userId int) (memberCache *OrganizationMemberBase, err error) {
+	var fromDB bool
+	defer func() {
+		if shouldUpdateRedis(fromDB, err) && memberCache != nil {
+			cache := *memberCache
+			gopool.Go(func() {
+				err := common.RedisHSetObj(getOrganizationMemberCacheKey(orgId, userId), &cache, time.Duration(constant.RedisKeyCacheSeconds())*time.Second)
+				if err != nil {
+					common.SysError("failed to update organization member cache: " + err.Error())
+				}
+			})
+		}
+	}()
+	if common.RedisEnabled {
+		var cache OrganizationMemberBase
+		if err := common.RedisHGetObj(getOrganizationMemberCacheKey(orgId, userId), &cache); err == nil {
+			return &cache, nil
+		}
+	}
+	fromDB = true
+	var member OrganizationMember
+	err = DB.Select("id", "quota_limit", "used_quota").Where("org_id = ? and user_id = ? and status = ?", orgId, userId, OrgMemberStatusActive).
+		First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &OrganizationMemberBase{Id: member.Id, QuotaLimit: member.QuotaLimit, UsedQuota: member.UsedQuota}, nil
+}
+
+func cacheIncrOrganizationQuota(orgId int, delta int64) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+	return common.RedisHIncrBy(getOrganizationCacheKey(orgId), "Quota", delta)
+}
+
+func cacheIncrOrganizationMemberUsedQuota(orgId int, userId int, delta int64) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+	return common.RedisHIncrBy(getOrganizationMemberCacheKey(orgId, userId), "UsedQuota", delta)
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestOrganization 创建用户和组织，组织额度池为 quota，返回组织与所有者
+func createTestOrganization(t *testing.T, quota int) (*Organization, *User) {
+	owner := &User{Username: "owner", Password: "password", Group: "default", AffCode: "owner", Quota: 100}
+	require.NoError(t, DB.Create(owner).Error)
+	org, err := CreateOrganization("org", owner.Id)
+	require.NoError(t, err)
+	require.NoError(t, IncreaseOrganizationQuota(org.Id, quota))
+	return org, owner
+}
+
+// TestOrganizationBillingQuota 测试成员可用额度取组织额度池与成员剩余上限中的较小值，扣费同时累计组织与成员的消费
+func TestOrganizationBillingQuota(t *testing.T) {
+	setupTestDB(t)
+	org, owner := createTestOrganization(t, 1000)
+	member := &User{Username: "member", Password: "password", Group: "default", AffCode: "member"}
+	require.NoError(t, DB.Create(member).Error)
+	require.NoError(t, AddOrganizationMember(&OrganizationMember{OrgId: org.Id, UserId: member.Id, Role: OrgRoleMember, QuotaLimit: 300}))
+
+	quota, err := GetOrganizationBillingQuota(org.Id, owner.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, quota)
+	quota, err = GetOrganizationBillingQuota(org.Id, member.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 300, quota)
+
+	require.NoError(t, ConsumeOrganizationQuota(org.Id, member.Id, 300))
+	org, err = GetOrganizationById(org.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 700, org.Quota)
+	assert.Equal(t, 300, org.UsedQuota)
+	orgMember, err := GetOrganizationMember(org.Id, member.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 300, orgMember.UsedQuota)
+	_, err = GetOrganizationBillingQuota(org.Id, member.Id)
+	assert.ErrorIs(t, err, ErrOrganizationMemberQuotaExceeded)
+
+	_, err = GetOrganizationBillingQuota(org.Id, member.Id+100)
+	assert.Error(t, err)
+	org.Status = OrganizationStatusDisabled
+	require.NoError(t, org.Update())
+	_, err = GetOrganizationBillingQuota(org.Id, owner.Id)
+	assert.Error(t, err)
+}
+
+// TestTransferUserQuotaToOrganization 测试用户额度不足时拒绝转入，转入成功后用户额度减少、组织额度池增加
+func TestTransferUserQuotaToOrganization(t *testing.T) {
+	setupTestDB(t)
+	org, owner := createTestOrganization(t, 0)
+
+	assert.Error(t, TransferUserQuotaToOrganization(owner.Id, org.Id, 0))
+	assert.Error(t, TransferUserQuotaToOrganization(owner.Id, org.Id, 200))
+	require.NoError(t, TransferUserQuotaToOrganization(owner.Id, org.Id, 60))
+
+	userQuota, err := GetUserQuota(owner.Id, true)
+	require.NoError(t, err)
+	assert.Equal(t, 40, userQuota)
+	org, err = GetOrganizationById(org.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 60, org.Quota)
+}
+
+// TestRefundBillingQuota 测试异步任务退款退回组织额度池，成员被移除后仍会退还
+func TestRefundBillingQuota(t *testing.T) {
+	setupTestDB(t)
+	org, owner := createTestOrganization(t, 1000)
+	require.NoError(t, ConsumeOrganizationQuota(org.Id, owner.Id, 200))
+	require.NoError(t, RefundBillingQuota(owner.Id, org.Id, 50))
+	require.NoError(t, RemoveOrganizationMember(org.Id, owner.Id))
+	require.NoError(t, RefundBillingQuota(owner.Id, org.Id, 50))
+
+	org, err := GetOrganizationById(org.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 900, org.Quota)
+	assert.Equal(t, 100, org.UsedQuota)
+
+	require.NoError(t, RefundBillingQuota(owner.Id, 0, 10))
+	userQuota, err := GetUserQuota(owner.Id, true)
+	require.NoError(t, err)
+	assert.Equal(t, 110, userQuota)
+}
+
+// TestOrganizationInvitation 测试被邀请的用户接受邀请前不能使用组织额度，也不计入所有者数量
+func TestOrganizationInvitation(t *testing.T) {
+	setupTestDB(t)
+	org, _ := createTestOrganization(t, 1000)
+	user := &User{Username: "invitee", Password: "password", Group: "default", AffCode: "invitee"}
+	require.NoError(t, DB.Create(user).Error)
+	require.NoError(t, InviteOrganizationMember(&OrganizationMember{OrgId: org.Id, UserId: user.Id, Role: OrgRoleOwner}))
+
+	_, err := GetOrganizationBillingQuota(org.Id, user.Id)
+	assert.Error(t, err)
+	count, err := CountOrganizationOwners(org.Id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	orgs, err := GetUserOrganizations(user.Id)
+	require.NoError(t, err)
+	require.Len(t, orgs, 1)
+	assert.Equal(t, OrgMemberStatusInvited, orgs[0].MemberStatus)
+
+	require.NoError(t, AcceptOrganizationInvitation(org.Id, user.Id))
+	assert.Error(t, AcceptOrganizationInvitation(org.Id, user.Id))
+	quota, err := GetOrganizationBillingQuota(org.Id, user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, quota)
+	count, err = CountOrganizationOwners(org.Id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
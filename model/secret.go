@@ -0,0 +1,200 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"one-api/common"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("secret", SecretSerializer{})
+}
+
+// SecretSerializer 带有 serializer:secret 标签的字段写入数据库时加密，读取时解密
+type SecretSerializer struct{}
+
+func (SecretSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	fieldValue := reflect.New(field.FieldType)
+	if dbValue != nil {
+		var stored string
+		switch v := dbValue.(type) {
+		case []byte:
+			stored = string(v)
+		case string:
+			stored = v
+		default:
+			return fmt.Errorf("failed to scan secret field %s: unsupported type %T", field.Name, dbValue)
+		}
+		plaintext, err := common.DecryptSecret(stored)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt field %s: %w", field.Name, err)
+		}
+		if field.FieldType.Kind() == reflect.Ptr {
+			fieldValue.Elem().Set(reflect.ValueOf(&plaintext))
+		} else {
+			fieldValue.Elem().SetString(plaintext)
+		}
+	}
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+func (SecretSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	switch v := fieldValue.(type) {
+	case string:
+		return common.EncryptSecret(v)
+	case *string:
+		if v == nil {
+			return nil, nil
+		}
+		return common.EncryptSecret(*v)
+	}
+	return fieldValue, nil
+}
+
+// encryptedOptionKeys 加密保存的配置项，使用固定列表，不随审计脱敏字段的配置变化
+var encryptedOptionKeys = []string{
+	"SMTPToken", "GitHubClientSecret", "WeChatServerToken", "TelegramBotToken", "TurnstileSecretKey",
+	"EpayKey", "WorkerValidKey", "oidc.client_secret", "metrics_setting.token",
+}
+
+// IsEncryptedOptionKey 判断配置项是否加密保存
+func IsEncryptedOptionKey(key string) bool {
+	return slices.Contains(encryptedOptionKeys, key)
+}
+
+// BeforeSave 敏感配置项（如 SMTP 与支付密钥）加密保存
+func (option *Option) BeforeSave(tx *gorm.DB) (err error) {
+	if IsEncryptedOptionKey(option.Key) && !common.IsEncryptedSecret(option.Value) {
+		option.Value, err = common.EncryptSecret(option.Value)
+	}
+	return err
+}
+
+// AfterFind 解密加密保存的配置项，解密失败时返回错误，避免把密文当作配置值使用
+func (option *Option) AfterFind(tx *gorm.DB) error {
+	value, err := common.DecryptSecret(option.Value)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt option %s: %w", option.Key, err)
+	}
+	option.Value = value
+	return nil
+}
+
+// reencryptValue 返回需要写回的密文，不需要重新加密时返回 false
+func reencryptValue(stored string, rotate bool) (string, bool, error) {
+	if stored == "" || !(common.SecretNeedsReencrypt(stored) || (rotate && common.IsEncryptedSecret(stored))) {
+		return "", false, nil
+	}
+	plaintext, err := common.DecryptSecret(stored)
+	if err != nil {
+		return "", false, err
+	}
+	encrypted, err := common.EncryptSecret(plaintext)
+	return encrypted, err == nil, err
+}
+
+type channelSecretRow struct {
+	Id        int
+	Key       string
+	Setting   *string
+	OtherInfo string
+}
+
+type channelVersionSecretRow struct {
+	Id       int
+	Snapshot string
+}
+
+type optionSecretRow struct {
+	Key   string
+	Value string
+}
+
+// EncryptSecretsAtRest 在同一事务中用当前主密钥加密渠道密钥、渠道设置、渠道版本与敏感配置项，
+// 明文与旧主密钥加密的值会被重新加密，rotate 为 true 时为全部加密值重新生成数据密钥，返回更新的行数
+func EncryptSecretsAtRest(rotate bool) (int, error) {
+	if !common.SecretEncryptionEnabled() {
+		return 0, fmt.Errorf("SECRET_ENCRYPTION_KEY is not configured")
+	}
+	updated := 0
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var channels []channelSecretRow
+		if err := tx.Table("channels").Select("id", "key", "setting", "other_info").Find(&channels).Error; err != nil {
+			return err
+		}
+		for _, row := range channels {
+			columns := make(map[string]interface{})
+			if value, ok, err := reencryptValue(row.Key, rotate); err != nil {
+				return fmt.Errorf("channel %d key: %w", row.Id, err)
+			} else if ok {
+				columns["key"] = value
+			}
+			if row.Setting != nil {
+				if value, ok, err := reencryptValue(*row.Setting, rotate); err != nil {
+					return fmt.Errorf("channel %d setting: %w", row.Id, err)
+				} else if ok {
+					columns["setting"] = value
+				}
+			}
+			if value, ok, err := reencryptValue(row.OtherInfo, rotate); err != nil {
+				return fmt.Errorf("channel %d other_info: %w", row.Id, err)
+			} else if ok {
+				columns["other_info"] = value
+			}
+			if len(columns) == 0 {
+				continue
+			}
+			if err := tx.Table("channels").Where("id = ?", row.Id).Updates(columns).Error; err != nil {
+				return err
+			}
+			updated++
+		}
+
+		var versions []channelVersionSecretRow
+		if err := tx.Table("channel_versions").Select("id", "snapshot").Find(&versions).Error; err != nil {
+			return err
+		}
+		for _, row := range versions {
+			value, ok, err := reencryptValue(row.Snapshot, rotate)
+			if err != nil {
+				return fmt.Errorf("channel version %d: %w", row.Id, err)
+			}
+			if !ok {
+				continue
+			}
+			if err = tx.Table("channel_versions").Where("id = ?", row.Id).Update("snapshot", value).Error; err != nil {
+				return err
+			}
+			updated++
+		}
+
+		var options []optionSecretRow
+		if err := tx.Table("options").Find(&options).Error; err != nil {
+			return err
+		}
+		for _, row := range options {
+			if !IsEncryptedOptionKey(row.Key) && !common.IsEncryptedSecret(row.Value) {
+				continue
+			}
+			value, ok, err := reencryptValue(row.Value, rotate)
+			if err != nil {
+				return fmt.Errorf("option %s: %w", row.Key, err)
+			}
+			if !ok {
+				continue
+			}
+			if err = tx.Table("options").Where(commonKeyCol+" = ?", row.Key).Update("value", value).Error; err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	})
+	return updated, err
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"one-api/common"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useSecretMasterKey 设置主密钥并重新初始化加密，测试结束后恢复为未加密状态
+func useSecretMasterKey(t *testing.T, key string) {
+	t.Cleanup(func() {
+		_ = common.InitSecretEncryption()
+	})
+	t.Setenv("SECRET_ENCRYPTION_KEY", key)
+	t.Setenv("SECRET_ENCRYPTION_PREVIOUS_KEYS", "")
+	require.NoError(t, common.InitSecretEncryption())
+}
+
+// TestOptionEncryption 测试只有固定列表中的配置项加密保存，无法解密时读取配置项返回错误
+func TestOptionEncryption(t *testing.T) {
+	setupTestDB(t)
+	useSecretMasterKey(t, "master-key-1")
+	require.NoError(t, saveOption(DB, "SMTPToken", "smtp-secret"))
+	require.NoError(t, saveOption(DB, "TurnstileSiteKey", "site-key"))
+
+	var rows []optionSecretRow
+	require.NoError(t, DB.Table("options").Order(commonKeyCol).Find(&rows).Error)
+	require.Len(t, rows, 2)
+	assert.True(t, common.IsEncryptedSecret(rows[0].Value))
+	assert.Equal(t, "site-key", rows[1].Value)
+
+	options, err := AllOption()
+	require.NoError(t, err)
+	values := make(map[string]string)
+	for _, option := range options {
+		values[option.Key] = option.Value
+	}
+	assert.Equal(t, map[string]string{"SMTPToken": "smtp-secret", "TurnstileSiteKey": "site-key"}, values)
+
+	useSecretMasterKey(t, "master-key-2")
+	_, err = AllOption()
+	assert.Error(t, err)
+	assert.Error(t, loadOptionsFromDatabase())
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"one-api/common"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ScanUserConsumeLogs 分批遍历用户在 [startTimestamp, endTimestamp) 内的消费日志
+func ScanUserConsumeLogs(userId int, startTimestamp int64, endTimestamp int64, fn func(logs []*Log)) error {
+	var logs []*Log
+	return LOG_DB.Select("id, created_at, token_name, model_name, quota, prompt_tokens, completion_tokens, "+logGroupCol+", other").
+		Where("user_id = ? and type = ? and created_at >= ? and created_at < ?", userId, LogTypeConsume, startTimestamp, endTimestamp).
+		FindInBatches(&logs, 1000, func(tx *gorm.DB, batch int) error {
+			fn(logs)
+			return nil
+		}).Error
+}
+
+// consumeDailyStatDays 返回完全落在 [startTimestamp, endTimestamp) 内的第一天和最后一天，
+// 按天统计无法拆分到小时，只覆盖部分时间的首尾两天不计入
+func consumeDailyStatDays(startTimestamp int64, endTimestamp int64) (string, string) {
+	start := time.Unix(startTimestamp, 0)
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local)
+	if startDay.Unix() < startTimestamp {
+		startDay = startDay.AddDate(0, 0, 1)
+	}
+	end := time.Unix(endTimestamp, 0)
+	endDay := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, -1)
+	return startDay.Format("2006-01-02"), endDay.Format("2006-01-02")
+}
+
+// GetUserConsumeDailyStats 获取用户在日期范围内已被清理、汇总为按天统计的消费，只包含完整落在范围内的日期
+func GetUserConsumeDailyStats(userId int, startTimestamp int64, endTimestamp int64) (stats []*LogDailyStat, err error) {
+	startDay, endDay := consumeDailyStatDays(startTimestamp, endTimestamp)
+	if startDay > endDay {
+		return nil, nil
+	}
+	err = LOG_DB.Where("user_id = ? and type = ? and day >= ? and day <= ?", userId, LogTypeConsume, startDay, endDay).
+		Order("day").Find(&stats).Error
+	return stats, err
+}
+
+// GetUserSuccessTopUps 获取用户在时间范围内成功的充值订单
+func GetUserSuccessTopUps(userId int, startTimestamp int64, endTimestamp int64) (topUps []*TopUp, err error) {
+	err = DB.Where("user_id = ? and status = ? and create_time >= ? and create_time < ?", userId, "success", startTimestamp, endTimestamp).
+		Order("create_time").Find(&topUps).Error
+	return topUps, err
+}
+
+// GetUserUsedRedemptions 获取用户在时间范围内兑换的兑换码
+func GetUserUsedRedemptions(userId int, startTimestamp int64, endTimestamp int64) (redemptions []*Redemption, err error) {
+	err = DB.Where("used_user_id = ? and status = ? and redeemed_time >= ? and redeemed_time < ?", userId, common.RedemptionCodeStatusUsed, startTimestamp, endTimestamp).
+		Order("redeemed_time").Find(&redemptions).Error
+	return redemptions, err
+}
+
+// GetStatementUserIds 获取时间范围内有消费、充值或兑换记录的用户
+func GetStatementUserIds(startTimestamp int64, endTimestamp int64) ([]int, error) {
+	seen := make(map[int]bool)
+	var userIds []int
+	collect := func(ids []int) {
+		for _, id := range ids {
+			if id != 0 && !seen[id] {
+				seen[id] = true
+				userIds = append(userIds, id)
+			}
+		}
+	}
+	var ids []int
+	if err := LOG_DB.Model(&Log{}).Where("type = ? and created_at >= ? and created_at < ?", LogTypeConsume, startTimestamp, endTimestamp).
+		Distinct().Pluck("user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	collect(ids)
+	ids = nil
+	if startDay, endDay := consumeDailyStatDays(startTimestamp, endTimestamp); startDay <= endDay {
+		if err := LOG_DB.Model(&LogDailyStat{}).Where("type = ? and day >= ? and day <= ?", LogTypeConsume, startDay, endDay).
+			Distinct().Pluck("user_id", &ids).Error; err != nil {
+			return nil, err
+		}
+		collect(ids)
+	}
+	ids = nil
+	if err := DB.Model(&TopUp{}).Where("status = ? and create_time >= ? and create_time < ?", "success", startTimestamp, endTimestamp).
+		Distinct().Pluck("user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	collect(ids)
+	ids = nil
+	if err := DB.Model(&Redemption{}).Where("status = ? and redeemed_time >= ? and redeemed_time < ?", common.RedemptionCodeStatusUsed, startTimestamp, endTimestamp).
+		Distinct().Pluck("used_user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	collect(ids)
+	return userIds, nil
+}
@@ -0,0 +1,201 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"one-api/common"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// storedResponseMaxChain previous_response_id 链的最大回溯深度，防止异常数据导致死循环
+const storedResponseMaxChain = 256
+
+// StoredResponse 网关保存的 /v1/responses 会话状态，使 previous_response_id 不依赖上游账号，可以在任意渠道上重建上下文
+type StoredResponse struct {
+	Id                 string `json:"id" gorm:"type:varchar(64);primaryKey"`
+	UserId             int    `json:"user_id" gorm:"index"`
+	TokenId            int    `json:"token_id" gorm:"index"`
+	Model              string `json:"model" gorm:"type:varchar(255)"`
+	PreviousResponseId string `json:"previous_response_id" gorm:"type:varchar(64)"`
+	ConversationId     string `json:"conversation_id" gorm:"type:varchar(64);index"` // 会话第一轮响应的 id，用于一次查出整段会话
+	Input              string `json:"input"`                                         // 本轮输入项
+	Output             string `json:"output"`                                        // 本轮输出项
+	Response           string `json:"response"`                                      // 完整的响应对象
+	CreatedAt          int64  `json:"created_at" gorm:"bigint;index"`
+	ExpiresAt          int64  `json:"expires_at" gorm:"bigint;index"` // 0 表示永不过期
+}
+
+func NewStoredResponseId() string {
+	return "resp_" + common.GetRandomString(48)
+}
+
+func (response *StoredResponse) SetItems(input []json.RawMessage, output []json.RawMessage) error {
+	inputData, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	outputData, err := json.Marshal(output)
+	if err != nil {
+		return err
+	}
+	response.Input = string(inputData)
+	response.Output = string(outputData)
+	return nil
+}
+
+func unmarshalStoredItems(data string) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+	if data == "" {
+		return items, nil
+	}
+	err := json.Unmarshal([]byte(data), &items)
+	return items, err
+}
+
+// GetInputItems 返回本轮的输入项
+func (response *StoredResponse) GetInputItems() ([]json.RawMessage, error) {
+	return unmarshalStoredItems(response.Input)
+}
+
+// GetItems 返回本轮的输入项与输出项
+func (response *StoredResponse) GetItems() ([]json.RawMessage, error) {
+	input, err := unmarshalStoredItems(response.Input)
+	if err != nil {
+		return nil, err
+	}
+	output, err := unmarshalStoredItems(response.Output)
+	if err != nil {
+		return nil, err
+	}
+	return append(input, output...), nil
+}
+
+func (response *StoredResponse) Insert() error {
+	return DB.Create(response).Error
+}
+
+// BeforeCreate 沿用上一轮响应的会话 id，会话第一轮使用自身 id
+func (response *StoredResponse) BeforeCreate(tx *gorm.DB) error {
+	if response.ConversationId != "" {
+		return nil
+	}
+	response.ConversationId = response.Id
+	if response.PreviousResponseId == "" {
+		return nil
+	}
+	var previous StoredResponse
+	err := tx.Session(&gorm.Session{NewDB: true}).Select("conversation_id").Where("id = ?", response.PreviousResponseId).Take(&previous).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if previous.ConversationId != "" {
+		response.ConversationId = previous.ConversationId
+	}
+	return nil
+}
+
+// storedResponseScope 限定用户、令牌与有效期，tokenId 为 0 时不按令牌隔离
+func storedResponseScope(userId int, tokenId int) *gorm.DB {
+	tx := DB.Model(&StoredResponse{}).Where("user_id = ?", userId).
+		Where("expires_at = 0 or expires_at > ?", common.GetTimestamp())
+	if tokenId != 0 {
+		tx = tx.Where("token_id = ?", tokenId)
+	}
+	return tx
+}
+
+func GetStoredResponse(userId int, tokenId int, id string) (*StoredResponse, error) {
+	var response StoredResponse
+	err := storedResponseScope(userId, tokenId).Where("id = ?", id).First(&response).Error
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// DeleteStoredResponse 删除保存的响应，不存在时返回 gorm.ErrRecordNotFound
+func DeleteStoredResponse(userId int, tokenId int, id string) error {
+	result := storedResponseScope(userId, tokenId).Where("id = ?", id).Delete(&StoredResponse{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetStoredResponseHistory 按会话 id 一次查出整段会话，沿 previous_response_id 回溯后按时间顺序返回输入项与输出项
+func GetStoredResponseHistory(userId int, tokenId int, id string) ([]json.RawMessage, error) {
+	response, err := GetStoredResponse(userId, tokenId, id)
+	if err != nil {
+		return nil, err
+	}
+	var responses []*StoredResponse
+	err = storedResponseScope(userId, tokenId).Where("conversation_id = ?", response.ConversationId).Find(&responses).Error
+	if err != nil {
+		return nil, err
+	}
+	responseMap := make(map[string]*StoredResponse, len(responses))
+	for _, item := range responses {
+		responseMap[item.Id] = item
+	}
+	chain := []*StoredResponse{response}
+	for response.PreviousResponseId != "" {
+		if len(chain) >= storedResponseMaxChain {
+			return nil, errors.New("previous response chain is too long")
+		}
+		previous, ok := responseMap[response.PreviousResponseId]
+		if !ok {
+			return nil, gorm.ErrRecordNotFound
+		}
+		chain = append(chain, previous)
+		response = previous
+	}
+	var items []json.RawMessage
+	for i := len(chain) - 1; i >= 0; i-- {
+		responseItems, err := chain[i].GetItems()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, responseItems...)
+	}
+	return items, nil
+}
+
+// DeleteExpiredStoredResponses 分批删除已过期的响应，返回删除的行数
+func DeleteExpiredStoredResponses(ctx context.Context, limit int) (int64, error) {
+	var total int64 = 0
+	now := common.GetTimestamp()
+	for {
+		if nil != ctx.Err() {
+			return total, ctx.Err()
+		}
+		result := DB.Where("expires_at > 0 and expires_at <= ?", now).Limit(limit).Delete(&StoredResponse{})
+		if nil != result.Error {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < int64(limit) {
+			break
+		}
+	}
+	return total, nil
+}
+
+// CleanExpiredStoredResponses 定期清理过期的响应
+func CleanExpiredStoredResponses() {
+	for {
+		count, err := DeleteExpiredStoredResponses(context.Background(), 1000)
+		if err != nil {
+			common.SysError("failed to clean stored responses: " + err.Error())
+		} else if count > 0 {
+			common.SysLog(fmt.Sprintf("cleaned %d stored responses", count))
+		}
+		time.Sleep(time.Hour)
+	}
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// insertStoredResponse 保存一轮会话，输入与输出各一项
+func insertStoredResponse(t *testing.T, id string, previousId string, tokenId int, expiresAt int64) {
+	response := &StoredResponse{Id: id, UserId: 1, TokenId: tokenId, PreviousResponseId: previousId, ExpiresAt: expiresAt}
+	require.NoError(t, response.SetItems(
+		[]json.RawMessage{json.RawMessage(`"` + id + `-in"`)},
+		[]json.RawMessage{json.RawMessage(`"` + id + `-out"`)},
+	))
+	require.NoError(t, response.Insert())
+}
+
+// TestGetStoredResponseHistory 测试沿 previous_response_id 回溯并按时间顺序返回会话，按令牌隔离并忽略过期的响应
+func TestGetStoredResponseHistory(t *testing.T) {
+	setupTestDB(t)
+	insertStoredResponse(t, "resp_1", "", 1, 0)
+	insertStoredResponse(t, "resp_2", "resp_1", 1, 0)
+	insertStoredResponse(t, "resp_3", "resp_2", 1, common.GetTimestamp()+3600)
+
+	items, err := GetStoredResponseHistory(1, 1, "resp_3")
+	require.NoError(t, err)
+	var values []string
+	for _, item := range items {
+		var value string
+		require.NoError(t, json.Unmarshal(item, &value))
+		values = append(values, value)
+	}
+	assert.Equal(t, []string{"resp_1-in", "resp_1-out", "resp_2-in", "resp_2-out", "resp_3-in", "resp_3-out"}, values)
+
+	_, err = GetStoredResponseHistory(1, 2, "resp_3")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	items, err = GetStoredResponseHistory(1, 0, "resp_2")
+	require.NoError(t, err)
+	assert.Len(t, items, 4)
+	response, err := GetStoredResponse(1, 1, "resp_3")
+	require.NoError(t, err)
+	assert.Equal(t, "resp_1", response.ConversationId)
+
+	insertStoredResponse(t, "resp_expired", "resp_3", 1, common.GetTimestamp()-1)
+	_, err = GetStoredResponse(1, 1, "resp_expired")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	count, err := DeleteExpiredStoredResponses(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	require.NoError(t, DeleteStoredResponse(1, 1, "resp_1"))
+	assert.ErrorIs(t, DeleteStoredResponse(1, 1, "resp_1"), gorm.ErrRecordNotFound)
+	_, err = GetStoredResponseHistory(1, 1, "resp_3")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+// TestGetStoredResponseHistoryQueries 测试回溯长会话时查询次数固定，不随会话轮数增加
+func TestGetStoredResponseHistoryQueries(t *testing.T) {
+	setupTestDB(t)
+	previousId := ""
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("resp_%d", i)
+		insertStoredResponse(t, id, previousId, 1, 0)
+		previousId = id
+	}
+	queries := 0
+	require.NoError(t, DB.Callback().Query().Before("gorm:query").Register("test:count_queries", func(*gorm.DB) {
+		queries++
+	}))
+	t.Cleanup(func() {
+		_ = DB.Callback().Query().Remove("test:count_queries")
+	})
+	items, err := GetStoredResponseHistory(1, 1, previousId)
+	require.NoError(t, err)
+	assert.Len(t, items, 40)
+	assert.Equal(t, 2, queries)
+}
@@ -27,7 +27,9 @@ type Task struct {
 	TaskID     string                `json:"task_id" gorm:"type:varchar(50);index"`  // 第三方id，不一定有/ song id\ Task id
 	Platform   constant.TaskPlatform `json:"platform" gorm:"type:varchar(30);index"` // 平台
 	UserId     int                   `json:"user_id" gorm:"index"`
+	OrgId      int                   `json:"org_id"` // 非 0 时任务失败退回组织额度池
 	ChannelId  int                   `json:"channel_id" gorm:"index"`
+	KeyIndex   int                   `json:"-"` // 提交任务时使用的多密钥下标，查询进度时沿用同一密钥
 	Quota      int                   `json:"quota"`
 	Action     string                `json:"action" gorm:"type:varchar(40);index"` // 任务类型, song, lyrics, description-mode
 	Status     TaskStatus            `json:"status" gorm:"type:varchar(20);index"` // 任务状态
@@ -80,10 +82,12 @@ type SyncTaskQueryParams struct {
 func InitTask(platform constant.TaskPlatform, relayInfo *commonRelay.TaskRelayInfo) *Task {
 	t := &Task{
 		UserId:     relayInfo.UserId,
+		OrgId:      relayInfo.OrgId,
 		SubmitTime: time.Now().Unix(),
 		Status:     TaskStatusNotStart,
 		Progress:   "0%",
 		ChannelId:  relayInfo.ChannelId,
+		KeyIndex:   relayInfo.ChannelKeyIndex,
 		Platform:   platform,
 	}
 	return t
@@ -26,6 +26,14 @@ type Token struct {
 	AllowIps           *string        `json:"allow_ips" gorm:"default:''"`
 	UsedQuota          int            `json:"used_quota" gorm:"default:0"` // used quota
 	Group              string         `json:"group" gorm:"default:''"`
+	ResponseCache      bool           `json:"response_cache" gorm:"default:false"`
+	DailyBudget        int            `json:"daily_budget" gorm:"default:0"`   // 0 means no budget
+	WeeklyBudget       int            `json:"weekly_budget" gorm:"default:0"`  // 0 means no budget
+	MonthlyBudget      int            `json:"monthly_budget" gorm:"default:0"` // 0 means no budget
+	BudgetRolling      bool           `json:"budget_rolling" gorm:"default:false"`
+	ModelQuotas        string         `json:"model_quotas" gorm:"type:text"` // JSON: model -> TokenModelQuota
+	AuditEnabled       bool           `json:"audit_enabled" gorm:"default:false"`
+	OrgId              int            `json:"org_id" gorm:"default:0;index"` // 非 0 时从组织额度池扣费
 	DeletedAt          gorm.DeletedAt `gorm:"index"`
 }
 
@@ -184,7 +192,8 @@ func (token *Token) Update() (err error) {
 		}
 	}()
 	err = DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota",
-		"model_limits_enabled", "model_limits", "allow_ips", "group").Updates(token).Error
+		"model_limits_enabled", "model_limits", "allow_ips", "group", "response_cache",
+		"daily_budget", "weekly_budget", "monthly_budget", "budget_rolling", "model_quotas", "org_id").Updates(token).Error
 	return err
 }
 
@@ -248,6 +257,22 @@ func DisableModelLimits(tokenId int) error {
 	return token.Update()
 }
 
+// UpdateTokenAuditEnabled 开启或关闭令牌的请求审计，只能由管理员修改
+func UpdateTokenAuditEnabled(token *Token, enabled bool) (err error) {
+	defer func() {
+		if shouldUpdateRedis(true, err) {
+			gopool.Go(func() {
+				err := cacheSetToken(*token)
+				if err != nil {
+					common.SysError("failed to update token cache: " + err.Error())
+				}
+			})
+		}
+	}()
+	token.AuditEnabled = enabled
+	return DB.Model(token).Select("audit_enabled").Updates(token).Error
+}
+
 func DeleteTokenById(id int, userId int) (err error) {
 	// Why we need userId here? In case user want to delete other's token.
 	if id == 0 || userId == 0 {
@@ -0,0 +1,175 @@
+package model
+
+import (
+	"fmt"
+	"one-api/common"
+	"time"
+)
+
+const (
+	TokenBudgetWindowDaily   = "daily"
+	TokenBudgetWindowWeekly  = "weekly"
+	TokenBudgetWindowMonthly = "monthly"
+)
+
+// 按小时与按天两种粒度记录令牌消耗，滚动日窗口使用小时桶，其余窗口使用天桶
+const (
+	tokenBudgetHourTTL = 26 * time.Hour
+	tokenBudgetDayTTL  = 32 * 24 * time.Hour
+)
+
+// TokenBudgetStatus 令牌某个预算窗口的使用情况
+type TokenBudgetStatus struct {
+	Window    string `json:"window"`
+	Budget    int    `json:"budget"`
+	Used      int    `json:"used"`
+	Remaining int    `json:"remaining"`
+	Rolling   bool   `json:"rolling"`
+	ResetAt   int64  `json:"reset_at"` // 滚动窗口为 0
+}
+
+// TokenBudgetExceededError 令牌超出预算时返回的错误
+type TokenBudgetExceededError struct {
+	Window  string
+	Budget  int
+	Used    int
+	ResetAt int64
+}
+
+func (e *TokenBudgetExceededError) Error() string {
+	msg := fmt.Sprintf("token %s budget exceeded, used: %s, budget: %s", e.Window, common.FormatQuota(e.Used), common.FormatQuota(e.Budget))
+	if e.ResetAt > 0 {
+		msg += fmt.Sprintf(", resets at %s", time.Unix(e.ResetAt, 0).Format(time.RFC3339))
+	}
+	return msg
+}
+
+func (token *Token) HasBudget() bool {
+	return token.DailyBudget > 0 || token.WeeklyBudget > 0 || token.MonthlyBudget > 0
+}
+
+func tokenBudgetHourKey(tokenId int, t time.Time) string {
+	return fmt.Sprintf("token_budget:%d:h:%s", tokenId, t.Format("2006010215"))
+}
+
+func tokenBudgetDayKey(tokenId int, t time.Time) string {
+	return fmt.Sprintf("token_budget:%d:d:%s", tokenId, t.Format("20060102"))
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// tokenBudgetWindowKeys 返回窗口覆盖的计数键以及日历窗口的重置时间
+func tokenBudgetWindowKeys(tokenId int, window string, rolling bool, now time.Time) ([]string, int64) {
+	var keys []string
+	today := startOfDay(now)
+	if rolling {
+		switch window {
+		case TokenBudgetWindowDaily:
+			for i := 0; i < 24; i++ {
+				keys = append(keys, tokenBudgetHourKey(tokenId, now.Add(-time.Duration(i)*time.Hour)))
+			}
+		case TokenBudgetWindowWeekly:
+			for i := 0; i < 7; i++ {
+				keys = append(keys, tokenBudgetDayKey(tokenId, today.AddDate(0, 0, -i)))
+			}
+		case TokenBudgetWindowMonthly:
+			for i := 0; i < 30; i++ {
+				keys = append(keys, tokenBudgetDayKey(tokenId, today.AddDate(0, 0, -i)))
+			}
+		}
+		return keys, 0
+	}
+	var start, reset time.Time
+	switch window {
+	case TokenBudgetWindowDaily:
+		start = today
+		reset = today.AddDate(0, 0, 1)
+	case TokenBudgetWindowWeekly:
+		// 以周一作为一周的开始
+		offset := (int(today.Weekday()) + 6) % 7
+		start = today.AddDate(0, 0, -offset)
+		reset = start.AddDate(0, 0, 7)
+	case TokenBudgetWindowMonthly:
+		start = time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+		reset = start.AddDate(0, 1, 0)
+	}
+	for day := start; !day.After(today); day = day.AddDate(0, 0, 1) {
+		keys = append(keys, tokenBudgetDayKey(tokenId, day))
+	}
+	return keys, reset.Unix()
+}
+
+// IncreaseTokenBudgetUsage 累加令牌在当前小时与当天的消耗，quota 为负时表示退还
+func IncreaseTokenBudgetUsage(tokenId int, quota int) error {
+	if quota == 0 {
+		return nil
+	}
+	now := time.Now()
+	_, err := incrUsageCounters(int64(quota),
+		usageCounter{key: tokenBudgetHourKey(tokenId, now), ttl: tokenBudgetHourTTL},
+		usageCounter{key: tokenBudgetDayKey(tokenId, now), ttl: tokenBudgetDayTTL},
+	)
+	return err
+}
+
+// GetTokenBudgetStatus 返回令牌已设置的各预算窗口的使用情况
+func GetTokenBudgetStatus(token *Token) ([]TokenBudgetStatus, error) {
+	budgets := []struct {
+		window string
+		budget int
+	}{
+		{TokenBudgetWindowDaily, token.DailyBudget},
+		{TokenBudgetWindowWeekly, token.WeeklyBudget},
+		{TokenBudgetWindowMonthly, token.MonthlyBudget},
+	}
+	now := time.Now()
+	statuses := make([]TokenBudgetStatus, 0, len(budgets))
+	for _, b := range budgets {
+		if b.budget <= 0 {
+			continue
+		}
+		keys, resetAt := tokenBudgetWindowKeys(token.Id, b.window, token.BudgetRolling, now)
+		total, err := sumUsageCounters(keys)
+		if err != nil {
+			return nil, err
+		}
+		used := int(max(total, 0))
+		remaining := b.budget - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		statuses = append(statuses, TokenBudgetStatus{
+			Window:    b.window,
+			Budget:    b.budget,
+			Used:      used,
+			Remaining: remaining,
+			Rolling:   token.BudgetRolling,
+			ResetAt:   resetAt,
+		})
+	}
+	return statuses, nil
+}
+
+// CheckTokenBudget 检查令牌在再消耗 quota 后是否会超出任一预算窗口
+func CheckTokenBudget(token *Token, quota int) error {
+	if !token.HasBudget() {
+		return nil
+	}
+	statuses, err := GetTokenBudgetStatus(token)
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if status.Used >= status.Budget || status.Used+quota > status.Budget {
+			return &TokenBudgetExceededError{
+				Window:  status.Window,
+				Budget:  status.Budget,
+				Used:    status.Used,
+				ResetAt: status.ResetAt,
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"one-api/common"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// useLocalUsageCounters 使用内存计数并在测试结束后清空
+func useLocalUsageCounters(t *testing.T) {
+	redisEnabled := common.RedisEnabled
+	common.RedisEnabled = false
+	reset := func() {
+		localUsageCounterLock.Lock()
+		localUsageCounters = make(map[string]*localUsageCounter)
+		localUsageCounterLock.Unlock()
+	}
+	reset()
+	t.Cleanup(func() {
+		common.RedisEnabled = redisEnabled
+		reset()
+	})
+}
+
+// TestTokenBudgetWindowKeys 测试日历窗口从当天、周一或月初开始并返回重置时间，滚动窗口按小时或天回溯
+func TestTokenBudgetWindowKeys(t *testing.T) {
+	// 2026-10-14 是周三
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.Local)
+
+	keys, resetAt := tokenBudgetWindowKeys(1, TokenBudgetWindowDaily, false, now)
+	assert.Equal(t, []string{"token_budget:1:d:20261014"}, keys)
+	assert.Equal(t, time.Date(2026, 10, 15, 0, 0, 0, 0, time.Local).Unix(), resetAt)
+
+	keys, resetAt = tokenBudgetWindowKeys(1, TokenBudgetWindowWeekly, false, now)
+	assert.Equal(t, []string{"token_budget:1:d:20261012", "token_budget:1:d:20261013", "token_budget:1:d:20261014"}, keys)
+	assert.Equal(t, time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local).Unix(), resetAt)
+
+	keys, resetAt = tokenBudgetWindowKeys(1, TokenBudgetWindowMonthly, false, now)
+	assert.Len(t, keys, 14)
+	assert.Equal(t, "token_budget:1:d:20261001", keys[0])
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.Local).Unix(), resetAt)
+
+	keys, resetAt = tokenBudgetWindowKeys(1, TokenBudgetWindowDaily, true, now)
+	assert.Len(t, keys, 24)
+	assert.Equal(t, "token_budget:1:h:2026101415", keys[0])
+	assert.Equal(t, "token_budget:1:h:2026101316", keys[23])
+	assert.Zero(t, resetAt)
+
+	keys, _ = tokenBudgetWindowKeys(1, TokenBudgetWindowMonthly, true, now)
+	assert.Len(t, keys, 30)
+}
+
+// TestCheckTokenBudget 测试消耗累计到各窗口，超出任一预算时返回预算错误，退还后恢复
+func TestCheckTokenBudget(t *testing.T) {
+	useLocalUsageCounters(t)
+	token := &Token{Id: 1, DailyBudget: 100, MonthlyBudget: 1000}
+	assert.NoError(t, CheckTokenBudget(&Token{Id: 1}, 1<<30))
+
+	assert.NoError(t, IncreaseTokenBudgetUsage(token.Id, 80))
+	assert.NoError(t, CheckTokenBudget(token, 20))
+	err := CheckTokenBudget(token, 21)
+	var budgetErr *TokenBudgetExceededError
+	assert.ErrorAs(t, err, &budgetErr)
+	assert.Equal(t, TokenBudgetWindowDaily, budgetErr.Window)
+	assert.Equal(t, 80, budgetErr.Used)
+
+	assert.NoError(t, IncreaseTokenBudgetUsage(token.Id, 20))
+	// 用完预算后免费请求也会被拒绝
+	assert.Error(t, CheckTokenBudget(token, 0))
+
+	assert.NoError(t, IncreaseTokenBudgetUsage(token.Id, -30))
+	statuses, err := GetTokenBudgetStatus(token)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 2)
+	assert.Equal(t, 70, statuses[0].Used)
+	assert.Equal(t, 30, statuses[0].Remaining)
+	assert.Equal(t, TokenBudgetWindowMonthly, statuses[1].Window)
+	assert.Equal(t, 930, statuses[1].Remaining)
+}
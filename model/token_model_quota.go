@@ -0,0 +1,266 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"sort"
+	"time"
+)
+
+// TokenModelQuota 令牌对单个模型的请求数与 token 数上限，0 表示不限制
+type TokenModelQuota struct {
+	DailyRequests   int `json:"daily_requests"`
+	MonthlyRequests int `json:"monthly_requests"`
+	DailyTokens     int `json:"daily_tokens"`
+	MonthlyTokens   int `json:"monthly_tokens"`
+}
+
+func (q TokenModelQuota) HasRequestLimit() bool {
+	return q.DailyRequests > 0 || q.MonthlyRequests > 0
+}
+
+func (q TokenModelQuota) HasTokenLimit() bool {
+	return q.DailyTokens > 0 || q.MonthlyTokens > 0
+}
+
+// TokenModelQuotaExceededError 令牌超出模型上限时返回的错误
+type TokenModelQuotaExceededError struct {
+	Model  string
+	Kind   string // requests 或 tokens
+	Window string
+	Limit  int
+	Used   int
+}
+
+func (e *TokenModelQuotaExceededError) Error() string {
+	return fmt.Sprintf("token %s %s limit for model %s exceeded, used: %d, limit: %d", e.Window, e.Kind, e.Model, e.Used, e.Limit)
+}
+
+// TokenModelUsage 令牌在单个模型上的消耗
+type TokenModelUsage struct {
+	ModelName       string           `json:"model_name"`
+	DailyRequests   int              `json:"daily_requests"`
+	DailyTokens     int              `json:"daily_tokens"`
+	DailyQuota      int              `json:"daily_quota"`
+	MonthlyRequests int              `json:"monthly_requests"`
+	MonthlyTokens   int              `json:"monthly_tokens"`
+	MonthlyQuota    int              `json:"monthly_quota"`
+	Limits          *TokenModelQuota `json:"limits,omitempty"`
+	Remaining       *TokenModelQuota `json:"remaining,omitempty"`
+}
+
+const (
+	tokenModelUsageDayTTL   = 26 * time.Hour
+	tokenModelUsageMonthTTL = 32 * 24 * time.Hour
+)
+
+// GetModelQuotas 解析令牌的模型上限配置
+func (token *Token) GetModelQuotas() map[string]TokenModelQuota {
+	quotas := make(map[string]TokenModelQuota)
+	if token.ModelQuotas == "" {
+		return quotas
+	}
+	if err := json.Unmarshal([]byte(token.ModelQuotas), &quotas); err != nil {
+		common.SysError(fmt.Sprintf("failed to unmarshal model quotas of token %d: %s", token.Id, err.Error()))
+		return map[string]TokenModelQuota{}
+	}
+	return quotas
+}
+
+// ValidateTokenModelQuotas 校验模型上限配置
+func ValidateTokenModelQuotas(modelQuotas string) error {
+	if modelQuotas == "" {
+		return nil
+	}
+	quotas := make(map[string]TokenModelQuota)
+	if err := json.Unmarshal([]byte(modelQuotas), &quotas); err != nil {
+		return err
+	}
+	for modelName, q := range quotas {
+		if q.DailyRequests < 0 || q.MonthlyRequests < 0 || q.DailyTokens < 0 || q.MonthlyTokens < 0 {
+			return fmt.Errorf("model %s has negative limit values", modelName)
+		}
+	}
+	return nil
+}
+
+func tokenModelUsageKeys(tokenId int, modelName string, kind string, now time.Time) (string, string) {
+	prefix := fmt.Sprintf("token_model_usage:%d:%s:%s", tokenId, modelName, kind)
+	return prefix + ":d:" + now.Format("20060102"), prefix + ":m:" + now.Format("200601")
+}
+
+func getTokenModelUsage(tokenId int, modelName string, kind string, now time.Time) (int, int, error) {
+	dayKey, monthKey := tokenModelUsageKeys(tokenId, modelName, kind, now)
+	daily, err := sumUsageCounters([]string{dayKey})
+	if err != nil {
+		return 0, 0, err
+	}
+	monthly, err := sumUsageCounters([]string{monthKey})
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(max(daily, 0)), int(max(monthly, 0)), nil
+}
+
+// ConsumeTokenModelRequest 记录一次模型请求，超出请求数上限时撤销记录并返回错误
+func ConsumeTokenModelRequest(tokenId int, modelName string, quota TokenModelQuota) error {
+	if !quota.HasRequestLimit() {
+		return nil
+	}
+	now := time.Now()
+	dayKey, monthKey := tokenModelUsageKeys(tokenId, modelName, "requests", now)
+	counters := []usageCounter{
+		{key: dayKey, ttl: tokenModelUsageDayTTL},
+		{key: monthKey, ttl: tokenModelUsageMonthTTL},
+	}
+	values, err := incrUsageCounters(1, counters...)
+	if err != nil {
+		return err
+	}
+	var exceeded *TokenModelQuotaExceededError
+	if quota.DailyRequests > 0 && values[0] > int64(quota.DailyRequests) {
+		exceeded = &TokenModelQuotaExceededError{Model: modelName, Kind: "requests", Window: "daily", Limit: quota.DailyRequests, Used: int(values[0] - 1)}
+	} else if quota.MonthlyRequests > 0 && values[1] > int64(quota.MonthlyRequests) {
+		exceeded = &TokenModelQuotaExceededError{Model: modelName, Kind: "requests", Window: "monthly", Limit: quota.MonthlyRequests, Used: int(values[1] - 1)}
+	}
+	if exceeded != nil {
+		_, _ = incrUsageCounters(-1, counters...)
+		return exceeded
+	}
+	return nil
+}
+
+// RefundTokenModelRequest 撤销一次已记录的模型请求，用于请求最终失败的情况
+func RefundTokenModelRequest(tokenId int, modelName string, quota TokenModelQuota) error {
+	if !quota.HasRequestLimit() {
+		return nil
+	}
+	dayKey, monthKey := tokenModelUsageKeys(tokenId, modelName, "requests", time.Now())
+	_, err := incrUsageCounters(-1,
+		usageCounter{key: dayKey, ttl: tokenModelUsageDayTTL},
+		usageCounter{key: monthKey, ttl: tokenModelUsageMonthTTL},
+	)
+	return err
+}
+
+// CheckTokenModelTokens 检查令牌再使用 tokens 个 token 后是否会超出模型的 token 上限
+func CheckTokenModelTokens(token *Token, modelName string, tokens int) error {
+	quota, ok := token.GetModelQuotas()[modelName]
+	if !ok || !quota.HasTokenLimit() {
+		return nil
+	}
+	daily, monthly, err := getTokenModelUsage(token.Id, modelName, "tokens", time.Now())
+	if err != nil {
+		return err
+	}
+	if quota.DailyTokens > 0 && (daily >= quota.DailyTokens || daily+tokens > quota.DailyTokens) {
+		return &TokenModelQuotaExceededError{Model: modelName, Kind: "tokens", Window: "daily", Limit: quota.DailyTokens, Used: daily}
+	}
+	if quota.MonthlyTokens > 0 && (monthly >= quota.MonthlyTokens || monthly+tokens > quota.MonthlyTokens) {
+		return &TokenModelQuotaExceededError{Model: modelName, Kind: "tokens", Window: "monthly", Limit: quota.MonthlyTokens, Used: monthly}
+	}
+	return nil
+}
+
+// IncreaseTokenModelTokenUsage 累加令牌在模型上使用的 token 数
+func IncreaseTokenModelTokenUsage(tokenId int, modelName string, tokens int) error {
+	if tokens == 0 {
+		return nil
+	}
+	dayKey, monthKey := tokenModelUsageKeys(tokenId, modelName, "tokens", time.Now())
+	_, err := incrUsageCounters(int64(tokens),
+		usageCounter{key: dayKey, ttl: tokenModelUsageDayTTL},
+		usageCounter{key: monthKey, ttl: tokenModelUsageMonthTTL},
+	)
+	return err
+}
+
+type tokenModelLogStat struct {
+	ModelName string
+	Requests  int
+	Tokens    int
+	Quota     int
+}
+
+func sumTokenModelLogs(tokenId int, startTimestamp int64) (stats []tokenModelLogStat, err error) {
+	err = LOG_DB.Table("logs").
+		Select("model_name, count(*) requests, sum(prompt_tokens) + sum(completion_tokens) tokens, sum(quota) quota").
+		Where("token_id = ? and type = ? and created_at >= ?", tokenId, LogTypeConsume, startTimestamp).
+		Group("model_name").Scan(&stats).Error
+	return stats, err
+}
+
+// GetTokenModelUsage 汇总令牌当天与当月在各模型上的消耗，设置了上限的模型同时返回剩余额度
+func GetTokenModelUsage(token *Token) ([]*TokenModelUsage, error) {
+	now := time.Now()
+	today := startOfDay(now)
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+	usages := make(map[string]*TokenModelUsage)
+	getUsage := func(modelName string) *TokenModelUsage {
+		usage, ok := usages[modelName]
+		if !ok {
+			usage = &TokenModelUsage{ModelName: modelName}
+			usages[modelName] = usage
+		}
+		return usage
+	}
+
+	monthStats, err := sumTokenModelLogs(token.Id, monthStart.Unix())
+	if err != nil {
+		return nil, err
+	}
+	for _, stat := range monthStats {
+		usage := getUsage(stat.ModelName)
+		usage.MonthlyRequests = stat.Requests
+		usage.MonthlyTokens = stat.Tokens
+		usage.MonthlyQuota = stat.Quota
+	}
+	dayStats, err := sumTokenModelLogs(token.Id, today.Unix())
+	if err != nil {
+		return nil, err
+	}
+	for _, stat := range dayStats {
+		usage := getUsage(stat.ModelName)
+		usage.DailyRequests = stat.Requests
+		usage.DailyTokens = stat.Tokens
+		usage.DailyQuota = stat.Quota
+	}
+
+	for modelName, quota := range token.GetModelQuotas() {
+		usage := getUsage(modelName)
+		limits := quota
+		usage.Limits = &limits
+		dailyRequests, monthlyRequests, err := getTokenModelUsage(token.Id, modelName, "requests", now)
+		if err != nil {
+			return nil, err
+		}
+		dailyTokens, monthlyTokens, err := getTokenModelUsage(token.Id, modelName, "tokens", now)
+		if err != nil {
+			return nil, err
+		}
+		usage.Remaining = &TokenModelQuota{
+			DailyRequests:   remainingLimit(quota.DailyRequests, dailyRequests),
+			MonthlyRequests: remainingLimit(quota.MonthlyRequests, monthlyRequests),
+			DailyTokens:     remainingLimit(quota.DailyTokens, dailyTokens),
+			MonthlyTokens:   remainingLimit(quota.MonthlyTokens, monthlyTokens),
+		}
+	}
+
+	result := make([]*TokenModelUsage, 0, len(usages))
+	for _, usage := range usages {
+		result = append(result, usage)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ModelName < result[j].ModelName
+	})
+	return result, nil
+}
+
+// remainingLimit 返回剩余额度，未设置上限时返回 -1
+func remainingLimit(limit int, used int) int {
+	if limit <= 0 {
+		return -1
+	}
+	return max(limit-used, 0)
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"one-api/common"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidateTokenModelQuotas 测试模型上限配置必须是合法 JSON 且不能为负数
+func TestValidateTokenModelQuotas(t *testing.T) {
+	assert.NoError(t, ValidateTokenModelQuotas(""))
+	assert.NoError(t, ValidateTokenModelQuotas(`{"gpt-4o":{"daily_requests":10}}`))
+	assert.Error(t, ValidateTokenModelQuotas(`{"gpt-4o":{"daily_tokens":-1}}`))
+	assert.Error(t, ValidateTokenModelQuotas(`[]`))
+
+	token := &Token{ModelQuotas: `{"gpt-4o":{"monthly_tokens":100}}`}
+	assert.Equal(t, 100, token.GetModelQuotas()["gpt-4o"].MonthlyTokens)
+	token.ModelQuotas = "invalid"
+	assert.Empty(t, token.GetModelQuotas())
+}
+
+// TestConsumeTokenModelRequest 测试超出请求数上限时拒绝请求且不计入本次请求，不同模型分别计数，失败的请求可以退还
+func TestConsumeTokenModelRequest(t *testing.T) {
+	useLocalUsageCounters(t)
+	quota := TokenModelQuota{DailyRequests: 2}
+	assert.NoError(t, ConsumeTokenModelRequest(1, "gpt-4o", quota))
+	assert.NoError(t, ConsumeTokenModelRequest(1, "gpt-4o", quota))
+	err := ConsumeTokenModelRequest(1, "gpt-4o", quota)
+	var exceeded *TokenModelQuotaExceededError
+	require.ErrorAs(t, err, &exceeded)
+	assert.Equal(t, "requests", exceeded.Kind)
+	assert.Equal(t, "daily", exceeded.Window)
+	assert.Equal(t, 2, exceeded.Used)
+	assert.NoError(t, ConsumeTokenModelRequest(1, "gpt-4o-mini", quota))
+	assert.NoError(t, ConsumeTokenModelRequest(1, "gpt-4o", TokenModelQuota{}))
+
+	// 请求失败退还后可以再次请求
+	assert.NoError(t, RefundTokenModelRequest(1, "gpt-4o", quota))
+	assert.NoError(t, ConsumeTokenModelRequest(1, "gpt-4o", quota))
+}
+
+// TestCheckTokenModelTokens 测试 token 上限按已用量加本次预估判断
+func TestCheckTokenModelTokens(t *testing.T) {
+	useLocalUsageCounters(t)
+	token := &Token{Id: 1, ModelQuotas: `{"gpt-4o":{"daily_tokens":1000,"monthly_tokens":1500}}`}
+	assert.NoError(t, IncreaseTokenModelTokenUsage(token.Id, "gpt-4o", 900))
+	assert.NoError(t, CheckTokenModelTokens(token, "gpt-4o", 100))
+	assert.NoError(t, CheckTokenModelTokens(token, "claude-3", 10000))
+	err := CheckTokenModelTokens(token, "gpt-4o", 101)
+	var exceeded *TokenModelQuotaExceededError
+	require.ErrorAs(t, err, &exceeded)
+	assert.Equal(t, "tokens", exceeded.Kind)
+	assert.Equal(t, 900, exceeded.Used)
+}
+
+// TestGetTokenModelUsage 测试按消费日志汇总各模型的用量，设置了上限的模型同时返回剩余额度
+func TestGetTokenModelUsage(t *testing.T) {
+	setupTestDB(t)
+	useLocalUsageCounters(t)
+	now := common.GetTimestamp()
+	logs := []*Log{
+		{TokenId: 1, Type: LogTypeConsume, ModelName: "gpt-4o", PromptTokens: 10, CompletionTokens: 5, Quota: 30, CreatedAt: now},
+		{TokenId: 1, Type: LogTypeConsume, ModelName: "gpt-4o", PromptTokens: 20, CompletionTokens: 5, Quota: 50, CreatedAt: now},
+		{TokenId: 1, Type: LogTypeError, ModelName: "gpt-4o", CreatedAt: now},
+		{TokenId: 2, Type: LogTypeConsume, ModelName: "gpt-4o", PromptTokens: 100, CreatedAt: now},
+	}
+	require.NoError(t, LOG_DB.Create(logs).Error)
+	token := &Token{Id: 1, ModelQuotas: `{"claude-3":{"daily_requests":5}}`}
+	require.NoError(t, ConsumeTokenModelRequest(token.Id, "claude-3", token.GetModelQuotas()["claude-3"]))
+
+	usages, err := GetTokenModelUsage(token)
+	require.NoError(t, err)
+	require.Len(t, usages, 2)
+	assert.Equal(t, "claude-3", usages[0].ModelName)
+	assert.Equal(t, 4, usages[0].Remaining.DailyRequests)
+	assert.Equal(t, -1, usages[0].Remaining.MonthlyTokens)
+	assert.Equal(t, "gpt-4o", usages[1].ModelName)
+	assert.Equal(t, 2, usages[1].DailyRequests)
+	assert.Equal(t, 40, usages[1].DailyTokens)
+	assert.Equal(t, 80, usages[1].MonthlyQuota)
+	assert.Nil(t, usages[1].Limits)
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"context"
+	"one-api/common"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// usageCounter 带过期时间的计数键，启用 Redis 时存放在 Redis，否则保存在本地内存
+type usageCounter struct {
+	key string
+	ttl time.Duration
+}
+
+type localUsageCounter struct {
+	value    int64
+	expireAt time.Time
+}
+
+var localUsageCounters = make(map[string]*localUsageCounter)
+var localUsageCounterLock sync.Mutex
+var localUsageCounterLastPurge time.Time
+
+// incrUsageCounters 为每个计数键累加 value 并返回累加后的值
+func incrUsageCounters(value int64, counters ...usageCounter) ([]int64, error) {
+	result := make([]int64, len(counters))
+	if common.RedisEnabled {
+		ctx := context.Background()
+		pipe := common.RDB.TxPipeline()
+		cmds := make([]interface{ Val() int64 }, len(counters))
+		for i, counter := range counters {
+			cmds[i] = pipe.IncrBy(ctx, counter.key, value)
+			pipe.Expire(ctx, counter.key, counter.ttl)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+		for i, cmd := range cmds {
+			result[i] = cmd.Val()
+		}
+		return result, nil
+	}
+	now := time.Now()
+	localUsageCounterLock.Lock()
+	defer localUsageCounterLock.Unlock()
+	for i, counter := range counters {
+		local, ok := localUsageCounters[counter.key]
+		if !ok || now.After(local.expireAt) {
+			local = &localUsageCounter{}
+			localUsageCounters[counter.key] = local
+		}
+		local.value += value
+		local.expireAt = now.Add(counter.ttl)
+		result[i] = local.value
+	}
+	if now.Sub(localUsageCounterLastPurge) > time.Hour {
+		for key, local := range localUsageCounters {
+			if now.After(local.expireAt) {
+				delete(localUsageCounters, key)
+			}
+		}
+		localUsageCounterLastPurge = now
+	}
+	return result, nil
+}
+
+// sumUsageCounters 返回多个计数键的合计值，不存在的键按 0 计算
+func sumUsageCounters(keys []string) (int64, error) {
+	var total int64
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	if common.RedisEnabled {
+		values, err := common.RDB.MGet(context.Background(), keys...).Result()
+		if err != nil {
+			return 0, err
+		}
+		for _, value := range values {
+			str, ok := value.(string)
+			if !ok {
+				continue
+			}
+			n, _ := strconv.ParseInt(str, 10, 64)
+			total += n
+		}
+		return total, nil
+	}
+	now := time.Now()
+	localUsageCounterLock.Lock()
+	defer localUsageCounterLock.Unlock()
+	for _, key := range keys {
+		if local, ok := localUsageCounters[key]; ok && now.Before(local.expireAt) {
+			total += local.value
+		}
+	}
+	return total, nil
+}
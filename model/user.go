@@ -42,6 +42,7 @@ type User struct {
 	LinuxDOId        string         `json:"linux_do_id" gorm:"column:linux_do_id;index"`
 	Setting          string         `json:"setting" gorm:"type:text;column:setting"`
 	Remark           string         `json:"remark,omitempty" gorm:"type:varchar(255)" validate:"max=255"`
+	AdminRoleId      int            `json:"admin_role_id" gorm:"type:int;default:0;column:admin_role_id"` // 管理员的权限角色，0 表示拥有全部管理权限
 }
 
 func (user *User) ToBaseUser() *UserBase {
@@ -798,6 +799,12 @@ func GetUsernameById(id int, fromDB bool) (username string, err error) {
 	return username, nil
 }
 
+func GetUserIdByUsername(username string) (int, error) {
+	var user User
+	err := DB.Select("id").Where("username = ?", username).First(&user).Error
+	return user.Id, err
+}
+
 func IsLinuxDOIdAlreadyTaken(linuxDOId string) bool {
 	var user User
 	err := DB.Unscoped().Where("linux_do_id = ?", linuxDOId).First(&user).Error
@@ -7,6 +7,8 @@ import (
 	"io"
 	"net/http"
 	common2 "one-api/common"
+	"one-api/common/tracing"
+	constant2 "one-api/constant"
 	"one-api/relay/common"
 	"one-api/relay/constant"
 	"one-api/relay/helper"
@@ -18,6 +20,7 @@ import (
 	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func SetupApiRequestHeader(info *common.RelayInfo, c *gin.Context, req *http.Header) {
@@ -204,6 +207,17 @@ func sendPingData(c *gin.Context, mutex *sync.Mutex) error {
 }
 
 func doRequest(c *gin.Context, req *http.Request, info *common.RelayInfo) (*http.Response, error) {
+	spanCtx, span := tracing.Start(c.Request.Context(), "upstream_request",
+		attribute.Int("channel.id", info.ChannelId),
+		attribute.String("upstream.model", info.UpstreamModelName),
+		attribute.Int("token.id", info.TokenId),
+		attribute.Bool("stream", info.IsStream),
+	)
+	defer span.End()
+	if passTrace, ok := info.ChannelSetting[constant2.ChannelSettingPassTraceContext].(bool); ok && passTrace {
+		tracing.Inject(spanCtx, req.Header)
+	}
+
 	var client *http.Client
 	var err error
 	if proxyURL, ok := info.ChannelSetting["proxy"]; ok {
@@ -238,11 +252,13 @@ func doRequest(c *gin.Context, req *http.Request, info *common.RelayInfo) (*http
 	resp, err := client.Do(req)
 
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 	if resp == nil {
 		return nil, errors.New("resp is nil")
 	}
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
 
 	_ = req.Body.Close()
 	_ = c.Request.Body.Close()
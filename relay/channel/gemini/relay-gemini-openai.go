@@ -0,0 +1,393 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type geminiFunctionDeclaration struct {
+	Name                 string `json:"name"`
+	Description          string `json:"description,omitempty"`
+	Parameters           any    `json:"parameters,omitempty"`
+	ParametersJsonSchema any    `json:"parametersJsonSchema,omitempty"`
+}
+
+// ShouldConvertGeminiRequest 判断 Gemini 原生请求是否需要转换为 OpenAI 格式，只有 Gemini 与 Vertex 的 Gemini 模型能直接处理
+func ShouldConvertGeminiRequest(info *relaycommon.RelayInfo) bool {
+	switch info.ChannelType {
+	case common.ChannelTypeGemini:
+		return false
+	case common.ChannelTypeVertexAi:
+		return !strings.HasPrefix(info.UpstreamModelName, "gemini")
+	}
+	return true
+}
+
+// normalizeGeminiSchema Gemini SDK 的 schema 类型为大写（如 OBJECT），转换为 JSON Schema 的小写类型
+func normalizeGeminiSchema(schema any) any {
+	switch v := schema.(type) {
+	case map[string]any:
+		normalized := make(map[string]any, len(v))
+		for key, value := range v {
+			if key == "type" {
+				if typeName, ok := value.(string); ok {
+					normalized[key] = strings.ToLower(typeName)
+					continue
+				}
+			}
+			normalized[key] = normalizeGeminiSchema(value)
+		}
+		return normalized
+	case []any:
+		normalized := make([]any, len(v))
+		for i, value := range v {
+			normalized[i] = normalizeGeminiSchema(value)
+		}
+		return normalized
+	}
+	return schema
+}
+
+func geminiInlineDataToMediaContent(data *GeminiInlineData) dto.MediaContent {
+	dataUrl := fmt.Sprintf("data:%s;base64,%s", data.MimeType, data.Data)
+	switch {
+	case strings.HasPrefix(data.MimeType, "image/"):
+		return dto.MediaContent{
+			Type:     dto.ContentTypeImageURL,
+			ImageUrl: dto.MessageImageUrl{Url: dataUrl, Detail: "auto"},
+		}
+	case strings.HasPrefix(data.MimeType, "audio/"):
+		return dto.MediaContent{
+			Type: dto.ContentTypeInputAudio,
+			InputAudio: dto.MessageInputAudio{
+				Data:   data.Data,
+				Format: strings.TrimPrefix(strings.TrimPrefix(data.MimeType, "audio/"), "x-"),
+			},
+		}
+	}
+	return dto.MediaContent{
+		Type: dto.ContentTypeFile,
+		File: dto.MessageFile{FileData: dataUrl},
+	}
+}
+
+// GeminiToOpenAIRequest 将 Gemini 原生请求转换为 OpenAI 请求，functionResponse 按函数名依次匹配之前的 functionCall
+func GeminiToOpenAIRequest(geminiRequest *GeminiChatRequest, info *relaycommon.RelayInfo) (*dto.GeneralOpenAIRequest, error) {
+	config := geminiRequest.GenerationConfig
+	openAIRequest := dto.GeneralOpenAIRequest{
+		Model:       info.UpstreamModelName,
+		Stream:      info.IsStream,
+		Temperature: config.Temperature,
+		TopP:        config.TopP,
+		MaxTokens:   config.MaxOutputTokens,
+		Seed:        float64(config.Seed),
+	}
+	if config.CandidateCount > 1 {
+		openAIRequest.N = config.CandidateCount
+	}
+	if len(config.StopSequences) == 1 {
+		openAIRequest.Stop = config.StopSequences[0]
+	} else if len(config.StopSequences) > 1 {
+		openAIRequest.Stop = config.StopSequences
+	}
+	if config.ResponseSchema != nil {
+		openAIRequest.ResponseFormat = &dto.ResponseFormat{
+			Type: "json_schema",
+			JsonSchema: &dto.FormatJsonSchema{
+				Name:   "response",
+				Schema: normalizeGeminiSchema(config.ResponseSchema),
+			},
+		}
+	} else if config.ResponseMimeType == "application/json" {
+		openAIRequest.ResponseFormat = &dto.ResponseFormat{Type: "json_object"}
+	}
+
+	for _, tool := range geminiRequest.Tools {
+		if tool.FunctionDeclarations == nil {
+			continue
+		}
+		declarations, err := common.Any2Type[[]geminiFunctionDeclaration](tool.FunctionDeclarations)
+		if err != nil {
+			return nil, fmt.Errorf("invalid functionDeclarations: %w", err)
+		}
+		for _, declaration := range declarations {
+			parameters := declaration.ParametersJsonSchema
+			if parameters == nil {
+				parameters = normalizeGeminiSchema(declaration.Parameters)
+			}
+			openAIRequest.Tools = append(openAIRequest.Tools, dto.ToolCallRequest{
+				Type: "function",
+				Function: dto.FunctionRequest{
+					Name:        declaration.Name,
+					Description: declaration.Description,
+					Parameters:  parameters,
+				},
+			})
+		}
+	}
+
+	messages := make([]dto.Message, 0, len(geminiRequest.Contents)+1)
+	if geminiRequest.SystemInstructions != nil {
+		var texts []string
+		for _, part := range geminiRequest.SystemInstructions.Parts {
+			if part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+		if len(texts) > 0 {
+			message := dto.Message{Role: "system"}
+			message.SetStringContent(strings.Join(texts, "\n"))
+			messages = append(messages, message)
+		}
+	}
+
+	// 每个函数名尚未收到结果的调用 id
+	pendingCallIds := make(map[string][]string)
+	for _, content := range geminiRequest.Contents {
+		if content.Role == "model" {
+			message := dto.Message{Role: "assistant"}
+			var text strings.Builder
+			var toolCalls []dto.ToolCallRequest
+			for _, part := range content.Parts {
+				if part.Thought {
+					continue
+				}
+				if part.FunctionCall != nil {
+					id := fmt.Sprintf("call_%s", common.GetUUID())
+					pendingCallIds[part.FunctionCall.FunctionName] = append(pendingCallIds[part.FunctionCall.FunctionName], id)
+					arguments, err := json.Marshal(part.FunctionCall.Arguments)
+					if err != nil {
+						return nil, err
+					}
+					toolCalls = append(toolCalls, dto.ToolCallRequest{
+						ID:   id,
+						Type: "function",
+						Function: dto.FunctionRequest{
+							Name:      part.FunctionCall.FunctionName,
+							Arguments: string(arguments),
+						},
+					})
+					continue
+				}
+				text.WriteString(part.Text)
+			}
+			if text.Len() == 0 && len(toolCalls) == 0 {
+				continue
+			}
+			if text.Len() > 0 {
+				message.SetStringContent(text.String())
+			}
+			if len(toolCalls) > 0 {
+				message.SetToolCalls(toolCalls)
+			}
+			messages = append(messages, message)
+			continue
+		}
+
+		mediaContents := make([]dto.MediaContent, 0, len(content.Parts))
+		for _, part := range content.Parts {
+			switch {
+			case part.FunctionResponse != nil:
+				name := part.FunctionResponse.Name
+				var id string
+				if ids := pendingCallIds[name]; len(ids) > 0 {
+					id = ids[0]
+					pendingCallIds[name] = ids[1:]
+				} else {
+					id = fmt.Sprintf("call_%s", common.GetUUID())
+				}
+				result, err := json.Marshal(part.FunctionResponse.Response)
+				if err != nil {
+					return nil, err
+				}
+				message := dto.Message{Role: "tool", ToolCallId: id, Name: &name}
+				message.SetStringContent(string(result))
+				messages = append(messages, message)
+			case part.InlineData != nil:
+				mediaContents = append(mediaContents, geminiInlineDataToMediaContent(part.InlineData))
+			case part.FileData != nil:
+				if strings.HasPrefix(part.FileData.MimeType, "image/") {
+					mediaContents = append(mediaContents, dto.MediaContent{
+						Type:     dto.ContentTypeImageURL,
+						ImageUrl: dto.MessageImageUrl{Url: part.FileData.FileUri, Detail: "auto"},
+					})
+				} else {
+					mediaContents = append(mediaContents, dto.MediaContent{
+						Type: dto.ContentTypeFile,
+						File: dto.MessageFile{FileId: part.FileData.FileUri},
+					})
+				}
+			case part.Text != "" && !part.Thought:
+				mediaContents = append(mediaContents, dto.MediaContent{Type: dto.ContentTypeText, Text: part.Text})
+			}
+		}
+		if len(mediaContents) == 0 {
+			continue
+		}
+		message := dto.Message{Role: "user"}
+		if len(mediaContents) == 1 && mediaContents[0].Type == dto.ContentTypeText {
+			message.SetStringContent(mediaContents[0].Text)
+		} else {
+			message.SetMediaContent(mediaContents)
+		}
+		messages = append(messages, message)
+	}
+	openAIRequest.Messages = messages
+	return &openAIRequest, nil
+}
+
+func finishReasonOpenAI2Gemini(reason string) string {
+	switch reason {
+	case constant.FinishReasonLength:
+		return "MAX_TOKENS"
+	case constant.FinishReasonContentFilter:
+		return "SAFETY"
+	}
+	return "STOP"
+}
+
+func usageOpenAI2Gemini(usage *dto.Usage) GeminiUsageMetadata {
+	reasoningTokens := usage.CompletionTokenDetails.ReasoningTokens
+	return GeminiUsageMetadata{
+		PromptTokenCount:     usage.PromptTokens,
+		CandidatesTokenCount: usage.CompletionTokens - reasoningTokens,
+		ThoughtsTokenCount:   reasoningTokens,
+		TotalTokenCount:      usage.TotalTokens,
+	}
+}
+
+func toolCallOpenAI2Gemini(name string, arguments string) GeminiPart {
+	var args any = map[string]any{}
+	if strings.TrimSpace(arguments) != "" {
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			args = map[string]any{"arguments": arguments}
+		}
+	}
+	return GeminiPart{FunctionCall: &FunctionCall{FunctionName: name, Arguments: args}}
+}
+
+// ResponseOpenAI2Gemini 将 OpenAI 非流式响应转换为 Gemini 响应
+func ResponseOpenAI2Gemini(openAIResponse *dto.OpenAITextResponse, usage *dto.Usage) *GeminiChatResponse {
+	response := &GeminiChatResponse{
+		Candidates:    make([]GeminiChatCandidate, 0, len(openAIResponse.Choices)),
+		UsageMetadata: usageOpenAI2Gemini(usage),
+	}
+	for _, choice := range openAIResponse.Choices {
+		parts := make([]GeminiPart, 0)
+		reasoning := choice.Message.ReasoningContent
+		if reasoning == "" {
+			reasoning = choice.Message.Reasoning
+		}
+		if reasoning != "" {
+			parts = append(parts, GeminiPart{Text: reasoning, Thought: true})
+		}
+		if text := choice.Message.StringContent(); text != "" {
+			parts = append(parts, GeminiPart{Text: text})
+		}
+		for _, toolCall := range choice.Message.ParseToolCalls() {
+			parts = append(parts, toolCallOpenAI2Gemini(toolCall.Function.Name, toolCall.Function.Arguments))
+		}
+		finishReason := finishReasonOpenAI2Gemini(choice.FinishReason)
+		response.Candidates = append(response.Candidates, GeminiChatCandidate{
+			Content:      GeminiChatContent{Role: "model", Parts: parts},
+			FinishReason: &finishReason,
+			Index:        int64(choice.Index),
+		})
+	}
+	return response
+}
+
+// GeminiConvertWriter 将渠道返回的 OpenAI 格式响应转换为 Gemini 格式后写给客户端，
+// 流式响应逐个事件转换，只保留第一个候选，工具调用在结束时整体输出；非流式响应在 Finish 时转换
+type GeminiConvertWriter struct {
+	*service.OpenAIConvertWriter
+	toolCalls    []*dto.ToolCallResponse
+	finishReason string
+}
+
+func NewGeminiConvertWriter(writer gin.ResponseWriter, stream bool) *GeminiConvertWriter {
+	w := &GeminiConvertWriter{OpenAIConvertWriter: service.NewOpenAIConvertWriter(writer, stream)}
+	w.OnStreamResponse = w.handleStreamResponse
+	return w
+}
+
+// handleStreamResponse 文本与思考内容立即输出，工具调用参数片段累积到结束时输出
+func (w *GeminiConvertWriter) handleStreamResponse(streamResponse *dto.ChatCompletionsStreamResponse) {
+	parts := make([]GeminiPart, 0)
+	for _, choice := range streamResponse.Choices {
+		if choice.Index != 0 {
+			continue
+		}
+		if reasoning := choice.Delta.GetReasoningContent(); reasoning != "" {
+			parts = append(parts, GeminiPart{Text: reasoning, Thought: true})
+		}
+		if text := choice.Delta.GetContentString(); text != "" {
+			parts = append(parts, GeminiPart{Text: text})
+		}
+		for _, toolCall := range choice.Delta.ToolCalls {
+			index := len(w.toolCalls) - 1
+			if toolCall.Index != nil {
+				index = *toolCall.Index
+			} else if toolCall.ID != "" || index < 0 {
+				index = len(w.toolCalls)
+			}
+			for len(w.toolCalls) <= index {
+				w.toolCalls = append(w.toolCalls, &dto.ToolCallResponse{})
+			}
+			if toolCall.Function.Name != "" {
+				w.toolCalls[index].Function.Name = toolCall.Function.Name
+			}
+			w.toolCalls[index].Function.Arguments += toolCall.Function.Arguments
+		}
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			w.finishReason = *choice.FinishReason
+		}
+	}
+	if len(parts) == 0 {
+		return
+	}
+	w.WriteEvent("", &GeminiChatResponse{
+		Candidates: []GeminiChatCandidate{{
+			Content: GeminiChatContent{Role: "model", Parts: parts},
+		}},
+	})
+}
+
+// Finish 写出转换后的结果，流式响应补发包含工具调用、结束原因与用量的最后一个事件
+func (w *GeminiConvertWriter) Finish(usage *dto.Usage) {
+	if w.Stream {
+		parts := make([]GeminiPart, 0, len(w.toolCalls))
+		for _, toolCall := range w.toolCalls {
+			if toolCall.Function.Name != "" {
+				parts = append(parts, toolCallOpenAI2Gemini(toolCall.Function.Name, toolCall.Function.Arguments))
+			}
+		}
+		finishReason := finishReasonOpenAI2Gemini(w.finishReason)
+		w.WriteEvent("", &GeminiChatResponse{
+			Candidates: []GeminiChatCandidate{{
+				Content:      GeminiChatContent{Role: "model", Parts: parts},
+				FinishReason: &finishReason,
+			}},
+			UsageMetadata: usageOpenAI2Gemini(usage),
+		})
+		return
+	}
+
+	body := w.Body()
+	var openAIResponse dto.OpenAITextResponse
+	if w.Status() == http.StatusOK && json.Unmarshal(body, &openAIResponse) == nil {
+		if data, err := json.Marshal(ResponseOpenAI2Gemini(&openAIResponse, usage)); err == nil {
+			body = data
+		}
+	}
+	w.WriteBody(body)
+}
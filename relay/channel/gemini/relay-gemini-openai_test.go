@@ -0,0 +1,139 @@
+package gemini
+
+import (
+	"encoding/json"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGeminiToOpenAIRequest 测试 Gemini 请求的生成配置、工具、系统指令与多轮内容转换
+func TestGeminiToOpenAIRequest(t *testing.T) {
+	var geminiRequest GeminiChatRequest
+	err := json.Unmarshal([]byte(`{
+		"systemInstruction": {"parts": [{"text": "be brief"}, {"text": "use celsius"}]},
+		"generationConfig": {
+			"temperature": 0.2,
+			"maxOutputTokens": 128,
+			"stopSequences": ["END"],
+			"responseMimeType": "application/json",
+			"responseSchema": {"type": "OBJECT", "properties": {"city": {"type": "STRING"}}}
+		},
+		"tools": [{"functionDeclarations": [{"name": "get_weather", "parameters": {"type": "OBJECT"}}]}],
+		"contents": [
+			{"role": "user", "parts": [{"text": "weather?"}, {"inlineData": {"mimeType": "image/png", "data": "aGk="}}]},
+			{"role": "model", "parts": [
+				{"text": "thinking", "thought": true},
+				{"functionCall": {"name": "get_weather", "args": {"city": "paris"}}},
+				{"functionCall": {"name": "get_weather", "args": {"city": "rome"}}}
+			]},
+			{"role": "user", "parts": [
+				{"functionResponse": {"name": "get_weather", "response": {"temp": 20}}},
+				{"functionResponse": {"name": "get_weather", "response": {"temp": 25}}}
+			]},
+			{"role": "model", "parts": [{"text": "It is warm."}]}
+		]
+	}`), &geminiRequest)
+	assert.NoError(t, err)
+
+	info := &relaycommon.RelayInfo{UpstreamModelName: "gpt-4o", IsStream: true}
+	openAIRequest, err := GeminiToOpenAIRequest(&geminiRequest, info)
+	assert.NoError(t, err)
+	assert.Equal(t, "gpt-4o", openAIRequest.Model)
+	assert.True(t, openAIRequest.Stream)
+	assert.Equal(t, 0.2, *openAIRequest.Temperature)
+	assert.Equal(t, uint(128), openAIRequest.MaxTokens)
+	assert.Equal(t, "END", openAIRequest.Stop)
+
+	// responseSchema 优先于 responseMimeType，类型名转换为小写
+	assert.Equal(t, "json_schema", openAIRequest.ResponseFormat.Type)
+	assert.Equal(t, map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"city": map[string]any{"type": "string"}},
+	}, openAIRequest.ResponseFormat.JsonSchema.Schema)
+
+	assert.Len(t, openAIRequest.Tools, 1)
+	assert.Equal(t, "get_weather", openAIRequest.Tools[0].Function.Name)
+	assert.Equal(t, map[string]any{"type": "object"}, openAIRequest.Tools[0].Function.Parameters)
+
+	messages := openAIRequest.Messages
+	assert.Len(t, messages, 6)
+	assert.Equal(t, "system", messages[0].Role)
+	assert.Equal(t, "be brief\nuse celsius", messages[0].StringContent())
+
+	assert.Equal(t, "user", messages[1].Role)
+	contents := messages[1].ParseContent()
+	assert.Len(t, contents, 2)
+	assert.Equal(t, dto.ContentTypeImageURL, contents[1].Type)
+	assert.Equal(t, dto.MessageImageUrl{Url: "data:image/png;base64,aGk=", Detail: "auto"}, contents[1].ImageUrl)
+
+	// 思考内容不回传，函数调用的结果按函数名依次匹配调用 id
+	assert.Equal(t, "assistant", messages[2].Role)
+	toolCalls := messages[2].ParseToolCalls()
+	assert.Len(t, toolCalls, 2)
+	assert.JSONEq(t, `{"city":"paris"}`, toolCalls[0].Function.Arguments)
+	assert.Equal(t, "tool", messages[3].Role)
+	assert.Equal(t, toolCalls[0].ID, messages[3].ToolCallId)
+	assert.JSONEq(t, `{"temp":20}`, messages[3].StringContent())
+	assert.Equal(t, toolCalls[1].ID, messages[4].ToolCallId)
+
+	assert.Equal(t, "assistant", messages[5].Role)
+	assert.Equal(t, "It is warm.", messages[5].StringContent())
+}
+
+// TestGeminiToOpenAIRequestJsonMimeType 测试只设置 responseMimeType 时转换为 json_object
+func TestGeminiToOpenAIRequestJsonMimeType(t *testing.T) {
+	geminiRequest := GeminiChatRequest{
+		Contents:         []GeminiChatContent{{Role: "user", Parts: []GeminiPart{{Text: "hi"}}}},
+		GenerationConfig: GeminiChatGenerationConfig{ResponseMimeType: "application/json", StopSequences: []string{"a", "b"}},
+	}
+	openAIRequest, err := GeminiToOpenAIRequest(&geminiRequest, &relaycommon.RelayInfo{UpstreamModelName: "gpt-4o"})
+	assert.NoError(t, err)
+	assert.Equal(t, "json_object", openAIRequest.ResponseFormat.Type)
+	assert.Equal(t, []string{"a", "b"}, openAIRequest.Stop)
+	assert.Len(t, openAIRequest.Messages, 1)
+	assert.Equal(t, "hi", openAIRequest.Messages[0].StringContent())
+}
+
+// TestResponseOpenAI2Gemini 测试 OpenAI 响应的思考内容、文本、工具调用、结束原因与用量转换
+func TestResponseOpenAI2Gemini(t *testing.T) {
+	var openAIResponse dto.OpenAITextResponse
+	err := json.Unmarshal([]byte(`{
+		"id": "chatcmpl-1",
+		"choices": [
+			{"index": 0, "finish_reason": "tool_calls", "message": {
+				"role": "assistant",
+				"reasoning_content": "let me check",
+				"content": "checking",
+				"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"paris\"}"}}]
+			}},
+			{"index": 1, "finish_reason": "length", "message": {"role": "assistant", "content": "partial"}}
+		]
+	}`), &openAIResponse)
+	assert.NoError(t, err)
+
+	usage := &dto.Usage{PromptTokens: 10, CompletionTokens: 8, TotalTokens: 18}
+	usage.CompletionTokenDetails.ReasoningTokens = 3
+	response := ResponseOpenAI2Gemini(&openAIResponse, usage)
+
+	assert.Len(t, response.Candidates, 2)
+	first := response.Candidates[0]
+	assert.Equal(t, "model", first.Content.Role)
+	assert.Equal(t, "STOP", *first.FinishReason)
+	assert.Len(t, first.Content.Parts, 3)
+	assert.True(t, first.Content.Parts[0].Thought)
+	assert.Equal(t, "let me check", first.Content.Parts[0].Text)
+	assert.Equal(t, "checking", first.Content.Parts[1].Text)
+	assert.Equal(t, "get_weather", first.Content.Parts[2].FunctionCall.FunctionName)
+	assert.Equal(t, map[string]any{"city": "paris"}, first.Content.Parts[2].FunctionCall.Arguments)
+
+	assert.Equal(t, int64(1), response.Candidates[1].Index)
+	assert.Equal(t, "MAX_TOKENS", *response.Candidates[1].FinishReason)
+
+	assert.Equal(t, 10, response.UsageMetadata.PromptTokenCount)
+	assert.Equal(t, 5, response.UsageMetadata.CandidatesTokenCount)
+	assert.Equal(t, 3, response.UsageMetadata.ThoughtsTokenCount)
+	assert.Equal(t, 18, response.UsageMetadata.TotalTokenCount)
+}
@@ -1,6 +1,9 @@
 package ollama
 
-import "one-api/dto"
+import (
+	"encoding/json"
+	"one-api/dto"
+)
 
 type OllamaRequest struct {
 	Model            string                `json:"model,omitempty"`
@@ -30,6 +33,7 @@ type Options struct {
 	PresencePenalty  float64  `json:"presence_penalty,omitempty"`
 	NumPredict       int      `json:"num_predict,omitempty"`
 	NumCtx           int      `json:"num_ctx,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
 }
 
 type OllamaEmbeddingRequest struct {
@@ -43,3 +47,95 @@ type OllamaEmbeddingResponse struct {
 	Model     string      `json:"model"`
 	Embedding [][]float64 `json:"embeddings,omitempty"`
 }
+
+// 以下为网关入站的 Ollama API 格式，见 https://github.com/ollama/ollama/blob/main/docs/api.md
+
+type OllamaToolCallFunction struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type OllamaToolCall struct {
+	Function OllamaToolCallFunction `json:"function"`
+}
+
+type OllamaChatMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []OllamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type OllamaChatRequest struct {
+	Model    string                `json:"model"`
+	Messages []OllamaChatMessage   `json:"messages"`
+	Tools    []dto.ToolCallRequest `json:"tools,omitempty"`
+	Format   json.RawMessage       `json:"format,omitempty"`
+	Options  *Options              `json:"options,omitempty"`
+	Stream   *bool                 `json:"stream,omitempty"`
+	Think    *bool                 `json:"think,omitempty"`
+}
+
+type OllamaGenerateRequest struct {
+	Model   string          `json:"model"`
+	Prompt  string          `json:"prompt"`
+	Suffix  string          `json:"suffix,omitempty"`
+	System  string          `json:"system,omitempty"`
+	Images  []string        `json:"images,omitempty"`
+	Format  json.RawMessage `json:"format,omitempty"`
+	Options *Options        `json:"options,omitempty"`
+	Stream  *bool           `json:"stream,omitempty"`
+	Think   *bool           `json:"think,omitempty"`
+}
+
+type OllamaEmbedRequest struct {
+	Model      string   `json:"model"`
+	Input      any      `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+	Options    *Options `json:"options,omitempty"`
+}
+
+type OllamaChatResponse struct {
+	Model              string             `json:"model"`
+	CreatedAt          string             `json:"created_at"`
+	Message            *OllamaChatMessage `json:"message,omitempty"`
+	Response           *string            `json:"response,omitempty"`
+	Thinking           string             `json:"thinking,omitempty"`
+	Done               bool               `json:"done"`
+	DoneReason         string             `json:"done_reason,omitempty"`
+	TotalDuration      int64              `json:"total_duration,omitempty"`
+	PromptEvalCount    int                `json:"prompt_eval_count,omitempty"`
+	EvalCount          int                `json:"eval_count,omitempty"`
+	EvalDuration       int64              `json:"eval_duration,omitempty"`
+	PromptEvalDuration int64              `json:"prompt_eval_duration,omitempty"`
+}
+
+type OllamaEmbedResponse struct {
+	Model           string      `json:"model"`
+	Embeddings      [][]float64 `json:"embeddings"`
+	TotalDuration   int64       `json:"total_duration,omitempty"`
+	PromptEvalCount int         `json:"prompt_eval_count,omitempty"`
+}
+
+type OllamaModelDetails struct {
+	Format            string   `json:"format"`
+	Family            string   `json:"family"`
+	Families          []string `json:"families"`
+	ParameterSize     string   `json:"parameter_size"`
+	QuantizationLevel string   `json:"quantization_level"`
+}
+
+type OllamaModel struct {
+	Name       string             `json:"name"`
+	Model      string             `json:"model"`
+	ModifiedAt string             `json:"modified_at"`
+	Size       int64              `json:"size"`
+	Digest     string             `json:"digest"`
+	Details    OllamaModelDetails `json:"details"`
+}
+
+type OllamaTagsResponse struct {
+	Models []OllamaModel `json:"models"`
+}
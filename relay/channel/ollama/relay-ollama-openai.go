@@ -0,0 +1,452 @@
+package ollama
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 网关入站支持的 Ollama API
+const (
+	OllamaApiChat     = "chat"
+	OllamaApiGenerate = "generate"
+	OllamaApiEmbed    = "embed"
+)
+
+// isOllamaStream Ollama 未指定 stream 时默认流式返回
+func isOllamaStream(stream *bool) bool {
+	return stream == nil || *stream
+}
+
+// ollamaImagesToMediaContent Ollama 的图片为不带前缀的 base64 数据，转换为 data URI
+func ollamaImagesToMediaContent(text string, images []string) []dto.MediaContent {
+	mediaContents := make([]dto.MediaContent, 0, len(images)+1)
+	if text != "" {
+		mediaContents = append(mediaContents, dto.MediaContent{Type: dto.ContentTypeText, Text: text})
+	}
+	for _, image := range images {
+		mimeType := "image/png"
+		if _, format, _, err := service.DecodeBase64ImageData(image); err == nil && format != "" {
+			mimeType = "image/" + format
+		}
+		mediaContents = append(mediaContents, dto.MediaContent{
+			Type:     dto.ContentTypeImageURL,
+			ImageUrl: dto.MessageImageUrl{Url: fmt.Sprintf("data:%s;base64,%s", mimeType, image), Detail: "auto"},
+		})
+	}
+	return mediaContents
+}
+
+// applyOllamaOptions 将 options 与 format 转换为 OpenAI 请求参数
+func applyOllamaOptions(openAIRequest *dto.GeneralOpenAIRequest, options *Options, format json.RawMessage) error {
+	if options != nil {
+		openAIRequest.Temperature = options.Temperature
+		openAIRequest.TopP = options.TopP
+		openAIRequest.TopK = options.TopK
+		openAIRequest.Seed = float64(options.Seed)
+		openAIRequest.FrequencyPenalty = options.FrequencyPenalty
+		openAIRequest.PresencePenalty = options.PresencePenalty
+		if options.NumPredict > 0 {
+			openAIRequest.MaxTokens = uint(options.NumPredict)
+		}
+		if len(options.Stop) > 0 {
+			openAIRequest.Stop = options.Stop
+		}
+	}
+	if len(format) == 0 || string(format) == "null" || string(format) == `""` {
+		return nil
+	}
+	var formatType string
+	if json.Unmarshal(format, &formatType) == nil {
+		if formatType != "json" {
+			return fmt.Errorf("unsupported format: %s", formatType)
+		}
+		openAIRequest.ResponseFormat = &dto.ResponseFormat{Type: "json_object"}
+		return nil
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(format, &schema); err != nil {
+		return errors.New("format must be \"json\" or a JSON schema")
+	}
+	openAIRequest.ResponseFormat = &dto.ResponseFormat{
+		Type:       "json_schema",
+		JsonSchema: &dto.FormatJsonSchema{Name: "response", Schema: schema},
+	}
+	return nil
+}
+
+func newOllamaOpenAIRequest(model string, stream bool) *dto.GeneralOpenAIRequest {
+	openAIRequest := &dto.GeneralOpenAIRequest{
+		Model:  model,
+		Stream: stream,
+	}
+	if stream {
+		// 结束时需要用量信息填充 prompt_eval_count 与 eval_count
+		openAIRequest.StreamOptions = &dto.StreamOptions{IncludeUsage: true}
+	}
+	return openAIRequest
+}
+
+// OllamaChatToOpenAIRequest 将 /api/chat 请求转换为 OpenAI 请求，tool 消息按 tool_name 依次匹配之前的工具调用
+func OllamaChatToOpenAIRequest(request *OllamaChatRequest) (*dto.GeneralOpenAIRequest, error) {
+	if request.Model == "" {
+		return nil, errors.New("model is required")
+	}
+	openAIRequest := newOllamaOpenAIRequest(request.Model, isOllamaStream(request.Stream))
+	openAIRequest.Tools = request.Tools
+	if err := applyOllamaOptions(openAIRequest, request.Options, request.Format); err != nil {
+		return nil, err
+	}
+
+	type pendingCall struct {
+		name string
+		id   string
+	}
+	var pendingCalls []pendingCall
+	messages := make([]dto.Message, 0, len(request.Messages))
+	for _, ollamaMessage := range request.Messages {
+		message := dto.Message{Role: ollamaMessage.Role}
+		switch ollamaMessage.Role {
+		case "assistant":
+			toolCalls := make([]dto.ToolCallRequest, 0, len(ollamaMessage.ToolCalls))
+			for _, toolCall := range ollamaMessage.ToolCalls {
+				id := fmt.Sprintf("call_%s", common.GetUUID())
+				pendingCalls = append(pendingCalls, pendingCall{name: toolCall.Function.Name, id: id})
+				arguments := "{}"
+				if len(toolCall.Function.Arguments) > 0 {
+					arguments = string(toolCall.Function.Arguments)
+				}
+				toolCalls = append(toolCalls, dto.ToolCallRequest{
+					ID:       id,
+					Type:     "function",
+					Function: dto.FunctionRequest{Name: toolCall.Function.Name, Arguments: arguments},
+				})
+			}
+			if len(toolCalls) > 0 {
+				message.SetToolCalls(toolCalls)
+			}
+			message.SetStringContent(ollamaMessage.Content)
+		case "tool":
+			index := -1
+			for i, call := range pendingCalls {
+				if ollamaMessage.ToolName == "" || call.name == ollamaMessage.ToolName {
+					index = i
+					break
+				}
+			}
+			if index >= 0 {
+				message.ToolCallId = pendingCalls[index].id
+				pendingCalls = append(pendingCalls[:index], pendingCalls[index+1:]...)
+			} else {
+				message.ToolCallId = fmt.Sprintf("call_%s", common.GetUUID())
+			}
+			if ollamaMessage.ToolName != "" {
+				name := ollamaMessage.ToolName
+				message.Name = &name
+			}
+			message.SetStringContent(ollamaMessage.Content)
+		default:
+			if len(ollamaMessage.Images) > 0 {
+				message.SetMediaContent(ollamaImagesToMediaContent(ollamaMessage.Content, ollamaMessage.Images))
+			} else {
+				message.SetStringContent(ollamaMessage.Content)
+			}
+		}
+		messages = append(messages, message)
+	}
+	if len(messages) == 0 {
+		return nil, errors.New("messages is required")
+	}
+	openAIRequest.Messages = messages
+	return openAIRequest, nil
+}
+
+// OllamaGenerateToOpenAIRequest 将 /api/generate 请求转换为 OpenAI Chat Completions 请求，system 转换为系统消息
+func OllamaGenerateToOpenAIRequest(request *OllamaGenerateRequest) (*dto.GeneralOpenAIRequest, error) {
+	if request.Model == "" {
+		return nil, errors.New("model is required")
+	}
+	if request.Prompt == "" && len(request.Images) == 0 {
+		return nil, errors.New("prompt is required")
+	}
+	openAIRequest := newOllamaOpenAIRequest(request.Model, isOllamaStream(request.Stream))
+	if err := applyOllamaOptions(openAIRequest, request.Options, request.Format); err != nil {
+		return nil, err
+	}
+	if request.Suffix != "" {
+		openAIRequest.Suffix = request.Suffix
+	}
+	if request.System != "" {
+		message := dto.Message{Role: "system"}
+		message.SetStringContent(request.System)
+		openAIRequest.Messages = append(openAIRequest.Messages, message)
+	}
+	message := dto.Message{Role: "user"}
+	if len(request.Images) > 0 {
+		message.SetMediaContent(ollamaImagesToMediaContent(request.Prompt, request.Images))
+	} else {
+		message.SetStringContent(request.Prompt)
+	}
+	openAIRequest.Messages = append(openAIRequest.Messages, message)
+	return openAIRequest, nil
+}
+
+// OllamaEmbedToOpenAIRequest 将 /api/embed 请求转换为 OpenAI Embeddings 请求
+func OllamaEmbedToOpenAIRequest(request *OllamaEmbedRequest) (*dto.EmbeddingRequest, error) {
+	if request.Model == "" {
+		return nil, errors.New("model is required")
+	}
+	if request.Input == nil {
+		return nil, errors.New("input is required")
+	}
+	embeddingRequest := &dto.EmbeddingRequest{
+		Model:      request.Model,
+		Input:      request.Input,
+		Dimensions: request.Dimensions,
+	}
+	if request.Options != nil {
+		embeddingRequest.Temperature = request.Options.Temperature
+		embeddingRequest.Seed = float64(request.Options.Seed)
+	}
+	return embeddingRequest, nil
+}
+
+func finishReasonOpenAI2Ollama(reason string) string {
+	if reason == constant.FinishReasonLength {
+		return "length"
+	}
+	return "stop"
+}
+
+func toolCallOpenAI2Ollama(name string, arguments string) OllamaToolCall {
+	args := json.RawMessage("{}")
+	if trimmed := bytes.TrimSpace([]byte(arguments)); len(trimmed) > 0 {
+		if json.Valid(trimmed) {
+			args = trimmed
+		} else {
+			args, _ = json.Marshal(map[string]string{"arguments": arguments})
+		}
+	}
+	return OllamaToolCall{Function: OllamaToolCallFunction{Name: name, Arguments: args}}
+}
+
+// OllamaConvertWriter 将渠道返回的 OpenAI 格式响应转换为 Ollama 格式后写给客户端，
+// 流式响应按 NDJSON 逐行输出，只保留第一个候选，工具调用在结束前整体输出；非流式响应与错误在 Finish 时转换
+type OllamaConvertWriter struct {
+	*service.OpenAIConvertWriter
+	api          string
+	model        string
+	startTime    time.Time
+	toolCalls    []*dto.ToolCallResponse
+	finishReason string
+	usage        *dto.Usage
+	errorBody    bytes.Buffer
+}
+
+func NewOllamaConvertWriter(writer gin.ResponseWriter, api string, model string, stream bool) *OllamaConvertWriter {
+	w := &OllamaConvertWriter{
+		OpenAIConvertWriter: service.NewOpenAIConvertWriter(writer, stream && api != OllamaApiEmbed),
+		api:                 api,
+		model:               model,
+		startTime:           time.Now(),
+	}
+	w.OnStreamResponse = w.handleStreamResponse
+	w.DropKeepAlive = true
+	return w
+}
+
+// Write 网关返回的错误响应单独缓存，在 Finish 时转换为 Ollama 的错误格式
+func (w *OllamaConvertWriter) Write(data []byte) (int, error) {
+	if w.Status() != http.StatusOK {
+		return w.errorBody.Write(data)
+	}
+	return w.OpenAIConvertWriter.Write(data)
+}
+
+func (w *OllamaConvertWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush 渠道设置的是 SSE 头部，写出头部前改为 NDJSON
+func (w *OllamaConvertWriter) Flush() {
+	w.setStreamHeader()
+	w.OpenAIConvertWriter.Flush()
+}
+
+func (w *OllamaConvertWriter) setStreamHeader() {
+	if w.Stream && !w.Written() {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+}
+
+func (w *OllamaConvertWriter) newResponse(done bool) *OllamaChatResponse {
+	return &OllamaChatResponse{
+		Model:     w.model,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		Done:      done,
+	}
+}
+
+// setContent chat 接口写入 message，generate 接口写入 response 与 thinking
+func (w *OllamaConvertWriter) setContent(response *OllamaChatResponse, content string, thinking string, toolCalls []OllamaToolCall) {
+	if w.api == OllamaApiChat {
+		response.Message = &OllamaChatMessage{Role: "assistant", Content: content, Thinking: thinking, ToolCalls: toolCalls}
+		return
+	}
+	response.Response = &content
+	response.Thinking = thinking
+}
+
+func (w *OllamaConvertWriter) setDone(response *OllamaChatResponse, finishReason string, usage *dto.Usage) {
+	response.Done = true
+	response.DoneReason = finishReasonOpenAI2Ollama(finishReason)
+	response.TotalDuration = time.Since(w.startTime).Nanoseconds()
+	if usage != nil {
+		response.PromptEvalCount = usage.PromptTokens
+		response.EvalCount = usage.CompletionTokens
+	}
+}
+
+func (w *OllamaConvertWriter) writeLine(object any) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		common.SysError("error marshalling ollama response: " + err.Error())
+		return
+	}
+	w.setStreamHeader()
+	_, _ = w.ResponseWriter.Write(append(data, '\n'))
+	w.ResponseWriter.Flush()
+}
+
+// handleStreamResponse 文本与思考内容立即输出，工具调用参数片段累积到结束时输出
+func (w *OllamaConvertWriter) handleStreamResponse(streamResponse *dto.ChatCompletionsStreamResponse) {
+	if streamResponse.Usage != nil {
+		w.usage = streamResponse.Usage
+	}
+	var content, thinking string
+	for _, choice := range streamResponse.Choices {
+		if choice.Index != 0 {
+			continue
+		}
+		thinking += choice.Delta.GetReasoningContent()
+		content += choice.Delta.GetContentString()
+		for _, toolCall := range choice.Delta.ToolCalls {
+			index := len(w.toolCalls) - 1
+			if toolCall.Index != nil {
+				index = *toolCall.Index
+			} else if toolCall.ID != "" || index < 0 {
+				index = len(w.toolCalls)
+			}
+			for len(w.toolCalls) <= index {
+				w.toolCalls = append(w.toolCalls, &dto.ToolCallResponse{})
+			}
+			if toolCall.Function.Name != "" {
+				w.toolCalls[index].Function.Name = toolCall.Function.Name
+			}
+			w.toolCalls[index].Function.Arguments += toolCall.Function.Arguments
+		}
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			w.finishReason = *choice.FinishReason
+		}
+	}
+	if content == "" && thinking == "" {
+		return
+	}
+	response := w.newResponse(false)
+	w.setContent(response, content, thinking, nil)
+	w.writeLine(response)
+}
+
+// writeError 将 OpenAI 格式的错误转换为 {"error": "..."}
+func (w *OllamaConvertWriter) writeError(body []byte) {
+	var errorResponse struct {
+		Error dto.OpenAIError `json:"error"`
+	}
+	message := string(body)
+	if json.Unmarshal(body, &errorResponse) == nil && errorResponse.Error.Message != "" {
+		message = errorResponse.Error.Message
+	}
+	data, _ := json.Marshal(map[string]string{"error": message})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Del("Content-Length")
+	_, _ = w.ResponseWriter.Write(data)
+}
+
+// Finish 写出转换后的结果，流式响应补发工具调用与包含结束原因、用量的最后一行
+func (w *OllamaConvertWriter) Finish() {
+	if w.Status() != http.StatusOK {
+		body := w.errorBody.Bytes()
+		if len(body) == 0 {
+			body = w.Body()
+		}
+		w.writeError(body)
+		return
+	}
+	if w.Stream {
+		if len(w.toolCalls) > 0 {
+			toolCalls := make([]OllamaToolCall, 0, len(w.toolCalls))
+			for _, toolCall := range w.toolCalls {
+				if toolCall.Function.Name != "" {
+					toolCalls = append(toolCalls, toolCallOpenAI2Ollama(toolCall.Function.Name, toolCall.Function.Arguments))
+				}
+			}
+			response := w.newResponse(false)
+			w.setContent(response, "", "", toolCalls)
+			w.writeLine(response)
+		}
+		response := w.newResponse(true)
+		w.setContent(response, "", "", nil)
+		w.setDone(response, w.finishReason, w.usage)
+		w.writeLine(response)
+		return
+	}
+
+	body := w.Body()
+	if w.api == OllamaApiEmbed {
+		var embeddingResponse dto.OpenAIEmbeddingResponse
+		if json.Unmarshal(body, &embeddingResponse) == nil {
+			response := OllamaEmbedResponse{
+				Model:           w.model,
+				Embeddings:      make([][]float64, 0, len(embeddingResponse.Data)),
+				TotalDuration:   time.Since(w.startTime).Nanoseconds(),
+				PromptEvalCount: embeddingResponse.Usage.PromptTokens,
+			}
+			for _, item := range embeddingResponse.Data {
+				response.Embeddings = append(response.Embeddings, item.Embedding)
+			}
+			if data, err := json.Marshal(response); err == nil {
+				body = data
+			}
+		}
+		w.WriteBody(body)
+		return
+	}
+	var openAIResponse dto.OpenAITextResponse
+	if json.Unmarshal(body, &openAIResponse) == nil && len(openAIResponse.Choices) > 0 {
+		choice := openAIResponse.Choices[0]
+		thinking := choice.Message.ReasoningContent
+		if thinking == "" {
+			thinking = choice.Message.Reasoning
+		}
+		var toolCalls []OllamaToolCall
+		for _, toolCall := range choice.Message.ParseToolCalls() {
+			toolCalls = append(toolCalls, toolCallOpenAI2Ollama(toolCall.Function.Name, toolCall.Function.Arguments))
+		}
+		response := w.newResponse(true)
+		w.setContent(response, choice.Message.StringContent(), thinking, toolCalls)
+		w.setDone(response, choice.FinishReason, &openAIResponse.Usage)
+		if data, err := json.Marshal(response); err == nil {
+			body = data
+		}
+	}
+	w.WriteBody(body)
+}
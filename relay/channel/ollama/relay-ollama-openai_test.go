@@ -0,0 +1,185 @@
+package ollama
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"one-api/dto"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOllamaChatToOpenAIRequest 测试 Ollama chat 请求的选项、格式、图片与工具消息转换
+func TestOllamaChatToOpenAIRequest(t *testing.T) {
+	var request OllamaChatRequest
+	err := json.Unmarshal([]byte(`{
+		"model": "llama3",
+		"options": {"temperature": 0.3, "num_predict": 64, "stop": ["END"]},
+		"format": {"type": "object"},
+		"messages": [
+			{"role": "system", "content": "be brief"},
+			{"role": "user", "content": "what is this?", "images": ["aGk="]},
+			{"role": "assistant", "content": "", "tool_calls": [
+				{"function": {"name": "get_weather", "arguments": {"city": "paris"}}},
+				{"function": {"name": "get_time", "arguments": {}}}
+			]},
+			{"role": "tool", "tool_name": "get_time", "content": "noon"},
+			{"role": "tool", "tool_name": "get_weather", "content": "20C"}
+		]
+	}`), &request)
+	assert.NoError(t, err)
+
+	openAIRequest, err := OllamaChatToOpenAIRequest(&request)
+	assert.NoError(t, err)
+	assert.Equal(t, "llama3", openAIRequest.Model)
+	// 未指定 stream 时默认流式，并请求用量
+	assert.True(t, openAIRequest.Stream)
+	assert.True(t, openAIRequest.StreamOptions.IncludeUsage)
+	assert.Equal(t, 0.3, *openAIRequest.Temperature)
+	assert.Equal(t, uint(64), openAIRequest.MaxTokens)
+	assert.Equal(t, []string{"END"}, openAIRequest.Stop)
+	assert.Equal(t, "json_schema", openAIRequest.ResponseFormat.Type)
+
+	messages := openAIRequest.Messages
+	assert.Len(t, messages, 5)
+	assert.Equal(t, "be brief", messages[0].StringContent())
+
+	contents := messages[1].ParseContent()
+	assert.Len(t, contents, 2)
+	assert.Equal(t, dto.ContentTypeText, contents[0].Type)
+	assert.Equal(t, dto.ContentTypeImageURL, contents[1].Type)
+
+	toolCalls := messages[2].ParseToolCalls()
+	assert.Len(t, toolCalls, 2)
+	assert.JSONEq(t, `{"city":"paris"}`, toolCalls[0].Function.Arguments)
+
+	// 工具结果按 tool_name 匹配对应的调用 id
+	assert.Equal(t, toolCalls[1].ID, messages[3].ToolCallId)
+	assert.Equal(t, "get_time", *messages[3].Name)
+	assert.Equal(t, toolCalls[0].ID, messages[4].ToolCallId)
+	assert.Equal(t, "20C", messages[4].StringContent())
+}
+
+// TestOllamaChatToOpenAIRequestInvalid 测试缺少模型、消息或格式不支持时返回错误
+func TestOllamaChatToOpenAIRequestInvalid(t *testing.T) {
+	_, err := OllamaChatToOpenAIRequest(&OllamaChatRequest{Messages: []OllamaChatMessage{{Role: "user", Content: "hi"}}})
+	assert.Error(t, err)
+
+	_, err = OllamaChatToOpenAIRequest(&OllamaChatRequest{Model: "llama3"})
+	assert.Error(t, err)
+
+	_, err = OllamaChatToOpenAIRequest(&OllamaChatRequest{
+		Model:    "llama3",
+		Format:   json.RawMessage(`"yaml"`),
+		Messages: []OllamaChatMessage{{Role: "user", Content: "hi"}},
+	})
+	assert.Error(t, err)
+
+	stream := false
+	openAIRequest, err := OllamaChatToOpenAIRequest(&OllamaChatRequest{
+		Model:    "llama3",
+		Stream:   &stream,
+		Format:   json.RawMessage(`"json"`),
+		Messages: []OllamaChatMessage{{Role: "user", Content: "hi"}},
+	})
+	assert.NoError(t, err)
+	assert.False(t, openAIRequest.Stream)
+	assert.Nil(t, openAIRequest.StreamOptions)
+	assert.Equal(t, "json_object", openAIRequest.ResponseFormat.Type)
+}
+
+func newOllamaTestWriter(api string, stream bool) (*httptest.ResponseRecorder, *OllamaConvertWriter) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	return recorder, NewOllamaConvertWriter(c.Writer, api, "llama3", stream)
+}
+
+func parseOllamaLines(t *testing.T, body string) []OllamaChatResponse {
+	var responses []OllamaChatResponse
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		var response OllamaChatResponse
+		assert.NoError(t, json.Unmarshal([]byte(line), &response))
+		responses = append(responses, response)
+	}
+	return responses
+}
+
+// TestOllamaConvertWriterChatStream 测试流式响应转换为 NDJSON，保活注释被丢弃，工具调用与用量在结束时输出
+func TestOllamaConvertWriterChatStream(t *testing.T) {
+	recorder, writer := newOllamaTestWriter(OllamaApiChat, true)
+	writer.Header().Set("Content-Type", "text/event-stream")
+	chunks := []string{
+		": keep-alive",
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"reasoning_content":"hmm"}}]}`,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"content":"Hi"}}]}`,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"get_weather","arguments":"{\"city\""}}]}}]}`,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":":\"paris\"}"}}]},"finish_reason":"tool_calls"}]}`,
+		`data: {"id":"c1","choices":[],"usage":{"prompt_tokens":4,"completion_tokens":6,"total_tokens":10}}`,
+		`data: [DONE]`,
+	}
+	for _, chunk := range chunks {
+		_, _ = writer.Write([]byte(chunk + "\n\n"))
+	}
+	writer.Finish()
+
+	assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+	assert.NotContains(t, recorder.Body.String(), "keep-alive")
+	lines := parseOllamaLines(t, recorder.Body.String())
+	assert.Len(t, lines, 4)
+	assert.Equal(t, "hmm", lines[0].Message.Thinking)
+	assert.Equal(t, "Hi", lines[1].Message.Content)
+	assert.False(t, lines[1].Done)
+
+	assert.Len(t, lines[2].Message.ToolCalls, 1)
+	assert.Equal(t, "get_weather", lines[2].Message.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"city":"paris"}`, string(lines[2].Message.ToolCalls[0].Function.Arguments))
+
+	last := lines[3]
+	assert.True(t, last.Done)
+	assert.Equal(t, "stop", last.DoneReason)
+	assert.Equal(t, 4, last.PromptEvalCount)
+	assert.Equal(t, 6, last.EvalCount)
+}
+
+// TestOllamaConvertWriterGenerateNonStream 测试 generate 接口的非流式响应写入 response 字段
+func TestOllamaConvertWriterGenerateNonStream(t *testing.T) {
+	recorder, writer := newOllamaTestWriter(OllamaApiGenerate, false)
+	_, _ = writer.Write([]byte(`{"id":"c1","choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"length"}],"usage":{"prompt_tokens":2,"completion_tokens":3,"total_tokens":5}}`))
+	writer.Finish()
+
+	var response OllamaChatResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Nil(t, response.Message)
+	assert.Equal(t, "Hello", *response.Response)
+	assert.True(t, response.Done)
+	assert.Equal(t, "length", response.DoneReason)
+	assert.Equal(t, 2, response.PromptEvalCount)
+	assert.Equal(t, 3, response.EvalCount)
+}
+
+// TestOllamaConvertWriterEmbed 测试 embed 接口的响应转换
+func TestOllamaConvertWriterEmbed(t *testing.T) {
+	recorder, writer := newOllamaTestWriter(OllamaApiEmbed, true)
+	_, _ = writer.Write([]byte(`{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.1,0.2]}],"model":"m","usage":{"prompt_tokens":3,"total_tokens":3}}`))
+	writer.Finish()
+
+	var response OllamaEmbedResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, [][]float64{{0.1, 0.2}}, response.Embeddings)
+	assert.Equal(t, 3, response.PromptEvalCount)
+}
+
+// TestOllamaConvertWriterError 测试错误响应转换为 Ollama 的错误格式
+func TestOllamaConvertWriterError(t *testing.T) {
+	recorder, writer := newOllamaTestWriter(OllamaApiChat, true)
+	writer.WriteHeader(http.StatusBadRequest)
+	_, _ = writer.Write([]byte(`{"error":{"message":"model not found","type":"invalid_request_error"}}`))
+	writer.Finish()
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.JSONEq(t, `{"error":"model not found"}`, recorder.Body.String())
+}
@@ -127,6 +127,9 @@ func ollamaEmbeddingHandler(c *gin.Context, resp *http.Response, promptTokens in
 	// For example, Postman will report error, and we cannot check the response at all.
 	// Copy headers
 	for k, v := range resp.Header {
+		if service.IsGatewayRateLimitHeader(c, k) {
+			continue
+		}
 		// 删除任何现有的相同头部，以防止重复添加头部
 		c.Writer.Header().Del(k)
 		for _, vv := range v {
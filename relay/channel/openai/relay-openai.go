@@ -261,6 +261,9 @@ func OpenaiHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayI
 	// So the httpClient will be confused by the response.
 	// For example, Postman will report error, and we cannot check the response at all.
 	for k, v := range resp.Header {
+		if service.IsGatewayRateLimitHeader(c, k) {
+			continue
+		}
 		c.Writer.Header().Set(k, v[0])
 	}
 	c.Writer.WriteHeader(resp.StatusCode)
@@ -285,6 +288,9 @@ func OpenaiTTSHandler(c *gin.Context, resp *http.Response, info *relaycommon.Rel
 	usage.PromptTokens = info.PromptTokens
 	usage.TotalTokens = info.PromptTokens
 	for k, v := range resp.Header {
+		if service.IsGatewayRateLimitHeader(c, k) {
+			continue
+		}
 		c.Writer.Header().Set(k, v[0])
 	}
 	c.Writer.WriteHeader(resp.StatusCode)
@@ -317,6 +323,9 @@ func OpenaiSTTHandler(c *gin.Context, resp *http.Response, info *relaycommon.Rel
 	// So the httpClient will be confused by the response.
 	// For example, Postman will report error, and we cannot check the response at all.
 	for k, v := range resp.Header {
+		if service.IsGatewayRateLimitHeader(c, k) {
+			continue
+		}
 		c.Writer.Header().Set(k, v[0])
 	}
 	c.Writer.WriteHeader(resp.StatusCode)
@@ -473,7 +482,7 @@ func OpenaiRealtimeHandler(c *gin.Context, info *relaycommon.RelayInfo) (*dto.Op
 					close(targetClosed)
 					return
 				}
-				info.SetFirstResponseTime()
+				helper.SetFirstResponseTime(c, info)
 				realtimeEvent := &dto.RealtimeEvent{}
 				err = json.Unmarshal(message, realtimeEvent)
 				if err != nil {
@@ -616,6 +625,9 @@ func OpenaiHandlerWithUsage(c *gin.Context, resp *http.Response, info *relaycomm
 	// So the httpClient will be confused by the response.
 	// For example, Postman will report error, and we cannot check the response at all.
 	for k, v := range resp.Header {
+		if service.IsGatewayRateLimitHeader(c, k) {
+			continue
+		}
 		c.Writer.Header().Set(k, v[0])
 	}
 	// reset content length
@@ -103,6 +103,9 @@ func xAIHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo
 	resp.Body = io.NopCloser(bytes.NewBuffer(encodeJson))
 
 	for k, v := range resp.Header {
+		if service.IsGatewayRateLimitHeader(c, k) {
+			continue
+		}
 		c.Writer.Header().Set(k, v[0])
 	}
 	c.Writer.WriteHeader(resp.StatusCode)
@@ -6,9 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 	"io"
 	"net/http"
 	"one-api/common"
+	"one-api/common/tracing"
 	"one-api/dto"
 	relaycommon "one-api/relay/common"
 	"one-api/relay/helper"
@@ -51,7 +53,10 @@ func ClaudeHelper(c *gin.Context) (claudeError *dto.ClaudeErrorWithStatusCode) {
 		return service.ClaudeErrorWrapperLocal(err, "model_mapped_error", http.StatusInternalServerError)
 	}
 
+	_, span := tracing.Start(c.Request.Context(), "count_prompt_tokens")
 	promptTokens, err := getClaudePromptTokens(textRequest, relayInfo)
+	span.SetAttributes(attribute.Int("usage.prompt_tokens", promptTokens))
+	span.End()
 	// count messages token error 计算promptTokens错误
 	if err != nil {
 		return service.ClaudeErrorWrapperLocal(err, "count_token_messages_failed", http.StatusInternalServerError)
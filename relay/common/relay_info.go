@@ -68,6 +68,10 @@ type RelayInfo struct {
 	Group             string
 	UserGroup         string
 	TokenUnlimited    bool
+	TokenHasBudget    bool // 令牌设置了日/周/月预算
+	TokenModelCapped  bool // 令牌对当前模型设置了 token 数上限
+	OrgId             int  // 非 0 时从组织额度池扣费
+	ChannelKeyIndex   int  // 多密钥渠道本次使用的密钥下标
 	StartTime         time.Time
 	FirstResponseTime time.Time
 	isFirstResponse   bool
@@ -105,6 +109,9 @@ type RelayInfo struct {
 	RelayFormat          string
 	SendResponseCount    int
 	ChannelCreateTime    int64
+	BatchId              string
+	RateLimitTokens      int   // 本次请求在 TPM 限流中预留的 token 数
+	RateLimitMinute      int64 // 预留所在的分钟
 	ThinkingContentInfo
 	*ClaudeConvertInfo
 	*RerankerInfo
@@ -126,6 +133,11 @@ var streamSupportedChannels = map[int]bool{
 	common.ChannelTypeBaiduV2:    true,
 }
 
+// ChannelSupportsStreamOptions 渠道类型是否支持 stream_options
+func ChannelSupportsStreamOptions(channelType int) bool {
+	return streamSupportedChannels[channelType]
+}
+
 func GenRelayInfoWs(c *gin.Context, ws *websocket.Conn) *RelayInfo {
 	info := GenRelayInfo(c)
 	info.ClientWs = ws
@@ -242,6 +254,10 @@ func GenRelayInfo(c *gin.Context) *RelayInfo {
 		Group:             group,
 		UserGroup:         c.GetString(constant.ContextKeyUserGroup),
 		TokenUnlimited:    tokenUnlimited,
+		TokenHasBudget:    c.GetBool("token_budget_enabled"),
+		TokenModelCapped:  c.GetBool("token_model_capped"),
+		OrgId:             c.GetInt("token_org_id"),
+		ChannelKeyIndex:   c.GetInt("channel_key_index"),
 		StartTime:         startTime,
 		FirstResponseTime: startTime.Add(-time.Second),
 		OriginModelName:   c.GetString("original_model"),
@@ -261,6 +277,9 @@ func GenRelayInfo(c *gin.Context) *RelayInfo {
 			SendLastThinkingContent: false,
 		},
 	}
+	if batchId, ok := c.Request.Context().Value(constant.ContextKeyBatchId).(string); ok {
+		info.BatchId = batchId
+	}
 	if strings.HasPrefix(c.Request.URL.Path, "/pg") {
 		info.IsPlayground = true
 		info.RequestURLPath = strings.TrimPrefix(info.RequestURLPath, "/pg")
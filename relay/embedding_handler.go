@@ -70,6 +70,11 @@ func EmbeddingHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode)
 		}
 	}()
 
+	responseCacheKey := getResponseCacheKey(c, relayInfo, embeddingRequest)
+	if entry, ok := lookupResponseCache(responseCacheKey, false); ok {
+		return replayResponseCache(c, relayInfo, entry, false, preConsumedQuota, userQuota, priceData)
+	}
+
 	adaptor := GetAdaptor(relayInfo.ApiType)
 	if adaptor == nil {
 		return service.OpenAIErrorWrapperLocal(fmt.Errorf("invalid api type: %d", relayInfo.ApiType), "invalid_api_type", http.StatusBadRequest)
@@ -103,12 +108,21 @@ func EmbeddingHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode)
 		}
 	}
 
+	var captureWriter *service.ResponseCaptureWriter
+	if responseCacheKey != "" {
+		captureWriter = service.NewResponseCaptureWriter(c.Writer)
+		c.Writer = captureWriter
+	}
 	usage, openaiErr := adaptor.DoResponse(c, httpResp, relayInfo)
+	if captureWriter != nil {
+		c.Writer = captureWriter.ResponseWriter
+	}
 	if openaiErr != nil {
 		// reset status code 重置状态码
 		service.ResetStatusCode(openaiErr, statusCodeMappingStr)
 		return openaiErr
 	}
+	saveResponseCache(responseCacheKey, relayInfo, captureWriter, usage.(*dto.Usage))
 	postConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
 	return nil
 }
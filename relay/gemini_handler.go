@@ -10,6 +10,7 @@ import (
 	"one-api/dto"
 	"one-api/relay/channel/gemini"
 	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
 	"one-api/relay/helper"
 	"one-api/service"
 	"one-api/setting"
@@ -134,6 +135,14 @@ func GeminiHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode) {
 		return service.OpenAIErrorWrapperLocal(fmt.Errorf("invalid api type: %d", relayInfo.ApiType), "invalid_api_type", http.StatusBadRequest)
 	}
 
+	// 非 Gemini 渠道先转换为 OpenAI 请求，再由渠道适配器转换为上游格式
+	convertToOpenAI := gemini.ShouldConvertGeminiRequest(relayInfo)
+	if convertToOpenAI {
+		relayInfo.RelayFormat = relaycommon.RelayFormatOpenAI
+		relayInfo.RelayMode = relayconstant.RelayModeChatCompletions
+		relayInfo.RequestURLPath = "/v1/chat/completions"
+	}
+
 	adaptor.Init(relayInfo)
 
 	// Clean up empty system instruction
@@ -150,7 +159,22 @@ func GeminiHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode) {
 		}
 	}
 
-	requestBody, err := json.Marshal(req)
+	var convertedRequest any = req
+	if convertToOpenAI {
+		openAIRequest, err := gemini.GeminiToOpenAIRequest(req, relayInfo)
+		if err != nil {
+			return service.OpenAIErrorWrapperLocal(err, "convert_request_failed", http.StatusBadRequest)
+		}
+		if relayInfo.IsStream && relayInfo.SupportStreamOptions {
+			openAIRequest.StreamOptions = &dto.StreamOptions{IncludeUsage: true}
+		}
+		convertedRequest, err = adaptor.ConvertOpenAIRequest(c, relayInfo, openAIRequest)
+		if err != nil {
+			return service.OpenAIErrorWrapperLocal(err, "convert_request_failed", http.StatusInternalServerError)
+		}
+	}
+
+	requestBody, err := json.Marshal(convertedRequest)
 	if err != nil {
 		return service.OpenAIErrorWrapperLocal(err, "marshal_text_request_failed", http.StatusInternalServerError)
 	}
@@ -179,11 +203,23 @@ func GeminiHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode) {
 		}
 	}
 
+	var convertWriter *gemini.GeminiConvertWriter
+	if convertToOpenAI {
+		convertWriter = gemini.NewGeminiConvertWriter(c.Writer, relayInfo.IsStream)
+		c.Writer = convertWriter
+	}
+
 	usage, openaiErr := adaptor.DoResponse(c, resp.(*http.Response), relayInfo)
+	if convertWriter != nil {
+		c.Writer = convertWriter.ResponseWriter
+	}
 	if openaiErr != nil {
 		service.ResetStatusCode(openaiErr, statusCodeMappingStr)
 		return openaiErr
 	}
+	if convertWriter != nil {
+		convertWriter.Finish(usage.(*dto.Usage))
+	}
 
 	postConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
 	return nil
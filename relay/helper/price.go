@@ -5,6 +5,7 @@ import (
 	"one-api/common"
 	constant2 "one-api/constant"
 	relaycommon "one-api/relay/common"
+	"one-api/setting/operation_setting"
 	"one-api/setting/ratio_setting"
 
 	"github.com/gin-gonic/gin"
@@ -25,6 +26,9 @@ type PriceData struct {
 	UsePrice               bool
 	ShouldPreConsumedQuota int
 	GroupRatioInfo         GroupRatioInfo
+	// 命中响应缓存时按 ResponseCacheRatio 折算费用
+	ResponseCacheHit   bool
+	ResponseCacheRatio float64
 }
 
 func (p PriceData) ToSetting() string {
@@ -65,6 +69,10 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 	modelPrice, usePrice := ratio_setting.GetModelPrice(info.OriginModelName, false)
 
 	groupRatioInfo := HandleGroupRatio(c, info)
+	// 批处理请求按折扣倍率计费
+	if info.BatchId != "" {
+		groupRatioInfo.GroupRatio *= operation_setting.GetBatchSetting().DiscountRatio
+	}
 
 	var preConsumedQuota int
 	var modelRatio float64
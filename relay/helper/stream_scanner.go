@@ -7,6 +7,8 @@ import (
 	"io"
 	"net/http"
 	"one-api/common"
+	"one-api/common/metrics"
+	"one-api/common/tracing"
 	"one-api/constant"
 	relaycommon "one-api/relay/common"
 	"one-api/setting/operation_setting"
@@ -17,6 +19,7 @@ import (
 	"github.com/bytedance/gopkg/util/gopool"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -25,12 +28,27 @@ const (
 	DefaultPingInterval      = 10 * time.Second
 )
 
+// SetFirstResponseTime 记录首字时间，并写入上下文供指标统计首字耗时
+func SetFirstResponseTime(c *gin.Context, info *relaycommon.RelayInfo) {
+	if info.HasSendResponse() {
+		return
+	}
+	info.SetFirstResponseTime()
+	c.Set(constant.ContextKeyFirstResponseTime, info.FirstResponseTime)
+}
+
 func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo, dataHandler func(data string) bool) {
 
 	if resp == nil || dataHandler == nil {
 		return
 	}
 
+	_, span := tracing.Start(c.Request.Context(), "stream",
+		attribute.Int("channel.id", info.ChannelId),
+		attribute.String("upstream.model", info.UpstreamModelName),
+	)
+	defer span.End()
+
 	// 确保响应体总是被关闭
 	defer func() {
 		if resp.Body != nil {
@@ -103,7 +121,9 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 	if pingEnabled && pingTicker != nil {
 		wg.Add(1)
 		gopool.Go(func() {
+			metrics.StreamScannerGoroutines.Inc()
 			defer func() {
+				metrics.StreamScannerGoroutines.Dec()
 				wg.Done()
 				if r := recover(); r != nil {
 					common.LogError(c, fmt.Sprintf("ping goroutine panic: %v", r))
@@ -165,7 +185,9 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 	// Scanner goroutine with improved error handling
 	wg.Add(1)
 	common.RelayCtxGo(ctx, func() {
+		metrics.StreamScannerGoroutines.Inc()
 		defer func() {
+			metrics.StreamScannerGoroutines.Dec()
 			wg.Done()
 			if r := recover(); r != nil {
 				common.LogError(c, fmt.Sprintf("scanner goroutine panic: %v", r))
@@ -204,7 +226,7 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 			data = strings.TrimLeft(data, " ")
 			data = strings.TrimSuffix(data, "\r")
 			if !strings.HasPrefix(data, "[DONE]") {
-				info.SetFirstResponseTime()
+				SetFirstResponseTime(c, info)
 				
 				// 使用超时机制防止写操作阻塞
 				done := make(chan bool, 1)
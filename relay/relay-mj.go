@@ -186,7 +186,7 @@ func RelaySwapFace(c *gin.Context) *dto.MidjourneyResponse {
 	}
 	groupRatio := ratio_setting.GetGroupRatio(group)
 	ratio := modelPrice * groupRatio
-	userQuota, err := model.GetUserQuota(userId, false)
+	userQuota, err := service.GetBillingQuota(relayInfo)
 	if err != nil {
 		return &dto.MidjourneyResponse{
 			Code:        4,
@@ -201,6 +201,9 @@ func RelaySwapFace(c *gin.Context) *dto.MidjourneyResponse {
 			Description: "quota_not_enough",
 		}
 	}
+	if err := service.CheckTokenBudgetQuota(relayInfo, quota); err != nil {
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, err.Error())
+	}
 	requestURL := getMjRequestPath(c.Request.URL.String())
 	baseURL := c.GetString("base_url")
 	fullRequestURL := fmt.Sprintf("%s%s", baseURL, requestURL)
@@ -235,6 +238,8 @@ func RelaySwapFace(c *gin.Context) *dto.MidjourneyResponse {
 	midjResponse := &mjResp.Response
 	midjourneyTask := &model.Midjourney{
 		UserId:      userId,
+		OrgId:       relayInfo.OrgId,
+		KeyIndex:    relayInfo.ChannelKeyIndex,
 		Code:        midjResponse.Code,
 		Action:      constant.MjActionSwapFace,
 		MjId:        midjResponse.Result,
@@ -283,7 +288,7 @@ func RelayMidjourneyTaskImageSeed(c *gin.Context) *dto.MidjourneyResponse {
 		return service.MidjourneyErrorWrapper(constant.MjRequestError, "该任务所属渠道已被禁用")
 	}
 	c.Set("channel_id", originTask.ChannelId)
-	c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", channel.Key))
+	c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", channel.GetKeyByIndex(originTask.KeyIndex)))
 
 	requestURL := getMjRequestPath(c.Request.URL.String())
 	fullRequestURL := fmt.Sprintf("%s%s", channel.GetBaseURL(), requestURL)
@@ -453,7 +458,10 @@ func RelayMidjourneySubmit(c *gin.Context, relayMode int) *dto.MidjourneyRespons
 			}
 			c.Set("base_url", channel.GetBaseURL())
 			c.Set("channel_id", originTask.ChannelId)
-			c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", channel.Key))
+			c.Set("channel_multi_key", channel.IsMultiKey())
+			c.Set("channel_key_index", originTask.KeyIndex)
+			c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", channel.GetKeyByIndex(originTask.KeyIndex)))
+			relayInfo.ChannelKeyIndex = originTask.KeyIndex
 			log.Printf("检测到此操作为放大、变换、重绘，获取原channel信息: %s,%s", strconv.Itoa(originTask.ChannelId), channel.GetBaseURL())
 		}
 		midjRequest.Prompt = originTask.Prompt
@@ -492,7 +500,7 @@ func RelayMidjourneySubmit(c *gin.Context, relayMode int) *dto.MidjourneyRespons
 	}
 	groupRatio := ratio_setting.GetGroupRatio(group)
 	ratio := modelPrice * groupRatio
-	userQuota, err := model.GetUserQuota(userId, false)
+	userQuota, err := service.GetBillingQuota(relayInfo)
 	if err != nil {
 		return &dto.MidjourneyResponse{
 			Code:        4,
@@ -507,6 +515,11 @@ func RelayMidjourneySubmit(c *gin.Context, relayMode int) *dto.MidjourneyRespons
 			Description: "quota_not_enough",
 		}
 	}
+	if consumeQuota {
+		if err := service.CheckTokenBudgetQuota(relayInfo, quota); err != nil {
+			return service.MidjourneyErrorWrapper(constant.MjRequestError, err.Error())
+		}
+	}
 
 	midjResponseWithStatus, responseBody, err := service.DoMidjourneyHttpRequest(c, time.Second*60, fullRequestURL)
 	if err != nil {
@@ -544,6 +557,8 @@ func RelayMidjourneySubmit(c *gin.Context, relayMode int) *dto.MidjourneyRespons
 	// other: 提交错误，description为错误描述
 	midjourneyTask := &model.Midjourney{
 		UserId:      userId,
+		OrgId:       relayInfo.OrgId,
+		KeyIndex:    relayInfo.ChannelKeyIndex,
 		Code:        midjResponse.Code,
 		Action:      midjRequest.Action,
 		MjId:        midjResponse.Result,
@@ -9,6 +9,7 @@ import (
 	"math"
 	"net/http"
 	"one-api/common"
+	"one-api/common/tracing"
 	"one-api/constant"
 	"one-api/dto"
 	"one-api/model"
@@ -26,6 +27,7 @@ import (
 	"github.com/shopspring/decimal"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func getAndValidateTextRequest(c *gin.Context, relayInfo *relaycommon.RelayInfo) (*dto.GeneralOpenAIRequest, error) {
@@ -119,7 +121,10 @@ func TextHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode) {
 		promptTokens = value.(int)
 		relayInfo.PromptTokens = promptTokens
 	} else {
+		_, span := tracing.Start(c.Request.Context(), "count_prompt_tokens")
 		promptTokens, err = getPromptTokens(textRequest, relayInfo)
+		span.SetAttributes(attribute.Int("usage.prompt_tokens", promptTokens))
+		span.End()
 		// count messages token error 计算promptTokens错误
 		if err != nil {
 			return service.OpenAIErrorWrapper(err, "count_token_messages_failed", http.StatusInternalServerError)
@@ -164,6 +169,11 @@ func TextHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode) {
 		relayInfo.ShouldIncludeUsage = true
 	}
 
+	responseCacheKey := getResponseCacheKey(c, relayInfo, textRequest)
+	if entry, ok := lookupResponseCache(responseCacheKey, relayInfo.IsStream); ok {
+		return replayResponseCache(c, relayInfo, entry, includeUsage, preConsumedQuota, userQuota, priceData)
+	}
+
 	adaptor := GetAdaptor(relayInfo.ApiType)
 	if adaptor == nil {
 		return service.OpenAIErrorWrapperLocal(fmt.Errorf("invalid api type: %d", relayInfo.ApiType), "invalid_api_type", http.StatusBadRequest)
@@ -229,12 +239,21 @@ func TextHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode) {
 		}
 	}
 
+	var captureWriter *service.ResponseCaptureWriter
+	if responseCacheKey != "" {
+		captureWriter = service.NewResponseCaptureWriter(c.Writer)
+		c.Writer = captureWriter
+	}
 	usage, openaiErr := adaptor.DoResponse(c, httpResp, relayInfo)
+	if captureWriter != nil {
+		c.Writer = captureWriter.ResponseWriter
+	}
 	if openaiErr != nil {
 		// reset status code 重置状态码
 		service.ResetStatusCode(openaiErr, statusCodeMappingStr)
 		return openaiErr
 	}
+	saveResponseCache(responseCacheKey, relayInfo, captureWriter, usage.(*dto.Usage))
 
 	if strings.HasPrefix(relayInfo.OriginModelName, "gpt-4o-audio") {
 		service.PostAudioConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
@@ -282,8 +301,14 @@ func checkRequestSensitive(textRequest *dto.GeneralOpenAIRequest, info *relaycom
 
 // 预扣费并返回用户剩余配额
 func preConsumeQuota(c *gin.Context, preConsumedQuota int, relayInfo *relaycommon.RelayInfo) (int, int, *dto.OpenAIErrorWithStatusCode) {
-	userQuota, err := model.GetUserQuota(relayInfo.UserId, false)
+	userQuota, err := service.GetBillingQuota(relayInfo)
 	if err != nil {
+		if errors.Is(err, model.ErrOrganizationMemberQuotaExceeded) {
+			return 0, 0, service.OpenAIErrorWrapperLocal(err, "organization_member_quota_exceeded", http.StatusTooManyRequests)
+		}
+		if relayInfo.OrgId != 0 {
+			return 0, 0, service.OpenAIErrorWrapperLocal(err, "get_organization_quota_failed", http.StatusForbidden)
+		}
 		return 0, 0, service.OpenAIErrorWrapperLocal(err, "get_user_quota_failed", http.StatusInternalServerError)
 	}
 	if userQuota <= 0 {
@@ -311,20 +336,36 @@ func preConsumeQuota(c *gin.Context, preConsumedQuota int, relayInfo *relaycommo
 		}
 	}
 
-	if preConsumedQuota > 0 {
+	// 设置了预算或模型上限的令牌即使免预扣也需要检查
+	if preConsumedQuota > 0 || relayInfo.TokenHasBudget || relayInfo.TokenModelCapped {
 		err := service.PreConsumeTokenQuota(relayInfo, preConsumedQuota)
 		if err != nil {
+			var budgetErr *model.TokenBudgetExceededError
+			if errors.As(err, &budgetErr) {
+				return 0, 0, service.OpenAIErrorWrapperLocal(err, "token_budget_exceeded", http.StatusTooManyRequests)
+			}
+			var modelQuotaErr *model.TokenModelQuotaExceededError
+			if errors.As(err, &modelQuotaErr) {
+				return 0, 0, service.OpenAIErrorWrapperLocal(err, "token_model_quota_exceeded", http.StatusTooManyRequests)
+			}
 			return 0, 0, service.OpenAIErrorWrapperLocal(err, "pre_consume_token_quota_failed", http.StatusForbidden)
 		}
-		err = model.DecreaseUserQuota(relayInfo.UserId, preConsumedQuota)
+	}
+	if preConsumedQuota > 0 {
+		err = service.ConsumeBillingQuota(relayInfo, preConsumedQuota)
 		if err != nil {
 			return 0, 0, service.OpenAIErrorWrapperLocal(err, "decrease_user_quota_failed", http.StatusInternalServerError)
 		}
 	}
+	if openaiErr := service.ReserveTokenRateLimit(c, relayInfo); openaiErr != nil {
+		returnPreConsumedQuota(c, relayInfo, userQuota, preConsumedQuota)
+		return 0, 0, openaiErr
+	}
 	return preConsumedQuota, userQuota, nil
 }
 
 func returnPreConsumedQuota(c *gin.Context, relayInfo *relaycommon.RelayInfo, userQuota int, preConsumedQuota int) {
+	service.ReleaseTokenRateLimit(relayInfo)
 	if preConsumedQuota != 0 {
 		gopool.Go(func() {
 			relayInfoCopy := *relayInfo
@@ -347,6 +388,14 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 		}
 		extraContent += "（可能是请求出错）"
 	}
+	if priceData.ResponseCacheHit {
+		// 命中缓存未请求上游，不计入 TPM
+		service.ReleaseTokenRateLimit(relayInfo)
+	} else {
+		service.ReconcileTokenRateLimit(relayInfo, usage.PromptTokens+usage.CompletionTokens)
+		service.ObserveChannelLatency(ctx, relayInfo)
+	}
+	service.RecordTokenModelUsage(relayInfo, usage.PromptTokens+usage.CompletionTokens)
 	useTimeSeconds := time.Now().Unix() - relayInfo.StartTime.Unix()
 	promptTokens := usage.PromptTokens
 	cacheTokens := usage.PromptTokensDetails.CachedTokens
@@ -465,6 +514,14 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 	quotaCalculateDecimal = quotaCalculateDecimal.Add(dFileSearchQuota)
 	// 添加 audio input 独立计费
 	quotaCalculateDecimal = quotaCalculateDecimal.Add(audioInputQuota)
+	// 响应缓存命中，按命中倍率折算
+	if priceData.ResponseCacheHit {
+		quotaCalculateDecimal = quotaCalculateDecimal.Mul(decimal.NewFromFloat(priceData.ResponseCacheRatio))
+		if extraContent != "" {
+			extraContent += "，"
+		}
+		extraContent += fmt.Sprintf("响应缓存命中，命中倍率 %.2f", priceData.ResponseCacheRatio)
+	}
 
 	quota := int(quotaCalculateDecimal.Round(0).IntPart())
 	totalTokens := promptTokens + completionTokens
@@ -486,7 +543,9 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 			"tokenId %d, model %s， pre-consumed quota %d", relayInfo.UserId, relayInfo.ChannelId, relayInfo.TokenId, modelName, preConsumedQuota))
 	} else {
 		model.UpdateUserUsedQuotaAndRequestCount(relayInfo.UserId, quota)
-		model.UpdateChannelUsedQuota(relayInfo.ChannelId, quota)
+		if !priceData.ResponseCacheHit {
+			model.UpdateChannelUsedQuota(relayInfo.ChannelId, quota)
+		}
 	}
 
 	quotaDelta := quota - preConsumedQuota
@@ -540,6 +599,10 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 		other["audio_input_token_count"] = audioTokens
 		other["audio_input_price"] = audioInputPrice
 	}
+	if priceData.ResponseCacheHit {
+		other["response_cache_hit"] = true
+		other["response_cache_ratio"] = priceData.ResponseCacheRatio
+	}
 	model.RecordConsumeLog(ctx, relayInfo.UserId, relayInfo.ChannelId, promptTokens, completionTokens, logModel,
 		tokenName, quota, logContent, relayInfo.TokenId, userQuota, int(useTimeSeconds), relayInfo.IsStream, relayInfo.Group, other)
 }
@@ -53,7 +53,7 @@ func RelayTaskSubmit(c *gin.Context, relayMode int) (taskErr *dto.TaskError) {
 	// 预扣
 	groupRatio := ratio_setting.GetGroupRatio(relayInfo.Group)
 	ratio := modelPrice * groupRatio
-	userQuota, err := model.GetUserQuota(relayInfo.UserId, false)
+	userQuota, err := service.GetBillingQuota(relayInfo.RelayInfo)
 	if err != nil {
 		taskErr = service.TaskErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
 		return
@@ -63,6 +63,10 @@ func RelayTaskSubmit(c *gin.Context, relayMode int) (taskErr *dto.TaskError) {
 		taskErr = service.TaskErrorWrapperLocal(errors.New("user quota is not enough"), "quota_not_enough", http.StatusForbidden)
 		return
 	}
+	if err := service.CheckTokenBudgetQuota(relayInfo.RelayInfo, quota); err != nil {
+		taskErr = service.TaskErrorWrapperLocal(err, "token_budget_exceeded", http.StatusTooManyRequests)
+		return
+	}
 
 	if relayInfo.OriginTaskID != "" {
 		originTask, exist, err := model.GetByTaskId(relayInfo.UserId, relayInfo.OriginTaskID)
@@ -85,10 +89,13 @@ func RelayTaskSubmit(c *gin.Context, relayMode int) (taskErr *dto.TaskError) {
 			}
 			c.Set("base_url", channel.GetBaseURL())
 			c.Set("channel_id", originTask.ChannelId)
-			c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", channel.Key))
+			c.Set("channel_multi_key", channel.IsMultiKey())
+			c.Set("channel_key_index", originTask.KeyIndex)
+			c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", channel.GetKeyByIndex(originTask.KeyIndex)))
 
 			relayInfo.BaseUrl = channel.GetBaseURL()
 			relayInfo.ChannelId = originTask.ChannelId
+			relayInfo.ChannelKeyIndex = originTask.KeyIndex
 		}
 	}
 
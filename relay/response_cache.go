@@ -0,0 +1,82 @@
+package relay
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/relay/helper"
+	"one-api/service"
+	"one-api/setting/operation_setting"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getResponseCacheKey 返回请求的响应缓存键，未启用缓存或请求不适合缓存时返回空字符串
+func getResponseCacheKey(c *gin.Context, relayInfo *relaycommon.RelayInfo, request any) string {
+	switch relayInfo.RelayMode {
+	case relayconstant.RelayModeChatCompletions, relayconstant.RelayModeCompletions, relayconstant.RelayModeEmbeddings:
+	default:
+		return ""
+	}
+	if strings.HasPrefix(relayInfo.OriginModelName, "gpt-4o-audio") {
+		return ""
+	}
+	if !service.ShouldUseResponseCache(c, relayInfo) {
+		return ""
+	}
+	key, err := service.GetResponseCacheKey(relayInfo, request)
+	if err != nil {
+		common.LogError(c, "get response cache key failed: "+err.Error())
+		return ""
+	}
+	return key
+}
+
+// lookupResponseCache 查找可用于本次请求的缓存，非流式请求不能使用流式缓存
+func lookupResponseCache(key string, stream bool) (*service.ResponseCacheEntry, bool) {
+	if key == "" {
+		return nil, false
+	}
+	entry, ok := service.GetResponseCache(key)
+	if !ok || (entry.Stream && !stream) {
+		return nil, false
+	}
+	return entry, true
+}
+
+// replayResponseCache 回放缓存的响应并按缓存命中倍率计费
+func replayResponseCache(c *gin.Context, relayInfo *relaycommon.RelayInfo, entry *service.ResponseCacheEntry, includeUsage bool,
+	preConsumedQuota int, userQuota int, priceData helper.PriceData) *dto.OpenAIErrorWithStatusCode {
+	err := service.ReplayResponseCache(c, entry, relayInfo.RelayMode, relayInfo.IsStream, includeUsage)
+	if err != nil {
+		return service.OpenAIErrorWrapperLocal(err, "replay_response_cache_failed", http.StatusInternalServerError)
+	}
+	common.LogInfo(c, fmt.Sprintf("response cache hit, model %s", relayInfo.OriginModelName))
+	c.Set("response_cache_hit", true)
+	priceData.ResponseCacheHit = true
+	priceData.ResponseCacheRatio = operation_setting.GetResponseCacheSetting().HitRatio
+	usage := entry.Usage
+	postConsumeQuota(c, relayInfo, &usage, preConsumedQuota, userQuota, priceData, "")
+	return nil
+}
+
+// saveResponseCache 将捕获的成功响应写入缓存
+func saveResponseCache(key string, relayInfo *relaycommon.RelayInfo, writer *service.ResponseCaptureWriter, usage *dto.Usage) {
+	if key == "" || writer == nil || usage == nil || usage.TotalTokens == 0 {
+		return
+	}
+	body, ok := writer.Body()
+	if !ok || body == "" {
+		return
+	}
+	service.SetResponseCache(key, &service.ResponseCacheEntry{
+		Stream:    relayInfo.IsStream,
+		Body:      body,
+		Usage:     *usage,
+		CreatedAt: common.GetTimestamp(),
+	})
+}
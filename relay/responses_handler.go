@@ -8,15 +8,20 @@ import (
 	"io"
 	"net/http"
 	"one-api/common"
+	"one-api/constant"
 	"one-api/dto"
+	"one-api/model"
 	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
 	"one-api/relay/helper"
 	"one-api/service"
 	"one-api/setting"
 	"one-api/setting/model_setting"
+	"one-api/setting/operation_setting"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func getAndValidateResponsesRequest(c *gin.Context) (*dto.OpenAIResponsesRequest, error) {
@@ -46,6 +51,79 @@ func getInputTokens(req *dto.OpenAIResponsesRequest, info *relaycommon.RelayInfo
 	return inputTokens
 }
 
+// shouldEmulateResponses 只有 OpenAI 与 Azure 渠道原生支持 Responses API，其他渠道转换为 Chat Completions，
+// OpenAI 类型的渠道可通过渠道设置 responses_emulation 强制转换
+func shouldEmulateResponses(info *relaycommon.RelayInfo) bool {
+	if info.ChannelType != common.ChannelTypeOpenAI && info.ChannelType != common.ChannelTypeAzure {
+		return true
+	}
+	emulation, ok := info.ChannelSetting[constant.ChannelSettingResponsesEmulation].(bool)
+	return ok && emulation
+}
+
+// getResponsesHistory 读取 previous_response_id 对应的历史会话，网关中不存在时返回 404 错误
+func getResponsesHistory(req *dto.OpenAIResponsesRequest, info *relaycommon.RelayInfo) ([]json.RawMessage, *dto.OpenAIErrorWithStatusCode) {
+	history, err := service.GetResponsesHistory(info, req.PreviousResponseID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = fmt.Errorf("previous response %s not found", req.PreviousResponseID)
+			return nil, service.OpenAIErrorWrapperLocal(err, "previous_response_not_found", http.StatusNotFound)
+		}
+		return nil, service.OpenAIErrorWrapperLocal(err, "get_previous_response_failed", http.StatusInternalServerError)
+	}
+	return history, nil
+}
+
+// expandPreviousResponse 原生渠道上用网关保存的历史会话替换 previous_response_id，使会话不依赖上游账号，
+// 网关中没有该响应（例如原生渠道未开启保存）时保持原样交给上游处理
+func expandPreviousResponse(req *dto.OpenAIResponsesRequest, info *relaycommon.RelayInfo, inputItems []json.RawMessage) (bool, *dto.OpenAIErrorWithStatusCode) {
+	if req.PreviousResponseID == "" {
+		return false, nil
+	}
+	history, openaiErr := getResponsesHistory(req, info)
+	if openaiErr != nil {
+		if openaiErr.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, openaiErr
+	}
+	input, err := json.Marshal(append(service.ResponsesHistoryForUpstream(history), inputItems...))
+	if err != nil {
+		return false, service.OpenAIErrorWrapperLocal(err, "marshal_request_error", http.StatusInternalServerError)
+	}
+	req.Input = input
+	req.PreviousResponseID = ""
+	return true, nil
+}
+
+// getEmulatedResponsesRequest 拼接 previous_response_id 对应的历史会话与本轮输入，转换为 Chat Completions 请求
+func getEmulatedResponsesRequest(req *dto.OpenAIResponsesRequest, info *relaycommon.RelayInfo, inputItems []json.RawMessage) (*dto.GeneralOpenAIRequest, *dto.OpenAIErrorWithStatusCode) {
+	rawItems := inputItems
+	if req.PreviousResponseID != "" {
+		history, openaiErr := getResponsesHistory(req, info)
+		if openaiErr != nil {
+			return nil, openaiErr
+		}
+		rawItems = append(history, inputItems...)
+	}
+	items, err := dto.ParseResponsesInputItems(rawItems)
+	if err != nil {
+		return nil, service.OpenAIErrorWrapperLocal(err, "invalid_responses_input", http.StatusBadRequest)
+	}
+	openAIRequest, err := service.ResponsesToOpenAIRequest(req, items)
+	if err != nil {
+		return nil, service.OpenAIErrorWrapperLocal(err, "convert_request_error", http.StatusBadRequest)
+	}
+	info.RelayFormat = relaycommon.RelayFormatOpenAI
+	info.RelayMode = relayconstant.RelayModeChatCompletions
+	info.RequestURLPath = "/v1/chat/completions"
+	info.SupportStreamOptions = relaycommon.ChannelSupportsStreamOptions(info.ChannelType)
+	if openAIRequest.Stream && info.SupportStreamOptions {
+		openAIRequest.StreamOptions = &dto.StreamOptions{IncludeUsage: true}
+	}
+	return openAIRequest, nil
+}
+
 func ResponsesHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode) {
 	req, err := getAndValidateResponsesRequest(c)
 	if err != nil {
@@ -68,9 +146,36 @@ func ResponsesHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode)
 		return service.OpenAIErrorWrapperLocal(err, "model_mapped_error", http.StatusBadRequest)
 	}
 
+	inputItems, err := req.GetInputItems()
+	if err != nil {
+		return service.OpenAIErrorWrapperLocal(err, "invalid_responses_input", http.StatusBadRequest)
+	}
+	previousResponseId := req.PreviousResponseID
+	var emulatedRequest *dto.GeneralOpenAIRequest
+	// 请求内容被改写后不能再透传原始请求体
+	requestRewritten := false
+	if shouldEmulateResponses(relayInfo) {
+		emulatedRequest, openaiErr = getEmulatedResponsesRequest(req, relayInfo, inputItems)
+		if openaiErr != nil {
+			return openaiErr
+		}
+		requestRewritten = true
+	} else {
+		requestRewritten, openaiErr = expandPreviousResponse(req, relayInfo, inputItems)
+		if openaiErr != nil {
+			return openaiErr
+		}
+	}
+
 	if value, exists := c.Get("prompt_tokens"); exists {
 		promptTokens := value.(int)
 		relayInfo.SetPromptTokens(promptTokens)
+	} else if emulatedRequest != nil {
+		promptTokens, err := getPromptTokens(emulatedRequest, relayInfo)
+		if err != nil {
+			return service.OpenAIErrorWrapperLocal(err, "count_input_tokens_error", http.StatusBadRequest)
+		}
+		c.Set("prompt_tokens", promptTokens)
 	} else {
 		promptTokens := getInputTokens(req, relayInfo)
 		c.Set("prompt_tokens", promptTokens)
@@ -96,14 +201,19 @@ func ResponsesHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode)
 	}
 	adaptor.Init(relayInfo)
 	var requestBody io.Reader
-	if model_setting.GetGlobalSettings().PassThroughRequestEnabled {
+	if model_setting.GetGlobalSettings().PassThroughRequestEnabled && !requestRewritten {
 		body, err := common.GetRequestBody(c)
 		if err != nil {
 			return service.OpenAIErrorWrapperLocal(err, "get_request_body_error", http.StatusInternalServerError)
 		}
 		requestBody = bytes.NewBuffer(body)
 	} else {
-		convertedRequest, err := adaptor.ConvertOpenAIResponsesRequest(c, relayInfo, *req)
+		var convertedRequest any
+		if emulatedRequest != nil {
+			convertedRequest, err = adaptor.ConvertOpenAIRequest(c, relayInfo, emulatedRequest)
+		} else {
+			convertedRequest, err = adaptor.ConvertOpenAIResponsesRequest(c, relayInfo, *req)
+		}
 		if err != nil {
 			return service.OpenAIErrorWrapperLocal(err, "convert_request_error", http.StatusBadRequest)
 		}
@@ -152,12 +262,48 @@ func ResponsesHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode)
 		}
 	}
 
+	storeResponse := service.ShouldStoreResponse(req, emulatedRequest != nil)
+	var convertWriter *service.ResponsesConvertWriter
+	var captureWriter *service.ResponseCaptureWriter
+	if emulatedRequest != nil {
+		response := service.NewEmulatedResponse(req, model.NewStoredResponseId(), relayInfo.OriginModelName)
+		convertWriter = service.NewResponsesConvertWriter(c.Writer, relayInfo.IsStream, response)
+		c.Writer = convertWriter
+	} else if storeResponse {
+		captureWriter = service.NewResponseCaptureWriterWithLimit(c.Writer, operation_setting.GetResponseStoreSetting().MaxCaptureBytes)
+		c.Writer = captureWriter
+	}
+
 	usage, openaiErr := adaptor.DoResponse(c, httpResp, relayInfo)
+	if convertWriter != nil {
+		c.Writer = convertWriter.ResponseWriter
+	} else if captureWriter != nil {
+		c.Writer = captureWriter.ResponseWriter
+	}
 	if openaiErr != nil {
 		// reset status code 重置状态码
 		service.ResetStatusCode(openaiErr, statusCodeMappingStr)
 		return openaiErr
 	}
+	var responseData []byte
+	if convertWriter != nil {
+		if response := convertWriter.Finish(usage.(*dto.Usage)); response != nil {
+			responseData, _ = json.Marshal(response)
+		}
+	} else if captureWriter != nil {
+		if body, ok := captureWriter.Body(); ok {
+			if relayInfo.IsStream {
+				responseData, _ = service.ParseResponsesStreamResult(body)
+			} else {
+				responseData = []byte(body)
+			}
+		}
+	}
+	if storeResponse && len(responseData) > 0 {
+		if err := service.SaveStoredResponse(relayInfo, previousResponseId, inputItems, responseData); err != nil {
+			common.LogError(c, "save stored response failed: "+err.Error())
+		}
+	}
 
 	if strings.HasPrefix(relayInfo.OriginModelName, "gpt-4o-audio") {
 		service.PostAudioConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
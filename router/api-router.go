@@ -1,6 +1,7 @@
 package router
 
 import (
+	"one-api/constant"
 	"one-api/controller"
 	"one-api/middleware"
 
@@ -18,7 +19,7 @@ func SetApiRouter(router *gin.Engine) {
 		apiRouter.GET("/status", controller.GetStatus)
 		apiRouter.GET("/uptime/status", controller.GetUptimeKumaStatus)
 		apiRouter.GET("/models", middleware.UserAuth(), controller.DashboardListModels)
-		apiRouter.GET("/status/test", middleware.AdminAuth(), controller.TestStatus)
+		apiRouter.GET("/status/test", middleware.PermissionAuth(constant.PermissionChannelRead), controller.TestStatus)
 		apiRouter.GET("/notice", controller.GetNotice)
 		apiRouter.GET("/about", controller.GetAbout)
 		//apiRouter.GET("/midjourney", controller.GetMidjourney)
@@ -69,18 +70,18 @@ func SetApiRouter(router *gin.Engine) {
 				selfRoute.POST("/amount", controller.RequestAmount)
 				selfRoute.POST("/aff_transfer", controller.TransferAffQuota)
 				selfRoute.PUT("/setting", controller.UpdateUserSetting)
+				selfRoute.GET("/self/permissions", controller.GetSelfPermissions)
 			}
 
 			adminRoute := userRoute.Group("/")
-			adminRoute.Use(middleware.AdminAuth())
 			{
-				adminRoute.GET("/", controller.GetAllUsers)
-				adminRoute.GET("/search", controller.SearchUsers)
-				adminRoute.GET("/:id", controller.GetUser)
-				adminRoute.POST("/", controller.CreateUser)
-				adminRoute.POST("/manage", controller.ManageUser)
-				adminRoute.PUT("/", controller.UpdateUser)
-				adminRoute.DELETE("/:id", controller.DeleteUser)
+				adminRoute.GET("/", middleware.PermissionAuth(constant.PermissionUserRead), controller.GetAllUsers)
+				adminRoute.GET("/search", middleware.PermissionAuth(constant.PermissionUserRead), controller.SearchUsers)
+				adminRoute.GET("/:id", middleware.PermissionAuth(constant.PermissionUserRead), controller.GetUser)
+				adminRoute.POST("/", middleware.PermissionAuth(constant.PermissionUserWrite), controller.CreateUser)
+				adminRoute.POST("/manage", middleware.PermissionAuth(constant.PermissionUserWrite), controller.ManageUser)
+				adminRoute.PUT("/", middleware.PermissionAuth(constant.PermissionUserWrite, constant.PermissionUserQuotaAdjust), controller.UpdateUser)
+				adminRoute.DELETE("/:id", middleware.PermissionAuth(constant.PermissionUserWrite), controller.DeleteUser)
 			}
 		}
 		optionRoute := apiRouter.Group("/option")
@@ -91,6 +92,23 @@ func SetApiRouter(router *gin.Engine) {
 			optionRoute.POST("/rest_model_ratio", controller.ResetModelRatio)
 			optionRoute.POST("/migrate_console_setting", controller.MigrateConsoleSetting) // 用于迁移检测的旧键，下个版本会删除
 		}
+		adminRoleRoute := apiRouter.Group("/admin_role")
+		adminRoleRoute.Use(middleware.RootAuth())
+		{
+			adminRoleRoute.GET("/", controller.GetAdminRoles)
+			adminRoleRoute.GET("/permissions", controller.GetAllPermissions)
+			adminRoleRoute.POST("/", controller.AddAdminRole)
+			adminRoleRoute.PUT("/", controller.UpdateAdminRole)
+			adminRoleRoute.DELETE("/:id", controller.DeleteAdminRole)
+			adminRoleRoute.POST("/assign", controller.AssignAdminRole)
+		}
+		configRoute := apiRouter.Group("/config")
+		configRoute.Use(middleware.RootAuth())
+		{
+			configRoute.GET("/export", controller.ExportConfig)
+			configRoute.POST("/import", controller.ImportConfig)
+		}
+		apiRouter.GET("/admin_audit", middleware.PermissionAuth(constant.PermissionAdminAuditRead), controller.GetAdminAuditLogs)
 		ratioSyncRoute := apiRouter.Group("/ratio_sync")
 		ratioSyncRoute.Use(middleware.RootAuth())
 		{
@@ -98,30 +116,39 @@ func SetApiRouter(router *gin.Engine) {
 			ratioSyncRoute.POST("/fetch", controller.FetchUpstreamRatios)
 		}
 		channelRoute := apiRouter.Group("/channel")
-		channelRoute.Use(middleware.AdminAuth())
 		{
-			channelRoute.GET("/", controller.GetAllChannels)
-			channelRoute.GET("/search", controller.SearchChannels)
-			channelRoute.GET("/models", controller.ChannelListModels)
-			channelRoute.GET("/models_enabled", controller.EnabledListModels)
-			channelRoute.GET("/:id", controller.GetChannel)
-			channelRoute.GET("/test", controller.TestAllChannels)
-			channelRoute.GET("/test/:id", controller.TestChannel)
-			channelRoute.GET("/update_balance", controller.UpdateAllChannelsBalance)
-			channelRoute.GET("/update_balance/:id", controller.UpdateChannelBalance)
-			channelRoute.POST("/", controller.AddChannel)
-			channelRoute.PUT("/", controller.UpdateChannel)
-			channelRoute.DELETE("/disabled", controller.DeleteDisabledChannel)
-			channelRoute.POST("/tag/disabled", controller.DisableTagChannels)
-			channelRoute.POST("/tag/enabled", controller.EnableTagChannels)
-			channelRoute.PUT("/tag", controller.EditTagChannels)
-			channelRoute.DELETE("/:id", controller.DeleteChannel)
-			channelRoute.POST("/batch", controller.DeleteChannelBatch)
-			channelRoute.POST("/fix", controller.FixChannelsAbilities)
-			channelRoute.GET("/fetch_models/:id", controller.FetchUpstreamModels)
-			channelRoute.POST("/fetch_models", controller.FetchModels)
-			channelRoute.POST("/batch/tag", controller.BatchSetChannelTag)
-			channelRoute.GET("/tag/models", controller.GetTagModels)
+			channelRoute.GET("/", middleware.PermissionAuth(constant.PermissionChannelRead), controller.GetAllChannels)
+			channelRoute.GET("/search", middleware.PermissionAuth(constant.PermissionChannelRead), controller.SearchChannels)
+			channelRoute.GET("/models", middleware.PermissionAuth(constant.PermissionChannelRead), controller.ChannelListModels)
+			channelRoute.GET("/models_enabled", middleware.PermissionAuth(constant.PermissionChannelRead), controller.EnabledListModels)
+			channelRoute.GET("/:id", middleware.PermissionAuth(constant.PermissionChannelRead), controller.GetChannel)
+			channelRoute.GET("/key/:id", middleware.PermissionAuth(constant.PermissionChannelKeyRead), controller.GetChannelKey)
+			channelRoute.GET("/test", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.TestAllChannels)
+			channelRoute.GET("/test/:id", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.TestChannel)
+			channelRoute.GET("/update_balance", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.UpdateAllChannelsBalance)
+			channelRoute.GET("/update_balance/:id", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.UpdateChannelBalance)
+			channelRoute.POST("/", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.AddChannel)
+			channelRoute.PUT("/", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.UpdateChannel)
+			channelRoute.DELETE("/disabled", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.DeleteDisabledChannel)
+			channelRoute.POST("/tag/disabled", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.DisableTagChannels)
+			channelRoute.POST("/tag/enabled", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.EnableTagChannels)
+			channelRoute.PUT("/tag", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.EditTagChannels)
+			channelRoute.DELETE("/:id", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.DeleteChannel)
+			channelRoute.POST("/batch", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.DeleteChannelBatch)
+			channelRoute.POST("/fix", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.FixChannelsAbilities)
+			channelRoute.GET("/fetch_models/:id", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.FetchUpstreamModels)
+			channelRoute.POST("/fetch_models", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.FetchModels)
+			channelRoute.POST("/batch/tag", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.BatchSetChannelTag)
+			channelRoute.GET("/tag/models", middleware.PermissionAuth(constant.PermissionChannelRead), controller.GetTagModels)
+			channelRoute.GET("/key_status/:id", middleware.PermissionAuth(constant.PermissionChannelRead), controller.GetChannelKeyStatus)
+			channelRoute.POST("/key_status/:id", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.UpdateChannelKeyStatus)
+			channelRoute.GET("/breaker", middleware.PermissionAuth(constant.PermissionChannelRead), controller.GetChannelBreakers)
+			channelRoute.GET("/breaker/:id", middleware.PermissionAuth(constant.PermissionChannelRead), controller.GetChannelBreaker)
+			channelRoute.POST("/breaker/reset/:id", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.ResetChannelBreaker)
+			channelRoute.GET("/routing_stats", middleware.PermissionAuth(constant.PermissionChannelRead), controller.GetChannelRoutingStats)
+			channelRoute.GET("/versions/diff", middleware.PermissionAuth(constant.PermissionChannelRead), controller.DiffChannelVersions)
+			channelRoute.GET("/versions/:id", middleware.PermissionAuth(constant.PermissionChannelRead), controller.GetChannelVersions)
+			channelRoute.POST("/rollback", middleware.PermissionAuth(constant.PermissionChannelWrite), controller.RollbackChannel)
 		}
 		tokenRoute := apiRouter.Group("/token")
 		tokenRoute.Use(middleware.UserAuth())
@@ -133,40 +160,62 @@ func SetApiRouter(router *gin.Engine) {
 			tokenRoute.PUT("/", controller.UpdateToken)
 			tokenRoute.DELETE("/:id", controller.DeleteToken)
 		}
+		orgRoute := apiRouter.Group("/org")
+		{
+			orgRoute.GET("/self", middleware.UserAuth(), controller.GetSelfOrganizations)
+			orgRoute.POST("/", middleware.UserAuth(), controller.CreateOrganization)
+			orgRoute.POST("/:id/accept", middleware.UserAuth(), controller.AcceptOrganizationInvitation)
+			orgRoute.POST("/:id/decline", middleware.UserAuth(), controller.DeclineOrganizationInvitation)
+			orgRoute.GET("/:id/members", middleware.UserAuth(), controller.GetOrganizationMembers)
+			orgRoute.POST("/:id/members", middleware.UserAuth(), controller.AddOrganizationMember)
+			orgRoute.PUT("/:id/members", middleware.UserAuth(), controller.UpdateOrganizationMember)
+			orgRoute.DELETE("/:id/members/:user_id", middleware.UserAuth(), controller.RemoveOrganizationMember)
+			orgRoute.POST("/:id/transfer", middleware.UserAuth(), controller.TransferQuotaToOrganization)
+			orgRoute.GET("/", middleware.PermissionAuth(constant.PermissionOrgRead), controller.GetAllOrganizations)
+			orgRoute.PUT("/", middleware.PermissionAuth(constant.PermissionOrgWrite), controller.UpdateOrganization)
+			orgRoute.GET("/:id/admin/members", middleware.PermissionAuth(constant.PermissionOrgRead), controller.AdminGetOrganizationMembers)
+			orgRoute.POST("/:id/quota", middleware.PermissionAuth(constant.PermissionOrgWrite), controller.AdjustOrganizationQuota)
+		}
 		redemptionRoute := apiRouter.Group("/redemption")
-		redemptionRoute.Use(middleware.AdminAuth())
 		{
-			redemptionRoute.GET("/", controller.GetAllRedemptions)
-			redemptionRoute.GET("/search", controller.SearchRedemptions)
-			redemptionRoute.GET("/:id", controller.GetRedemption)
-			redemptionRoute.POST("/", controller.AddRedemption)
-			redemptionRoute.PUT("/", controller.UpdateRedemption)
-			redemptionRoute.DELETE("/invalid", controller.DeleteInvalidRedemption)
-			redemptionRoute.DELETE("/:id", controller.DeleteRedemption)
+			redemptionRoute.GET("/", middleware.PermissionAuth(constant.PermissionRedemptionRead), controller.GetAllRedemptions)
+			redemptionRoute.GET("/search", middleware.PermissionAuth(constant.PermissionRedemptionRead), controller.SearchRedemptions)
+			redemptionRoute.GET("/:id", middleware.PermissionAuth(constant.PermissionRedemptionRead), controller.GetRedemption)
+			redemptionRoute.POST("/", middleware.PermissionAuth(constant.PermissionRedemptionWrite), controller.AddRedemption)
+			redemptionRoute.PUT("/", middleware.PermissionAuth(constant.PermissionRedemptionWrite), controller.UpdateRedemption)
+			redemptionRoute.DELETE("/invalid", middleware.PermissionAuth(constant.PermissionRedemptionWrite), controller.DeleteInvalidRedemption)
+			redemptionRoute.DELETE("/:id", middleware.PermissionAuth(constant.PermissionRedemptionWrite), controller.DeleteRedemption)
 		}
 		authCodeRoute := apiRouter.Group("/auth_code")
-		authCodeRoute.Use(middleware.AdminAuth())
 		{
-			authCodeRoute.GET("/", controller.GetAllAuthCodes)
-			authCodeRoute.GET("/search", controller.SearchAuthCodes)
-			authCodeRoute.GET("/available_tokens", controller.GetAvailableTokens)
-			authCodeRoute.GET("/:id", controller.GetAuthCode)
-			authCodeRoute.POST("/", controller.AddAuthCode)
-			authCodeRoute.POST("/batch", controller.BatchCreateAuthCodes)
-			authCodeRoute.PUT("/", controller.UpdateAuthCode)
-			authCodeRoute.DELETE("/:id", controller.DeleteAuthCode)
+			authCodeRoute.GET("/", middleware.PermissionAuth(constant.PermissionAuthCodeRead), controller.GetAllAuthCodes)
+			authCodeRoute.GET("/search", middleware.PermissionAuth(constant.PermissionAuthCodeRead), controller.SearchAuthCodes)
+			authCodeRoute.GET("/available_tokens", middleware.PermissionAuth(constant.PermissionAuthCodeRead), controller.GetAvailableTokens)
+			authCodeRoute.GET("/:id", middleware.PermissionAuth(constant.PermissionAuthCodeRead), controller.GetAuthCode)
+			authCodeRoute.POST("/", middleware.PermissionAuth(constant.PermissionAuthCodeWrite), controller.AddAuthCode)
+			authCodeRoute.POST("/batch", middleware.PermissionAuth(constant.PermissionAuthCodeWrite), controller.BatchCreateAuthCodes)
+			authCodeRoute.PUT("/", middleware.PermissionAuth(constant.PermissionAuthCodeWrite), controller.UpdateAuthCode)
+			authCodeRoute.DELETE("/:id", middleware.PermissionAuth(constant.PermissionAuthCodeWrite), controller.DeleteAuthCode)
 		}
 		logRoute := apiRouter.Group("/log")
-		logRoute.GET("/", middleware.AdminAuth(), controller.GetAllLogs)
-		logRoute.DELETE("/", middleware.AdminAuth(), controller.DeleteHistoryLogs)
-		logRoute.GET("/stat", middleware.AdminAuth(), controller.GetLogsStat)
+		logRoute.GET("/", middleware.PermissionAuth(constant.PermissionLogRead), controller.GetAllLogs)
+		logRoute.DELETE("/", middleware.PermissionAuth(constant.PermissionLogWrite), controller.DeleteHistoryLogs)
+		logRoute.GET("/stat", middleware.PermissionAuth(constant.PermissionLogRead), controller.GetLogsStat)
 		logRoute.GET("/self/stat", middleware.UserAuth(), controller.GetLogsSelfStat)
-		logRoute.GET("/search", middleware.AdminAuth(), controller.SearchAllLogs)
+		logRoute.GET("/self/statement", middleware.UserAuth(), controller.GetSelfStatement)
+		logRoute.GET("/statement", middleware.PermissionAuth(constant.PermissionStatementRead), controller.GetUserStatement)
+		logRoute.GET("/statement/export", middleware.PermissionAuth(constant.PermissionStatementRead), controller.ExportStatements)
+		logRoute.GET("/search", middleware.PermissionAuth(constant.PermissionLogRead), controller.SearchAllLogs)
+		logRoute.GET("/audit", middleware.PermissionAuth(constant.PermissionAuditRead), controller.GetAuditRecord)
+		logRoute.PUT("/audit/token", middleware.PermissionAuth(constant.PermissionAuditWrite), controller.UpdateTokenAudit)
+		logRoute.GET("/daily_stat", middleware.PermissionAuth(constant.PermissionLogRead), controller.GetLogDailyStats)
+		logRoute.GET("/retention", middleware.PermissionAuth(constant.PermissionLogRead), controller.GetLogRetentionStatus)
+		logRoute.POST("/retention/run", middleware.PermissionAuth(constant.PermissionLogWrite), controller.RunLogRetention)
 		logRoute.GET("/self", middleware.UserAuth(), controller.GetUserLogs)
 		logRoute.GET("/self/search", middleware.UserAuth(), controller.SearchUserLogs)
 
 		dataRoute := apiRouter.Group("/data")
-		dataRoute.GET("/", middleware.AdminAuth(), controller.GetAllQuotaDates)
+		dataRoute.GET("/", middleware.PermissionAuth(constant.PermissionLogRead), controller.GetAllQuotaDates)
 		dataRoute.GET("/self", middleware.UserAuth(), controller.GetUserQuotaDates)
 
 		logRoute.Use(middleware.CORS())
@@ -175,18 +224,17 @@ func SetApiRouter(router *gin.Engine) {
 
 		}
 		groupRoute := apiRouter.Group("/group")
-		groupRoute.Use(middleware.AdminAuth())
 		{
-			groupRoute.GET("/", controller.GetGroups)
+			groupRoute.GET("/", middleware.PermissionAuth(constant.PermissionChannelRead, constant.PermissionUserRead, constant.PermissionRedemptionRead), controller.GetGroups)
 		}
 		mjRoute := apiRouter.Group("/mj")
 		mjRoute.GET("/self", middleware.UserAuth(), controller.GetUserMidjourney)
-		mjRoute.GET("/", middleware.AdminAuth(), controller.GetAllMidjourney)
+		mjRoute.GET("/", middleware.PermissionAuth(constant.PermissionLogRead), controller.GetAllMidjourney)
 
 		taskRoute := apiRouter.Group("/task")
 		{
 			taskRoute.GET("/self", middleware.UserAuth(), controller.GetUserTask)
-			taskRoute.GET("/", middleware.AdminAuth(), controller.GetAllTask)
+			taskRoute.GET("/", middleware.PermissionAuth(constant.PermissionLogRead), controller.GetAllTask)
 		}
 	}
 }
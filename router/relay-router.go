@@ -25,6 +25,8 @@ func SetRelayRouter(router *gin.Engine) {
 		playgroundRouter.POST("/chat/completions", controller.Playground)
 	}
 	relayV1Router := router.Group("/v1")
+	relayV1Router.Use(middleware.Tracing())
+	relayV1Router.Use(middleware.RelayMetrics())
 	relayV1Router.Use(middleware.TokenAuth())
 	relayV1Router.Use(middleware.ModelRequestRateLimit())
 	{
@@ -33,10 +35,26 @@ func SetRelayRouter(router *gin.Engine) {
 		wsRouter.Use(middleware.Distribute())
 		wsRouter.GET("/realtime", controller.WssRelay)
 	}
+	{
+		// 文件、批处理与保存的响应由网关自身实现，不需要分发渠道
+		relayV1Router.GET("/files", controller.ListFiles)
+		relayV1Router.POST("/files", controller.UploadFile)
+		relayV1Router.DELETE("/files/:id", controller.DeleteFile)
+		relayV1Router.GET("/files/:id", controller.RetrieveFile)
+		relayV1Router.GET("/files/:id/content", controller.RetrieveFileContent)
+		relayV1Router.POST("/batches", controller.CreateBatch)
+		relayV1Router.GET("/batches", controller.ListBatches)
+		relayV1Router.GET("/batches/:id", controller.RetrieveBatch)
+		relayV1Router.POST("/batches/:id/cancel", controller.CancelBatch)
+		relayV1Router.GET("/responses/:id", controller.RetrieveResponse)
+		relayV1Router.DELETE("/responses/:id", controller.DeleteResponse)
+		relayV1Router.GET("/responses/:id/input_items", controller.ListResponseInputItems)
+	}
 	{
 		//http router
 		httpRouter := relayV1Router.Group("")
 		httpRouter.Use(middleware.Distribute())
+		httpRouter.Use(middleware.AuditCapture())
 		httpRouter.POST("/messages", controller.RelayClaude)
 		httpRouter.POST("/completions", controller.Relay)
 		httpRouter.POST("/chat/completions", controller.Relay)
@@ -50,11 +68,6 @@ func SetRelayRouter(router *gin.Engine) {
 		httpRouter.POST("/audio/translations", controller.Relay)
 		httpRouter.POST("/audio/speech", controller.Relay)
 		httpRouter.POST("/responses", controller.Relay)
-		httpRouter.GET("/files", controller.RelayNotImplemented)
-		httpRouter.POST("/files", controller.RelayNotImplemented)
-		httpRouter.DELETE("/files/:id", controller.RelayNotImplemented)
-		httpRouter.GET("/files/:id", controller.RelayNotImplemented)
-		httpRouter.GET("/files/:id/content", controller.RelayNotImplemented)
 		httpRouter.POST("/fine-tunes", controller.RelayNotImplemented)
 		httpRouter.GET("/fine-tunes", controller.RelayNotImplemented)
 		httpRouter.GET("/fine-tunes/:id", controller.RelayNotImplemented)
@@ -65,6 +78,44 @@ func SetRelayRouter(router *gin.Engine) {
 		httpRouter.POST("/rerank", controller.Relay)
 	}
 
+	// Ollama 兼容接口，请求转换为 OpenAI 格式后转发
+	relayOllamaRouter := router.Group("/api")
+	relayOllamaRouter.Use(middleware.Tracing())
+	relayOllamaRouter.Use(middleware.RelayMetrics())
+	{
+		relayOllamaRouter.GET("/tags", middleware.TokenAuth(), controller.OllamaListModels)
+		ollamaHttpRouter := relayOllamaRouter.Group("")
+		// 审计放在格式转换之外，保存客户端收到的 Ollama 格式响应
+		ollamaHttpRouter.Use(middleware.AuditCapture())
+		ollamaHttpRouter.Use(middleware.OllamaCompatible())
+		ollamaHttpRouter.Use(middleware.TokenAuth())
+		ollamaHttpRouter.Use(middleware.ModelRequestRateLimit())
+		ollamaHttpRouter.Use(middleware.Distribute())
+		ollamaHttpRouter.POST("/chat", controller.Relay)
+		ollamaHttpRouter.POST("/generate", controller.Relay)
+		ollamaHttpRouter.POST("/embed", controller.Relay)
+	}
+
+	// Azure OpenAI 兼容接口，部署名映射为模型后按 /v1 接口转发
+	relayAzureRouter := router.Group("/openai/deployments/:deployment")
+	relayAzureRouter.Use(middleware.Tracing())
+	relayAzureRouter.Use(middleware.RelayMetrics())
+	relayAzureRouter.Use(middleware.TokenAuth())
+	relayAzureRouter.Use(middleware.AuditCapture())
+	relayAzureRouter.Use(middleware.AzureCompatible())
+	relayAzureRouter.Use(middleware.ModelRequestRateLimit())
+	relayAzureRouter.Use(middleware.Distribute())
+	{
+		relayAzureRouter.POST("/chat/completions", controller.Relay)
+		relayAzureRouter.POST("/completions", controller.Relay)
+		relayAzureRouter.POST("/embeddings", controller.Relay)
+		relayAzureRouter.POST("/images/generations", controller.Relay)
+		relayAzureRouter.POST("/images/edits", controller.Relay)
+		relayAzureRouter.POST("/audio/transcriptions", controller.Relay)
+		relayAzureRouter.POST("/audio/translations", controller.Relay)
+		relayAzureRouter.POST("/audio/speech", controller.Relay)
+	}
+
 	relayMjRouter := router.Group("/mj")
 	registerMjRouterGroup(relayMjRouter)
 
@@ -81,9 +132,12 @@ func SetRelayRouter(router *gin.Engine) {
 	}
 
 	relayGeminiRouter := router.Group("/v1beta")
+	relayGeminiRouter.Use(middleware.Tracing())
+	relayGeminiRouter.Use(middleware.RelayMetrics())
 	relayGeminiRouter.Use(middleware.TokenAuth())
 	relayGeminiRouter.Use(middleware.ModelRequestRateLimit())
 	relayGeminiRouter.Use(middleware.Distribute())
+	relayGeminiRouter.Use(middleware.AuditCapture())
 	{
 		// Gemini API 路径格式: /v1beta/models/{model_name}:{action}
 		relayGeminiRouter.POST("/models/*path", controller.Relay)
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"one-api/model"
+	"os"
+)
+
+const secretsCommandUsage = `Usage:
+  one-api secrets encrypt   encrypt plaintext secrets and secrets under previous master keys
+  one-api secrets rotate    re-encrypt every secret with SECRET_ENCRYPTION_KEY
+
+To rotate the master key, set the new key as SECRET_ENCRYPTION_KEY and the old one in
+SECRET_ENCRYPTION_PREVIOUS_KEYS, run rotate, then remove the old key.
+`
+
+// runSecretsCommand 处理 secrets 子命令，加密存量明文或在更换主密钥后重新加密全部密钥
+func runSecretsCommand(args []string) {
+	if len(args) != 1 || (args[0] != "encrypt" && args[0] != "rotate") {
+		fmt.Fprint(os.Stderr, secretsCommandUsage)
+		os.Exit(2)
+	}
+	initCommandEnvironment()
+	updated, err := model.EncryptSecretsAtRest(args[0] == "rotate")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "secrets "+args[0]+" failed: "+err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("%d rows re-encrypted\n", updated)
+}
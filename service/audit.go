@@ -0,0 +1,176 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"one-api/setting/operation_setting"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// 连续 1000 个以上 base64 字符视为图片、音频等二进制内容
+var auditBase64Pattern = regexp.MustCompile(`(data:[\w/+.-]+;base64,)?[A-Za-z0-9+/]{1000,}={0,2}`)
+
+var auditRegexCache sync.Map
+
+func getAuditRegex(pattern string) *regexp.Regexp {
+	if re, ok := auditRegexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		common.SysError(fmt.Sprintf("invalid audit redact pattern %q: %s", pattern, err.Error()))
+		re = nil
+	}
+	auditRegexCache.Store(pattern, re)
+	return re
+}
+
+// RedactAuditContent 按审计配置对内容脱敏
+func RedactAuditContent(content string) string {
+	setting := operation_setting.GetAuditSetting()
+	if setting.RedactBase64 {
+		content = auditBase64Pattern.ReplaceAllStringFunc(content, func(s string) string {
+			prefix := ""
+			if strings.HasPrefix(s, "data:") {
+				prefix = s[:strings.Index(s, ",")+1]
+			}
+			return fmt.Sprintf("%s[base64 redacted, %d bytes]", prefix, len(s)-len(prefix))
+		})
+	}
+	for _, field := range setting.RedactFields {
+		re := getAuditRegex(`("` + regexp.QuoteMeta(field) + `"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+		if re != nil {
+			content = re.ReplaceAllString(content, `$1"[redacted]"`)
+		}
+	}
+	for _, pattern := range setting.RedactPatterns {
+		if re := getAuditRegex(pattern); re != nil {
+			content = re.ReplaceAllString(content, "[redacted]")
+		}
+	}
+	return content
+}
+
+// TruncateAuditContent 在 UTF-8 字符边界处截断到 maxBytes 字节
+func TruncateAuditContent(content string, maxBytes int) (string, bool) {
+	if maxBytes <= 0 || len(content) <= maxBytes {
+		return content, false
+	}
+	end := maxBytes
+	for end > 0 && !utf8.RuneStart(content[end]) {
+		end--
+	}
+	return content[:end], true
+}
+
+type auditStreamChunk struct {
+	Type    string          `json:"type"`
+	Delta   json.RawMessage `json:"delta"`
+	Choices []struct {
+		Text  string `json:"text"`
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+			ToolCalls        []struct {
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"content"`
+	} `json:"candidates"`
+}
+
+type auditClaudeDelta struct {
+	Text        string `json:"text"`
+	Thinking    string `json:"thinking"`
+	PartialJson string `json:"partial_json"`
+}
+
+// AuditStreamAssembler 将 OpenAI、Claude、Gemini 与 Responses 格式的 SSE 流拼接为完整文本
+type AuditStreamAssembler struct {
+	builder   strings.Builder
+	maxBytes  int
+	truncated bool
+}
+
+func NewAuditStreamAssembler(maxBytes int) *AuditStreamAssembler {
+	return &AuditStreamAssembler{maxBytes: maxBytes}
+}
+
+func (a *AuditStreamAssembler) append(s string) {
+	if s == "" || a.truncated {
+		return
+	}
+	if a.maxBytes > 0 && a.builder.Len()+len(s) > a.maxBytes {
+		s, _ = TruncateAuditContent(s, a.maxBytes-a.builder.Len())
+		a.truncated = true
+	}
+	a.builder.WriteString(s)
+}
+
+// AddLine 处理一行 SSE 数据
+func (a *AuditStreamAssembler) AddLine(line string) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "data:") {
+		return
+	}
+	data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+	if data == "" || data == "[DONE]" {
+		return
+	}
+	var chunk auditStreamChunk
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return
+	}
+	for _, choice := range chunk.Choices {
+		a.append(choice.Text)
+		a.append(choice.Delta.ReasoningContent)
+		a.append(choice.Delta.Content)
+		for _, toolCall := range choice.Delta.ToolCalls {
+			a.append(toolCall.Function.Name)
+			a.append(toolCall.Function.Arguments)
+		}
+	}
+	for _, candidate := range chunk.Candidates {
+		for _, part := range candidate.Content.Parts {
+			a.append(part.Text)
+		}
+	}
+	if len(chunk.Delta) == 0 {
+		return
+	}
+	// Responses API 的 delta 为字符串，Claude 的 delta 为对象
+	var text string
+	if err := json.Unmarshal(chunk.Delta, &text); err == nil {
+		if strings.HasSuffix(chunk.Type, ".delta") {
+			a.append(text)
+		}
+		return
+	}
+	var delta auditClaudeDelta
+	if err := json.Unmarshal(chunk.Delta, &delta); err == nil {
+		a.append(delta.Thinking)
+		a.append(delta.Text)
+		a.append(delta.PartialJson)
+	}
+}
+
+func (a *AuditStreamAssembler) String() string {
+	return a.builder.String()
+}
+
+func (a *AuditStreamAssembler) Truncated() bool {
+	return a.truncated
+}
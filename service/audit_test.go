@@ -0,0 +1,90 @@
+package service
+
+import (
+	"one-api/setting/operation_setting"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// useAuditSetting 修改审计配置，测试结束后恢复
+func useAuditSetting(t *testing.T, update func(setting *operation_setting.AuditSetting)) {
+	setting := operation_setting.GetAuditSetting()
+	saved := *setting
+	update(setting)
+	t.Cleanup(func() {
+		*setting = saved
+	})
+}
+
+// TestRedactAuditContent 测试替换长 base64 内容、指定 JSON 字段的值以及匹配正则的内容
+func TestRedactAuditContent(t *testing.T) {
+	useAuditSetting(t, func(setting *operation_setting.AuditSetting) {
+		setting.RedactBase64 = true
+		setting.RedactFields = []string{"api_key"}
+		setting.RedactPatterns = []string{`sk-[A-Za-z0-9]{8,}`, `(`}
+	})
+	image := "data:image/png;base64," + strings.Repeat("A", 1200)
+	content := `{"image":"` + image + `","api_key":"secret \"quoted\"","text":"use sk-abcdefgh1234 please"}`
+	assert.Equal(t, `{"image":"data:image/png;base64,[base64 redacted, 1200 bytes]","api_key":"[redacted]","text":"use [redacted] please"}`,
+		RedactAuditContent(content))
+
+	short := strings.Repeat("A", 999)
+	assert.Equal(t, short, RedactAuditContent(short))
+}
+
+// TestTruncateAuditContent 测试在 UTF-8 字符边界处截断
+func TestTruncateAuditContent(t *testing.T) {
+	content, truncated := TruncateAuditContent("你好世界", 7)
+	assert.Equal(t, "你好", content)
+	assert.True(t, truncated)
+	content, truncated = TruncateAuditContent("hello", 5)
+	assert.Equal(t, "hello", content)
+	assert.False(t, truncated)
+}
+
+// TestAuditStreamAssembler 测试拼接 OpenAI、Claude、Gemini 与 Responses 格式的流式内容
+func TestAuditStreamAssembler(t *testing.T) {
+	lines := map[string][]string{
+		"openai": {
+			`data: {"choices":[{"delta":{"reasoning_content":"think "}}]}`,
+			`data: {"choices":[{"delta":{"content":"hello"}}]}`,
+			`data: {"choices":[{"delta":{"tool_calls":[{"function":{"name":"get_weather","arguments":"{}"}}]}}]}`,
+			`data: [DONE]`,
+		},
+		"claude": {
+			`event: content_block_delta`,
+			`data: {"type":"content_block_delta","delta":{"type":"thinking_delta","thinking":"think "}}`,
+			`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"hello"}}`,
+		},
+		"gemini": {
+			`data: {"candidates":[{"content":{"parts":[{"text":"think "},{"text":"hello"}]}}]}`,
+		},
+		"responses": {
+			`data: {"type":"response.output_text.delta","delta":"think "}`,
+			`data: {"type":"response.output_text.delta","delta":"hello"}`,
+			`data: {"type":"response.output_text.done","delta":"think hello"}`,
+		},
+	}
+	expected := map[string]string{
+		"openai":    "think helloget_weather{}",
+		"claude":    "think hello",
+		"gemini":    "think hello",
+		"responses": "think hello",
+	}
+	for format, formatLines := range lines {
+		assembler := NewAuditStreamAssembler(0)
+		for _, line := range formatLines {
+			assembler.AddLine(line)
+		}
+		assert.Equal(t, expected[format], assembler.String(), format)
+		assert.False(t, assembler.Truncated())
+	}
+
+	assembler := NewAuditStreamAssembler(8)
+	assembler.AddLine(`data: {"choices":[{"delta":{"content":"hello"}}]}`)
+	assembler.AddLine(`data: {"choices":[{"delta":{"content":" world"}}]}`)
+	assert.Equal(t, "hello wo", assembler.String())
+	assert.True(t, assembler.Truncated())
+}
@@ -0,0 +1,500 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+// BatchEndpoints 支持批处理的接口
+var BatchEndpoints = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+	"/v1/embeddings":       true,
+}
+
+// 批处理单行最大长度
+const batchMaxLineBytes = 16 << 20
+
+// 单行请求遇到限流或上游错误时的最大尝试次数
+const batchLineMaxAttempts = 3
+
+var runningBatches sync.Map
+
+var ErrBatchNotMasterNode = errors.New("the batch api is only available on the master node")
+
+// CheckBatchNode 批处理任务只由主节点的后台任务执行，输入文件也保存在主节点本地，从节点不能创建批处理与上传文件
+func CheckBatchNode() error {
+	if !common.IsMasterNode {
+		return ErrBatchNotMasterNode
+	}
+	return nil
+}
+
+// StartBatchWorker 后台轮询并执行批处理任务，handler 为网关自身的路由，
+// 每一行请求都会完整经过鉴权、渠道分发、重试与计费流程
+func StartBatchWorker(handler http.Handler) {
+	for {
+		setting := operation_setting.GetBatchSetting()
+		pollSeconds := setting.PollSeconds
+		if pollSeconds <= 0 {
+			pollSeconds = 10
+		}
+		time.Sleep(time.Duration(pollSeconds) * time.Second)
+		if !setting.Enabled {
+			continue
+		}
+		batches, err := model.GetPendingBatches(20)
+		if err != nil {
+			common.SysError("failed to get pending batches: " + err.Error())
+			continue
+		}
+		for _, batch := range batches {
+			if _, loaded := runningBatches.LoadOrStore(batch.Id, true); loaded {
+				continue
+			}
+			batch := batch
+			gopool.Go(func() {
+				defer runningBatches.Delete(batch.Id)
+				runner := &batchRunner{batch: batch, handler: handler}
+				if err := runner.run(); err != nil {
+					common.SysError(fmt.Sprintf("batch %s failed: %s", batch.Id, err.Error()))
+				}
+			})
+		}
+	}
+}
+
+type batchRunner struct {
+	batch   *model.Batch
+	handler http.Handler
+	store   FileStore
+	token   *model.Token
+
+	lock        sync.Mutex
+	outputFile  *os.File
+	errorFile   *os.File
+	processed   map[string]bool
+	lastRefresh time.Time
+	status      string
+}
+
+func batchPartialDir() string {
+	return filepath.Join(operation_setting.GetBatchSetting().StorageDir, "batch_partial")
+}
+
+func (r *batchRunner) partialPath(kind string) string {
+	return filepath.Join(batchPartialDir(), fmt.Sprintf("%s_%s.jsonl", r.batch.Id, kind))
+}
+
+func (r *batchRunner) run() error {
+	var err error
+	r.store, err = GetFileStore()
+	if err != nil {
+		return err
+	}
+	now := common.GetTimestamp()
+	batch := r.batch
+
+	if batch.Status == model.BatchStatusCancelling {
+		return r.finalize(model.BatchStatusCancelled)
+	}
+
+	lines, batchErrors, err := r.loadInput()
+	if err != nil {
+		return err
+	}
+	if batch.Status == model.BatchStatusValidating {
+		if len(batchErrors) > 0 {
+			batch.SetErrors(batchErrors)
+			batch.Status = model.BatchStatusFailed
+			batch.FailedAt = now
+			return batch.UpdateFields("status", "errors", "failed_at")
+		}
+		batch.RequestTotal = len(lines)
+		if err = batch.UpdateFields("request_total"); err != nil {
+			return err
+		}
+		ok, err := batch.TransitionStatus(model.BatchStatusValidating, model.BatchStatusInProgress, "in_progress_at", now)
+		if err != nil || !ok {
+			return err
+		}
+		batch.InProgressAt = now
+	} else if len(batchErrors) > 0 {
+		// 执行过程中输入文件被删除
+		return r.fail(batchErrors[0].Code, batchErrors[0].Message)
+	}
+
+	r.token, err = model.GetTokenById(batch.TokenId)
+	if err != nil {
+		return r.fail("token_not_found", "the token used to create this batch no longer exists")
+	}
+	if err = r.openPartialFiles(); err != nil {
+		return err
+	}
+
+	concurrency := operation_setting.GetBatchSetting().Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	finalStatus := model.BatchStatusCompleted
+	remaining := make([]dto.BatchInputLine, 0)
+	for i, line := range lines {
+		if r.isProcessed(line.CustomId) {
+			continue
+		}
+		status := r.currentStatus()
+		if status == model.BatchStatusCancelling {
+			finalStatus = model.BatchStatusCancelled
+			break
+		}
+		if batch.ExpiresAt > 0 && common.GetTimestamp() > batch.ExpiresAt {
+			finalStatus = model.BatchStatusExpired
+			remaining = append(remaining, lines[i:]...)
+			break
+		}
+		semaphore <- struct{}{}
+		wg.Add(1)
+		line := line
+		gopool.Go(func() {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			r.executeLine(line)
+		})
+	}
+	wg.Wait()
+
+	// 过期后未执行的请求写入错误文件
+	for _, line := range remaining {
+		if r.processed[line.CustomId] {
+			continue
+		}
+		r.writeResult(line.CustomId, nil, &dto.BatchOutputError{
+			Code:    "batch_expired",
+			Message: "This request could not be executed before the completion window expired.",
+		})
+	}
+	if finalStatus == model.BatchStatusCompleted && r.currentStatus() == model.BatchStatusCancelling {
+		finalStatus = model.BatchStatusCancelled
+	}
+	return r.finalize(finalStatus)
+}
+
+// loadInput 读取并校验输入文件
+func (r *batchRunner) loadInput() ([]dto.BatchInputLine, []dto.BatchError, error) {
+	file, err := model.GetUserFileById(r.batch.UserId, r.batch.InputFileId)
+	if err != nil {
+		return nil, []dto.BatchError{{Code: "file_not_found", Message: "input file not found"}}, nil
+	}
+	reader, err := r.store.Open(file.StoragePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reader.Close()
+
+	lines := make([]dto.BatchInputLine, 0)
+	batchErrors := make([]dto.BatchError, 0)
+	customIds := make(map[string]bool)
+	addError := func(lineNumber int, code string, message string) {
+		if len(batchErrors) < 100 {
+			line := lineNumber
+			batchErrors = append(batchErrors, dto.BatchError{Code: code, Message: message, Line: &line})
+		}
+	}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), batchMaxLineBytes)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var line dto.BatchInputLine
+		if err := json.Unmarshal(text, &line); err != nil {
+			addError(lineNumber, "invalid_json_line", "This line is not parseable as valid JSON.")
+			continue
+		}
+		if line.CustomId == "" {
+			addError(lineNumber, "missing_required_parameter", "Missing required parameter: 'custom_id'.")
+			continue
+		}
+		if customIds[line.CustomId] {
+			addError(lineNumber, "duplicate_custom_id", fmt.Sprintf("The custom_id '%s' is not unique.", line.CustomId))
+			continue
+		}
+		customIds[line.CustomId] = true
+		if line.Method != http.MethodPost {
+			addError(lineNumber, "invalid_method", "Only POST requests are supported.")
+			continue
+		}
+		if line.Url != r.batch.Endpoint {
+			addError(lineNumber, "mismatched_endpoint", fmt.Sprintf("The url '%s' does not match the batch endpoint '%s'.", line.Url, r.batch.Endpoint))
+			continue
+		}
+		var body map[string]any
+		if err := json.Unmarshal(line.Body, &body); err != nil || body["model"] == nil || body["model"] == "" {
+			addError(lineNumber, "missing_required_parameter", "Missing required parameter: 'body.model'.")
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		addError(lineNumber+1, "invalid_json_line", err.Error())
+	}
+	if len(lines) == 0 && len(batchErrors) == 0 {
+		batchErrors = append(batchErrors, dto.BatchError{Code: "empty_file", Message: "The input file is empty."})
+	}
+	maxRequests := operation_setting.GetBatchSetting().MaxRequests
+	if maxRequests > 0 && len(lines) > maxRequests {
+		batchErrors = append(batchErrors, dto.BatchError{Code: "too_many_requests", Message: fmt.Sprintf("A batch may contain at most %d requests.", maxRequests)})
+	}
+	return lines, batchErrors, nil
+}
+
+// openPartialFiles 打开中间结果文件，已有结果的请求在重启后不会重复执行
+func (r *batchRunner) openPartialFiles() error {
+	if err := os.MkdirAll(batchPartialDir(), 0755); err != nil {
+		return err
+	}
+	r.processed = make(map[string]bool)
+	completed, failed := 0, 0
+	for _, kind := range []string{"output", "error"} {
+		data, err := os.ReadFile(r.partialPath(kind))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for _, text := range bytes.Split(data, []byte("\n")) {
+			var line dto.BatchOutputLine
+			if len(bytes.TrimSpace(text)) == 0 || json.Unmarshal(text, &line) != nil {
+				continue
+			}
+			r.processed[line.CustomId] = true
+			if kind == "output" {
+				completed++
+			} else {
+				failed++
+			}
+		}
+	}
+	r.batch.RequestCompleted = completed
+	r.batch.RequestFailed = failed
+
+	var err error
+	r.outputFile, err = os.OpenFile(r.partialPath("output"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	r.errorFile, err = os.OpenFile(r.partialPath("error"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	return err
+}
+
+func (r *batchRunner) closePartialFiles() {
+	if r.outputFile != nil {
+		_ = r.outputFile.Close()
+	}
+	if r.errorFile != nil {
+		_ = r.errorFile.Close()
+	}
+}
+
+func (r *batchRunner) isProcessed(customId string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.processed[customId]
+}
+
+// currentStatus 定期从数据库刷新任务状态，以便响应取消
+func (r *batchRunner) currentStatus() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.status == "" || time.Since(r.lastRefresh) > 5*time.Second {
+		status, err := r.batch.GetStatus()
+		if err == nil {
+			r.status = status
+		}
+		r.lastRefresh = time.Now()
+	}
+	return r.status
+}
+
+func (r *batchRunner) executeLine(line dto.BatchInputLine) {
+	body := line.Body
+	var bodyMap map[string]any
+	if err := json.Unmarshal(body, &bodyMap); err == nil {
+		// 批处理不支持流式输出
+		delete(bodyMap, "stream")
+		delete(bodyMap, "stream_options")
+		if data, err := json.Marshal(bodyMap); err == nil {
+			body = data
+		}
+	}
+
+	var recorder *httptest.ResponseRecorder
+	for attempt := 0; attempt < batchLineMaxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(1<<attempt) * time.Second)
+		}
+		ctx := context.WithValue(context.Background(), constant.ContextKeyBatchId, r.batch.Id)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, line.Url, bytes.NewReader(body))
+		if err != nil {
+			r.writeResult(line.CustomId, nil, &dto.BatchOutputError{Code: "invalid_request", Message: err.Error()})
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer sk-"+r.token.Key)
+		if r.batch.ClientIp != "" {
+			req.RemoteAddr = net.JoinHostPort(r.batch.ClientIp, "0")
+		}
+		recorder = httptest.NewRecorder()
+		r.handler.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusTooManyRequests && recorder.Code < 500 {
+			break
+		}
+	}
+
+	responseBody := recorder.Body.Bytes()
+	if !json.Valid(responseBody) {
+		responseBody, _ = json.Marshal(string(responseBody))
+	}
+	r.writeResult(line.CustomId, &dto.BatchOutputResponse{
+		StatusCode: recorder.Code,
+		RequestId:  recorder.Header().Get(common.RequestIdKey),
+		Body:       responseBody,
+	}, nil)
+}
+
+// writeResult 成功的请求写入输出文件，失败的请求写入错误文件，格式与 OpenAI 一致
+func (r *batchRunner) writeResult(customId string, response *dto.BatchOutputResponse, outputError *dto.BatchOutputError) {
+	result := dto.BatchOutputLine{
+		Id:       "batch_req_" + common.GetRandomString(24),
+		CustomId: customId,
+		Response: response,
+		Error:    outputError,
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	success := response != nil && response.StatusCode == http.StatusOK
+	file := r.errorFile
+	if success {
+		file = r.outputFile
+		r.batch.RequestCompleted++
+	} else {
+		r.batch.RequestFailed++
+	}
+	if _, err = file.Write(data); err != nil {
+		common.SysError(fmt.Sprintf("failed to write batch %s result: %s", r.batch.Id, err.Error()))
+	}
+	r.processed[customId] = true
+	if err = r.batch.UpdateFields("request_completed", "request_failed"); err != nil {
+		common.SysError(fmt.Sprintf("failed to update batch %s counts: %s", r.batch.Id, err.Error()))
+	}
+}
+
+func (r *batchRunner) fail(code string, message string) error {
+	r.batch.SetErrors([]dto.BatchError{{Code: code, Message: message}})
+	r.batch.Status = model.BatchStatusFailed
+	r.batch.FailedAt = common.GetTimestamp()
+	return r.batch.UpdateFields("status", "errors", "failed_at")
+}
+
+// finalize 将中间结果保存为输出文件与错误文件，并更新任务的最终状态
+func (r *batchRunner) finalize(status string) error {
+	r.closePartialFiles()
+	batch := r.batch
+	now := common.GetTimestamp()
+	if status == model.BatchStatusCompleted {
+		batch.Status = model.BatchStatusFinalizing
+		batch.FinalizingAt = now
+		if err := batch.UpdateFields("status", "finalizing_at"); err != nil {
+			return err
+		}
+	}
+	var err error
+	batch.OutputFileId, err = r.savePartialFile("output")
+	if err != nil {
+		return err
+	}
+	batch.ErrorFileId, err = r.savePartialFile("error")
+	if err != nil {
+		return err
+	}
+	batch.Status = status
+	fields := []string{"status", "output_file_id", "error_file_id", "request_completed", "request_failed"}
+	switch status {
+	case model.BatchStatusCompleted:
+		batch.CompletedAt = now
+		fields = append(fields, "completed_at")
+	case model.BatchStatusCancelled:
+		batch.CancelledAt = now
+		fields = append(fields, "cancelled_at")
+	case model.BatchStatusExpired:
+		batch.ExpiredAt = now
+		fields = append(fields, "expired_at")
+	}
+	if err = batch.UpdateFields(fields...); err != nil {
+		return err
+	}
+	_ = os.Remove(r.partialPath("output"))
+	_ = os.Remove(r.partialPath("error"))
+	return nil
+}
+
+func (r *batchRunner) savePartialFile(kind string) (string, error) {
+	partial, err := os.Open(r.partialPath(kind))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer partial.Close()
+	info, err := partial.Stat()
+	if err != nil || info.Size() == 0 {
+		return "", err
+	}
+	file := &model.File{
+		Id:        model.NewFileId(),
+		UserId:    r.batch.UserId,
+		Purpose:   model.FilePurposeBatchOutput,
+		Filename:  fmt.Sprintf("%s_%s.jsonl", r.batch.Id, kind),
+		CreatedAt: common.GetTimestamp(),
+	}
+	file.StoragePath = file.Id
+	file.Bytes, err = r.store.Save(file.StoragePath, partial, 0)
+	if err != nil {
+		return "", err
+	}
+	if err = file.Insert(); err != nil {
+		_ = r.store.Delete(file.StoragePath)
+		return "", err
+	}
+	return file.Id, nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupBatchTest 准备数据库、本地文件存储和批处理令牌，返回令牌
+func setupBatchTest(t *testing.T) *model.Token {
+	setupTestDB(t)
+	setting := operation_setting.GetBatchSetting()
+	saved := *setting
+	setting.StorageType = "local"
+	setting.StorageDir = t.TempDir()
+	setting.Concurrency = 2
+	setting.MaxRequests = 0
+	t.Cleanup(func() {
+		*setting = saved
+	})
+	token := &model.Token{UserId: 1, Name: "batch", Key: "batchtestkey", Status: common.TokenStatusEnabled}
+	require.NoError(t, model.DB.Create(token).Error)
+	return token
+}
+
+// createTestBatch 保存输入文件并创建处于 validating 状态的批处理任务
+func createTestBatch(t *testing.T, token *model.Token, input string) *model.Batch {
+	store, err := GetFileStore()
+	require.NoError(t, err)
+	file := &model.File{Id: model.NewFileId(), UserId: token.UserId, Purpose: model.FilePurposeBatch, Filename: "input.jsonl"}
+	file.StoragePath = file.Id
+	file.Bytes, err = store.Save(file.StoragePath, strings.NewReader(input), 0)
+	require.NoError(t, err)
+	require.NoError(t, file.Insert())
+	batch := &model.Batch{
+		Id:          model.NewBatchId(),
+		UserId:      token.UserId,
+		TokenId:     token.Id,
+		Endpoint:    "/v1/chat/completions",
+		InputFileId: file.Id,
+		Status:      model.BatchStatusValidating,
+		CreatedAt:   common.GetTimestamp(),
+	}
+	require.NoError(t, batch.Insert())
+	return batch
+}
+
+func readBatchFile(t *testing.T, userId int, fileId string) string {
+	file, err := model.GetUserFileById(userId, fileId)
+	require.NoError(t, err)
+	store, err := GetFileStore()
+	require.NoError(t, err)
+	reader, err := store.Open(file.StoragePath)
+	require.NoError(t, err)
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// TestBatchRunnerCompleted 测试每行请求以令牌身份经过网关执行，成功结果写入输出文件、失败结果写入错误文件
+func TestBatchRunnerCompleted(t *testing.T) {
+	token := setupBatchTest(t)
+	batch := createTestBatch(t, token, strings.Join([]string{
+		`{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o","stream":true}}`,
+		``,
+		`{"custom_id":"b","method":"POST","url":"/v1/chat/completions","body":{"model":"bad-model"}}`,
+		`{"custom_id":"c","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o"}}`,
+	}, "\n"))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer sk-"+token.Key, r.Header.Get("Authorization"))
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.NotContains(t, body, "stream")
+		if body["model"] == "bad-model" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"model not found"}}`))
+			return
+		}
+		w.Write([]byte(`{"object":"chat.completion"}`))
+	})
+	runner := &batchRunner{batch: batch, handler: handler}
+	require.NoError(t, runner.run())
+
+	batch, err := model.GetUserBatchById(token.UserId, batch.Id)
+	require.NoError(t, err)
+	assert.Equal(t, model.BatchStatusCompleted, batch.Status)
+	assert.Equal(t, 3, batch.RequestTotal)
+	assert.Equal(t, 2, batch.RequestCompleted)
+	assert.Equal(t, 1, batch.RequestFailed)
+	assert.NotZero(t, batch.CompletedAt)
+
+	output := readBatchFile(t, token.UserId, batch.OutputFileId)
+	assert.Equal(t, 2, strings.Count(output, "\n"))
+	assert.Contains(t, output, `"custom_id":"a"`)
+	assert.Contains(t, output, `"custom_id":"c"`)
+	errorOutput := readBatchFile(t, token.UserId, batch.ErrorFileId)
+	assert.Contains(t, errorOutput, `"custom_id":"b"`)
+	assert.Contains(t, errorOutput, `"status_code":400`)
+}
+
+// TestBatchRunnerValidation 测试输入文件校验失败时任务直接失败，并按行返回错误
+func TestBatchRunnerValidation(t *testing.T) {
+	token := setupBatchTest(t)
+	batch := createTestBatch(t, token, strings.Join([]string{
+		`{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o"}}`,
+		`{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o"}}`,
+		`{"custom_id":"b","method":"POST","url":"/v1/embeddings","body":{"model":"gpt-4o"}}`,
+		`not json`,
+	}, "\n"))
+
+	runner := &batchRunner{batch: batch, handler: http.NotFoundHandler()}
+	require.NoError(t, runner.run())
+	batch, err := model.GetUserBatchById(token.UserId, batch.Id)
+	require.NoError(t, err)
+	assert.Equal(t, model.BatchStatusFailed, batch.Status)
+	result := batch.ToOpenAIBatch()
+	require.NotNil(t, result.Errors)
+	codes := make([]string, 0)
+	for _, batchError := range result.Errors.Data {
+		codes = append(codes, batchError.Code)
+	}
+	assert.Equal(t, []string{"duplicate_custom_id", "mismatched_endpoint", "invalid_json_line"}, codes)
+	assert.Equal(t, 2, *result.Errors.Data[0].Line)
+}
+
+// TestBatchRunnerCancelled 测试处于 cancelling 状态的任务不再执行请求，直接结束为 cancelled
+func TestBatchRunnerCancelled(t *testing.T) {
+	token := setupBatchTest(t)
+	batch := createTestBatch(t, token, `{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o"}}`)
+	ok, err := batch.TransitionStatus(model.BatchStatusValidating, model.BatchStatusCancelling, "cancelling_at", common.GetTimestamp())
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("cancelled batch should not execute requests")
+	})
+	runner := &batchRunner{batch: batch, handler: handler}
+	require.NoError(t, runner.run())
+	batch, err = model.GetUserBatchById(token.UserId, batch.Id)
+	require.NoError(t, err)
+	assert.Equal(t, model.BatchStatusCancelled, batch.Status)
+	assert.NotZero(t, batch.CancelledAt)
+}
+
+// TestLocalFileStore 测试本地文件存储拒绝非法文件名，并限制写入大小
+func TestLocalFileStore(t *testing.T) {
+	store := &localFileStore{dir: t.TempDir()}
+	_, err := store.Save("../escape", strings.NewReader("x"), 0)
+	assert.Error(t, err)
+	_, err = store.Save("a/b", strings.NewReader("x"), 0)
+	assert.Error(t, err)
+
+	_, err = store.Save("large", strings.NewReader("12345"), 4)
+	assert.ErrorIs(t, err, ErrFileTooLarge)
+	_, err = store.Open("large")
+	assert.Error(t, err)
+
+	n, err := store.Save("small", strings.NewReader("1234"), 4)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+	assert.NoError(t, store.Delete("small"))
+	assert.NoError(t, store.Delete("small"))
+}
+
+// TestCheckBatchNode 测试从节点拒绝创建批处理任务
+func TestCheckBatchNode(t *testing.T) {
+	isMasterNode := common.IsMasterNode
+	t.Cleanup(func() {
+		common.IsMasterNode = isMasterNode
+	})
+	common.IsMasterNode = true
+	assert.NoError(t, CheckBatchNode())
+	common.IsMasterNode = false
+	assert.ErrorIs(t, CheckBatchNode(), ErrBatchNotMasterNode)
+}
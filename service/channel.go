@@ -4,10 +4,16 @@ import (
 	"fmt"
 	"net/http"
 	"one-api/common"
+	"one-api/common/metrics"
+	"one-api/constant"
 	"one-api/dto"
 	"one-api/model"
+	relaycommon "one-api/relay/common"
 	"one-api/setting/operation_setting"
 	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
 )
 
 func formatNotifyType(channelId int, status int) string {
@@ -21,6 +27,7 @@ func DisableChannel(channelId int, channelName string, reason string) {
 		subject := fmt.Sprintf("通道「%s」（#%d）已被禁用", channelName, channelId)
 		content := fmt.Sprintf("通道「%s」（#%d）已被禁用，原因：%s", channelName, channelId, reason)
 		NotifyRootUser(formatNotifyType(channelId, common.ChannelStatusAutoDisabled), subject, content)
+		metrics.ChannelStatusEvents.WithLabelValues(metrics.ChannelLabel(channelId), "disabled").Inc()
 	}
 }
 
@@ -30,9 +37,62 @@ func EnableChannel(channelId int, channelName string) {
 		subject := fmt.Sprintf("通道「%s」（#%d）已被启用", channelName, channelId)
 		content := fmt.Sprintf("通道「%s」（#%d）已被启用", channelName, channelId)
 		NotifyRootUser(formatNotifyType(channelId, common.ChannelStatusEnabled), subject, content)
+		metrics.ChannelStatusEvents.WithLabelValues(metrics.ChannelLabel(channelId), "enabled").Inc()
 	}
 }
 
+// DisableChannelKey 禁用多密钥渠道中的单个密钥，所有密钥都被禁用时再禁用整个渠道
+func DisableChannelKey(channelId int, channelName string, keyIndex int, reason string) {
+	changed, allDisabled, err := model.UpdateChannelKeyStatus(channelId, keyIndex, common.ChannelStatusAutoDisabled, reason)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to disable key #%d of channel #%d: %s", keyIndex, channelId, err.Error()))
+		return
+	}
+	if !changed {
+		return
+	}
+	common.SysLog(fmt.Sprintf("key #%d of channel #%d disabled, reason: %s", keyIndex, channelId, reason))
+	if allDisabled {
+		DisableChannel(channelId, channelName, "所有密钥均已被禁用，最后一次原因："+reason)
+		return
+	}
+	if operation_setting.GetChannelKeySetting().NotifyOnKeyDisabled {
+		subject := fmt.Sprintf("通道「%s」（#%d）的密钥 #%d 已被禁用", channelName, channelId, keyIndex)
+		content := fmt.Sprintf("通道「%s」（#%d）的密钥 #%d 已被禁用，原因：%s", channelName, channelId, keyIndex, reason)
+		NotifyRootUser(fmt.Sprintf("%s_key_%d", formatNotifyType(channelId, common.ChannelStatusAutoDisabled), keyIndex), subject, content)
+	}
+}
+
+// CoolDownChannelKey 让触发限流的密钥暂时退出轮换
+func CoolDownChannelKey(channelId int, keyIndex int) {
+	seconds := operation_setting.GetChannelKeySetting().CooldownSeconds
+	if seconds <= 0 {
+		model.RecordChannelKeyFailure(channelId, keyIndex)
+		return
+	}
+	model.CoolDownChannelKey(channelId, keyIndex, time.Duration(seconds)*time.Second)
+}
+
+// ShouldCoolDownChannelKey 判断错误是否应当让当前密钥进入冷却
+func ShouldCoolDownChannelKey(err *dto.OpenAIErrorWithStatusCode) bool {
+	if err == nil || err.LocalError {
+		return false
+	}
+	return err.StatusCode == http.StatusTooManyRequests
+}
+
+// ShouldRecordChannelFailure 判断错误是否计入渠道熔断统计，客户端请求错误不计入
+func ShouldRecordChannelFailure(err *dto.OpenAIErrorWithStatusCode) bool {
+	if err == nil || err.LocalError {
+		return false
+	}
+	switch err.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return err.StatusCode/100 == 5
+}
+
 func ShouldDisableChannel(channelType int, err *dto.OpenAIErrorWithStatusCode) bool {
 	if !common.AutomaticDisableChannelEnabled {
 		return false
@@ -100,3 +160,16 @@ func ShouldEnableChannel(err error, openaiWithStatusErr *dto.OpenAIErrorWithStat
 	}
 	return true
 }
+
+// ObserveChannelLatency 记录本次渠道请求的首字时间，非流式请求使用完整耗时，用于自适应路由
+func ObserveChannelLatency(ctx *gin.Context, relayInfo *relaycommon.RelayInfo) {
+	start := ctx.GetTime(constant.ContextKeyChannelStartTime)
+	if start.IsZero() {
+		start = relayInfo.StartTime
+	}
+	end := time.Now()
+	if relayInfo.HasSendResponse() && relayInfo.FirstResponseTime.After(start) {
+		end = relayInfo.FirstResponseTime
+	}
+	model.RecordChannelLatency(relayInfo.ChannelId, relayInfo.OriginModelName, end.Sub(start))
+}
@@ -0,0 +1,718 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/model"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigDocumentVersion 当前配置文档的格式版本
+const ConfigDocumentVersion = 1
+
+const (
+	ConfigFormatYAML = "yaml"
+	ConfigFormatJSON = "json"
+)
+
+const (
+	ConfigChangeCreate    = "create"
+	ConfigChangeUpdate    = "update"
+	ConfigChangeUnchanged = "unchanged"
+)
+
+// configSecretPrefix 加密值的前缀，其后为 base64 编码的 AES-GCM 密文
+const configSecretPrefix = "enc:"
+
+// configSecretCheck 用于在导入时校验口令是否正确的明文
+const configSecretCheck = "new-api-config"
+
+// configGroupKeys 文档 groups 部分对应的配置项
+var configGroupKeys = []string{"GroupRatio", "GroupGroupRatio", "UserUsableGroups", "TopupGroupRatio"}
+
+// configRatioKeys 文档 ratios 部分对应的配置项
+var configRatioKeys = []string{"ModelRatio", "ModelPrice", "CompletionRatio", "CacheRatio"}
+
+// ConfigDocument 可在实例之间迁移的声明式配置，导入时只新增或更新文档中列出的渠道与配置项
+type ConfigDocument struct {
+	Version    int                    `json:"version" yaml:"version"`
+	ExportedAt int64                  `json:"exported_at,omitempty" yaml:"exported_at,omitempty"`
+	Secrets    *ConfigSecrets         `json:"secrets,omitempty" yaml:"secrets,omitempty"`
+	Channels   []*ConfigChannel       `json:"channels,omitempty" yaml:"channels,omitempty"`
+	Groups     map[string]interface{} `json:"groups,omitempty" yaml:"groups,omitempty"`
+	Ratios     map[string]interface{} `json:"ratios,omitempty" yaml:"ratios,omitempty"`
+	Options    map[string]string      `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// ConfigSecrets 加密密钥所用的算法与口令派生参数
+type ConfigSecrets struct {
+	Cipher string `json:"cipher" yaml:"cipher"`
+	Kdf    string `json:"kdf" yaml:"kdf"`
+	Salt   string `json:"salt" yaml:"salt"`
+	Check  string `json:"check" yaml:"check"`
+}
+
+// ConfigChannel 文档中的渠道，以名称或“标签/名称”作为稳定标识，导入时根据模型与分组重建 abilities，
+// 再用 Abilities 覆盖单个能力的启用状态、优先级与权重
+type ConfigChannel struct {
+	Name               string          `json:"name" yaml:"name"`
+	Tag                string          `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Type               int             `json:"type" yaml:"type"`
+	BusinessType       int             `json:"business_type" yaml:"business_type"`
+	Status             int             `json:"status" yaml:"status"`
+	Key                string          `json:"key,omitempty" yaml:"key,omitempty"`
+	BaseURL            string          `json:"base_url,omitempty" yaml:"base_url,omitempty"`
+	OpenAIOrganization string          `json:"openai_organization,omitempty" yaml:"openai_organization,omitempty"`
+	TestModel          string          `json:"test_model,omitempty" yaml:"test_model,omitempty"`
+	Models             string          `json:"models" yaml:"models"`
+	Group              string          `json:"group" yaml:"group"`
+	ModelMapping       string          `json:"model_mapping,omitempty" yaml:"model_mapping,omitempty"`
+	StatusCodeMapping  string          `json:"status_code_mapping,omitempty" yaml:"status_code_mapping,omitempty"`
+	Priority           int64           `json:"priority" yaml:"priority"`
+	Weight             uint            `json:"weight" yaml:"weight"`
+	AutoBan            int             `json:"auto_ban" yaml:"auto_ban"`
+	Other              string          `json:"other,omitempty" yaml:"other,omitempty"`
+	OtherInfo          string          `json:"other_info,omitempty" yaml:"other_info,omitempty"`
+	Setting            string          `json:"setting,omitempty" yaml:"setting,omitempty"`
+	ParamOverride      string          `json:"param_override,omitempty" yaml:"param_override,omitempty"`
+	MultiKeyMode       string          `json:"multi_key_mode,omitempty" yaml:"multi_key_mode,omitempty"`
+	Abilities          []ConfigAbility `json:"abilities,omitempty" yaml:"abilities,omitempty"`
+}
+
+type ConfigAbility struct {
+	Group    string `json:"group" yaml:"group"`
+	Model    string `json:"model" yaml:"model"`
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	Priority int64  `json:"priority" yaml:"priority"`
+	Weight   uint   `json:"weight" yaml:"weight"`
+}
+
+// secretFields 返回需要与密钥同样处理的字段，导出时加密或省略
+func (channel *ConfigChannel) secretFields() []*string {
+	return []*string{&channel.Key, &channel.OtherInfo, &channel.Setting}
+}
+
+// Identity 渠道在文档与数据库之间匹配所用的标识
+func (channel *ConfigChannel) Identity() string {
+	if channel.Tag != "" {
+		return channel.Tag + "/" + channel.Name
+	}
+	return channel.Name
+}
+
+type ConfigExportOptions struct {
+	// 是否导出渠道密钥、额外设置与敏感配置项，导出时使用 Passphrase 加密
+	IncludeSecrets bool
+	Passphrase     string
+}
+
+// ConfigChannelChange 导入计划中单个渠道的变更
+type ConfigChannelChange struct {
+	Name      string                                 `json:"name"`
+	Tag       string                                 `json:"tag,omitempty"`
+	Action    string                                 `json:"action"`
+	ChannelId int                                    `json:"channel_id,omitempty"`
+	Diff      map[string]model.AdminAuditFieldChange `json:"diff,omitempty"`
+
+	old       *model.Channel
+	channel   *model.Channel
+	abilities []model.Ability
+}
+
+// ConfigOptionChange 导入计划中单个配置项的变更，敏感配置项的值会被脱敏
+type ConfigOptionChange struct {
+	Key    string      `json:"key"`
+	Action string      `json:"action"`
+	Old    interface{} `json:"old"`
+	New    interface{} `json:"new"`
+
+	oldValue string
+	newValue string
+}
+
+// ConfigImportPlan 导入前计算出的变更，试运行时直接返回，正式导入时按计划写入
+type ConfigImportPlan struct {
+	Channels []*ConfigChannelChange `json:"channels"`
+	Options  []*ConfigOptionChange  `json:"options"`
+	Warnings []string               `json:"warnings"`
+	BatchId  string                 `json:"batch_id,omitempty"`
+}
+
+type configCipher struct {
+	key []byte
+}
+
+func (c *configCipher) encrypt(value string) (string, error) {
+	encrypted, err := common.EncryptAESGCM(c.key, value)
+	if err != nil {
+		return "", err
+	}
+	return configSecretPrefix + encrypted, nil
+}
+
+// decrypt 解密带前缀的值，未加密的值原样返回
+func (c *configCipher) decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, configSecretPrefix) {
+		return value, nil
+	}
+	if c == nil {
+		return "", errors.New("文档包含加密的密钥，请提供导出时使用的口令")
+	}
+	plaintext, err := common.DecryptAESGCM(c.key, strings.TrimPrefix(value, configSecretPrefix))
+	if err != nil {
+		return "", errors.New("解密失败，口令不正确或文档已损坏")
+	}
+	return plaintext, nil
+}
+
+func newConfigCipher(passphrase string) (*configCipher, *ConfigSecrets, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, err
+	}
+	key, err := common.DeriveKeyFromPassphrase(passphrase, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := &configCipher{key: key}
+	check, err := c.encrypt(configSecretCheck)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, &ConfigSecrets{
+		Cipher: "aes-256-gcm",
+		Kdf:    "scrypt",
+		Salt:   base64.StdEncoding.EncodeToString(salt),
+		Check:  check,
+	}, nil
+}
+
+func openConfigCipher(secrets *ConfigSecrets, passphrase string) (*configCipher, error) {
+	if secrets == nil || passphrase == "" {
+		return nil, nil
+	}
+	if secrets.Cipher != "aes-256-gcm" || secrets.Kdf != "scrypt" {
+		return nil, fmt.Errorf("不支持的加密方式：%s/%s", secrets.Cipher, secrets.Kdf)
+	}
+	salt, err := base64.StdEncoding.DecodeString(secrets.Salt)
+	if err != nil {
+		return nil, errors.New("文档中的 salt 无效")
+	}
+	key, err := common.DeriveKeyFromPassphrase(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	c := &configCipher{key: key}
+	if check, err := c.decrypt(secrets.Check); err != nil || check != configSecretCheck {
+		return nil, errors.New("口令不正确")
+	}
+	return c, nil
+}
+
+func toConfigChannel(channel *model.Channel) *ConfigChannel {
+	autoBan := 1
+	if channel.AutoBan != nil {
+		autoBan = *channel.AutoBan
+	}
+	return &ConfigChannel{
+		Name:               channel.Name,
+		Tag:                stringValue(channel.Tag),
+		Type:               channel.Type,
+		BusinessType:       channel.BusinessType,
+		Status:             channel.Status,
+		Key:                channel.Key,
+		BaseURL:            channel.GetBaseURL(),
+		OpenAIOrganization: stringValue(channel.OpenAIOrganization),
+		TestModel:          stringValue(channel.TestModel),
+		Models:             channel.Models,
+		Group:              channel.Group,
+		ModelMapping:       channel.GetModelMapping(),
+		StatusCodeMapping:  channel.GetStatusCodeMapping(),
+		Priority:           channel.GetPriority(),
+		Weight:             uint(channel.GetWeight()),
+		AutoBan:            autoBan,
+		Other:              channel.Other,
+		OtherInfo:          channel.OtherInfo,
+		Setting:            stringValue(channel.Setting),
+		ParamOverride:      stringValue(channel.ParamOverride),
+		MultiKeyMode:       channel.MultiKeyMode,
+	}
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func (channel *ConfigChannel) toChannel() *model.Channel {
+	return &model.Channel{
+		Type:               channel.Type,
+		BusinessType:       channel.BusinessType,
+		Key:                channel.Key,
+		OpenAIOrganization: common.GetPointer(channel.OpenAIOrganization),
+		TestModel:          common.GetPointer(channel.TestModel),
+		Status:             channel.Status,
+		Name:               channel.Name,
+		Weight:             common.GetPointer(channel.Weight),
+		CreatedTime:        common.GetTimestamp(),
+		BaseURL:            common.GetPointer(channel.BaseURL),
+		Other:              channel.Other,
+		Models:             channel.Models,
+		Group:              channel.Group,
+		ModelMapping:       common.GetPointer(channel.ModelMapping),
+		StatusCodeMapping:  common.GetPointer(channel.StatusCodeMapping),
+		Priority:           common.GetPointer(channel.Priority),
+		AutoBan:            common.GetPointer(channel.AutoBan),
+		OtherInfo:          channel.OtherInfo,
+		Tag:                common.GetPointer(channel.Tag),
+		Setting:            common.GetPointer(channel.Setting),
+		ParamOverride:      common.GetPointer(channel.ParamOverride),
+		MultiKeyMode:       channel.MultiKeyMode,
+	}
+}
+
+// normalizeConfigValue 将 YAML 或 JSON 解析出的值统一为 JSON 解码后的类型，便于比较
+func normalizeConfigValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
+
+// ExportConfig 导出渠道、abilities、分组与模型倍率以及系统配置项
+func ExportConfig(opts ConfigExportOptions) (*ConfigDocument, error) {
+	doc := &ConfigDocument{
+		Version:    ConfigDocumentVersion,
+		ExportedAt: common.GetTimestamp(),
+		Groups:     make(map[string]interface{}),
+		Ratios:     make(map[string]interface{}),
+		Options:    make(map[string]string),
+	}
+	var cipher *configCipher
+	if opts.IncludeSecrets {
+		if opts.Passphrase == "" {
+			return nil, errors.New("导出密钥时必须提供口令")
+		}
+		var err error
+		cipher, doc.Secrets, err = newConfigCipher(opts.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	channels, err := model.GetAllChannels(0, 0, true, false)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].Id < channels[j].Id
+	})
+	channelAbilities, err := getConfigAbilities()
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range channels {
+		configChannel := toConfigChannel(channel)
+		// 密钥与渠道额外设置（可能包含凭据）只在提供口令时加密导出
+		for _, field := range configChannel.secretFields() {
+			value := *field
+			*field = ""
+			if cipher != nil && value != "" {
+				if *field, err = cipher.encrypt(value); err != nil {
+					return nil, err
+				}
+			}
+		}
+		configChannel.Abilities = channelAbilities[channel.Id]
+		doc.Channels = append(doc.Channels, configChannel)
+	}
+
+	options := make(map[string]string)
+	common.OptionMapRWMutex.RLock()
+	for k, v := range common.OptionMap {
+		options[k] = v
+	}
+	common.OptionMapRWMutex.RUnlock()
+	for k, v := range options {
+		switch {
+		case slices.Contains(configGroupKeys, k), slices.Contains(configRatioKeys, k):
+			var value interface{}
+			if err = common.DecodeJsonStr(v, &value); err != nil {
+				return nil, fmt.Errorf("配置项 %s 不是有效的 JSON：%w", k, err)
+			}
+			if slices.Contains(configGroupKeys, k) {
+				doc.Groups[k] = value
+			} else {
+				doc.Ratios[k] = value
+			}
+		case model.IsSensitiveFieldName(k):
+			if cipher == nil || v == "" {
+				continue
+			}
+			if doc.Options[k], err = cipher.encrypt(v); err != nil {
+				return nil, err
+			}
+		default:
+			doc.Options[k] = v
+		}
+	}
+	return doc, nil
+}
+
+// MarshalConfigDocument 将配置文档编码为 YAML 或 JSON
+func MarshalConfigDocument(doc *ConfigDocument, format string) ([]byte, error) {
+	switch format {
+	case ConfigFormatJSON:
+		return json.MarshalIndent(doc, "", "  ")
+	case ConfigFormatYAML, "":
+		return yaml.Marshal(doc)
+	default:
+		return nil, fmt.Errorf("不支持的格式：%s", format)
+	}
+}
+
+// ParseConfigDocument 解析 YAML 或 JSON 格式的配置文档
+func ParseConfigDocument(data []byte) (*ConfigDocument, error) {
+	var doc ConfigDocument
+	var err error
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		err = json.Unmarshal(data, &doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("配置文档解析失败：%w", err)
+	}
+	if doc.Version <= 0 || doc.Version > ConfigDocumentVersion {
+		return nil, fmt.Errorf("不支持的配置文档版本：%d", doc.Version)
+	}
+	return &doc, nil
+}
+
+// PlanConfigImport 将文档与当前配置比较，计算需要创建或更新的渠道与配置项
+func PlanConfigImport(doc *ConfigDocument, passphrase string) (*ConfigImportPlan, error) {
+	cipher, err := openConfigCipher(doc.Secrets, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	plan := &ConfigImportPlan{
+		Channels: make([]*ConfigChannelChange, 0),
+		Options:  make([]*ConfigOptionChange, 0),
+		Warnings: make([]string, 0),
+	}
+	if err = planChannelImport(plan, doc, cipher); err != nil {
+		return nil, err
+	}
+	if err = planOptionImport(plan, doc, cipher); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func planChannelImport(plan *ConfigImportPlan, doc *ConfigDocument, cipher *configCipher) error {
+	existing, err := model.GetAllChannels(0, 0, true, false)
+	if err != nil {
+		return err
+	}
+	channelAbilities, err := getConfigAbilities()
+	if err != nil {
+		return err
+	}
+	existingByIdentity := make(map[string][]*model.Channel)
+	for _, channel := range existing {
+		identity := toConfigChannel(channel).Identity()
+		existingByIdentity[identity] = append(existingByIdentity[identity], channel)
+	}
+	seen := make(map[string]bool)
+	for _, configChannel := range doc.Channels {
+		if configChannel == nil || configChannel.Name == "" {
+			return errors.New("文档中的渠道必须有名称")
+		}
+		identity := configChannel.Identity()
+		if seen[identity] {
+			return fmt.Errorf("文档中渠道 %s 重复", identity)
+		}
+		seen[identity] = true
+		desired := *configChannel
+		for _, field := range desired.secretFields() {
+			if *field, err = cipher.decrypt(*field); err != nil {
+				return err
+			}
+		}
+		if desired.Status == 0 {
+			desired.Status = common.ChannelStatusEnabled
+		}
+		if desired.BusinessType == 0 {
+			desired.BusinessType = 1
+		}
+		if desired.Group == "" {
+			desired.Group = "default"
+		}
+		var warnings []string
+		desired.Abilities, warnings = normalizeConfigAbilities(&desired)
+		plan.Warnings = append(plan.Warnings, warnings...)
+		change := &ConfigChannelChange{Name: desired.Name, Tag: desired.Tag, abilities: desired.toAbilities()}
+		matches := existingByIdentity[identity]
+		switch len(matches) {
+		case 0:
+			change.Action = ConfigChangeCreate
+			if desired.Key == "" {
+				desired.Status = common.ChannelStatusManuallyDisabled
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("新渠道 %s 没有密钥，将以禁用状态创建", identity))
+			}
+			change.channel = desired.toChannel()
+			change.Diff = model.BuildAdminAuditDiff(nil, &desired)
+		case 1:
+			old := matches[0]
+			change.ChannelId = old.Id
+			current := toConfigChannel(old)
+			if len(desired.Abilities) > 0 {
+				current.Abilities = channelAbilities[old.Id]
+			}
+			if desired.Key == "" {
+				current.Key = ""
+				if old.Key == "" && desired.Status == common.ChannelStatusEnabled {
+					desired.Status = common.ChannelStatusManuallyDisabled
+					plan.Warnings = append(plan.Warnings, fmt.Sprintf("渠道 %s 没有密钥，保持禁用状态", identity))
+				}
+			}
+			// 未导出的额外设置保留已有值
+			if desired.OtherInfo == "" {
+				desired.OtherInfo = current.OtherInfo
+			}
+			if desired.Setting == "" {
+				desired.Setting = current.Setting
+			}
+			change.Diff = model.BuildAdminAuditDiff(current, &desired)
+			if len(change.Diff) == 0 {
+				change.Action = ConfigChangeUnchanged
+				change.Diff = nil
+				break
+			}
+			change.Action = ConfigChangeUpdate
+			change.old = old
+			change.channel = desired.toChannel()
+			change.channel.Id = old.Id
+			change.channel.CreatedTime = old.CreatedTime
+		default:
+			return fmt.Errorf("渠道 %s 匹配到多个已有渠道，请先修改名称或标签使其唯一", identity)
+		}
+		plan.Channels = append(plan.Channels, change)
+	}
+	return nil
+}
+
+// getConfigAbilities 按渠道分组读取所有 abilities，组内按分组与模型排序
+func getConfigAbilities() (map[int][]ConfigAbility, error) {
+	abilities, err := model.GetAllAbilities()
+	if err != nil {
+		return nil, err
+	}
+	channelAbilities := make(map[int][]ConfigAbility)
+	for _, ability := range abilities {
+		priority := int64(0)
+		if ability.Priority != nil {
+			priority = *ability.Priority
+		}
+		channelAbilities[ability.ChannelId] = append(channelAbilities[ability.ChannelId], ConfigAbility{
+			Group:    ability.Group,
+			Model:    ability.Model,
+			Enabled:  ability.Enabled,
+			Priority: priority,
+			Weight:   ability.Weight,
+		})
+	}
+	for _, abilities := range channelAbilities {
+		sortConfigAbilities(abilities)
+	}
+	return channelAbilities, nil
+}
+
+func sortConfigAbilities(abilities []ConfigAbility) {
+	sort.Slice(abilities, func(i, j int) bool {
+		if abilities[i].Group != abilities[j].Group {
+			return abilities[i].Group < abilities[j].Group
+		}
+		return abilities[i].Model < abilities[j].Model
+	})
+}
+
+// normalizeConfigAbilities 去掉不在渠道模型与分组范围内或重复的能力并排序，返回被忽略能力的提示
+func normalizeConfigAbilities(channel *ConfigChannel) ([]ConfigAbility, []string) {
+	if len(channel.Abilities) == 0 {
+		return nil, nil
+	}
+	valid := make(map[string]bool)
+	for _, modelName := range strings.Split(channel.Models, ",") {
+		for _, group := range strings.Split(channel.Group, ",") {
+			valid[group+"|"+modelName] = true
+		}
+	}
+	seen := make(map[string]bool)
+	abilities := make([]ConfigAbility, 0, len(channel.Abilities))
+	var warnings []string
+	for _, ability := range channel.Abilities {
+		key := ability.Group + "|" + ability.Model
+		if !valid[key] {
+			warnings = append(warnings, fmt.Sprintf("渠道 %s 的能力 %s/%s 不在模型与分组范围内，已忽略", channel.Identity(), ability.Group, ability.Model))
+			continue
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		abilities = append(abilities, ability)
+	}
+	sortConfigAbilities(abilities)
+	return abilities, warnings
+}
+
+// toAbilities 转换为导入时需要覆盖的 abilities
+func (channel *ConfigChannel) toAbilities() []model.Ability {
+	if len(channel.Abilities) == 0 {
+		return nil
+	}
+	abilities := make([]model.Ability, 0, len(channel.Abilities))
+	for _, ability := range channel.Abilities {
+		priority := ability.Priority
+		abilities = append(abilities, model.Ability{
+			Group:    ability.Group,
+			Model:    ability.Model,
+			Enabled:  ability.Enabled,
+			Priority: &priority,
+			Weight:   ability.Weight,
+		})
+	}
+	return abilities
+}
+
+func planOptionImport(plan *ConfigImportPlan, doc *ConfigDocument, cipher *configCipher) error {
+	current := make(map[string]string)
+	common.OptionMapRWMutex.RLock()
+	for k, v := range common.OptionMap {
+		current[k] = v
+	}
+	common.OptionMapRWMutex.RUnlock()
+
+	desired := make(map[string]string)
+	for section, values := range map[string]map[string]interface{}{"groups": doc.Groups, "ratios": doc.Ratios} {
+		keys := configGroupKeys
+		if section == "ratios" {
+			keys = configRatioKeys
+		}
+		for k, v := range values {
+			if !slices.Contains(keys, k) {
+				return fmt.Errorf("%s 中不支持的配置项：%s", section, k)
+			}
+			normalized, err := normalizeConfigValue(v)
+			if err != nil {
+				return err
+			}
+			var old interface{}
+			if err = common.DecodeJsonStr(current[k], &old); err == nil && reflect.DeepEqual(old, normalized) {
+				continue
+			}
+			data, err := json.Marshal(normalized)
+			if err != nil {
+				return err
+			}
+			desired[k] = string(data)
+		}
+	}
+	for k, v := range doc.Options {
+		if _, ok := current[k]; !ok {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("未知的配置项 %s，已跳过", k))
+			continue
+		}
+		if _, ok := desired[k]; ok {
+			continue
+		}
+		value, err := cipher.decrypt(v)
+		if err != nil {
+			return err
+		}
+		if value != current[k] {
+			desired[k] = value
+		}
+	}
+
+	keys := make([]string, 0, len(desired))
+	for k := range desired {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		diff := model.BuildAdminAuditDiff(map[string]interface{}{k: current[k]}, map[string]interface{}{k: desired[k]})
+		plan.Options = append(plan.Options, &ConfigOptionChange{
+			Key:      k,
+			Action:   ConfigChangeUpdate,
+			Old:      diff[k].Old,
+			New:      diff[k].New,
+			oldValue: current[k],
+			newValue: desired[k],
+		})
+	}
+	return nil
+}
+
+// ApplyConfigImport 按导入计划在同一事务中写入渠道与配置项，并为渠道记录导入版本
+func ApplyConfigImport(plan *ConfigImportPlan, operatorId int, operatorName string) error {
+	imports := make([]model.ChannelImport, 0)
+	for _, change := range plan.Channels {
+		if change.Action != ConfigChangeUnchanged {
+			imports = append(imports, model.ChannelImport{Channel: change.channel, Abilities: change.abilities})
+		}
+	}
+	options := make([]model.Option, 0, len(plan.Options))
+	for _, change := range plan.Options {
+		options = append(options, model.Option{Key: change.Key, Value: change.newValue})
+	}
+	if len(imports) == 0 && len(options) == 0 {
+		return nil
+	}
+	if len(imports) > 0 {
+		plan.BatchId = fmt.Sprintf("import-%d-%s", common.GetTimestamp(), common.GetRandomString(8))
+	}
+	if err := model.ImportConfig(imports, options, plan.BatchId, operatorId, operatorName); err != nil {
+		return err
+	}
+	for _, change := range plan.Channels {
+		if change.channel != nil {
+			change.ChannelId = change.channel.Id
+		}
+	}
+	return nil
+}
+
+// RecordConfigImportAudit 为导入写入的每个渠道与配置项记录管理操作审计
+func RecordConfigImportAudit(c *gin.Context, plan *ConfigImportPlan) {
+	for _, change := range plan.Channels {
+		switch change.Action {
+		case ConfigChangeCreate:
+			model.RecordAdminAudit(c, model.AdminAuditActionCreate, model.AdminAuditEntityChannel, strconv.Itoa(change.ChannelId), nil, change.channel)
+		case ConfigChangeUpdate:
+			model.RecordAdminAudit(c, model.AdminAuditActionUpdate, model.AdminAuditEntityChannel, strconv.Itoa(change.ChannelId), change.old, change.channel)
+		}
+	}
+	for _, change := range plan.Options {
+		model.RecordAdminAudit(c, model.AdminAuditActionUpdate, model.AdminAuditEntityOption, change.Key,
+			map[string]interface{}{change.Key: change.oldValue}, map[string]interface{}{change.Key: change.newValue})
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"one-api/setting/operation_setting"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStore 保存 /v1/files 上传的文件以及批处理生成的结果文件
+type FileStore interface {
+	// Save 写入文件并返回写入的字节数，超过 maxBytes 时返回错误（maxBytes <= 0 表示不限制）
+	Save(name string, reader io.Reader, maxBytes int64) (int64, error)
+	Open(name string) (io.ReadCloser, error)
+	Delete(name string) error
+}
+
+var ErrFileTooLarge = errors.New("file is too large")
+
+type localFileStore struct {
+	dir string
+}
+
+func (s *localFileStore) path(name string) (string, error) {
+	if name == "" || strings.Contains(name, "..") || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid file name: %s", name)
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+func (s *localFileStore) Save(name string, reader io.Reader, maxBytes int64) (int64, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return 0, err
+	}
+	if err = os.MkdirAll(s.dir, 0755); err != nil {
+		return 0, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	if maxBytes > 0 {
+		reader = io.LimitReader(reader, maxBytes+1)
+	}
+	n, err := io.Copy(file, reader)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil && maxBytes > 0 && n > maxBytes {
+		err = ErrFileTooLarge
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s *localFileStore) Open(name string) (io.ReadCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (s *localFileStore) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// GetFileStore 根据配置返回文件存储
+func GetFileStore() (FileStore, error) {
+	setting := operation_setting.GetBatchSetting()
+	switch setting.StorageType {
+	case "", "local":
+		return &localFileStore{dir: setting.StorageDir}, nil
+	default:
+		return nil, fmt.Errorf("unsupported file storage type: %s", setting.StorageType)
+	}
+}
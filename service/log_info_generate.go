@@ -3,6 +3,7 @@ package service
 import (
 	"one-api/dto"
 	relaycommon "one-api/relay/common"
+	"one-api/setting/operation_setting"
 
 	"github.com/gin-gonic/gin"
 )
@@ -25,6 +26,10 @@ func GenerateTextOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, m
 		other["is_model_mapped"] = true
 		other["upstream_model_name"] = relayInfo.UpstreamModelName
 	}
+	if relayInfo.BatchId != "" {
+		other["batch_id"] = relayInfo.BatchId
+		other["batch_discount_ratio"] = operation_setting.GetBatchSetting().DiscountRatio
+	}
 	adminInfo := make(map[string]interface{})
 	adminInfo["use_channel"] = ctx.GetStringSlice("use_channel")
 	other["admin_info"] = adminInfo
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LogRetentionStatus 日志清理任务的执行进度
+type LogRetentionStatus struct {
+	Running      bool             `json:"running"`
+	StartedAt    int64            `json:"started_at"`
+	FinishedAt   int64            `json:"finished_at"`
+	CurrentType  string           `json:"current_type"`
+	Deleted      map[string]int64 `json:"deleted"`
+	ArchiveFiles []string         `json:"archive_files"`
+	LastError    string           `json:"last_error"`
+}
+
+var ErrLogRetentionRunning = errors.New("log retention is already running")
+
+var (
+	logRetentionStatus = LogRetentionStatus{Deleted: map[string]int64{}}
+	logRetentionLock   sync.Mutex
+)
+
+type logRetentionType struct {
+	logType int
+	name    string
+	days    int
+}
+
+func getLogRetentionTypes() []logRetentionType {
+	setting := operation_setting.GetLogRetentionSetting()
+	return []logRetentionType{
+		{model.LogTypeConsume, "consume", setting.ConsumeDays},
+		{model.LogTypeError, "error", setting.ErrorDays},
+		{model.LogTypeManage, "manage", setting.ManageDays},
+		{model.LogTypeSystem, "system", setting.SystemDays},
+		{model.LogTypeTopup, "topup", setting.TopupDays},
+	}
+}
+
+// GetLogRetentionStatus 返回最近一次日志清理的进度
+func GetLogRetentionStatus() LogRetentionStatus {
+	logRetentionLock.Lock()
+	defer logRetentionLock.Unlock()
+	status := logRetentionStatus
+	status.Deleted = make(map[string]int64, len(logRetentionStatus.Deleted))
+	for k, v := range logRetentionStatus.Deleted {
+		status.Deleted[k] = v
+	}
+	status.ArchiveFiles = append([]string(nil), logRetentionStatus.ArchiveFiles...)
+	return status
+}
+
+func updateLogRetentionStatus(fn func(status *LogRetentionStatus)) {
+	logRetentionLock.Lock()
+	defer logRetentionLock.Unlock()
+	fn(&logRetentionStatus)
+}
+
+// logArchiveWriter 将日志写入 gzip 压缩的 NDJSON 文件，已存在的同名文件会被覆盖
+type logArchiveWriter struct {
+	file   *os.File
+	gzip   *gzip.Writer
+	writer *bufio.Writer
+}
+
+func newLogArchiveWriter(path string) (*logArchiveWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(file)
+	return &logArchiveWriter{file: file, gzip: gz, writer: bufio.NewWriter(gz)}, nil
+}
+
+// Write 写入日志并落盘，保证删除前归档已写入文件
+func (w *logArchiveWriter) Write(logs []*model.Log) error {
+	for _, log := range logs {
+		data, err := common.EncodeJson(model.LogExportRecord{Log: log, Other: common.StrToMap(log.Other)})
+		if err != nil {
+			return err
+		}
+		_, _ = w.writer.Write(data)
+		_ = w.writer.WriteByte('\n')
+	}
+	if err := w.writer.Flush(); err != nil {
+		return err
+	}
+	if err := w.gzip.Flush(); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+func (w *logArchiveWriter) Close() error {
+	_ = w.writer.Flush()
+	if err := w.gzip.Close(); err != nil {
+		_ = w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// RunLogRetention 按保留天数清理各类型日志。每批日志在同一事务中汇总并删除，
+// 中断后再次执行会从剩余的过期日志继续。开启归档时每批日志单独写入一个以首条日志 id 命名的文件，
+// 归档后删除失败的批次重试时会覆盖同一文件，不会重复归档
+func RunLogRetention(ctx context.Context) error {
+	logRetentionLock.Lock()
+	if logRetentionStatus.Running {
+		logRetentionLock.Unlock()
+		return ErrLogRetentionRunning
+	}
+	startedAt := time.Now()
+	logRetentionStatus = LogRetentionStatus{
+		Running:   true,
+		StartedAt: startedAt.Unix(),
+		Deleted:   map[string]int64{},
+	}
+	logRetentionLock.Unlock()
+
+	err := runLogRetention(ctx, startedAt)
+	updateLogRetentionStatus(func(status *LogRetentionStatus) {
+		status.Running = false
+		status.CurrentType = ""
+		status.FinishedAt = time.Now().Unix()
+		if err != nil {
+			status.LastError = err.Error()
+		}
+	})
+	if err != nil {
+		common.SysError("log retention failed: " + err.Error())
+	}
+	return err
+}
+
+func runLogRetention(ctx context.Context, startedAt time.Time) error {
+	setting := operation_setting.GetLogRetentionSetting()
+	batchSize := setting.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	for _, t := range getLogRetentionTypes() {
+		if t.days <= 0 {
+			continue
+		}
+		updateLogRetentionStatus(func(status *LogRetentionStatus) {
+			status.CurrentType = t.name
+		})
+		cutoff := startedAt.AddDate(0, 0, -t.days).Unix()
+		var total int64
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			logs, err := model.GetExpiredLogs(t.logType, cutoff, batchSize)
+			if err != nil {
+				return err
+			}
+			if len(logs) == 0 {
+				break
+			}
+			if setting.ArchiveEnabled {
+				path := filepath.Join(setting.ArchiveDir, fmt.Sprintf("logs-%s-%d.ndjson.gz", t.name, logs[0].Id))
+				if err = writeLogArchive(path, logs); err != nil {
+					return err
+				}
+				updateLogRetentionStatus(func(status *LogRetentionStatus) {
+					status.ArchiveFiles = append(status.ArchiveFiles, path)
+				})
+			}
+			if err = model.RollupAndDeleteLogs(logs); err != nil {
+				return err
+			}
+			total += int64(len(logs))
+			updateLogRetentionStatus(func(status *LogRetentionStatus) {
+				status.Deleted[t.name] = total
+			})
+			if len(logs) < batchSize {
+				break
+			}
+		}
+		if total > 0 {
+			common.SysLog(fmt.Sprintf("log retention: deleted %d %s logs older than %d days", total, t.name, t.days))
+		}
+	}
+	return nil
+}
+
+func writeLogArchive(path string, logs []*model.Log) error {
+	archive, err := newLogArchiveWriter(path)
+	if err != nil {
+		return err
+	}
+	if err = archive.Write(logs); err != nil {
+		_ = archive.Close()
+		return err
+	}
+	return archive.Close()
+}
+
+// logRetentionLastRunDayKey 保存最近一次完成清理的日期，重启后当天不会重复执行
+const logRetentionLastRunDayKey = "log_retention_setting.last_run_day"
+
+// StartLogRetentionTask 每天在配置的时间执行一次日志清理
+func StartLogRetentionTask() {
+	for {
+		time.Sleep(time.Minute)
+		runScheduledLogRetention(context.Background(), time.Now())
+	}
+}
+
+// runScheduledLogRetention 到达执行时间且当天尚未执行时清理日志，并将日期保存到配置项
+func runScheduledLogRetention(ctx context.Context, now time.Time) {
+	setting := operation_setting.GetLogRetentionSetting()
+	today := now.Format("2006-01-02")
+	if !setting.Enabled || now.Hour() < setting.RunHour || setting.LastRunDay == today {
+		return
+	}
+	if err := RunLogRetention(ctx); errors.Is(err, ErrLogRetentionRunning) {
+		return
+	}
+	if err := model.UpdateOption(logRetentionLastRunDayKey, today); err != nil {
+		common.SysError("failed to save log retention run day: " + err.Error())
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readLogArchive 读取 gzip NDJSON 归档文件，返回其中的日志 id
+func readLogArchive(t *testing.T, path string) []int {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	var ids []int
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		ids = append(ids, int(record["id"].(float64)))
+	}
+	require.NoError(t, scanner.Err())
+	return ids
+}
+
+// TestRunLogRetention 测试过期日志分批归档、汇总并删除，未过期日志和未配置保留天数的类型不受影响
+func TestRunLogRetention(t *testing.T) {
+	setupTestDB(t)
+	setting := operation_setting.GetLogRetentionSetting()
+	saved := *setting
+	setting.ConsumeDays = 30
+	setting.ErrorDays = 0
+	setting.ArchiveEnabled = true
+	setting.ArchiveDir = t.TempDir()
+	setting.BatchSize = 2
+	t.Cleanup(func() {
+		*setting = saved
+	})
+
+	old := time.Now().AddDate(0, 0, -31).Unix()
+	logs := []*model.Log{
+		{UserId: 1, Username: "alice", CreatedAt: old, Type: model.LogTypeConsume, ModelName: "gpt-4o", Quota: 1, Other: `{"cache_tokens":1}`},
+		{UserId: 1, Username: "alice", CreatedAt: old, Type: model.LogTypeConsume, ModelName: "gpt-4o", Quota: 2},
+		{UserId: 1, Username: "alice", CreatedAt: old, Type: model.LogTypeConsume, ModelName: "gpt-4o", Quota: 3},
+		{UserId: 1, Username: "alice", CreatedAt: time.Now().Unix(), Type: model.LogTypeConsume, ModelName: "gpt-4o", Quota: 4},
+		{UserId: 1, Username: "alice", CreatedAt: old, Type: model.LogTypeError, ModelName: "gpt-4o"},
+	}
+	require.NoError(t, model.LOG_DB.Create(&logs).Error)
+
+	require.NoError(t, RunLogRetention(context.Background()))
+
+	var remaining []*model.Log
+	require.NoError(t, model.LOG_DB.Order("id").Find(&remaining).Error)
+	require.Len(t, remaining, 2)
+	assert.Equal(t, logs[3].Id, remaining[0].Id)
+	assert.Equal(t, logs[4].Id, remaining[1].Id)
+
+	stats, _, err := model.GetLogDailyStats(model.LogTypeConsume, "", "", "alice", "", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 3, stats[0].RequestCount)
+	assert.Equal(t, 6, stats[0].Quota)
+
+	status := GetLogRetentionStatus()
+	assert.False(t, status.Running)
+	assert.Empty(t, status.LastError)
+	assert.Equal(t, int64(3), status.Deleted["consume"])
+	require.Len(t, status.ArchiveFiles, 2)
+	assert.Equal(t, []int{logs[0].Id, logs[1].Id}, readLogArchive(t, status.ArchiveFiles[0]))
+	assert.Equal(t, []int{logs[2].Id}, readLogArchive(t, status.ArchiveFiles[1]))
+}
+
+// TestRunLogRetentionRetry 测试归档后删除失败的批次重试时覆盖同一归档文件，不会重复归档
+func TestRunLogRetentionRetry(t *testing.T) {
+	setupTestDB(t)
+	setting := operation_setting.GetLogRetentionSetting()
+	saved := *setting
+	setting.ConsumeDays = 30
+	setting.ArchiveEnabled = true
+	setting.ArchiveDir = t.TempDir()
+	setting.BatchSize = 10
+	t.Cleanup(func() {
+		*setting = saved
+	})
+
+	old := time.Now().AddDate(0, 0, -31).Unix()
+	logs := []*model.Log{
+		{UserId: 1, Username: "alice", CreatedAt: old, Type: model.LogTypeConsume, ModelName: "gpt-4o", Quota: 1},
+		{UserId: 1, Username: "alice", CreatedAt: old, Type: model.LogTypeConsume, ModelName: "gpt-4o", Quota: 2},
+	}
+	require.NoError(t, model.LOG_DB.Create(&logs).Error)
+	// 模拟上一次执行归档了更多日志后删除失败
+	path := filepath.Join(setting.ArchiveDir, fmt.Sprintf("logs-consume-%d.ndjson.gz", logs[0].Id))
+	require.NoError(t, writeLogArchive(path, []*model.Log{logs[0], logs[1], {Id: logs[1].Id + 1}}))
+
+	require.NoError(t, RunLogRetention(context.Background()))
+	status := GetLogRetentionStatus()
+	assert.Equal(t, []string{path}, status.ArchiveFiles)
+	assert.Equal(t, []int{logs[0].Id, logs[1].Id}, readLogArchive(t, path))
+	files, err := os.ReadDir(setting.ArchiveDir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+// TestRunScheduledLogRetention 测试清理完成后保存执行日期，同一天内不再重复执行
+func TestRunScheduledLogRetention(t *testing.T) {
+	setupTestDB(t)
+	useConfigOptions(t, map[string]string{})
+	setting := operation_setting.GetLogRetentionSetting()
+	saved := *setting
+	setting.Enabled = true
+	setting.RunHour = 3
+	setting.ConsumeDays = 30
+	setting.LastRunDay = ""
+	t.Cleanup(func() {
+		*setting = saved
+	})
+	now := time.Date(2026, 1, 2, 4, 0, 0, 0, time.Local)
+
+	runScheduledLogRetention(context.Background(), now.Add(-2*time.Hour))
+	assert.Empty(t, setting.LastRunDay)
+	runScheduledLogRetention(context.Background(), now)
+	assert.Equal(t, "2026-01-02", setting.LastRunDay)
+	option := model.Option{Key: logRetentionLastRunDayKey}
+	require.NoError(t, model.DB.First(&option).Error)
+	assert.Equal(t, "2026-01-02", option.Value)
+
+	finishedAt := GetLogRetentionStatus().FinishedAt
+	time.Sleep(time.Second)
+	runScheduledLogRetention(context.Background(), now)
+	assert.Equal(t, finishedAt, GetLogRetentionStatus().FinishedAt)
+}
+
+// TestRunLogRetentionRunning 测试清理任务执行中时再次触发返回 ErrLogRetentionRunning
+func TestRunLogRetentionRunning(t *testing.T) {
+	updateLogRetentionStatus(func(status *LogRetentionStatus) {
+		status.Running = true
+	})
+	t.Cleanup(func() {
+		updateLogRetentionStatus(func(status *LogRetentionStatus) {
+			status.Running = false
+		})
+	})
+	assert.ErrorIs(t, RunLogRetention(context.Background()), ErrLogRetentionRunning)
+}
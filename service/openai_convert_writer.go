@@ -0,0 +1,95 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"one-api/common"
+	"one-api/dto"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAIConvertWriter 截获渠道写给客户端的 OpenAI Chat Completions 格式响应，用于转换为其他入站格式：
+// 流式响应逐个事件交给 OnStreamResponse 处理，非流式响应缓存到 Body 中由调用方转换后通过 WriteBody 写出
+type OpenAIConvertWriter struct {
+	gin.ResponseWriter
+	Stream           bool
+	OnStreamResponse func(streamResponse *dto.ChatCompletionsStreamResponse)
+	DropKeepAlive    bool         // NDJSON 等不支持注释行的格式丢弃保活注释
+	buffer           bytes.Buffer // 非流式为完整响应体，流式为尚未处理的不完整行
+}
+
+func NewOpenAIConvertWriter(writer gin.ResponseWriter, stream bool) *OpenAIConvertWriter {
+	return &OpenAIConvertWriter{ResponseWriter: writer, Stream: stream}
+}
+
+func (w *OpenAIConvertWriter) Write(data []byte) (int, error) {
+	w.buffer.Write(data)
+	if w.Stream {
+		w.processLines()
+	}
+	return len(data), nil
+}
+
+func (w *OpenAIConvertWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *OpenAIConvertWriter) processLines() {
+	for {
+		index := bytes.IndexByte(w.buffer.Bytes(), '\n')
+		if index < 0 {
+			return
+		}
+		line := strings.TrimSpace(string(w.buffer.Next(index + 1)))
+		if strings.HasPrefix(line, ":") {
+			// 保活注释原样转发
+			if !w.DropKeepAlive {
+				_, _ = w.ResponseWriter.WriteString(line + "\n\n")
+			}
+			continue
+		}
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+		var streamResponse dto.ChatCompletionsStreamResponse
+		if err := json.Unmarshal([]byte(data), &streamResponse); err != nil {
+			common.SysError("error unmarshalling openai stream response: " + err.Error())
+			continue
+		}
+		if w.OnStreamResponse != nil {
+			w.OnStreamResponse(&streamResponse)
+		}
+	}
+}
+
+// WriteEvent 向客户端写出一个转换后的 SSE 事件，event 为空时只写 data 行
+func (w *OpenAIConvertWriter) WriteEvent(event string, object any) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		common.SysError("error marshalling stream response: " + err.Error())
+		return
+	}
+	if event != "" {
+		_, _ = w.ResponseWriter.WriteString("event: " + event + "\n")
+	}
+	_, _ = w.ResponseWriter.WriteString("data: " + string(data) + "\n\n")
+	w.ResponseWriter.Flush()
+}
+
+// Body 返回缓存的非流式响应体
+func (w *OpenAIConvertWriter) Body() []byte {
+	return w.buffer.Bytes()
+}
+
+// WriteBody 向客户端写出转换后的非流式响应体
+func (w *OpenAIConvertWriter) WriteBody(body []byte) {
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.ResponseWriter.Write(body)
+}
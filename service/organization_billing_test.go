@@ -0,0 +1,42 @@
+package service
+
+import (
+	"one-api/model"
+	relaycommon "one-api/relay/common"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOrganizationBillingPath 测试组织令牌的请求从组织额度池扣费，不影响成员自己的额度
+func TestOrganizationBillingPath(t *testing.T) {
+	setupTestDB(t)
+	token := createTestToken(t, &model.Token{Id: 96002, UserId: 1, Key: "orgbillingkey", RemainQuota: 1000}, 1000)
+	org, err := model.CreateOrganization("org", token.UserId)
+	require.NoError(t, err)
+	require.NoError(t, model.IncreaseOrganizationQuota(org.Id, 500))
+	info := &relaycommon.RelayInfo{UserId: token.UserId, TokenId: token.Id, TokenKey: token.Key, OrgId: org.Id}
+
+	quota, err := GetBillingQuota(info)
+	require.NoError(t, err)
+	assert.Equal(t, 500, quota)
+	require.NoError(t, PostConsumeQuota(info, 100, 0, false))
+	require.NoError(t, PostConsumeQuota(info, -30, 0, false))
+
+	org, err = model.GetOrganizationById(org.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 430, org.Quota)
+	assert.Equal(t, 70, org.UsedQuota)
+	userQuota, err := model.GetUserQuota(token.UserId, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, userQuota)
+	token, err = model.GetTokenById(token.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 930, token.RemainQuota)
+
+	info.OrgId = 0
+	quota, err = GetBillingQuota(info)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, quota)
+}
@@ -79,7 +79,7 @@ func PreWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usag
 	if relayInfo.UsePrice {
 		return nil
 	}
-	userQuota, err := model.GetUserQuota(relayInfo.UserId, false)
+	userQuota, err := GetBillingQuota(relayInfo)
 	if err != nil {
 		return err
 	}
@@ -146,6 +146,9 @@ func PreWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usag
 func PostWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, modelName string,
 	usage *dto.RealtimeUsage, preConsumedQuota int, userQuota int, priceData helper.PriceData, extraContent string) {
 
+	ReconcileTokenRateLimit(relayInfo, usage.InputTokens+usage.OutputTokens)
+	RecordTokenModelUsage(relayInfo, usage.InputTokens+usage.OutputTokens)
+	ObserveChannelLatency(ctx, relayInfo)
 	useTimeSeconds := time.Now().Unix() - relayInfo.StartTime.Unix()
 	textInputTokens := usage.InputTokenDetails.TextTokens
 	textOutTokens := usage.OutputTokenDetails.TextTokens
@@ -215,6 +218,9 @@ func PostWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, mod
 func PostClaudeConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 	usage *dto.Usage, preConsumedQuota int, userQuota int, priceData helper.PriceData, extraContent string) {
 
+	ReconcileTokenRateLimit(relayInfo, usage.PromptTokens+usage.CompletionTokens)
+	RecordTokenModelUsage(relayInfo, usage.PromptTokens+usage.CompletionTokens)
+	ObserveChannelLatency(ctx, relayInfo)
 	useTimeSeconds := time.Now().Unix() - relayInfo.StartTime.Unix()
 	promptTokens := usage.PromptTokens
 	completionTokens := usage.CompletionTokens
@@ -281,6 +287,9 @@ func PostClaudeConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 	usage *dto.Usage, preConsumedQuota int, userQuota int, priceData helper.PriceData, extraContent string) {
 
+	ReconcileTokenRateLimit(relayInfo, usage.PromptTokens+usage.CompletionTokens)
+	RecordTokenModelUsage(relayInfo, usage.PromptTokens+usage.CompletionTokens)
+	ObserveChannelLatency(ctx, relayInfo)
 	useTimeSeconds := time.Now().Unix() - relayInfo.StartTime.Unix()
 	textInputTokens := usage.PromptTokensDetails.TextTokens
 	textOutTokens := usage.CompletionTokenDetails.TextTokens
@@ -372,20 +381,100 @@ func PreConsumeTokenQuota(relayInfo *relaycommon.RelayInfo, quota int) error {
 	if !relayInfo.TokenUnlimited && token.RemainQuota < quota {
 		return fmt.Errorf("token quota is not enough, token remain quota: %s, need quota: %s", common.FormatQuota(token.RemainQuota), common.FormatQuota(quota))
 	}
+	err = checkTokenBudget(relayInfo, token, quota)
+	if err != nil {
+		return err
+	}
+	if relayInfo.TokenModelCapped {
+		err = model.CheckTokenModelTokens(token, relayInfo.OriginModelName, relayInfo.PromptTokens)
+		if err != nil {
+			var quotaErr *model.TokenModelQuotaExceededError
+			if errors.As(err, &quotaErr) {
+				return err
+			}
+			common.SysError(fmt.Sprintf("failed to check model quota of token %d: %s", relayInfo.TokenId, err.Error()))
+		}
+	}
+	if quota == 0 {
+		return nil
+	}
 	err = model.DecreaseTokenQuota(relayInfo.TokenId, relayInfo.TokenKey, quota)
 	if err != nil {
 		return err
 	}
+	updateTokenBudgetUsage(relayInfo, quota)
 	return nil
 }
 
-func PostConsumeQuota(relayInfo *relaycommon.RelayInfo, quota int, preConsumedQuota int, sendEmail bool) (err error) {
+// RecordTokenModelUsage 记录令牌在当前模型上使用的 token 数
+func RecordTokenModelUsage(relayInfo *relaycommon.RelayInfo, tokens int) {
+	if !relayInfo.TokenModelCapped || tokens == 0 {
+		return
+	}
+	err := model.IncreaseTokenModelTokenUsage(relayInfo.TokenId, relayInfo.OriginModelName, tokens)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to update model usage of token %d: %s", relayInfo.TokenId, err.Error()))
+	}
+}
 
+// updateTokenBudgetUsage 记录令牌在预算窗口内的消耗
+func checkTokenBudget(relayInfo *relaycommon.RelayInfo, token *model.Token, quota int) error {
+	err := model.CheckTokenBudget(token, quota)
+	if err != nil {
+		var budgetErr *model.TokenBudgetExceededError
+		if errors.As(err, &budgetErr) {
+			return err
+		}
+		// 预算计数不可用时不阻塞请求
+		common.SysError(fmt.Sprintf("failed to check budget of token %d: %s", relayInfo.TokenId, err.Error()))
+	}
+	return nil
+}
+
+// CheckTokenBudgetQuota 检查令牌预算是否足够支付 quota，用于成功后才扣费、不经过预扣费的 Midjourney 与异步任务
+func CheckTokenBudgetQuota(relayInfo *relaycommon.RelayInfo, quota int) error {
+	if relayInfo.IsPlayground || !relayInfo.TokenHasBudget {
+		return nil
+	}
+	token, err := model.GetTokenByKey(relayInfo.TokenKey, false)
+	if err != nil {
+		return err
+	}
+	return checkTokenBudget(relayInfo, token, quota)
+}
+
+func updateTokenBudgetUsage(relayInfo *relaycommon.RelayInfo, quota int) {
+	if !relayInfo.TokenHasBudget || quota == 0 {
+		return
+	}
+	err := model.IncreaseTokenBudgetUsage(relayInfo.TokenId, quota)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to update budget usage of token %d: %s", relayInfo.TokenId, err.Error()))
+	}
+}
+
+// GetBillingQuota 获取本次请求可用的额度，组织令牌返回成员在组织额度池中可用的额度
+func GetBillingQuota(relayInfo *relaycommon.RelayInfo) (int, error) {
+	if relayInfo.OrgId != 0 {
+		return model.GetOrganizationBillingQuota(relayInfo.OrgId, relayInfo.UserId)
+	}
+	return model.GetUserQuota(relayInfo.UserId, false)
+}
+
+// ConsumeBillingQuota 向用户或组织额度池扣费，quota 为负数时退还
+func ConsumeBillingQuota(relayInfo *relaycommon.RelayInfo, quota int) error {
+	if relayInfo.OrgId != 0 {
+		return model.ConsumeOrganizationQuota(relayInfo.OrgId, relayInfo.UserId, quota)
+	}
 	if quota > 0 {
-		err = model.DecreaseUserQuota(relayInfo.UserId, quota)
-	} else {
-		err = model.IncreaseUserQuota(relayInfo.UserId, -quota, false)
+		return model.DecreaseUserQuota(relayInfo.UserId, quota)
 	}
+	return model.IncreaseUserQuota(relayInfo.UserId, -quota, false)
+}
+
+func PostConsumeQuota(relayInfo *relaycommon.RelayInfo, quota int, preConsumedQuota int, sendEmail bool) (err error) {
+
+	err = ConsumeBillingQuota(relayInfo, quota)
 	if err != nil {
 		return err
 	}
@@ -399,6 +488,7 @@ func PostConsumeQuota(relayInfo *relaycommon.RelayInfo, quota int, preConsumedQu
 		if err != nil {
 			return err
 		}
+		updateTokenBudgetUsage(relayInfo, quota)
 	}
 
 	if sendEmail {
@@ -425,13 +515,23 @@ func checkAndSendQuotaNotify(relayInfo *relaycommon.RelayInfo, quota int, preCon
 			quotaTooLow = true
 		}
 		if quotaTooLow {
-			prompt := "您的额度即将用尽"
-			topUpLink := fmt.Sprintf("%s/topup", setting.ServerAddress)
-			content := "{{value}}，当前剩余额度为 {{value}}，为了不影响您的使用，请及时充值。<br/>充值链接：<a href='{{value}}'>{{value}}</a>"
-			err := NotifyUser(relayInfo.UserId, relayInfo.UserEmail, relayInfo.UserSetting, dto.NewNotify(dto.NotifyTypeQuotaExceed, prompt, content, []interface{}{prompt, common.FormatQuota(relayInfo.UserQuota), topUpLink, topUpLink}))
+			err := NotifyUser(relayInfo.UserId, relayInfo.UserEmail, relayInfo.UserSetting, buildQuotaNotify(relayInfo))
 			if err != nil {
 				common.SysError(fmt.Sprintf("failed to send quota notify to user %d: %s", relayInfo.UserId, err.Error()))
 			}
 		}
 	})
 }
+
+// buildQuotaNotify 生成额度不足提醒，组织令牌的 UserQuota 为成员可用的组织额度，提醒联系组织充值
+func buildQuotaNotify(relayInfo *relaycommon.RelayInfo) dto.Notify {
+	if relayInfo.OrgId != 0 {
+		prompt := "您可用的组织额度即将用尽"
+		content := "{{value}}，当前剩余组织额度为 {{value}}，为了不影响您的使用，请联系组织所有者或账单管理员充值或调整您的消费上限。"
+		return dto.NewNotify(dto.NotifyTypeQuotaExceed, prompt, content, []interface{}{prompt, common.FormatQuota(relayInfo.UserQuota)})
+	}
+	prompt := "您的额度即将用尽"
+	topUpLink := fmt.Sprintf("%s/topup", setting.ServerAddress)
+	content := "{{value}}，当前剩余额度为 {{value}}，为了不影响您的使用，请及时充值。<br/>充值链接：<a href='{{value}}'>{{value}}</a>"
+	return dto.NewNotify(dto.NotifyTypeQuotaExceed, prompt, content, []interface{}{prompt, common.FormatQuota(relayInfo.UserQuota), topUpLink, topUpLink})
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"one-api/common"
+	"one-api/model"
+	relaycommon "one-api/relay/common"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestToken 创建测试用户与令牌，令牌 id 固定以免与其他测试的预算计数冲突
+func createTestToken(t *testing.T, token *model.Token, userQuota int) *model.Token {
+	user := &model.User{Id: token.UserId, Username: "quota_user", Password: "password", Quota: userQuota, Status: common.UserStatusEnabled}
+	require.NoError(t, model.DB.Create(user).Error)
+	token.Status = common.TokenStatusEnabled
+	require.NoError(t, model.DB.Create(token).Error)
+	return token
+}
+
+// TestTokenBudgetQuotaPath 测试预扣时检查令牌预算，超出预算时不扣减额度，结算退还后预算恢复
+func TestTokenBudgetQuotaPath(t *testing.T) {
+	setupTestDB(t)
+	token := createTestToken(t, &model.Token{Id: 96001, UserId: 1, Key: "budgettestkey", RemainQuota: 1000, DailyBudget: 100}, 1000)
+	info := &relaycommon.RelayInfo{UserId: token.UserId, TokenId: token.Id, TokenKey: token.Key, TokenHasBudget: true}
+
+	require.NoError(t, PreConsumeTokenQuota(info, 60))
+	err := PreConsumeTokenQuota(info, 50)
+	var budgetErr *model.TokenBudgetExceededError
+	require.ErrorAs(t, err, &budgetErr)
+	assert.Equal(t, 60, budgetErr.Used)
+	token, err = model.GetTokenById(token.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 940, token.RemainQuota)
+
+	// 实际消耗比预扣少 20，退还后预算同步减少
+	require.NoError(t, PostConsumeQuota(info, -20, 60, false))
+	statuses, err := model.GetTokenBudgetStatus(token)
+	require.NoError(t, err)
+	assert.Equal(t, 40, statuses[0].Used)
+	require.NoError(t, PreConsumeTokenQuota(info, 50))
+
+	require.NoError(t, PostConsumeQuota(info, 10, 50, false))
+	assert.ErrorAs(t, PreConsumeTokenQuota(info, 0), &budgetErr)
+	token, err = model.GetTokenById(token.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 900, token.RemainQuota)
+}
+
+// TestCheckTokenBudgetQuota 测试成功后才扣费的请求在提交前检查令牌预算，不扣减额度
+func TestCheckTokenBudgetQuota(t *testing.T) {
+	setupTestDB(t)
+	token := createTestToken(t, &model.Token{Id: 96003, UserId: 1, Key: "budgetcheckkey", RemainQuota: 1000, DailyBudget: 100}, 1000)
+	info := &relaycommon.RelayInfo{UserId: token.UserId, TokenId: token.Id, TokenKey: token.Key, TokenHasBudget: true}
+
+	require.NoError(t, CheckTokenBudgetQuota(info, 100))
+	require.NoError(t, PostConsumeQuota(info, 80, 0, true))
+	var budgetErr *model.TokenBudgetExceededError
+	assert.ErrorAs(t, CheckTokenBudgetQuota(info, 30), &budgetErr)
+	assert.NoError(t, CheckTokenBudgetQuota(info, 20))
+
+	info.TokenHasBudget = false
+	assert.NoError(t, CheckTokenBudgetQuota(info, 30))
+	token, err := model.GetTokenById(token.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 920, token.RemainQuota)
+}
+
+// TestBuildQuotaNotify 测试组织令牌的额度提醒说明为组织额度且不包含个人充值链接
+func TestBuildQuotaNotify(t *testing.T) {
+	notify := buildQuotaNotify(&relaycommon.RelayInfo{UserQuota: 100})
+	assert.Equal(t, "您的额度即将用尽", notify.Title)
+	assert.Len(t, notify.Values, 4)
+
+	notify = buildQuotaNotify(&relaycommon.RelayInfo{UserQuota: 100, OrgId: 1})
+	assert.Equal(t, "您可用的组织额度即将用尽", notify.Title)
+	assert.Contains(t, notify.Content, "组织额度")
+	assert.NotContains(t, notify.Content, "充值链接")
+}
@@ -0,0 +1,392 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/relay/helper"
+	"one-api/setting/operation_setting"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const responseCacheKeyPrefix = "response_cache:"
+
+// ResponseCacheEntry 缓存的上游响应，Body 为写给客户端的原始内容（流式为 SSE 文本）
+type ResponseCacheEntry struct {
+	Stream    bool      `json:"stream"`
+	Body      string    `json:"body"`
+	Usage     dto.Usage `json:"usage"`
+	CreatedAt int64     `json:"created_at"`
+}
+
+type memoryResponseCacheItem struct {
+	entry    *ResponseCacheEntry
+	expireAt int64
+}
+
+var memoryResponseCache = make(map[string]memoryResponseCacheItem)
+var memoryResponseCacheLock sync.Mutex
+
+// ShouldUseResponseCache 判断当前请求是否参与响应缓存
+func ShouldUseResponseCache(c *gin.Context, info *relaycommon.RelayInfo) bool {
+	return operation_setting.IsResponseCacheEnabled(info.Group, c.GetBool("token_response_cache"))
+}
+
+// GetResponseCacheKey 对请求做归一化后计算缓存键：去掉 stream、stream_options、user 等不影响结果的字段，
+// 模型名使用用户请求的原始模型名，未开启共享时按用户隔离
+func GetResponseCacheKey(info *relaycommon.RelayInfo, request any) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	normalized := make(map[string]any)
+	if err = json.Unmarshal(data, &normalized); err != nil {
+		return "", err
+	}
+	delete(normalized, "stream")
+	delete(normalized, "stream_options")
+	delete(normalized, "user")
+	normalized["model"] = info.OriginModelName
+	normalized["__relay_mode"] = info.RelayMode
+	if !operation_setting.GetResponseCacheSetting().ShareAcrossUsers {
+		normalized["__user_id"] = info.UserId
+	}
+	// map 序列化时键有序，保证相同请求得到相同的哈希
+	data, err = json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return responseCacheKeyPrefix + hex.EncodeToString(sum[:]), nil
+}
+
+func GetResponseCache(key string) (*ResponseCacheEntry, bool) {
+	if common.RedisEnabled {
+		value, err := common.RedisGet(key)
+		if err != nil || value == "" {
+			return nil, false
+		}
+		var entry ResponseCacheEntry
+		if err = json.Unmarshal([]byte(value), &entry); err != nil {
+			return nil, false
+		}
+		return &entry, true
+	}
+	memoryResponseCacheLock.Lock()
+	defer memoryResponseCacheLock.Unlock()
+	item, ok := memoryResponseCache[key]
+	if !ok {
+		return nil, false
+	}
+	if item.expireAt <= time.Now().Unix() {
+		delete(memoryResponseCache, key)
+		return nil, false
+	}
+	return item.entry, true
+}
+
+func SetResponseCache(key string, entry *ResponseCacheEntry) {
+	setting := operation_setting.GetResponseCacheSetting()
+	if setting.MaxEntryBytes > 0 && len(entry.Body) > setting.MaxEntryBytes {
+		return
+	}
+	ttl := time.Duration(setting.TTLSeconds) * time.Second
+	if common.RedisEnabled {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return
+		}
+		if err = common.RedisSet(key, string(data), ttl); err != nil {
+			common.SysError("failed to set response cache: " + err.Error())
+		}
+		return
+	}
+	now := time.Now().Unix()
+	memoryResponseCacheLock.Lock()
+	defer memoryResponseCacheLock.Unlock()
+	if setting.MemoryMaxEntries > 0 && len(memoryResponseCache) >= setting.MemoryMaxEntries {
+		// 先清理过期条目，仍然已满时淘汰最早过期的条目
+		oldestKey := ""
+		var oldestExpire int64
+		for k, item := range memoryResponseCache {
+			if item.expireAt <= now {
+				delete(memoryResponseCache, k)
+				continue
+			}
+			if oldestKey == "" || item.expireAt < oldestExpire {
+				oldestKey = k
+				oldestExpire = item.expireAt
+			}
+		}
+		if len(memoryResponseCache) >= setting.MemoryMaxEntries && oldestKey != "" {
+			delete(memoryResponseCache, oldestKey)
+		}
+	}
+	memoryResponseCache[key] = memoryResponseCacheItem{
+		entry:    entry,
+		expireAt: now + int64(setting.TTLSeconds),
+	}
+}
+
+// ResponseCaptureWriter 在写给客户端的同时保存响应内容，用于写入缓存
+type ResponseCaptureWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func NewResponseCaptureWriter(writer gin.ResponseWriter) *ResponseCaptureWriter {
+	return NewResponseCaptureWriterWithLimit(writer, operation_setting.GetResponseCacheSetting().MaxEntryBytes)
+}
+
+// NewResponseCaptureWriterWithLimit limit 为 0 时不限制捕获的字节数
+func NewResponseCaptureWriterWithLimit(writer gin.ResponseWriter, limit int) *ResponseCaptureWriter {
+	return &ResponseCaptureWriter{
+		ResponseWriter: writer,
+		limit:          limit,
+	}
+}
+
+func (w *ResponseCaptureWriter) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.limit > 0 && w.body.Len()+len(data) > w.limit {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(data)
+}
+
+func (w *ResponseCaptureWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *ResponseCaptureWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Body 返回捕获的响应内容，响应过大时返回 false
+func (w *ResponseCaptureWriter) Body() (string, bool) {
+	if w.overflow || w.Status() != http.StatusOK {
+		return "", false
+	}
+	return w.body.String(), true
+}
+
+// ReplayResponseCache 将缓存的响应写回客户端，流式请求命中非流式缓存时转换为 SSE 分块，
+// 用量分块按本次请求的 include_usage 决定是否返回
+func ReplayResponseCache(c *gin.Context, entry *ResponseCacheEntry, relayMode int, stream bool, includeUsage bool) error {
+	if !stream {
+		if entry.Stream {
+			return errors.New("cached response is a stream")
+		}
+		c.Data(http.StatusOK, "application/json", []byte(entry.Body))
+		return nil
+	}
+	helper.SetEventStreamHeaders(c)
+	if entry.Stream {
+		return replayStreamResponseCache(c, entry, includeUsage)
+	}
+	if relayMode == relayconstant.RelayModeCompletions {
+		return replayCompletionsAsStream(c, entry, includeUsage)
+	}
+
+	var textResponse dto.OpenAITextResponse
+	if err := json.Unmarshal([]byte(entry.Body), &textResponse); err != nil {
+		return err
+	}
+	chunk := &dto.ChatCompletionsStreamResponse{
+		Id:      textResponse.Id,
+		Object:  "chat.completion.chunk",
+		Created: textResponse.Created,
+		Model:   textResponse.Model,
+		Choices: make([]dto.ChatCompletionsStreamResponseChoice, 0, len(textResponse.Choices)),
+	}
+	stop := &dto.ChatCompletionsStreamResponse{
+		Id:      textResponse.Id,
+		Object:  "chat.completion.chunk",
+		Created: textResponse.Created,
+		Model:   textResponse.Model,
+		Choices: make([]dto.ChatCompletionsStreamResponseChoice, 0, len(textResponse.Choices)),
+	}
+	for _, choice := range textResponse.Choices {
+		delta := dto.ChatCompletionsStreamResponseChoiceDelta{Role: "assistant"}
+		delta.SetContentString(choice.Message.StringContent())
+		if choice.Message.ReasoningContent != "" {
+			delta.SetReasoningContent(choice.Message.ReasoningContent)
+		}
+		for i, toolCall := range choice.Message.ParseToolCalls() {
+			toolCallResponse := dto.ToolCallResponse{
+				ID:   toolCall.ID,
+				Type: toolCall.Type,
+				Function: dto.FunctionResponse{
+					Name:      toolCall.Function.Name,
+					Arguments: toolCall.Function.Arguments,
+				},
+			}
+			toolCallResponse.SetIndex(i)
+			delta.ToolCalls = append(delta.ToolCalls, toolCallResponse)
+		}
+		chunk.Choices = append(chunk.Choices, dto.ChatCompletionsStreamResponseChoice{
+			Index: choice.Index,
+			Delta: delta,
+		})
+		finishReason := choice.FinishReason
+		stop.Choices = append(stop.Choices, dto.ChatCompletionsStreamResponseChoice{
+			Index:        choice.Index,
+			FinishReason: &finishReason,
+		})
+	}
+	if err := helper.ObjectData(c, chunk); err != nil {
+		return err
+	}
+	if err := helper.ObjectData(c, stop); err != nil {
+		return err
+	}
+	if includeUsage {
+		if err := helper.ObjectData(c, helper.GenerateFinalUsageResponse(textResponse.Id, textResponse.Created, textResponse.Model, entry.Usage)); err != nil {
+			return err
+		}
+	}
+	helper.Done(c)
+	return nil
+}
+
+// cachedStreamChunk 回放流式缓存时需要读取的分块字段
+type cachedStreamChunk struct {
+	Id      string            `json:"id"`
+	Object  string            `json:"object"`
+	Created int64             `json:"created"`
+	Model   string            `json:"model"`
+	Choices []json.RawMessage `json:"choices"`
+	Usage   *dto.Usage        `json:"usage"`
+}
+
+// replayStreamResponseCache 逐个回放缓存的 SSE 事件，不需要用量时去掉用量分块，需要但缓存中没有时在结束前补充
+func replayStreamResponseCache(c *gin.Context, entry *ResponseCacheEntry, includeUsage bool) error {
+	var last cachedStreamChunk
+	usageSent := false
+	writeUsage := func() error {
+		if !includeUsage || usageSent {
+			return nil
+		}
+		usageSent = true
+		usageChunk := helper.GenerateFinalUsageResponse(last.Id, last.Created, last.Model, entry.Usage)
+		if last.Object != "" {
+			usageChunk.Object = last.Object
+		}
+		return helper.ObjectData(c, usageChunk)
+	}
+	for _, event := range strings.Split(entry.Body, "\n\n") {
+		event = strings.TrimSpace(event)
+		if event == "" {
+			continue
+		}
+		if data, ok := strings.CutPrefix(event, "data:"); ok {
+			data = strings.TrimSpace(data)
+			if data == "[DONE]" {
+				if err := writeUsage(); err != nil {
+					return err
+				}
+			} else {
+				var chunk cachedStreamChunk
+				if json.Unmarshal([]byte(data), &chunk) == nil {
+					if chunk.Usage != nil && len(chunk.Choices) == 0 {
+						if !includeUsage || usageSent {
+							continue
+						}
+						usageSent = true
+					} else {
+						last = chunk
+					}
+				}
+			}
+		}
+		c.Writer.WriteString(event + "\n\n")
+		c.Writer.Flush()
+	}
+	return nil
+}
+
+// completionsStreamChoice 与 completionsStreamResponse 为 /v1/completions 流式分块的格式
+type completionsStreamChoice struct {
+	Text         string  `json:"text"`
+	Index        int     `json:"index"`
+	Logprobs     any     `json:"logprobs"`
+	FinishReason *string `json:"finish_reason"`
+}
+
+type completionsStreamResponse struct {
+	Id      string                    `json:"id"`
+	Object  string                    `json:"object"`
+	Created int64                     `json:"created"`
+	Model   string                    `json:"model"`
+	Choices []completionsStreamChoice `json:"choices"`
+}
+
+// replayCompletionsAsStream 将非流式的 text_completion 缓存转换为流式分块
+func replayCompletionsAsStream(c *gin.Context, entry *ResponseCacheEntry, includeUsage bool) error {
+	var response struct {
+		Id      string `json:"id"`
+		Created int64  `json:"created"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Text         string `json:"text"`
+			Index        int    `json:"index"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal([]byte(entry.Body), &response); err != nil {
+		return err
+	}
+	chunk := &completionsStreamResponse{
+		Id:      response.Id,
+		Object:  "text_completion",
+		Created: response.Created,
+		Model:   response.Model,
+		Choices: make([]completionsStreamChoice, 0, len(response.Choices)),
+	}
+	stop := &completionsStreamResponse{
+		Id:      response.Id,
+		Object:  "text_completion",
+		Created: response.Created,
+		Model:   response.Model,
+		Choices: make([]completionsStreamChoice, 0, len(response.Choices)),
+	}
+	for _, choice := range response.Choices {
+		chunk.Choices = append(chunk.Choices, completionsStreamChoice{Text: choice.Text, Index: choice.Index})
+		finishReason := choice.FinishReason
+		stop.Choices = append(stop.Choices, completionsStreamChoice{Index: choice.Index, FinishReason: &finishReason})
+	}
+	if err := helper.ObjectData(c, chunk); err != nil {
+		return err
+	}
+	if err := helper.ObjectData(c, stop); err != nil {
+		return err
+	}
+	if includeUsage {
+		usageChunk := helper.GenerateFinalUsageResponse(response.Id, response.Created, response.Model, entry.Usage)
+		usageChunk.Object = "text_completion"
+		if err := helper.ObjectData(c, usageChunk); err != nil {
+			return err
+		}
+	}
+	helper.Done(c)
+	return nil
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/setting/operation_setting"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// useResponseCacheSetting 修改响应缓存配置并使用内存缓存，测试结束后恢复
+func useResponseCacheSetting(t *testing.T, update func(setting *operation_setting.ResponseCacheSetting)) {
+	setting := operation_setting.GetResponseCacheSetting()
+	saved := *setting
+	redisEnabled := common.RedisEnabled
+	common.RedisEnabled = false
+	update(setting)
+	memoryResponseCacheLock.Lock()
+	memoryResponseCache = make(map[string]memoryResponseCacheItem)
+	memoryResponseCacheLock.Unlock()
+	t.Cleanup(func() {
+		*setting = saved
+		common.RedisEnabled = redisEnabled
+		memoryResponseCacheLock.Lock()
+		memoryResponseCache = make(map[string]memoryResponseCacheItem)
+		memoryResponseCacheLock.Unlock()
+	})
+}
+
+// TestIsResponseCacheEnabled 测试总开关关闭时不缓存，开启后按令牌开关或分组启用
+func TestIsResponseCacheEnabled(t *testing.T) {
+	useResponseCacheSetting(t, func(setting *operation_setting.ResponseCacheSetting) {
+		setting.Enabled = false
+		setting.Groups = []string{"vip"}
+	})
+	assert.False(t, operation_setting.IsResponseCacheEnabled("vip", true))
+
+	operation_setting.GetResponseCacheSetting().Enabled = true
+	assert.True(t, operation_setting.IsResponseCacheEnabled("vip", false))
+	assert.True(t, operation_setting.IsResponseCacheEnabled("default", true))
+	assert.False(t, operation_setting.IsResponseCacheEnabled("default", false))
+}
+
+// TestGetResponseCacheKey 测试缓存键忽略 stream、user 等字段，使用原始模型名并按用户隔离
+func TestGetResponseCacheKey(t *testing.T) {
+	useResponseCacheSetting(t, func(setting *operation_setting.ResponseCacheSetting) {
+		setting.ShareAcrossUsers = false
+	})
+	info := &relaycommon.RelayInfo{UserId: 1, OriginModelName: "gpt-4o", RelayMode: relayconstant.RelayModeChatCompletions}
+	request := map[string]any{"model": "gpt-4o-mapped", "messages": []any{map[string]any{"role": "user", "content": "hi"}}}
+	streamRequest := map[string]any{"model": "gpt-4o", "stream": true, "stream_options": map[string]any{"include_usage": true},
+		"user": "u1", "messages": []any{map[string]any{"role": "user", "content": "hi"}}}
+
+	key, err := GetResponseCacheKey(info, request)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, responseCacheKeyPrefix))
+	streamKey, err := GetResponseCacheKey(info, streamRequest)
+	assert.NoError(t, err)
+	assert.Equal(t, key, streamKey)
+
+	request["temperature"] = 0.5
+	otherKey, _ := GetResponseCacheKey(info, request)
+	assert.NotEqual(t, key, otherKey)
+	delete(request, "temperature")
+
+	otherUser := *info
+	otherUser.UserId = 2
+	otherKey, _ = GetResponseCacheKey(&otherUser, request)
+	assert.NotEqual(t, key, otherKey)
+
+	operation_setting.GetResponseCacheSetting().ShareAcrossUsers = true
+	key, _ = GetResponseCacheKey(info, request)
+	otherKey, _ = GetResponseCacheKey(&otherUser, request)
+	assert.Equal(t, key, otherKey)
+}
+
+// TestMemoryResponseCache 测试内存缓存的读写、过期、单条大小上限和条目数上限
+func TestMemoryResponseCache(t *testing.T) {
+	useResponseCacheSetting(t, func(setting *operation_setting.ResponseCacheSetting) {
+		setting.TTLSeconds = 60
+		setting.MaxEntryBytes = 16
+		setting.MemoryMaxEntries = 2
+	})
+
+	SetResponseCache("a", &ResponseCacheEntry{Body: "a"})
+	entry, ok := GetResponseCache("a")
+	assert.True(t, ok)
+	assert.Equal(t, "a", entry.Body)
+
+	SetResponseCache("large", &ResponseCacheEntry{Body: strings.Repeat("x", 17)})
+	_, ok = GetResponseCache("large")
+	assert.False(t, ok)
+
+	SetResponseCache("b", &ResponseCacheEntry{Body: "b"})
+	SetResponseCache("c", &ResponseCacheEntry{Body: "c"})
+	memoryResponseCacheLock.Lock()
+	assert.Len(t, memoryResponseCache, 2)
+	memoryResponseCacheLock.Unlock()
+	_, ok = GetResponseCache("c")
+	assert.True(t, ok)
+
+	memoryResponseCacheLock.Lock()
+	item := memoryResponseCache["c"]
+	item.expireAt = time.Now().Unix()
+	memoryResponseCache["c"] = item
+	memoryResponseCacheLock.Unlock()
+	_, ok = GetResponseCache("c")
+	assert.False(t, ok)
+}
+
+// TestResponseCaptureWriter 测试捕获写给客户端的内容，超过上限或状态码非 200 时不返回
+func TestResponseCaptureWriter(t *testing.T) {
+	useResponseCacheSetting(t, func(setting *operation_setting.ResponseCacheSetting) {
+		setting.MaxEntryBytes = 8
+	})
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	writer := NewResponseCaptureWriter(c.Writer)
+	writer.WriteString("hello")
+	body, ok := writer.Body()
+	assert.True(t, ok)
+	assert.Equal(t, "hello", body)
+	writer.Write([]byte(" world"))
+	_, ok = writer.Body()
+	assert.False(t, ok)
+	assert.Equal(t, "hello world", recorder.Body.String())
+
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	c.Status(500)
+	writer = NewResponseCaptureWriter(c.Writer)
+	writer.WriteString("error")
+	_, ok = writer.Body()
+	assert.False(t, ok)
+}
+
+// TestReplayResponseCacheAsStream 测试流式请求命中非流式缓存时转换为 SSE 分块，并按 include_usage 返回用量
+func TestReplayResponseCacheAsStream(t *testing.T) {
+	entry := &ResponseCacheEntry{
+		Body:  `{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`,
+		Usage: dto.Usage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4},
+	}
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	assert.NoError(t, ReplayResponseCache(c, entry, relayconstant.RelayModeChatCompletions, true, true))
+	body := recorder.Body.String()
+	assert.Contains(t, body, `"object":"chat.completion.chunk"`)
+	assert.Contains(t, body, `"content":"hi"`)
+	assert.Contains(t, body, `"finish_reason":"stop"`)
+	assert.Contains(t, body, `"total_tokens":4`)
+	assert.True(t, strings.HasSuffix(body, "data: [DONE]\n\n"))
+
+	recorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(recorder)
+	assert.NoError(t, ReplayResponseCache(c, entry, relayconstant.RelayModeChatCompletions, false, false))
+	assert.JSONEq(t, entry.Body, recorder.Body.String())
+}
+
+// TestReplayCompletionsAsStream 测试 /v1/completions 的非流式缓存回放为 text_completion 分块
+func TestReplayCompletionsAsStream(t *testing.T) {
+	entry := &ResponseCacheEntry{
+		Body:  `{"id":"cmpl-1","object":"text_completion","created":1,"model":"gpt-3.5-turbo-instruct","choices":[{"text":"hi","index":0,"finish_reason":"stop"}]}`,
+		Usage: dto.Usage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4},
+	}
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	assert.NoError(t, ReplayResponseCache(c, entry, relayconstant.RelayModeCompletions, true, false))
+	body := recorder.Body.String()
+	assert.Contains(t, body, `"object":"text_completion"`)
+	assert.Contains(t, body, `"text":"hi"`)
+	assert.NotContains(t, body, "chat.completion.chunk")
+	assert.NotContains(t, body, `"usage"`)
+}
+
+// TestReplayStreamResponseCacheUsage 测试回放流式缓存时按本次请求的 include_usage 去掉或补充用量分块
+func TestReplayStreamResponseCacheUsage(t *testing.T) {
+	chunk := `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"content":"hi"}}]}`
+	usage := `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`
+	withUsage := &ResponseCacheEntry{Stream: true, Body: chunk + "\n\n" + usage + "\n\ndata: [DONE]\n\n",
+		Usage: dto.Usage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4}}
+	withoutUsage := &ResponseCacheEntry{Stream: true, Body: chunk + "\n\ndata: [DONE]\n\n",
+		Usage: dto.Usage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4}}
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	assert.NoError(t, ReplayResponseCache(c, withUsage, relayconstant.RelayModeChatCompletions, true, false))
+	assert.NotContains(t, recorder.Body.String(), `"usage"`)
+	assert.Contains(t, recorder.Body.String(), "data: [DONE]")
+
+	recorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(recorder)
+	assert.NoError(t, ReplayResponseCache(c, withoutUsage, relayconstant.RelayModeChatCompletions, true, true))
+	body := recorder.Body.String()
+	assert.Equal(t, 1, strings.Count(body, `"total_tokens":4`))
+	assert.Less(t, strings.Index(body, `"total_tokens":4`), strings.Index(body, "data: [DONE]"))
+
+	recorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(recorder)
+	assert.NoError(t, ReplayResponseCache(c, withUsage, relayconstant.RelayModeChatCompletions, true, true))
+	assert.Equal(t, 1, strings.Count(recorder.Body.String(), `"total_tokens":4`))
+}
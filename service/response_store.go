@@ -0,0 +1,123 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/model"
+	relaycommon "one-api/relay/common"
+	"one-api/setting/operation_setting"
+	"strings"
+)
+
+// ResponseStoreTokenId 返回查询保存的响应时使用的令牌 id，不按令牌隔离时为 0
+func ResponseStoreTokenId(tokenId int) int {
+	if !operation_setting.GetResponseStoreSetting().ScopeByToken {
+		return 0
+	}
+	return tokenId
+}
+
+// ShouldStoreResponse 请求未指定 store: false 时，转换为 Chat Completions 的渠道始终保存，原生渠道按网关配置保存
+func ShouldStoreResponse(request *dto.OpenAIResponsesRequest, emulated bool) bool {
+	if request.Store != nil && !*request.Store {
+		return false
+	}
+	return emulated || operation_setting.GetResponseStoreSetting().Enabled
+}
+
+// GetResponsesHistory 返回 previous_response_id 对应的整段会话，不存在时返回 gorm.ErrRecordNotFound
+func GetResponsesHistory(info *relaycommon.RelayInfo, previousResponseId string) ([]json.RawMessage, error) {
+	return model.GetStoredResponseHistory(info.UserId, ResponseStoreTokenId(info.TokenId), previousResponseId)
+}
+
+// ResponsesHistoryForUpstream 整理用于原生 Responses 渠道的历史会话：
+// 新的上游账号无法按 id 找到之前的推理项，没有 encrypted_content 的推理项被丢弃，消息与函数调用去掉 id 以免被关联到推理项
+func ResponsesHistoryForUpstream(history []json.RawMessage) []json.RawMessage {
+	items := make([]json.RawMessage, 0, len(history))
+	for _, rawItem := range history {
+		var item map[string]json.RawMessage
+		if err := json.Unmarshal(rawItem, &item); err != nil {
+			items = append(items, rawItem)
+			continue
+		}
+		var itemType string
+		_ = json.Unmarshal(item["type"], &itemType)
+		switch itemType {
+		case "reasoning":
+			if len(item["encrypted_content"]) == 0 || string(item["encrypted_content"]) == "null" {
+				continue
+			}
+		case "", "message", "function_call":
+			if _, ok := item["id"]; ok {
+				delete(item, "id")
+				if data, err := json.Marshal(item); err == nil {
+					rawItem = data
+				}
+			}
+		}
+		items = append(items, rawItem)
+	}
+	return items
+}
+
+// ParseResponsesStreamResult 从捕获的 SSE 内容中取出最终的响应对象
+func ParseResponsesStreamResult(body string) ([]byte, bool) {
+	var result []byte
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		var event struct {
+			Type     string          `json:"type"`
+			Response json.RawMessage `json:"response"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event); err != nil {
+			continue
+		}
+		if event.Type == "response.completed" || event.Type == "response.incomplete" {
+			result = event.Response
+		}
+	}
+	return result, len(result) > 0
+}
+
+// SaveStoredResponse 保存本轮的输入项与上游（或转换后）的响应对象，供后续请求通过 previous_response_id 引用
+func SaveStoredResponse(info *relaycommon.RelayInfo, previousResponseId string, input []json.RawMessage, responseData []byte) error {
+	var response struct {
+		Id        string            `json:"id"`
+		CreatedAt int64             `json:"created_at"`
+		Output    []json.RawMessage `json:"output"`
+	}
+	if err := json.Unmarshal(responseData, &response); err != nil {
+		return err
+	}
+	if response.Id == "" {
+		return errors.New("response id is empty")
+	}
+	now := common.GetTimestamp()
+	if response.CreatedAt == 0 {
+		response.CreatedAt = now
+	}
+	storedResponse := &model.StoredResponse{
+		Id:                 response.Id,
+		UserId:             info.UserId,
+		TokenId:            info.TokenId,
+		Model:              info.OriginModelName,
+		PreviousResponseId: previousResponseId,
+		Response:           string(responseData),
+		CreatedAt:          response.CreatedAt,
+	}
+	if hours := operation_setting.GetResponseStoreSetting().RetentionHours; hours > 0 {
+		storedResponse.ExpiresAt = now + int64(hours)*3600
+	}
+	if err := storedResponse.SetItems(input, response.Output); err != nil {
+		return err
+	}
+	return storedResponse.Insert()
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"encoding/json"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/model"
+	relaycommon "one-api/relay/common"
+	"one-api/setting/operation_setting"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestResponsesHistoryForUpstream 测试丢弃没有 encrypted_content 的推理项，并去掉消息与函数调用的 id
+func TestResponsesHistoryForUpstream(t *testing.T) {
+	items := ResponsesHistoryForUpstream([]json.RawMessage{
+		json.RawMessage(`{"type":"reasoning","id":"rs_1","summary":[]}`),
+		json.RawMessage(`{"type":"reasoning","id":"rs_2","encrypted_content":"abc"}`),
+		json.RawMessage(`{"type":"message","id":"msg_1","role":"assistant","content":[]}`),
+		json.RawMessage(`{"type":"function_call_output","call_id":"call_1","output":"ok"}`),
+	})
+	require.Len(t, items, 3)
+	assert.JSONEq(t, `{"type":"reasoning","id":"rs_2","encrypted_content":"abc"}`, string(items[0]))
+	assert.JSONEq(t, `{"type":"message","role":"assistant","content":[]}`, string(items[1]))
+	assert.JSONEq(t, `{"type":"function_call_output","call_id":"call_1","output":"ok"}`, string(items[2]))
+}
+
+// TestParseResponsesStreamResult 测试从 SSE 内容中取出 response.completed 事件的响应对象
+func TestParseResponsesStreamResult(t *testing.T) {
+	body := "event: response.created\ndata: {\"type\":\"response.created\",\"response\":{\"id\":\"resp_1\",\"status\":\"in_progress\"}}\n\n" +
+		"event: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"id\":\"resp_1\",\"status\":\"completed\"}}\n\n"
+	result, ok := ParseResponsesStreamResult(body)
+	require.True(t, ok)
+	assert.JSONEq(t, `{"id":"resp_1","status":"completed"}`, string(result))
+
+	_, ok = ParseResponsesStreamResult("data: {\"type\":\"response.created\"}\n\n")
+	assert.False(t, ok)
+}
+
+// TestSaveStoredResponse 测试保存本轮输入与响应输出并设置过期时间，store 为 false 时不保存，
+// 转换为 Chat Completions 的渠道不受存储开关影响
+func TestSaveStoredResponse(t *testing.T) {
+	setupTestDB(t)
+	setting := operation_setting.GetResponseStoreSetting()
+	saved := *setting
+	setting.Enabled = false
+	setting.RetentionHours = 1
+	setting.ScopeByToken = true
+	t.Cleanup(func() {
+		*setting = saved
+	})
+	assert.False(t, ShouldStoreResponse(&dto.OpenAIResponsesRequest{}, false))
+	assert.True(t, ShouldStoreResponse(&dto.OpenAIResponsesRequest{}, true))
+	assert.False(t, ShouldStoreResponse(&dto.OpenAIResponsesRequest{Store: common.GetPointer(false)}, true))
+	setting.Enabled = true
+	assert.True(t, ShouldStoreResponse(&dto.OpenAIResponsesRequest{}, false))
+	assert.False(t, ShouldStoreResponse(&dto.OpenAIResponsesRequest{Store: common.GetPointer(false)}, false))
+
+	info := &relaycommon.RelayInfo{UserId: 1, TokenId: 2, OriginModelName: "gpt-4o"}
+	input := []json.RawMessage{json.RawMessage(`{"role":"user","content":"hi"}`)}
+	require.NoError(t, SaveStoredResponse(info, "", input, []byte(`{"id":"resp_a","output":[{"type":"message","content":[]}]}`)))
+	assert.Error(t, SaveStoredResponse(info, "", input, []byte(`{"output":[]}`)))
+
+	response, err := model.GetStoredResponse(1, ResponseStoreTokenId(2), "resp_a")
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o", response.Model)
+	assert.InDelta(t, common.GetTimestamp()+3600, response.ExpiresAt, 5)
+	history, err := GetResponsesHistory(info, "resp_a")
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
@@ -0,0 +1,544 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type responsesTextFormat struct {
+	Format struct {
+		Type        string `json:"type"`
+		Name        string `json:"name,omitempty"`
+		Description string `json:"description,omitempty"`
+		Schema      any    `json:"schema,omitempty"`
+		Strict      any    `json:"strict,omitempty"`
+	} `json:"format"`
+}
+
+type responsesToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// ResponsesToOpenAIRequest 将 /v1/responses 请求转换为 Chat Completions 请求，items 为包含历史会话在内的全部输入项
+func ResponsesToOpenAIRequest(request *dto.OpenAIResponsesRequest, items []dto.ResponsesInputItem) (*dto.GeneralOpenAIRequest, error) {
+	openAIRequest := dto.GeneralOpenAIRequest{
+		Model:     request.Model,
+		Stream:    request.Stream,
+		MaxTokens: request.MaxOutputTokens,
+		TopP:      request.TopP,
+		User:      request.User,
+	}
+	if request.Temperature != 0 {
+		openAIRequest.Temperature = common.GetPointer[float64](request.Temperature)
+	}
+	if request.Reasoning != nil && request.Reasoning.Effort != "" {
+		openAIRequest.ReasoningEffort = request.Reasoning.Effort
+	}
+
+	for _, tool := range request.Tools {
+		if tool.Type != "function" {
+			return nil, fmt.Errorf("tool type %s is not supported by this channel", tool.Type)
+		}
+		openAITool := dto.ToolCallRequest{
+			Type: "function",
+			Function: dto.FunctionRequest{
+				Name:        tool.Name,
+				Description: tool.Description,
+			},
+		}
+		if len(tool.Parameters) > 0 {
+			openAITool.Function.Parameters = tool.Parameters
+		}
+		openAIRequest.Tools = append(openAIRequest.Tools, openAITool)
+	}
+
+	if len(request.ToolChoice) > 0 {
+		var toolChoice string
+		var namedToolChoice responsesToolChoice
+		if err := json.Unmarshal(request.ToolChoice, &toolChoice); err == nil {
+			openAIRequest.ToolChoice = toolChoice
+		} else if err = json.Unmarshal(request.ToolChoice, &namedToolChoice); err == nil {
+			if namedToolChoice.Type == "function" {
+				openAIRequest.ToolChoice = map[string]any{
+					"type":     "function",
+					"function": map[string]any{"name": namedToolChoice.Name},
+				}
+			} else if namedToolChoice.Type == "auto" || namedToolChoice.Type == "none" || namedToolChoice.Type == "required" {
+				openAIRequest.ToolChoice = namedToolChoice.Type
+			}
+		}
+	}
+
+	if len(request.Text) > 0 {
+		var text responsesTextFormat
+		if err := json.Unmarshal(request.Text, &text); err == nil {
+			switch text.Format.Type {
+			case "json_schema":
+				openAIRequest.ResponseFormat = &dto.ResponseFormat{
+					Type: "json_schema",
+					JsonSchema: &dto.FormatJsonSchema{
+						Name:        text.Format.Name,
+						Description: text.Format.Description,
+						Schema:      text.Format.Schema,
+						Strict:      text.Format.Strict,
+					},
+				}
+			case "json_object":
+				openAIRequest.ResponseFormat = &dto.ResponseFormat{Type: "json_object"}
+			}
+		}
+	}
+
+	messages := make([]dto.Message, 0, len(items)+1)
+	if instructions := request.GetInstructions(); instructions != "" {
+		message := dto.Message{Role: "system"}
+		message.SetStringContent(instructions)
+		messages = append(messages, message)
+	}
+	itemMessages, err := responsesItemsToMessages(items)
+	if err != nil {
+		return nil, err
+	}
+	openAIRequest.Messages = append(messages, itemMessages...)
+	return &openAIRequest, nil
+}
+
+func responsesContentToMediaContent(content dto.ResponsesInputContent) (*dto.MediaContent, error) {
+	switch content.Type {
+	case "input_text", "output_text", "text":
+		return &dto.MediaContent{Type: dto.ContentTypeText, Text: content.Text}, nil
+	case "input_image":
+		if content.ImageUrl == "" {
+			return nil, errors.New("input_image without image_url is not supported by this channel")
+		}
+		detail := content.Detail
+		if detail == "" {
+			detail = "auto"
+		}
+		return &dto.MediaContent{
+			Type:     dto.ContentTypeImageURL,
+			ImageUrl: dto.MessageImageUrl{Url: content.ImageUrl, Detail: detail},
+		}, nil
+	case "input_file":
+		return &dto.MediaContent{
+			Type: dto.ContentTypeFile,
+			File: dto.MessageFile{FileName: content.Filename, FileData: content.FileData, FileId: content.FileId},
+		}, nil
+	case "refusal":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("input content type %s is not supported by this channel", content.Type)
+}
+
+// responsesMessageContent 助手与系统消息只保留文本，用户消息多模态内容转换为数组
+func responsesMessageContent(message *dto.Message, raw json.RawMessage) error {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		message.SetStringContent(text)
+		return nil
+	}
+	var contents []dto.ResponsesInputContent
+	if err := json.Unmarshal(raw, &contents); err != nil {
+		return fmt.Errorf("invalid message content: %w", err)
+	}
+	mediaContents := make([]dto.MediaContent, 0, len(contents))
+	for _, content := range contents {
+		mediaContent, err := responsesContentToMediaContent(content)
+		if err != nil {
+			return err
+		}
+		if mediaContent != nil {
+			mediaContents = append(mediaContents, *mediaContent)
+		}
+	}
+	onlyText := true
+	var texts []string
+	for _, mediaContent := range mediaContents {
+		if mediaContent.Type != dto.ContentTypeText {
+			onlyText = false
+			break
+		}
+		texts = append(texts, mediaContent.Text)
+	}
+	if onlyText && (message.Role != "user" || len(texts) == 1) {
+		message.SetStringContent(strings.Join(texts, "\n"))
+	} else {
+		message.SetMediaContent(mediaContents)
+	}
+	return nil
+}
+
+func responsesFunctionOutput(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var contents []dto.ResponsesInputContent
+	if err := json.Unmarshal(raw, &contents); err == nil {
+		var texts []string
+		for _, content := range contents {
+			if content.Text != "" {
+				texts = append(texts, content.Text)
+			}
+		}
+		return strings.Join(texts, "\n")
+	}
+	return string(raw)
+}
+
+func responsesItemsToMessages(items []dto.ResponsesInputItem) ([]dto.Message, error) {
+	messages := make([]dto.Message, 0, len(items))
+	for _, item := range items {
+		switch item.Type {
+		case "message":
+			role := item.Role
+			if role == "developer" {
+				role = "system"
+			}
+			message := dto.Message{Role: role}
+			if err := responsesMessageContent(&message, item.Content); err != nil {
+				return nil, err
+			}
+			messages = append(messages, message)
+		case "function_call":
+			toolCall := dto.ToolCallRequest{
+				ID:   item.CallId,
+				Type: "function",
+				Function: dto.FunctionRequest{
+					Name:      item.Name,
+					Arguments: item.Arguments,
+				},
+			}
+			// 连续的函数调用合并到同一条助手消息中
+			if len(messages) > 0 && messages[len(messages)-1].Role == "assistant" {
+				last := &messages[len(messages)-1]
+				last.SetToolCalls(append(last.ParseToolCalls(), toolCall))
+				continue
+			}
+			message := dto.Message{Role: "assistant"}
+			message.SetToolCalls([]dto.ToolCallRequest{toolCall})
+			messages = append(messages, message)
+		case "function_call_output":
+			message := dto.Message{Role: "tool", ToolCallId: item.CallId}
+			message.SetStringContent(responsesFunctionOutput(item.Output))
+			messages = append(messages, message)
+		case "reasoning":
+			// 推理内容不回传给上游
+		default:
+			return nil, fmt.Errorf("input item type %s is not supported by this channel", item.Type)
+		}
+	}
+	return messages, nil
+}
+
+// NewEmulatedResponse 根据请求生成尚未填充输出的 Responses 响应对象
+func NewEmulatedResponse(request *dto.OpenAIResponsesRequest, id string, modelName string) *dto.OpenAIResponsesResponse {
+	response := &dto.OpenAIResponsesResponse{
+		ID:                 id,
+		Object:             "response",
+		CreatedAt:          int(common.GetTimestamp()),
+		Status:             "in_progress",
+		Instructions:       request.GetInstructions(),
+		MaxOutputTokens:    int(request.MaxOutputTokens),
+		Model:              modelName,
+		Output:             []dto.ResponsesOutput{},
+		ParallelToolCalls:  request.ParallelToolCalls,
+		PreviousResponseID: request.PreviousResponseID,
+		Reasoning:          request.Reasoning,
+		Store:              request.Store == nil || *request.Store,
+		Temperature:        request.Temperature,
+		ToolChoice:         "auto",
+		Tools:              request.Tools,
+		TopP:               request.TopP,
+		Truncation:         request.Truncation,
+		Metadata:           request.Metadata,
+	}
+	var toolChoice string
+	if len(request.ToolChoice) > 0 && json.Unmarshal(request.ToolChoice, &toolChoice) == nil {
+		response.ToolChoice = toolChoice
+	}
+	if response.Truncation == "" {
+		response.Truncation = "disabled"
+	}
+	if response.Tools == nil {
+		response.Tools = []dto.ResponsesToolsCall{}
+	}
+	if request.User != "" {
+		response.User, _ = json.Marshal(request.User)
+	}
+	return response
+}
+
+func usageOpenAI2Responses(usage *dto.Usage) *dto.Usage {
+	return &dto.Usage{
+		PromptTokens:           usage.PromptTokens,
+		CompletionTokens:       usage.CompletionTokens,
+		TotalTokens:            usage.TotalTokens,
+		PromptTokensDetails:    usage.PromptTokensDetails,
+		CompletionTokenDetails: usage.CompletionTokenDetails,
+		InputTokens:            usage.PromptTokens,
+		OutputTokens:           usage.CompletionTokens,
+		InputTokensDetails:     &dto.InputTokenDetails{CachedTokens: usage.PromptTokensDetails.CachedTokens},
+		OutputTokensDetails:    &dto.OutputTokenDetails{ReasoningTokens: usage.CompletionTokenDetails.ReasoningTokens},
+	}
+}
+
+// completeEmulatedResponse 根据结束原因设置响应状态，因长度截断时为 incomplete
+func completeEmulatedResponse(response *dto.OpenAIResponsesResponse, finishReason string, usage *dto.Usage) {
+	response.Status = "completed"
+	switch finishReason {
+	case constant.FinishReasonLength:
+		response.Status = "incomplete"
+		response.IncompleteDetails = &dto.IncompleteDetails{Reasoning: "max_output_tokens"}
+	case constant.FinishReasonContentFilter:
+		response.Status = "incomplete"
+		response.IncompleteDetails = &dto.IncompleteDetails{Reasoning: "content_filter"}
+	}
+	response.Usage = usageOpenAI2Responses(usage)
+}
+
+func newResponsesMessageItem(text string, status string) dto.ResponsesOutput {
+	return dto.ResponsesOutput{
+		Type:    "message",
+		ID:      "msg_" + common.GetUUID(),
+		Status:  status,
+		Role:    "assistant",
+		Content: []dto.ResponsesOutputContent{{Type: "output_text", Text: text, Annotations: []interface{}{}}},
+	}
+}
+
+func newResponsesFunctionCallItem(callId string, name string, arguments string, status string) dto.ResponsesOutput {
+	if callId == "" {
+		callId = "call_" + common.GetUUID()
+	}
+	return dto.ResponsesOutput{
+		Type:      "function_call",
+		ID:        "fc_" + common.GetUUID(),
+		Status:    status,
+		CallId:    callId,
+		Name:      name,
+		Arguments: common.GetPointer[string](arguments),
+	}
+}
+
+func newResponsesReasoningItem(text string) dto.ResponsesOutput {
+	return dto.ResponsesOutput{
+		Type:    "reasoning",
+		ID:      "rs_" + common.GetUUID(),
+		Content: []dto.ResponsesOutputContent{},
+		Summary: []dto.ResponsesOutputContent{{Type: "summary_text", Text: text}},
+	}
+}
+
+// ResponseOpenAI2Responses 将 Chat Completions 非流式响应的第一个候选转换为 Responses 输出
+func ResponseOpenAI2Responses(openAIResponse *dto.OpenAITextResponse, response *dto.OpenAIResponsesResponse, usage *dto.Usage) {
+	finishReason := ""
+	if len(openAIResponse.Choices) > 0 {
+		choice := openAIResponse.Choices[0]
+		finishReason = choice.FinishReason
+		reasoning := choice.Message.ReasoningContent
+		if reasoning == "" {
+			reasoning = choice.Message.Reasoning
+		}
+		if reasoning != "" {
+			response.Output = append(response.Output, newResponsesReasoningItem(reasoning))
+		}
+		if text := choice.Message.StringContent(); text != "" {
+			response.Output = append(response.Output, newResponsesMessageItem(text, "completed"))
+		}
+		for _, toolCall := range choice.Message.ParseToolCalls() {
+			response.Output = append(response.Output, newResponsesFunctionCallItem(toolCall.ID, toolCall.Function.Name, toolCall.Function.Arguments, "completed"))
+		}
+	}
+	completeEmulatedResponse(response, finishReason, usage)
+}
+
+// ResponsesConvertWriter 将渠道返回的 Chat Completions 响应转换为 Responses 响应写给客户端，
+// 流式响应转换为 response.created、response.output_text.delta、response.completed 等类型化事件
+type ResponsesConvertWriter struct {
+	*OpenAIConvertWriter
+	response       *dto.OpenAIResponsesResponse
+	sequence       int
+	started        bool
+	reasoningIndex int
+	messageIndex   int
+	toolIndexes    map[int]int // Chat Completions 工具调用下标到输出项下标
+	lastToolIndex  int
+	finishReason   string
+}
+
+func NewResponsesConvertWriter(writer gin.ResponseWriter, stream bool, response *dto.OpenAIResponsesResponse) *ResponsesConvertWriter {
+	w := &ResponsesConvertWriter{
+		OpenAIConvertWriter: NewOpenAIConvertWriter(writer, stream),
+		response:            response,
+		reasoningIndex:      -1,
+		messageIndex:        -1,
+		toolIndexes:         make(map[int]int),
+		lastToolIndex:       -1,
+	}
+	w.OnStreamResponse = w.handleStreamResponse
+	return w
+}
+
+func (w *ResponsesConvertWriter) emit(event dto.ResponsesStreamResponse) {
+	event.SequenceNumber = w.sequence
+	w.sequence++
+	w.WriteEvent(event.Type, &event)
+}
+
+func (w *ResponsesConvertWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	w.emit(dto.ResponsesStreamResponse{Type: "response.created", Response: w.response})
+	w.emit(dto.ResponsesStreamResponse{Type: "response.in_progress", Response: w.response})
+}
+
+func (w *ResponsesConvertWriter) addItem(item dto.ResponsesOutput) int {
+	index := len(w.response.Output)
+	w.response.Output = append(w.response.Output, item)
+	w.emit(dto.ResponsesStreamResponse{Type: dto.ResponsesOutputTypeItemAdded, OutputIndex: common.GetPointer[int](index), Item: &item})
+	return index
+}
+
+func (w *ResponsesConvertWriter) closeReasoning() {
+	if w.reasoningIndex < 0 {
+		return
+	}
+	index := w.reasoningIndex
+	w.reasoningIndex = -1
+	item := &w.response.Output[index]
+	part := item.Summary[0]
+	w.emit(dto.ResponsesStreamResponse{Type: "response.reasoning_summary_text.done", ItemId: item.ID, OutputIndex: common.GetPointer[int](index), SummaryIndex: common.GetPointer[int](0), Text: part.Text})
+	w.emit(dto.ResponsesStreamResponse{Type: "response.reasoning_summary_part.done", ItemId: item.ID, OutputIndex: common.GetPointer[int](index), SummaryIndex: common.GetPointer[int](0), Part: &part})
+	w.emit(dto.ResponsesStreamResponse{Type: dto.ResponsesOutputTypeItemDone, OutputIndex: common.GetPointer[int](index), Item: item})
+}
+
+func (w *ResponsesConvertWriter) closeMessage() {
+	if w.messageIndex < 0 {
+		return
+	}
+	index := w.messageIndex
+	w.messageIndex = -1
+	item := &w.response.Output[index]
+	item.Status = "completed"
+	part := item.Content[0]
+	w.emit(dto.ResponsesStreamResponse{Type: "response.output_text.done", ItemId: item.ID, OutputIndex: common.GetPointer[int](index), ContentIndex: common.GetPointer[int](0), Text: part.Text})
+	w.emit(dto.ResponsesStreamResponse{Type: "response.content_part.done", ItemId: item.ID, OutputIndex: common.GetPointer[int](index), ContentIndex: common.GetPointer[int](0), Part: &part})
+	w.emit(dto.ResponsesStreamResponse{Type: dto.ResponsesOutputTypeItemDone, OutputIndex: common.GetPointer[int](index), Item: item})
+}
+
+func (w *ResponsesConvertWriter) closeToolCall() {
+	if w.lastToolIndex < 0 {
+		return
+	}
+	index := w.lastToolIndex
+	w.lastToolIndex = -1
+	item := &w.response.Output[index]
+	item.Status = "completed"
+	w.emit(dto.ResponsesStreamResponse{Type: "response.function_call_arguments.done", ItemId: item.ID, OutputIndex: common.GetPointer[int](index), Arguments: *item.Arguments})
+	w.emit(dto.ResponsesStreamResponse{Type: dto.ResponsesOutputTypeItemDone, OutputIndex: common.GetPointer[int](index), Item: item})
+}
+
+// handleStreamResponse 只转换第一个候选，每种输出在下一种输出开始时结束
+func (w *ResponsesConvertWriter) handleStreamResponse(streamResponse *dto.ChatCompletionsStreamResponse) {
+	w.start()
+	for _, choice := range streamResponse.Choices {
+		if choice.Index != 0 {
+			continue
+		}
+		if reasoning := choice.Delta.GetReasoningContent(); reasoning != "" {
+			if w.reasoningIndex < 0 {
+				w.closeMessage()
+				w.closeToolCall()
+				w.reasoningIndex = w.addItem(newResponsesReasoningItem(""))
+				item := w.response.Output[w.reasoningIndex]
+				w.emit(dto.ResponsesStreamResponse{Type: "response.reasoning_summary_part.added", ItemId: item.ID, OutputIndex: common.GetPointer[int](w.reasoningIndex), SummaryIndex: common.GetPointer[int](0), Part: &item.Summary[0]})
+			}
+			item := &w.response.Output[w.reasoningIndex]
+			item.Summary[0].Text += reasoning
+			w.emit(dto.ResponsesStreamResponse{Type: "response.reasoning_summary_text.delta", ItemId: item.ID, OutputIndex: common.GetPointer[int](w.reasoningIndex), SummaryIndex: common.GetPointer[int](0), Delta: reasoning})
+		}
+		if text := choice.Delta.GetContentString(); text != "" {
+			if w.messageIndex < 0 {
+				w.closeReasoning()
+				w.closeToolCall()
+				w.messageIndex = w.addItem(newResponsesMessageItem("", "in_progress"))
+				item := w.response.Output[w.messageIndex]
+				w.emit(dto.ResponsesStreamResponse{Type: "response.content_part.added", ItemId: item.ID, OutputIndex: common.GetPointer[int](w.messageIndex), ContentIndex: common.GetPointer[int](0), Part: &item.Content[0]})
+			}
+			item := &w.response.Output[w.messageIndex]
+			item.Content[0].Text += text
+			w.emit(dto.ResponsesStreamResponse{Type: "response.output_text.delta", ItemId: item.ID, OutputIndex: common.GetPointer[int](w.messageIndex), ContentIndex: common.GetPointer[int](0), Delta: text})
+		}
+		for _, toolCall := range choice.Delta.ToolCalls {
+			toolIndex := len(w.toolIndexes)
+			if toolCall.Index != nil {
+				toolIndex = *toolCall.Index
+			} else if toolCall.ID == "" && len(w.toolIndexes) > 0 {
+				toolIndex = len(w.toolIndexes) - 1
+			}
+			index, ok := w.toolIndexes[toolIndex]
+			if !ok {
+				w.closeReasoning()
+				w.closeMessage()
+				w.closeToolCall()
+				index = w.addItem(newResponsesFunctionCallItem(toolCall.ID, toolCall.Function.Name, "", "in_progress"))
+				w.toolIndexes[toolIndex] = index
+				w.lastToolIndex = index
+			}
+			item := &w.response.Output[index]
+			if item.Name == "" && toolCall.Function.Name != "" {
+				item.Name = toolCall.Function.Name
+			}
+			if toolCall.Function.Arguments != "" {
+				*item.Arguments += toolCall.Function.Arguments
+				w.emit(dto.ResponsesStreamResponse{Type: "response.function_call_arguments.delta", ItemId: item.ID, OutputIndex: common.GetPointer[int](index), Delta: toolCall.Function.Arguments})
+			}
+		}
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			w.finishReason = *choice.FinishReason
+		}
+	}
+}
+
+// Finish 写出转换后的结果并返回最终的响应对象，上游响应无法转换时原样写出并返回 nil
+func (w *ResponsesConvertWriter) Finish(usage *dto.Usage) *dto.OpenAIResponsesResponse {
+	if w.Stream {
+		w.start()
+		w.closeReasoning()
+		w.closeMessage()
+		w.closeToolCall()
+		completeEmulatedResponse(w.response, w.finishReason, usage)
+		eventType := "response.completed"
+		if w.response.Status == "incomplete" {
+			eventType = "response.incomplete"
+		}
+		w.emit(dto.ResponsesStreamResponse{Type: eventType, Response: w.response})
+		return w.response
+	}
+
+	var openAIResponse dto.OpenAITextResponse
+	if w.Status() != http.StatusOK || json.Unmarshal(w.Body(), &openAIResponse) != nil {
+		w.WriteBody(w.Body())
+		return nil
+	}
+	ResponseOpenAI2Responses(&openAIResponse, w.response, usage)
+	data, err := json.Marshal(w.response)
+	if err != nil {
+		w.WriteBody(w.Body())
+		return nil
+	}
+	w.WriteBody(data)
+	return w.response
+}
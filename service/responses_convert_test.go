@@ -0,0 +1,211 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"one-api/dto"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseResponsesRequest(t *testing.T, body string) (*dto.OpenAIResponsesRequest, []dto.ResponsesInputItem) {
+	var request dto.OpenAIResponsesRequest
+	assert.NoError(t, json.Unmarshal([]byte(body), &request))
+	var items []dto.ResponsesInputItem
+	assert.NoError(t, json.Unmarshal(request.Input, &items))
+	return &request, items
+}
+
+// TestResponsesToOpenAIRequest 测试 Responses 请求的指令、输入项、工具与输出格式转换
+func TestResponsesToOpenAIRequest(t *testing.T) {
+	request, items := parseResponsesRequest(t, `{
+		"model": "gpt-4o",
+		"instructions": "be brief",
+		"max_output_tokens": 256,
+		"temperature": 0.5,
+		"reasoning": {"effort": "high"},
+		"tools": [{"type": "function", "name": "get_weather", "description": "weather", "parameters": {"type": "object"}}],
+		"tool_choice": {"type": "function", "name": "get_weather"},
+		"text": {"format": {"type": "json_schema", "name": "answer", "schema": {"type": "object"}}},
+		"input": [
+			{"type": "message", "role": "developer", "content": "use celsius"},
+			{"type": "message", "role": "user", "content": [
+				{"type": "input_text", "text": "weather in paris and rome?"},
+				{"type": "input_image", "image_url": "https://example.com/a.png"}
+			]},
+			{"type": "reasoning", "id": "rs_1"},
+			{"type": "function_call", "call_id": "call_1", "name": "get_weather", "arguments": "{\"city\":\"paris\"}"},
+			{"type": "function_call", "call_id": "call_2", "name": "get_weather", "arguments": "{\"city\":\"rome\"}"},
+			{"type": "function_call_output", "call_id": "call_1", "output": "20C"},
+			{"type": "function_call_output", "call_id": "call_2", "output": [{"type": "input_text", "text": "25C"}]}
+		]
+	}`)
+
+	openAIRequest, err := ResponsesToOpenAIRequest(request, items)
+	assert.NoError(t, err)
+	assert.Equal(t, "gpt-4o", openAIRequest.Model)
+	assert.Equal(t, uint(256), openAIRequest.MaxTokens)
+	assert.Equal(t, 0.5, *openAIRequest.Temperature)
+	assert.Equal(t, "high", openAIRequest.ReasoningEffort)
+
+	assert.Len(t, openAIRequest.Tools, 1)
+	assert.Equal(t, "get_weather", openAIRequest.Tools[0].Function.Name)
+	assert.Equal(t, map[string]any{"type": "function", "function": map[string]any{"name": "get_weather"}}, openAIRequest.ToolChoice)
+	assert.Equal(t, "json_schema", openAIRequest.ResponseFormat.Type)
+	assert.Equal(t, "answer", openAIRequest.ResponseFormat.JsonSchema.Name)
+
+	messages := openAIRequest.Messages
+	assert.Len(t, messages, 6)
+	assert.Equal(t, "system", messages[0].Role)
+	assert.Equal(t, "be brief", messages[0].StringContent())
+	assert.Equal(t, "system", messages[1].Role)
+	assert.Equal(t, "use celsius", messages[1].StringContent())
+
+	assert.Equal(t, "user", messages[2].Role)
+	contents := messages[2].ParseContent()
+	assert.Len(t, contents, 2)
+	assert.Equal(t, dto.ContentTypeText, contents[0].Type)
+	assert.Equal(t, dto.ContentTypeImageURL, contents[1].Type)
+
+	// 连续的函数调用合并为一条助手消息
+	assert.Equal(t, "assistant", messages[3].Role)
+	toolCalls := messages[3].ParseToolCalls()
+	assert.Len(t, toolCalls, 2)
+	assert.Equal(t, "call_1", toolCalls[0].ID)
+	assert.Equal(t, "call_2", toolCalls[1].ID)
+
+	assert.Equal(t, "tool", messages[4].Role)
+	assert.Equal(t, "call_1", messages[4].ToolCallId)
+	assert.Equal(t, "20C", messages[4].StringContent())
+	assert.Equal(t, "25C", messages[5].StringContent())
+}
+
+// TestResponsesToOpenAIRequestUnsupported 测试不支持的工具与输入项返回错误
+func TestResponsesToOpenAIRequestUnsupported(t *testing.T) {
+	request, items := parseResponsesRequest(t, `{"model": "gpt-4o", "tools": [{"type": "web_search_preview"}], "input": []}`)
+	_, err := ResponsesToOpenAIRequest(request, items)
+	assert.Error(t, err)
+
+	request, items = parseResponsesRequest(t, `{"model": "gpt-4o", "input": [{"type": "computer_call"}]}`)
+	_, err = ResponsesToOpenAIRequest(request, items)
+	assert.Error(t, err)
+}
+
+type responsesEvent struct {
+	Event string
+	Data  dto.ResponsesStreamResponse
+}
+
+func parseResponsesEvents(t *testing.T, body string) []responsesEvent {
+	var events []responsesEvent
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		var event responsesEvent
+		for _, line := range strings.Split(block, "\n") {
+			if strings.HasPrefix(line, "event: ") {
+				event.Event = strings.TrimPrefix(line, "event: ")
+			} else if strings.HasPrefix(line, "data: ") {
+				assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.Data))
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func newResponsesTestWriter(stream bool) (*httptest.ResponseRecorder, *ResponsesConvertWriter) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	request := &dto.OpenAIResponsesRequest{Model: "gpt-4o"}
+	return recorder, NewResponsesConvertWriter(c.Writer, stream, NewEmulatedResponse(request, "resp_1", "gpt-4o"))
+}
+
+// TestResponsesConvertWriterStream 测试流式响应按推理、文本、工具调用的顺序输出类型化事件
+func TestResponsesConvertWriterStream(t *testing.T) {
+	recorder, writer := newResponsesTestWriter(true)
+	chunks := []string{
+		`{"id":"c1","choices":[{"index":0,"delta":{"reasoning_content":"think"}}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{"content":"Hel"}}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{"content":"lo"}}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\""}}]}}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":":\"paris\"}"}}]}}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+	}
+	for _, chunk := range chunks {
+		_, _ = writer.Write([]byte("data: " + chunk + "\n\n"))
+	}
+	_, _ = writer.Write([]byte("data: [DONE]\n\n"))
+	response := writer.Finish(&dto.Usage{PromptTokens: 3, CompletionTokens: 5, TotalTokens: 8})
+
+	events := parseResponsesEvents(t, recorder.Body.String())
+	types := make([]string, 0, len(events))
+	for i, event := range events {
+		assert.Equal(t, event.Event, event.Data.Type)
+		assert.Equal(t, i, event.Data.SequenceNumber)
+		types = append(types, event.Event)
+	}
+	assert.Equal(t, []string{
+		"response.created",
+		"response.in_progress",
+		"response.output_item.added",
+		"response.reasoning_summary_part.added",
+		"response.reasoning_summary_text.delta",
+		"response.reasoning_summary_text.done",
+		"response.reasoning_summary_part.done",
+		"response.output_item.done",
+		"response.output_item.added",
+		"response.content_part.added",
+		"response.output_text.delta",
+		"response.output_text.delta",
+		"response.output_text.done",
+		"response.content_part.done",
+		"response.output_item.done",
+		"response.output_item.added",
+		"response.function_call_arguments.delta",
+		"response.function_call_arguments.delta",
+		"response.function_call_arguments.done",
+		"response.output_item.done",
+		"response.completed",
+	}, types)
+	assert.Equal(t, "Hello", events[12].Data.Text)
+	assert.Equal(t, `{"city":"paris"}`, events[18].Data.Arguments)
+
+	assert.Equal(t, "completed", response.Status)
+	assert.Len(t, response.Output, 3)
+	assert.Equal(t, "reasoning", response.Output[0].Type)
+	assert.Equal(t, "Hello", response.Output[1].Content[0].Text)
+	assert.Equal(t, "call_1", response.Output[2].CallId)
+	assert.Equal(t, "get_weather", response.Output[2].Name)
+	assert.Equal(t, 8, response.Usage.TotalTokens)
+	assert.Equal(t, 3, response.Usage.InputTokens)
+}
+
+// TestResponsesConvertWriterStreamIncomplete 测试因长度截断结束时输出 response.incomplete 事件
+func TestResponsesConvertWriterStreamIncomplete(t *testing.T) {
+	recorder, writer := newResponsesTestWriter(true)
+	_, _ = writer.Write([]byte(`data: {"id":"c1","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"length"}]}` + "\n\n"))
+	response := writer.Finish(&dto.Usage{})
+
+	events := parseResponsesEvents(t, recorder.Body.String())
+	assert.Equal(t, "response.incomplete", events[len(events)-1].Event)
+	assert.Equal(t, "incomplete", response.Status)
+	assert.Equal(t, "max_output_tokens", response.IncompleteDetails.Reasoning)
+}
+
+// TestResponsesConvertWriterNonStream 测试非流式响应转换为 Responses 响应对象
+func TestResponsesConvertWriterNonStream(t *testing.T) {
+	recorder, writer := newResponsesTestWriter(false)
+	_, _ = writer.Write([]byte(`{"id":"c1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`))
+	response := writer.Finish(&dto.Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2})
+	assert.NotNil(t, response)
+
+	var written dto.OpenAIResponsesResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &written))
+	assert.Equal(t, "resp_1", written.ID)
+	assert.Equal(t, "completed", written.Status)
+	assert.Len(t, written.Output, 1)
+	assert.Equal(t, "Hello", written.Output[0].Content[0].Text)
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"one-api/common"
+	"one-api/model"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// StatementLine 账单中按模型、令牌、分组与计费倍率汇总的消费
+type StatementLine struct {
+	ModelName        string  `json:"model_name"`
+	TokenName        string  `json:"token_name"`
+	Group            string  `json:"group"`
+	ModelRatio       float64 `json:"model_ratio"`
+	CompletionRatio  float64 `json:"completion_ratio"`
+	GroupRatio       float64 `json:"group_ratio"`
+	ModelPrice       float64 `json:"model_price"`
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Quota            int     `json:"quota"`
+	Amount           float64 `json:"amount"`
+	// 来自已清理日志的按天汇总，没有倍率明细。只计入完整落在账单期内的日期，
+	// 账单期首尾不足一天部分中已被清理的消费不会出现在账单中
+	Archived bool `json:"archived"`
+}
+
+// StatementCredit 账单期内的充值与兑换
+type StatementCredit struct {
+	Type      string  `json:"type"` // topup 或 redemption
+	Reference string  `json:"reference"`
+	Time      int64   `json:"time"`
+	Quota     int     `json:"quota"`
+	Amount    float64 `json:"amount"`
+	Money     float64 `json:"money"`
+}
+
+// Statement 用户在账单期内的消费与充值汇总，金额按 QuotaPerUnit 换算
+type Statement struct {
+	UserId         int                `json:"user_id"`
+	Username       string             `json:"username"`
+	StartTimestamp int64              `json:"start_timestamp"`
+	EndTimestamp   int64              `json:"end_timestamp"`
+	GeneratedAt    int64              `json:"generated_at"`
+	Lines          []*StatementLine   `json:"lines"`
+	Credits        []*StatementCredit `json:"credits"`
+	TotalRequests  int                `json:"total_requests"`
+	TotalQuota     int                `json:"total_quota"`
+	TotalAmount    float64            `json:"total_amount"`
+	CreditQuota    int                `json:"credit_quota"`
+	CreditAmount   float64            `json:"credit_amount"`
+}
+
+type statementLineKey struct {
+	modelName       string
+	tokenName       string
+	group           string
+	modelRatio      float64
+	completionRatio float64
+	groupRatio      float64
+	modelPrice      float64
+	archived        bool
+}
+
+func quotaToAmount(quota int) float64 {
+	return float64(quota) / common.QuotaPerUnit
+}
+
+func otherFloat(other map[string]interface{}, key string) float64 {
+	if v, ok := other[key].(float64); ok {
+		return v
+	}
+	return 0
+}
+
+// GenerateStatement 汇总用户在 [startTimestamp, endTimestamp) 内的消费日志、完整日期的已汇总历史消费以及充值与兑换
+func GenerateStatement(userId int, startTimestamp int64, endTimestamp int64) (*Statement, error) {
+	username, _ := model.GetUsernameById(userId, false)
+	statement := &Statement{
+		UserId:         userId,
+		Username:       username,
+		StartTimestamp: startTimestamp,
+		EndTimestamp:   endTimestamp,
+		GeneratedAt:    common.GetTimestamp(),
+		Lines:          []*StatementLine{},
+		Credits:        []*StatementCredit{},
+	}
+	lines := make(map[statementLineKey]*StatementLine)
+	addLine := func(key statementLineKey, requests int, promptTokens int, completionTokens int, quota int) {
+		line, ok := lines[key]
+		if !ok {
+			line = &StatementLine{
+				ModelName:       key.modelName,
+				TokenName:       key.tokenName,
+				Group:           key.group,
+				ModelRatio:      key.modelRatio,
+				CompletionRatio: key.completionRatio,
+				GroupRatio:      key.groupRatio,
+				ModelPrice:      key.modelPrice,
+				Archived:        key.archived,
+			}
+			lines[key] = line
+		}
+		line.Requests += requests
+		line.PromptTokens += promptTokens
+		line.CompletionTokens += completionTokens
+		line.Quota += quota
+	}
+
+	err := model.ScanUserConsumeLogs(userId, startTimestamp, endTimestamp, func(logs []*model.Log) {
+		for _, log := range logs {
+			other := common.StrToMap(log.Other)
+			addLine(statementLineKey{
+				modelName:       log.ModelName,
+				tokenName:       log.TokenName,
+				group:           log.Group,
+				modelRatio:      otherFloat(other, "model_ratio"),
+				completionRatio: otherFloat(other, "completion_ratio"),
+				groupRatio:      otherFloat(other, "group_ratio"),
+				modelPrice:      otherFloat(other, "model_price"),
+			}, 1, log.PromptTokens, log.CompletionTokens, log.Quota)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats, err := model.GetUserConsumeDailyStats(userId, startTimestamp, endTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	for _, stat := range stats {
+		addLine(statementLineKey{
+			modelName: stat.ModelName,
+			tokenName: stat.TokenName,
+			group:     stat.Group,
+			archived:  true,
+		}, stat.RequestCount, stat.PromptTokens, stat.CompletionTokens, stat.Quota)
+	}
+	for _, line := range lines {
+		line.Amount = quotaToAmount(line.Quota)
+		statement.Lines = append(statement.Lines, line)
+		statement.TotalRequests += line.Requests
+		statement.TotalQuota += line.Quota
+	}
+	sort.Slice(statement.Lines, func(i, j int) bool {
+		a, b := statement.Lines[i], statement.Lines[j]
+		if a.ModelName != b.ModelName {
+			return a.ModelName < b.ModelName
+		}
+		if a.TokenName != b.TokenName {
+			return a.TokenName < b.TokenName
+		}
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		return a.Quota > b.Quota
+	})
+	statement.TotalAmount = quotaToAmount(statement.TotalQuota)
+
+	topUps, err := model.GetUserSuccessTopUps(userId, startTimestamp, endTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	for _, topUp := range topUps {
+		quota := int(float64(topUp.Amount) * common.QuotaPerUnit)
+		statement.Credits = append(statement.Credits, &StatementCredit{
+			Type:      "topup",
+			Reference: topUp.TradeNo,
+			Time:      topUp.CreateTime,
+			Quota:     quota,
+			Amount:    quotaToAmount(quota),
+			Money:     topUp.Money,
+		})
+	}
+	redemptions, err := model.GetUserUsedRedemptions(userId, startTimestamp, endTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	for _, redemption := range redemptions {
+		statement.Credits = append(statement.Credits, &StatementCredit{
+			Type:      "redemption",
+			Reference: redemption.Name,
+			Time:      redemption.RedeemedTime,
+			Quota:     redemption.Quota,
+			Amount:    quotaToAmount(redemption.Quota),
+		})
+	}
+	sort.SliceStable(statement.Credits, func(i, j int) bool {
+		return statement.Credits[i].Time < statement.Credits[j].Time
+	})
+	for _, credit := range statement.Credits {
+		statement.CreditQuota += credit.Quota
+	}
+	statement.CreditAmount = quotaToAmount(statement.CreditQuota)
+	return statement, nil
+}
+
+// GenerateStatements 为时间范围内有消费或充值记录的所有用户生成账单
+func GenerateStatements(startTimestamp int64, endTimestamp int64) ([]*Statement, error) {
+	userIds, err := model.GetStatementUserIds(startTimestamp, endTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	sort.Ints(userIds)
+	statements := make([]*Statement, 0, len(userIds))
+	for _, userId := range userIds {
+		statement, err := GenerateStatement(userId, startTimestamp, endTimestamp)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+	}
+	return statements, nil
+}
+
+func formatStatementFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatStatementAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 6, 64)
+}
+
+func formatStatementTime(timestamp int64) string {
+	return time.Unix(timestamp, 0).Format("2006-01-02 15:04:05")
+}
+
+var statementCsvHeader = []string{"user_id", "username", "period_start", "period_end", "record_type", "model_name", "token_name", "group",
+	"model_ratio", "completion_ratio", "group_ratio", "model_price", "requests", "prompt_tokens", "completion_tokens",
+	"quota", "amount", "reference", "time", "money", "archived"}
+
+func writeStatementCsvRows(w *csv.Writer, statement *Statement) error {
+	prefix := []string{strconv.Itoa(statement.UserId), statement.Username,
+		formatStatementTime(statement.StartTimestamp), formatStatementTime(statement.EndTimestamp)}
+	for _, line := range statement.Lines {
+		row := append(append([]string{}, prefix...), "usage", line.ModelName, line.TokenName, line.Group,
+			formatStatementFloat(line.ModelRatio), formatStatementFloat(line.CompletionRatio), formatStatementFloat(line.GroupRatio), formatStatementFloat(line.ModelPrice),
+			strconv.Itoa(line.Requests), strconv.Itoa(line.PromptTokens), strconv.Itoa(line.CompletionTokens),
+			strconv.Itoa(line.Quota), formatStatementAmount(line.Amount), "", "", "", strconv.FormatBool(line.Archived))
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	for _, credit := range statement.Credits {
+		row := append(append([]string{}, prefix...), credit.Type, "", "", "", "", "", "", "", "", "", "",
+			strconv.Itoa(credit.Quota), formatStatementAmount(credit.Amount), credit.Reference, formatStatementTime(credit.Time),
+			formatStatementFloat(credit.Money), "false")
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteStatementsCSV 以 CSV 格式输出账单，每个消费汇总或充值记录一行
+func WriteStatementsCSV(writer io.Writer, statements []*Statement) error {
+	w := csv.NewWriter(writer)
+	if err := w.Write(statementCsvHeader); err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		if err := writeStatementCsvRows(w, statement); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+var statementTemplate = template.Must(template.New("statement").Funcs(template.FuncMap{
+	"time":   formatStatementTime,
+	"amount": formatStatementAmount,
+	"ratio":  formatStatementFloat,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Statement {{.Username}} {{time .StartTimestamp}} - {{time .EndTimestamp}}</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; margin: 32px; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 24px; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+td.num { text-align: right; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h2>Statement</h2>
+<p>User: {{.Username}} (#{{.UserId}})<br>
+Period: {{time .StartTimestamp}} - {{time .EndTimestamp}}<br>
+Generated: {{time .GeneratedAt}}</p>
+<h3>Usage</h3>
+<table>
+<tr><th>Model</th><th>Token</th><th>Group</th><th>Model ratio</th><th>Completion ratio</th><th>Group ratio</th><th>Model price</th><th>Requests</th><th>Prompt tokens</th><th>Completion tokens</th><th>Quota</th><th>Amount</th></tr>
+{{range .Lines}}<tr><td>{{.ModelName}}</td><td>{{.TokenName}}</td><td>{{.Group}}</td>{{if .Archived}}<td colspan="4">archived daily summary</td>{{else}}<td class="num">{{ratio .ModelRatio}}</td><td class="num">{{ratio .CompletionRatio}}</td><td class="num">{{ratio .GroupRatio}}</td><td class="num">{{ratio .ModelPrice}}</td>{{end}}<td class="num">{{.Requests}}</td><td class="num">{{.PromptTokens}}</td><td class="num">{{.CompletionTokens}}</td><td class="num">{{.Quota}}</td><td class="num">{{amount .Amount}}</td></tr>
+{{end}}<tr><th colspan="7">Total</th><th class="num">{{.TotalRequests}}</th><th></th><th></th><th class="num">{{.TotalQuota}}</th><th class="num">{{amount .TotalAmount}}</th></tr>
+</table>
+<h3>Credits</h3>
+<table>
+<tr><th>Time</th><th>Type</th><th>Reference</th><th>Quota</th><th>Amount</th><th>Paid</th></tr>
+{{range .Credits}}<tr><td>{{time .Time}}</td><td>{{.Type}}</td><td>{{.Reference}}</td><td class="num">{{.Quota}}</td><td class="num">{{amount .Amount}}</td><td class="num">{{ratio .Money}}</td></tr>
+{{end}}<tr><th colspan="3">Total</th><th class="num">{{.CreditQuota}}</th><th class="num">{{amount .CreditAmount}}</th><th></th></tr>
+</table>
+</body>
+</html>
+`))
+
+// WriteStatementHTML 输出可打印为 PDF 的 HTML 账单
+func WriteStatementHTML(writer io.Writer, statement *Statement) error {
+	return statementTemplate.Execute(writer, statement)
+}
+
+// StatementFileName 账单下载文件名
+func StatementFileName(statement *Statement, ext string) string {
+	return fmt.Sprintf("statement-%d-%s-%s.%s", statement.UserId,
+		time.Unix(statement.StartTimestamp, 0).Format("20060102"), time.Unix(statement.EndTimestamp, 0).Format("20060102"), ext)
+}
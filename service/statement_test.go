@@ -0,0 +1,115 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"one-api/common"
+	"one-api/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerateStatement 测试账单按模型、令牌和倍率汇总消费日志，合并已汇总的历史消费，并统计期内充值与兑换
+func TestGenerateStatement(t *testing.T) {
+	setupTestDB(t)
+	user := &model.User{Username: "statement", Password: "password", Group: "default"}
+	require.NoError(t, model.DB.Create(user).Error)
+	start := time.Date(2026, 9, 1, 0, 0, 0, 0, time.Local).Unix()
+	end := time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local).Unix()
+	ratio := `{"model_ratio":2.5,"completion_ratio":4,"group_ratio":1}`
+	logs := []*model.Log{
+		{UserId: user.Id, CreatedAt: start + 10, Type: model.LogTypeConsume, ModelName: "gpt-4o", TokenName: "a", Group: "default", Quota: 100, PromptTokens: 10, CompletionTokens: 5, Other: ratio},
+		{UserId: user.Id, CreatedAt: start + 20, Type: model.LogTypeConsume, ModelName: "gpt-4o", TokenName: "a", Group: "default", Quota: 200, PromptTokens: 20, CompletionTokens: 5, Other: ratio},
+		{UserId: user.Id, CreatedAt: start + 30, Type: model.LogTypeConsume, ModelName: "gpt-4o", TokenName: "a", Group: "default", Quota: 50, Other: `{"model_ratio":1}`},
+		{UserId: user.Id, CreatedAt: end, Type: model.LogTypeConsume, ModelName: "gpt-4o", Quota: 1000},
+		{UserId: user.Id, CreatedAt: start + 40, Type: model.LogTypeError, ModelName: "gpt-4o", Quota: 1000},
+	}
+	require.NoError(t, model.LOG_DB.Create(&logs).Error)
+	require.NoError(t, model.LOG_DB.Create(&model.LogDailyStat{Day: "2026-09-02", Type: model.LogTypeConsume, UserId: user.Id,
+		ModelName: "claude", TokenName: "a", Group: "default", RequestCount: 3, Quota: 300}).Error)
+	require.NoError(t, model.DB.Create(&model.TopUp{UserId: user.Id, Amount: 2, Money: 14, TradeNo: "trade-1", CreateTime: start + 50, Status: "success"}).Error)
+	require.NoError(t, model.DB.Create(&model.TopUp{UserId: user.Id, Amount: 5, TradeNo: "trade-2", CreateTime: start + 60, Status: "pending"}).Error)
+	require.NoError(t, model.DB.Create(&model.Redemption{Key: "statementkey", Name: "gift", Status: common.RedemptionCodeStatusUsed,
+		Quota: 500, UsedUserId: user.Id, RedeemedTime: start + 40}).Error)
+
+	statement, err := GenerateStatement(user.Id, start, end)
+	require.NoError(t, err)
+	assert.Equal(t, "statement", statement.Username)
+	require.Len(t, statement.Lines, 3)
+	assert.Equal(t, "claude", statement.Lines[0].ModelName)
+	assert.True(t, statement.Lines[0].Archived)
+	assert.Equal(t, 3, statement.Lines[0].Requests)
+	assert.Equal(t, 2.5, statement.Lines[1].ModelRatio)
+	assert.Equal(t, 2, statement.Lines[1].Requests)
+	assert.Equal(t, 30, statement.Lines[1].PromptTokens)
+	assert.Equal(t, 300, statement.Lines[1].Quota)
+	assert.Equal(t, 1.0, statement.Lines[2].ModelRatio)
+	assert.Equal(t, 6, statement.TotalRequests)
+	assert.Equal(t, 650, statement.TotalQuota)
+	assert.InDelta(t, 650/common.QuotaPerUnit, statement.TotalAmount, 1e-9)
+
+	require.Len(t, statement.Credits, 2)
+	assert.Equal(t, "redemption", statement.Credits[0].Type)
+	assert.Equal(t, "topup", statement.Credits[1].Type)
+	assert.Equal(t, int(2*common.QuotaPerUnit), statement.Credits[1].Quota)
+	assert.Equal(t, 500+int(2*common.QuotaPerUnit), statement.CreditQuota)
+
+	userIds, err := model.GetStatementUserIds(start, end)
+	require.NoError(t, err)
+	assert.Equal(t, []int{user.Id}, userIds)
+}
+
+// TestGenerateStatementPartialDays 测试账单期首尾不足一天时不计入当天的按天汇总，避免多算
+func TestGenerateStatementPartialDays(t *testing.T) {
+	setupTestDB(t)
+	for _, day := range []string{"2026-09-01", "2026-09-02", "2026-09-03"} {
+		require.NoError(t, model.LOG_DB.Create(&model.LogDailyStat{Day: day, Type: model.LogTypeConsume, UserId: 1,
+			ModelName: "claude", RequestCount: 1, Quota: 100}).Error)
+	}
+	start := time.Date(2026, 9, 1, 12, 0, 0, 0, time.Local).Unix()
+	end := time.Date(2026, 9, 3, 12, 0, 0, 0, time.Local).Unix()
+	statement, err := GenerateStatement(1, start, end)
+	require.NoError(t, err)
+	assert.Equal(t, 100, statement.TotalQuota)
+
+	end = time.Date(2026, 9, 2, 0, 0, 0, 0, time.Local).Unix()
+	statement, err = GenerateStatement(1, start, end)
+	require.NoError(t, err)
+	assert.Zero(t, statement.TotalQuota)
+	userIds, err := model.GetStatementUserIds(start, end)
+	require.NoError(t, err)
+	assert.Empty(t, userIds)
+
+	start = time.Date(2026, 9, 1, 0, 0, 0, 0, time.Local).Unix()
+	statement, err = GenerateStatement(1, start, end)
+	require.NoError(t, err)
+	assert.Equal(t, 100, statement.TotalQuota)
+}
+
+// TestWriteStatements 测试 CSV 每条消费汇总和充值记录各占一行，HTML 对用户名转义
+func TestWriteStatements(t *testing.T) {
+	statement := &Statement{
+		UserId:   1,
+		Username: "<b>alice</b>",
+		Lines:    []*StatementLine{{ModelName: "gpt-4o", Requests: 2, Quota: 300, Amount: 0.0006}},
+		Credits:  []*StatementCredit{{Type: "topup", Reference: "trade-1", Quota: 1000, Money: 7}},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, WriteStatementsCSV(&buf, []*Statement{statement}))
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, statementCsvHeader, records[0])
+	assert.Equal(t, "usage", records[1][4])
+	assert.Equal(t, "0.000600", records[1][16])
+	assert.Equal(t, "topup", records[2][4])
+	assert.Equal(t, "trade-1", records[2][17])
+
+	buf.Reset()
+	require.NoError(t, WriteStatementHTML(&buf, statement))
+	assert.Contains(t, buf.String(), "&lt;b&gt;alice&lt;/b&gt;")
+	assert.NotContains(t, buf.String(), "<b>alice</b>")
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"one-api/common"
+	"one-api/model"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestDB 使用临时 SQLite 数据库作为主库和日志库并完成迁移，测试结束后关闭并恢复全局配置
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	t.Setenv("SQL_DSN", "")
+	t.Setenv("LOG_SQL_DSN", "")
+	isMasterNode, redisEnabled := common.IsMasterNode, common.RedisEnabled
+	sqlitePath, usingSQLite := common.SQLitePath, common.UsingSQLite
+	common.IsMasterNode = true
+	common.RedisEnabled = false
+	common.SQLitePath = filepath.Join(t.TempDir(), "one-api.db") + "?_busy_timeout=5000"
+	require.NoError(t, model.InitDB())
+	require.NoError(t, model.InitLogDB())
+	t.Cleanup(func() {
+		_ = model.CloseDB()
+		common.IsMasterNode, common.RedisEnabled = isMasterNode, redisEnabled
+		common.SQLitePath, common.UsingSQLite = sqlitePath, usingSQLite
+	})
+}
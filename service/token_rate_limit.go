@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/setting/operation_setting"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TPM 计数按分钟分桶，用当前分钟与上一分钟按时间加权近似 60 秒滑动窗口
+const tokenRateLimitKeyTTL = 2 * time.Minute
+
+type tokenRateLimitScope struct {
+	name  string
+	id    string
+	limit int
+}
+
+// 未启用 Redis 时的本地计数
+var tokenRateLimitCounters = make(map[string]int64)
+var tokenRateLimitLock sync.Mutex
+var tokenRateLimitLastPurge int64
+
+func getTokenRateLimitScopes(relayInfo *relaycommon.RelayInfo) []tokenRateLimitScope {
+	setting := operation_setting.GetTokenRateLimitSetting()
+	if !setting.Enabled || relayInfo.IsPlayground {
+		return nil
+	}
+	var scopes []tokenRateLimitScope
+	if setting.UserTPM > 0 {
+		scopes = append(scopes, tokenRateLimitScope{name: "user", id: strconv.Itoa(relayInfo.UserId), limit: setting.UserTPM})
+	}
+	if setting.TokenTPM > 0 && relayInfo.TokenId > 0 {
+		scopes = append(scopes, tokenRateLimitScope{name: "token", id: strconv.Itoa(relayInfo.TokenId), limit: setting.TokenTPM})
+	}
+	if limit := operation_setting.GetGroupTPM(relayInfo.Group); limit > 0 {
+		scopes = append(scopes, tokenRateLimitScope{name: "group", id: relayInfo.Group, limit: limit})
+	}
+	return scopes
+}
+
+func tokenRateLimitKey(scope tokenRateLimitScope, minute int64) string {
+	return fmt.Sprintf("tpm:%s:%s:%d", scope.name, scope.id, minute)
+}
+
+// incrTokenRateLimit 累加指定分钟的计数并返回当前分钟与上一分钟的计数
+func incrTokenRateLimit(scope tokenRateLimitScope, minute int64, tokens int) (int64, int64, error) {
+	key := tokenRateLimitKey(scope, minute)
+	prevKey := tokenRateLimitKey(scope, minute-1)
+	if common.RedisEnabled {
+		ctx := context.Background()
+		pipe := common.RDB.TxPipeline()
+		incr := pipe.IncrBy(ctx, key, int64(tokens))
+		pipe.Expire(ctx, key, tokenRateLimitKeyTTL)
+		prev := pipe.Get(ctx, prevKey)
+		_, _ = pipe.Exec(ctx)
+		if incr.Err() != nil {
+			return 0, 0, incr.Err()
+		}
+		prevCount, _ := prev.Int64()
+		return incr.Val(), prevCount, nil
+	}
+	tokenRateLimitLock.Lock()
+	defer tokenRateLimitLock.Unlock()
+	tokenRateLimitCounters[key] += int64(tokens)
+	if minute > tokenRateLimitLastPurge {
+		// 每分钟清理一次过期的计数
+		for k := range tokenRateLimitCounters {
+			var m int64
+			if idx := strings.LastIndexByte(k, ':'); idx >= 0 {
+				m, _ = strconv.ParseInt(k[idx+1:], 10, 64)
+			}
+			if m < minute-1 {
+				delete(tokenRateLimitCounters, k)
+			}
+		}
+		tokenRateLimitLastPurge = minute
+	}
+	return tokenRateLimitCounters[key], tokenRateLimitCounters[prevKey], nil
+}
+
+// slidingTokenCount 按当前分钟已过去的比例加权上一分钟的计数
+func slidingTokenCount(current int64, prev int64, now time.Time) int64 {
+	if current < 0 {
+		current = 0
+	}
+	if prev < 0 {
+		prev = 0
+	}
+	elapsed := float64(now.Second()) + float64(now.Nanosecond())/1e9
+	return current + int64(float64(prev)*(60-elapsed)/60)
+}
+
+func setTokenRateLimitHeaders(c *gin.Context, limit int, remaining int64, now time.Time) {
+	if remaining < 0 {
+		remaining = 0
+	}
+	reset := time.Duration(60-now.Second()) * time.Second
+	c.Header("x-ratelimit-limit-tokens", strconv.Itoa(limit))
+	c.Header("x-ratelimit-remaining-tokens", strconv.FormatInt(remaining, 10))
+	c.Header("x-ratelimit-reset-tokens", reset.String())
+}
+
+// ReserveTokenRateLimit 在转发前按预估的提示 token 数预留 TPM 额度，超出任一限制时返回 429
+func ReserveTokenRateLimit(c *gin.Context, relayInfo *relaycommon.RelayInfo) *dto.OpenAIErrorWithStatusCode {
+	scopes := getTokenRateLimitScopes(relayInfo)
+	if len(scopes) == 0 {
+		return nil
+	}
+	tokens := relayInfo.PromptTokens
+	now := time.Now()
+	minute := now.Unix() / 60
+	reserved := make([]tokenRateLimitScope, 0, len(scopes))
+	headerLimit := 0
+	headerRemaining := int64(-1)
+	for _, scope := range scopes {
+		current, prev, err := incrTokenRateLimit(scope, minute, tokens)
+		if err != nil {
+			// 计数不可用时不阻塞请求
+			common.SysError(fmt.Sprintf("failed to reserve tpm for %s %s: %s", scope.name, scope.id, err.Error()))
+			continue
+		}
+		reserved = append(reserved, scope)
+		used := slidingTokenCount(current, prev, now)
+		remaining := int64(scope.limit) - used
+		if headerRemaining < 0 || remaining < headerRemaining {
+			headerLimit = scope.limit
+			headerRemaining = remaining
+		}
+		if used > int64(scope.limit) {
+			for _, s := range reserved {
+				_, _, _ = incrTokenRateLimit(s, minute, -tokens)
+			}
+			setTokenRateLimitHeaders(c, scope.limit, remaining+int64(tokens), now)
+			return OpenAIErrorWrapperLocal(fmt.Errorf("rate limit reached for %s tokens per minute: limit %d, used %d, requested %d",
+				scope.name, scope.limit, used-int64(tokens), tokens), "rate_limit_exceeded", http.StatusTooManyRequests)
+		}
+	}
+	if headerRemaining >= 0 {
+		setTokenRateLimitHeaders(c, headerLimit, headerRemaining, now)
+	}
+	relayInfo.RateLimitTokens = tokens
+	relayInfo.RateLimitMinute = minute
+	return nil
+}
+
+// ReconcileTokenRateLimit 用实际消耗的 token 数修正预留的 TPM 额度
+func ReconcileTokenRateLimit(relayInfo *relaycommon.RelayInfo, actualTokens int) {
+	if relayInfo.RateLimitMinute == 0 {
+		return
+	}
+	delta := actualTokens - relayInfo.RateLimitTokens
+	minute := relayInfo.RateLimitMinute
+	relayInfo.RateLimitTokens = 0
+	relayInfo.RateLimitMinute = 0
+	if delta == 0 {
+		return
+	}
+	for _, scope := range getTokenRateLimitScopes(relayInfo) {
+		if _, _, err := incrTokenRateLimit(scope, minute, delta); err != nil {
+			common.SysError(fmt.Sprintf("failed to reconcile tpm for %s %s: %s", scope.name, scope.id, err.Error()))
+		}
+	}
+}
+
+// ReleaseTokenRateLimit 请求失败时释放预留的 TPM 额度
+func ReleaseTokenRateLimit(relayInfo *relaycommon.RelayInfo) {
+	ReconcileTokenRateLimit(relayInfo, 0)
+}
+
+// IsGatewayRateLimitHeader 判断响应头是否为网关已设置的限流头，复制上游响应头时不应覆盖
+func IsGatewayRateLimitHeader(c *gin.Context, key string) bool {
+	return strings.HasPrefix(strings.ToLower(key), "x-ratelimit-") && c.Writer.Header().Get(key) != ""
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	relaycommon "one-api/relay/common"
+	"one-api/setting/operation_setting"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTokenRateLimitSetting 修改 TPM 配置并使用内存计数，测试结束后恢复
+func useTokenRateLimitSetting(t *testing.T, setting operation_setting.TokenRateLimitSetting) {
+	current := operation_setting.GetTokenRateLimitSetting()
+	saved := *current
+	redisEnabled := common.RedisEnabled
+	common.RedisEnabled = false
+	*current = setting
+	reset := func() {
+		tokenRateLimitLock.Lock()
+		tokenRateLimitCounters = make(map[string]int64)
+		tokenRateLimitLock.Unlock()
+	}
+	reset()
+	t.Cleanup(func() {
+		*current = saved
+		common.RedisEnabled = redisEnabled
+		reset()
+	})
+}
+
+// TestReserveTokenRateLimit 测试按提示 token 数预留 TPM，超出限制时返回 429 并撤销本次预留
+func TestReserveTokenRateLimit(t *testing.T) {
+	useTokenRateLimitSetting(t, operation_setting.TokenRateLimitSetting{Enabled: true, UserTPM: 1000, TokenTPM: 800})
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	info := &relaycommon.RelayInfo{UserId: 1, TokenId: 2, Group: "default", PromptTokens: 600}
+	assert.Nil(t, ReserveTokenRateLimit(c, info))
+	assert.Equal(t, "800", c.Writer.Header().Get("x-ratelimit-limit-tokens"))
+	assert.Equal(t, "200", c.Writer.Header().Get("x-ratelimit-remaining-tokens"))
+	assert.Equal(t, 600, info.RateLimitTokens)
+
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	other := &relaycommon.RelayInfo{UserId: 1, TokenId: 3, Group: "default", PromptTokens: 500}
+	err := ReserveTokenRateLimit(c, other)
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, err.StatusCode)
+	assert.Zero(t, other.RateLimitMinute)
+
+	// 撤销预留后同一令牌的其他请求仍可通过
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	other.PromptTokens = 400
+	assert.Nil(t, ReserveTokenRateLimit(c, other))
+}
+
+// TestReconcileTokenRateLimit 测试按实际消耗修正预留，请求失败时释放全部预留
+func TestReconcileTokenRateLimit(t *testing.T) {
+	useTokenRateLimitSetting(t, operation_setting.TokenRateLimitSetting{Enabled: true, GroupTPM: map[string]int{"vip": 1000}})
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	info := &relaycommon.RelayInfo{UserId: 1, TokenId: 2, Group: "vip", PromptTokens: 500}
+	require.Nil(t, ReserveTokenRateLimit(c, info))
+	ReconcileTokenRateLimit(info, 900)
+
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	next := &relaycommon.RelayInfo{UserId: 2, TokenId: 3, Group: "vip", PromptTokens: 200}
+	assert.NotNil(t, ReserveTokenRateLimit(c, next))
+
+	failed := &relaycommon.RelayInfo{UserId: 3, TokenId: 4, Group: "vip", PromptTokens: 50}
+	require.Nil(t, ReserveTokenRateLimit(c, failed))
+	ReleaseTokenRateLimit(failed)
+	ReleaseTokenRateLimit(failed)
+	next.PromptTokens = 100
+	assert.Nil(t, ReserveTokenRateLimit(c, next))
+
+	// 未启用时不限流
+	operation_setting.GetTokenRateLimitSetting().Enabled = false
+	next.PromptTokens = 10000
+	assert.Nil(t, ReserveTokenRateLimit(c, next))
+}
+
+// TestSlidingTokenCount 测试上一分钟的计数按当前分钟剩余比例加权
+func TestSlidingTokenCount(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 15, 0, time.UTC)
+	assert.Equal(t, int64(175), slidingTokenCount(100, 100, now))
+	assert.Equal(t, int64(0), slidingTokenCount(-5, -5, now))
+}
@@ -0,0 +1,28 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// AdminAuditSetting 管理操作审计配置，记录配置、渠道、用户额度与授权码的修改前后差异
+type AdminAuditSetting struct {
+	Enabled bool `json:"enabled"`
+	// 开启后审计表只允许追加，应用层拒绝修改与删除，并在开启时与启动时为数据库安装触发器
+	AppendOnly bool `json:"append_only"`
+	// 需要脱敏的字段名，名称以 key、secret、token、password 结尾的字段与加密存储的字段始终脱敏
+	MaskFields []string `json:"mask_fields"`
+}
+
+// 默认配置
+var adminAuditSetting = AdminAuditSetting{
+	Enabled:    true,
+	AppendOnly: false,
+	MaskFields: []string{"code", "machine_code"},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("admin_audit_setting", &adminAuditSetting)
+}
+
+func GetAdminAuditSetting() *AdminAuditSetting {
+	return &adminAuditSetting
+}
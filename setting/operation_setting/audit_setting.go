@@ -0,0 +1,51 @@
+package operation_setting
+
+import (
+	"one-api/setting/config"
+	"slices"
+)
+
+// AuditSetting 请求与响应内容审计配置，开启后对指定分组或开启了审计的令牌保存请求体与响应内容
+type AuditSetting struct {
+	Enabled bool `json:"enabled"`
+	// 需要审计的分组，令牌单独开启审计时不受此限制
+	Groups []string `json:"groups"`
+	// 单个请求体或响应内容保存的最大字节数，超出部分截断
+	MaxBodyBytes int `json:"max_body_bytes"`
+	// 审计记录保留天数，0 表示不自动清理
+	RetentionDays int `json:"retention_days"`
+	// 将 base64 图片等长 base64 内容替换为占位符
+	RedactBase64 bool `json:"redact_base64"`
+	// 需要脱敏的 JSON 字段名，字段的字符串值会被替换
+	RedactFields []string `json:"redact_fields"`
+	// 需要脱敏的正则表达式，匹配内容会被替换
+	RedactPatterns []string `json:"redact_patterns"`
+}
+
+// 默认配置
+var auditSetting = AuditSetting{
+	Enabled:        false,
+	Groups:         []string{},
+	MaxBodyBytes:   64 * 1024,
+	RetentionDays:  30,
+	RedactBase64:   true,
+	RedactFields:   []string{},
+	RedactPatterns: []string{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("audit_setting", &auditSetting)
+}
+
+func GetAuditSetting() *AuditSetting {
+	return &auditSetting
+}
+
+// ShouldAudit 判断请求是否需要审计
+func ShouldAudit(group string, tokenAuditEnabled bool) bool {
+	if !auditSetting.Enabled {
+		return false
+	}
+	return tokenAuditEnabled || slices.Contains(auditSetting.Groups, group)
+}
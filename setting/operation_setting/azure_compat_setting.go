@@ -0,0 +1,37 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// AzureCompatSetting Azure OpenAI 兼容接口配置，未配置映射的部署名直接作为模型名
+type AzureCompatSetting struct {
+	// 部署名到模型名的映射
+	DeploymentModels map[string]string `json:"deployment_models"`
+	// 按分组配置的部署名映射，优先于 DeploymentModels
+	GroupDeploymentModels map[string]map[string]string `json:"group_deployment_models"`
+}
+
+// 默认配置
+var azureCompatSetting = AzureCompatSetting{
+	DeploymentModels:      map[string]string{},
+	GroupDeploymentModels: map[string]map[string]string{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("azure_compat_setting", &azureCompatSetting)
+}
+
+func GetAzureCompatSetting() *AzureCompatSetting {
+	return &azureCompatSetting
+}
+
+// GetDeploymentModel 返回部署名对应的模型名
+func GetDeploymentModel(group string, deployment string) string {
+	if modelName, ok := azureCompatSetting.GroupDeploymentModels[group][deployment]; ok && modelName != "" {
+		return modelName
+	}
+	if modelName, ok := azureCompatSetting.DeploymentModels[deployment]; ok && modelName != "" {
+		return modelName
+	}
+	return deployment
+}
@@ -0,0 +1,24 @@
+package operation_setting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGetDeploymentModel 测试分组映射优先于全局映射，未配置映射的部署名直接作为模型名
+func TestGetDeploymentModel(t *testing.T) {
+	saved := azureCompatSetting
+	t.Cleanup(func() {
+		azureCompatSetting = saved
+	})
+	azureCompatSetting = AzureCompatSetting{
+		DeploymentModels:      map[string]string{"prod-gpt": "gpt-4o", "empty": ""},
+		GroupDeploymentModels: map[string]map[string]string{"vip": {"prod-gpt": "gpt-4.1"}},
+	}
+
+	assert.Equal(t, "gpt-4.1", GetDeploymentModel("vip", "prod-gpt"))
+	assert.Equal(t, "gpt-4o", GetDeploymentModel("default", "prod-gpt"))
+	assert.Equal(t, "gpt-4o-mini", GetDeploymentModel("vip", "gpt-4o-mini"))
+	assert.Equal(t, "empty", GetDeploymentModel("default", "empty"))
+}
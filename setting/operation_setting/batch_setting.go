@@ -0,0 +1,43 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// BatchSetting Batch API 与文件存储配置
+type BatchSetting struct {
+	Enabled bool `json:"enabled"`
+	// 文件存储类型，目前支持 local
+	StorageType string `json:"storage_type"`
+	// 本地存储目录
+	StorageDir string `json:"storage_dir"`
+	// 单个上传文件的最大字节数
+	MaxFileBytes int64 `json:"max_file_bytes"`
+	// 单个批处理任务的最大请求数
+	MaxRequests int `json:"max_requests"`
+	// 批处理请求的计费折扣倍率
+	DiscountRatio float64 `json:"discount_ratio"`
+	// 单个批处理任务的并发请求数
+	Concurrency int `json:"concurrency"`
+	// 后台任务轮询间隔
+	PollSeconds int `json:"poll_seconds"`
+}
+
+// 默认配置
+var batchSetting = BatchSetting{
+	Enabled:       false,
+	StorageType:   "local",
+	StorageDir:    "./data/files",
+	MaxFileBytes:  200 << 20,
+	MaxRequests:   50000,
+	DiscountRatio: 0.5,
+	Concurrency:   4,
+	PollSeconds:   10,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("batch_setting", &batchSetting)
+}
+
+func GetBatchSetting() *BatchSetting {
+	return &batchSetting
+}
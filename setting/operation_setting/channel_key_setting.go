@@ -0,0 +1,26 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// ChannelKeySetting 多密钥渠道的单密钥冷却配置
+type ChannelKeySetting struct {
+	// 密钥遇到 429 等限流错误后的冷却时间
+	CooldownSeconds int `json:"cooldown_seconds"`
+	// 单个密钥被禁用后是否通知管理员
+	NotifyOnKeyDisabled bool `json:"notify_on_key_disabled"`
+}
+
+// 默认配置
+var channelKeySetting = ChannelKeySetting{
+	CooldownSeconds:     60,
+	NotifyOnKeyDisabled: false,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_key_setting", &channelKeySetting)
+}
+
+func GetChannelKeySetting() *ChannelKeySetting {
+	return &channelKeySetting
+}
@@ -0,0 +1,47 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// ChannelRoutingSetting 自适应渠道路由配置
+type ChannelRoutingSetting struct {
+	// 启用自适应路由的分组，未列出的分组仍按静态权重选择
+	AdaptiveGroups []string `json:"adaptive_groups"`
+	// EWMA 平滑系数，越大越偏向最近的请求
+	EWMAAlpha float64 `json:"ewma_alpha"`
+	// 样本数达到该值后才参与调整权重
+	MinSamples int `json:"min_samples"`
+	// 错误率惩罚指数，权重乘以 (1-错误率)^ErrorPenalty
+	ErrorPenalty float64 `json:"error_penalty"`
+	// 超过该时间没有新样本的统计视为过期
+	StaleSeconds int `json:"stale_seconds"`
+	// 单个渠道权重调整倍数的上限，同时以其倒数作为下限，保证慢渠道仍有少量流量
+	MaxWeightFactor float64 `json:"max_weight_factor"`
+}
+
+// 默认配置
+var channelRoutingSetting = ChannelRoutingSetting{
+	AdaptiveGroups:  []string{},
+	EWMAAlpha:       0.2,
+	MinSamples:      5,
+	ErrorPenalty:    2,
+	StaleSeconds:    600,
+	MaxWeightFactor: 10,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_routing_setting", &channelRoutingSetting)
+}
+
+func GetChannelRoutingSetting() *ChannelRoutingSetting {
+	return &channelRoutingSetting
+}
+
+func IsAdaptiveRoutingGroup(group string) bool {
+	for _, g := range channelRoutingSetting.AdaptiveGroups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
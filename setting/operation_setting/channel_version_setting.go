@@ -0,0 +1,23 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// ChannelVersionSetting 渠道配置历史版本配置
+type ChannelVersionSetting struct {
+	// 每个渠道保留的最大版本数，超出时删除最旧的版本，0 表示不限制
+	MaxVersions int `json:"max_versions"`
+}
+
+// 默认配置
+var channelVersionSetting = ChannelVersionSetting{
+	MaxVersions: 100,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_version_setting", &channelVersionSetting)
+}
+
+func GetChannelVersionSetting() *ChannelVersionSetting {
+	return &channelVersionSetting
+}
@@ -0,0 +1,43 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// CircuitBreakerSetting 渠道熔断配置
+type CircuitBreakerSetting struct {
+	Enabled bool `json:"enabled"`
+	// 统计错误率的滚动窗口
+	WindowSeconds int `json:"window_seconds"`
+	// 窗口内请求数达到该值后才按错误率熔断
+	MinRequests int `json:"min_requests"`
+	// 错误率阈值，0-1
+	ErrorRateThreshold float64 `json:"error_rate_threshold"`
+	// 连续失败次数阈值，0 表示不按连续失败熔断
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// 熔断后进入半开状态前的等待时间
+	OpenSeconds int `json:"open_seconds"`
+	// 半开状态下同时放行的探测请求数
+	HalfOpenMaxProbes int `json:"half_open_max_probes"`
+	// 半开状态下连续成功多少次后恢复
+	HalfOpenSuccessThreshold int `json:"half_open_success_threshold"`
+}
+
+// 默认配置
+var circuitBreakerSetting = CircuitBreakerSetting{
+	Enabled:                  false,
+	WindowSeconds:            60,
+	MinRequests:              20,
+	ErrorRateThreshold:       0.5,
+	ConsecutiveFailures:      5,
+	OpenSeconds:              30,
+	HalfOpenMaxProbes:        1,
+	HalfOpenSuccessThreshold: 3,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("circuit_breaker_setting", &circuitBreakerSetting)
+}
+
+func GetCircuitBreakerSetting() *CircuitBreakerSetting {
+	return &circuitBreakerSetting
+}
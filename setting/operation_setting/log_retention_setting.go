@@ -0,0 +1,47 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// LogRetentionSetting 日志定期清理配置，清理前汇总为按天统计并可归档到本地文件
+type LogRetentionSetting struct {
+	Enabled bool `json:"enabled"`
+	// 各类型日志的保留天数，0 表示永久保留
+	ConsumeDays int `json:"consume_days"`
+	ErrorDays   int `json:"error_days"`
+	ManageDays  int `json:"manage_days"`
+	SystemDays  int `json:"system_days"`
+	TopupDays   int `json:"topup_days"`
+	// 删除前导出为 gzip 压缩的 NDJSON 文件
+	ArchiveEnabled bool `json:"archive_enabled"`
+	// 归档目录
+	ArchiveDir string `json:"archive_dir"`
+	// 每批处理的日志条数
+	BatchSize int `json:"batch_size"`
+	// 每天执行清理的时间（0-23 点）
+	RunHour int `json:"run_hour"`
+	// 最近一次执行清理的日期，由清理任务写入
+	LastRunDay string `json:"last_run_day"`
+}
+
+// 默认配置
+var logRetentionSetting = LogRetentionSetting{
+	Enabled:        false,
+	ConsumeDays:    0,
+	ErrorDays:      0,
+	ManageDays:     0,
+	SystemDays:     0,
+	TopupDays:      0,
+	ArchiveEnabled: false,
+	ArchiveDir:     "./data/log_archive",
+	BatchSize:      1000,
+	RunHour:        3,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("log_retention_setting", &logRetentionSetting)
+}
+
+func GetLogRetentionSetting() *LogRetentionSetting {
+	return &logRetentionSetting
+}
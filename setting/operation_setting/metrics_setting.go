@@ -0,0 +1,28 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// MetricsSetting Prometheus 指标接口配置
+type MetricsSetting struct {
+	Enabled bool `json:"enabled"`
+	// 抓取时使用的 Bearer Token
+	Token string `json:"token"`
+	// 允许无需 Token 抓取的 IP
+	AllowIps []string `json:"allow_ips"`
+}
+
+// 默认配置
+var metricsSetting = MetricsSetting{
+	Enabled:  false,
+	Token:    "",
+	AllowIps: []string{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("metrics_setting", &metricsSetting)
+}
+
+func GetMetricsSetting() *MetricsSetting {
+	return &metricsSetting
+}
@@ -0,0 +1,56 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// ResponseCacheSetting 精确匹配响应缓存配置
+type ResponseCacheSetting struct {
+	Enabled bool `json:"enabled"`
+	// 对这些分组的所有令牌启用缓存，其余令牌需单独开启
+	Groups []string `json:"groups"`
+	// 缓存有效期
+	TTLSeconds int `json:"ttl_seconds"`
+	// 命中缓存时按正常费用乘以该倍率计费
+	HitRatio float64 `json:"hit_ratio"`
+	// 是否在不同用户之间共享缓存
+	ShareAcrossUsers bool `json:"share_across_users"`
+	// 单条缓存的最大字节数，超过则不缓存
+	MaxEntryBytes int `json:"max_entry_bytes"`
+	// 未启用 Redis 时内存缓存的最大条目数
+	MemoryMaxEntries int `json:"memory_max_entries"`
+}
+
+// 默认配置
+var responseCacheSetting = ResponseCacheSetting{
+	Enabled:          false,
+	Groups:           []string{},
+	TTLSeconds:       3600,
+	HitRatio:         0.1,
+	ShareAcrossUsers: false,
+	MaxEntryBytes:    1 << 20,
+	MemoryMaxEntries: 1000,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("response_cache_setting", &responseCacheSetting)
+}
+
+func GetResponseCacheSetting() *ResponseCacheSetting {
+	return &responseCacheSetting
+}
+
+// IsResponseCacheEnabled 判断当前令牌或分组是否启用了响应缓存
+func IsResponseCacheEnabled(group string, tokenEnabled bool) bool {
+	if !responseCacheSetting.Enabled {
+		return false
+	}
+	if tokenEnabled {
+		return true
+	}
+	for _, g := range responseCacheSetting.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// ResponseStoreSetting /v1/responses 会话状态存储配置
+type ResponseStoreSetting struct {
+	// 是否在网关中保存原生 Responses 渠道的响应，用于跨渠道的 previous_response_id 与响应查询；
+	// 转换为 Chat Completions 的渠道始终保存，否则无法支持 previous_response_id
+	Enabled bool `json:"enabled"`
+	// 保存时长（小时），0 表示永久保存，对所有渠道生效
+	RetentionHours int `json:"retention_hours"`
+	// 按令牌隔离，关闭时同一用户的令牌之间可以互相引用
+	ScopeByToken bool `json:"scope_by_token"`
+	// 单个流式响应捕获的最大字节数，超过时不保存
+	MaxCaptureBytes int `json:"max_capture_bytes"`
+}
+
+// 默认配置
+var responseStoreSetting = ResponseStoreSetting{
+	Enabled:         false,
+	RetentionHours:  720,
+	ScopeByToken:    true,
+	MaxCaptureBytes: 8 << 20,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("response_store_setting", &responseStoreSetting)
+}
+
+func GetResponseStoreSetting() *ResponseStoreSetting {
+	return &responseStoreSetting
+}
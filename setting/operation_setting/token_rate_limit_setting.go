@@ -0,0 +1,39 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// TokenRateLimitSetting 每分钟 token 数（TPM）限流配置，0 表示不限制
+type TokenRateLimitSetting struct {
+	Enabled bool `json:"enabled"`
+	// 每个用户的 TPM
+	UserTPM int `json:"user_tpm"`
+	// 每个令牌的 TPM
+	TokenTPM int `json:"token_tpm"`
+	// 分组内所有请求合计的 TPM
+	GroupTPM map[string]int `json:"group_tpm"`
+}
+
+// 默认配置
+var tokenRateLimitSetting = TokenRateLimitSetting{
+	Enabled:  false,
+	UserTPM:  0,
+	TokenTPM: 0,
+	GroupTPM: map[string]int{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("token_rate_limit_setting", &tokenRateLimitSetting)
+}
+
+func GetTokenRateLimitSetting() *TokenRateLimitSetting {
+	return &tokenRateLimitSetting
+}
+
+// GetGroupTPM 返回分组的 TPM 限制，未配置时返回 0
+func GetGroupTPM(group string) int {
+	if tokenRateLimitSetting.GroupTPM == nil {
+		return 0
+	}
+	return tokenRateLimitSetting.GroupTPM[group]
+}